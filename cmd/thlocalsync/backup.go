@@ -1,17 +1,31 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
 	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/process"
 	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
 	"github.com/spf13/cobra"
 )
 
 var (
 	backupList    bool
 	backupRestore string
+	backupForce   bool
+	backupSlot    string
+	backupToLocal bool
+	backupAll     bool
+	backupPin     string
+	backupUnpin   string
+	backupCreate  bool
 )
 
 var backupCmd = &cobra.Command{
@@ -21,17 +35,60 @@ var backupCmd = &cobra.Command{
 
 使用例:
   thlocalsync backup th08 --list          履歴一覧を表示
-  thlocalsync backup th08 --restore <name> 指定バックアップを復元`,
-	Args: cobra.ExactArgs(1),
+  thlocalsync backup th08 --restore <name> 指定バックアップを復元
+  thlocalsync backup th08 --restore <name> --force  同一内容でも復元
+  thlocalsync backup th08 --restore <name> --to-local  ローカルのゲームへ直接復元
+  thlocalsync backup th08 --slot scoring --list    スロット "scoring" の履歴を表示
+  thlocalsync backup th08 --pin <name>    指定バックアップを保護（履歴の自動削除から除外）
+  thlocalsync backup th08 --unpin <name>  保護を解除
+  thlocalsync backup th08 --create        現在のvaultファイルをバックアップ
+  thlocalsync backup th08 --create --force  直前のバックアップと同一でもバックアップ
+  thlocalsync backup --all --list         全タイトルの履歴数・最新日時・使用容量を表示
+
+--to-local を指定すると、vaultではなくこのデバイスの優先ローカルパスへ復元します。
+ゲーム実行中やファイルロック中は復元を拒否します（--force で無視）。
+復元前に現在のローカルファイルは履歴へバックアップされます。
+
+--pin はファイル名のタイムスタンプの後ろに "pinned" を付けて名前を変更します
+（例: 2025-11-11T06-20-30Z-pinned-score.dat）。保護されたバックアップは
+history_limit・history_max_age_days による削除の対象にならず、件数にも数えられません。
+
+バックアップは、同じファイルの最新のバックアップと内容が同じなら作成されません
+（pull/push の自動バックアップも同様）。--create --force で明示的に作成できます。`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runBackup,
 }
 
 func init() {
 	backupCmd.Flags().BoolVarP(&backupList, "list", "l", false, "バックアップ履歴を一覧表示")
 	backupCmd.Flags().StringVarP(&backupRestore, "restore", "r", "", "指定バックアップを復元")
+	backupCmd.Flags().BoolVarP(&backupForce, "force", "f", false, "現在のファイルと同一でも復元・作成（--to-local ではゲーム実行中の警告も無視）")
+	backupCmd.Flags().BoolVar(&backupToLocal, "to-local", false, "vaultではなくローカルのセーブデータへ復元")
+	backupCmd.Flags().StringVar(&backupSlot, "slot", backup.DefaultSlot, "対象のvaultスロット")
+	backupCmd.Flags().StringVar(&backupPin, "pin", "", "指定バックアップを保護（履歴の自動削除から除外）")
+	backupCmd.Flags().StringVar(&backupUnpin, "unpin", "", "指定バックアップの保護を解除")
+	backupCmd.Flags().BoolVar(&backupCreate, "create", false, "現在のvaultファイルのバックアップを作成（最新のバックアップと同一なら作成しない）")
+	backupCmd.Flags().BoolVar(&backupAll, "all", false, "vault内の全タイトルの履歴を集計して表示（--list と併用）")
 }
 
 func runBackup(cmd *cobra.Command, args []string) error {
+	listing := backupRestore == "" && backupPin == "" && backupUnpin == "" && !backupCreate
+	if listing {
+		checkReadOnlyStorage()
+	}
+
+	if backupAll {
+		if len(args) > 0 {
+			return errors.New("--all cannot be combined with a title argument")
+		}
+		if !listing {
+			return errors.New("--all can only list backups")
+		}
+		return listAllBackups()
+	}
+	if len(args) == 0 {
+		return errors.New("a title is required (or use --all --list)")
+	}
 	title := args[0]
 
 	// Validate title code
@@ -39,26 +96,56 @@ func runBackup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid title code: %s", title)
 	}
 
-	fmt.Printf("=== thlocalsync backup: %s ===\n\n", title)
+	if err := backup.ValidateSlot(backupSlot); err != nil {
+		return err
+	}
 
-	// Determine vault file name
-	titleInfo := pathdetect.GetTitleByCode(title)
-	var fileName string
-	if titleInfo != nil {
-		fileName = titleInfo.FileName
-	} else {
-		fileName = "score.dat"
+	// Listing is read-only
+	if !listing {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
 	}
 
+	// Apply backup compression from rules.json
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	fmt.Printf("=== thlocalsync backup: %s ===\n", title)
+	printSlot(backupSlot)
+	fmt.Println()
+
+	// Pin or unpin by renaming the backup
+	if backupPin != "" || backupUnpin != "" {
+		if backupRestore != "" || backupCreate || (backupPin != "" && backupUnpin != "") {
+			return errors.New("--pin, --unpin, --restore and --create cannot be combined")
+		}
+		return pinBackup(title)
+	}
+
+	// Determine vault file name
+	fileName := getVaultFileName(title)
+
 	// Get vault path for restoration target
-	vaultPath, err := sync.GetVaultFilePath(title, fileName)
+	vaultPath, err := sync.GetVaultFilePath(title, backupSlot, fileName)
 	if err != nil {
 		return fmt.Errorf("failed to get vault path: %w", err)
 	}
 
+	// Back up the current vault file on demand
+	if backupCreate {
+		if backupRestore != "" || backupToLocal {
+			return errors.New("--create cannot be combined with --restore or --to-local")
+		}
+		return createBackup(title, vaultPath)
+	}
+
 	// List backups
 	if backupList || backupRestore == "" {
-		details, err := backup.GetBackupDetails(title)
+		details, err := backup.GetBackupDetails(title, backupSlot)
 		if err != nil {
 			return fmt.Errorf("failed to list backups: %w", err)
 		}
@@ -70,9 +157,13 @@ func runBackup(cmd *cobra.Command, args []string) error {
 
 		fmt.Printf("Found %d backup(s):\n\n", len(details))
 		for i, detail := range details {
-			fmt.Printf("[%d] %s\n", i+1, detail.Name)
+			if backup.IsPinned(detail.Name) {
+				fmt.Printf("[%d] %s (pinned)\n", i+1, detail.Name)
+			} else {
+				fmt.Printf("[%d] %s\n", i+1, detail.Name)
+			}
 			if !detail.Timestamp.IsZero() {
-				fmt.Printf("    Time: %s\n", detail.Timestamp.Format("2006-01-02 15:04:05 MST"))
+				fmt.Printf("    Time: %s\n", formatTimeAgo(detail.Timestamp, "2006-01-02 15:04:05 MST"))
 			}
 			if detail.Size > 0 {
 				fmt.Printf("    Size: %d bytes\n", detail.Size)
@@ -86,15 +177,27 @@ func runBackup(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Restore backup straight into the game's save directory
+	if backupToLocal {
+		return restoreBackupToLocal(title)
+	}
+
 	// Restore backup
 	if backupRestore != "" {
 		fmt.Printf("Restoring backup: %s\n", backupRestore)
 
-		err := backup.RestoreBackup(title, backupRestore, vaultPath)
+		restored, err := backup.RestoreBackup(title, backupSlot, backupRestore, vaultPath, backupForce)
 		if err != nil {
 			return fmt.Errorf("failed to restore backup: %w", err)
 		}
 
+		if !restored {
+			fmt.Printf("- Vault is already at this state, nothing restored (use --force to restore anyway)\n")
+			return nil
+		}
+
+		logVaultWrite("backup_restore", title, backupSlot, vaultPath)
+
 		fmt.Printf("✓ Successfully restored %s to vault\n", backupRestore)
 		fmt.Printf("  Target: %s\n", vaultPath)
 
@@ -103,3 +206,151 @@ func runBackup(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// createBackup backs up the title's current vault file with thlocalsync.BackupVaultFile.
+// Unless --force is given, nothing is written when the newest backup already holds the
+// same contents.
+func createBackup(title, vaultPath string) error {
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	result, err := thlocalsync.BackupVaultFile(title, backupSlot, vaultPath, backupForce, false, log)
+	if err != nil {
+		return err
+	}
+	if result.Existing != "" {
+		fmt.Printf("- Newest backup %s already has these contents, nothing created (use --force to back up anyway)\n", result.Existing)
+		return nil
+	}
+	fmt.Printf("✓ Created backup %s\n", result.Created)
+
+	return nil
+}
+
+// pinBackup sets or clears the pin marker of backupPin/backupUnpin in the title's history.
+func pinBackup(title string) error {
+	if backupPin != "" {
+		name, err := backup.PinBackup(title, backupSlot, backupPin)
+		if err != nil {
+			return fmt.Errorf("failed to pin backup: %w", err)
+		}
+		fmt.Printf("✓ Pinned %s (kept by history cleanup)\n", name)
+		return nil
+	}
+
+	name, err := backup.UnpinBackup(title, backupSlot, backupUnpin)
+	if err != nil {
+		return fmt.Errorf("failed to unpin backup: %w", err)
+	}
+	fmt.Printf("✓ Unpinned %s\n", name)
+	return nil
+}
+
+// listAllBackups prints the backup count, newest backup and disk usage of every
+// title directory in the vault, followed by the totals.
+func listAllBackups() error {
+	if err := backup.ValidateSlot(backupSlot); err != nil {
+		return err
+	}
+
+	fmt.Printf("=== thlocalsync backup: all ===\n")
+	printSlot(backupSlot)
+	fmt.Println()
+
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	vaultTitles, err := backup.ListVaultTitles(vaultDir)
+	if err != nil {
+		return err
+	}
+
+	var titles []string
+	for _, title := range vaultTitles {
+		if pathdetect.IsValidTitleCode(title) {
+			titles = append(titles, title)
+		}
+	}
+	if len(titles) == 0 {
+		fmt.Println("No titles in the vault.")
+		return nil
+	}
+
+	fmt.Printf("%-8s %8s  %-23s  %s\n", "Title", "Backups", "Newest", "Disk")
+	fmt.Println(strings.Repeat("-", 60))
+
+	var totalCount int
+	var totalBytes int64
+	for _, title := range pathdetect.SortTitlesByRelease(titles) {
+		details, err := backup.GetBackupDetails(title, backupSlot)
+		if err != nil {
+			fmt.Printf("%-8s ERROR: %v\n", title, err)
+			continue
+		}
+
+		summary := backup.Summarize(title, details)
+		newest := "-"
+		if !summary.Newest.IsZero() {
+			newest = formatTimeAgo(summary.Newest, "2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-8s %8d  %-23s  %s\n", title, summary.Count, newest, formatBytes(summary.DiskBytes))
+
+		totalCount += summary.Count
+		totalBytes += summary.DiskBytes
+	}
+
+	fmt.Printf("\nTotal: %d backup(s) in %d title(s), %s on disk\n", totalCount, len(titles), formatBytes(totalBytes))
+	return nil
+}
+
+// restoreBackupToLocal restores backupRestore to this device's preferred local path.
+// The same safety check as push applies, so a running game or locked file blocks
+// the restore unless --force is given.
+func restoreBackupToLocal(title string) error {
+	deviceID, _, _, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	localPath, err := sync.GetPreferredLocalPath(pathsConfig, title, deviceID)
+	if err != nil {
+		return fmt.Errorf("no local path configured: %w", err)
+	}
+
+	// Check if it's safe to write to local file
+	safe, reason, err := process.CanSafelyWrite(localPath, title)
+	if err != nil {
+		return fmt.Errorf("failed to check if safe to write: %w", err)
+	}
+	if !safe {
+		if !backupForce {
+			return fmt.Errorf("cannot restore to local: %s (use --force to override)", reason)
+		}
+		fmt.Printf("⚠ %s (continuing because of --force)\n", reason)
+	}
+
+	fmt.Printf("Restoring backup to local: %s\n", backupRestore)
+
+	restored, err := backup.RestoreBackup(title, backupSlot, backupRestore, localPath, backupForce)
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	if !restored {
+		fmt.Printf("- Local file is already at this state, nothing restored (use --force to restore anyway)\n")
+		return nil
+	}
+
+	fmt.Printf("✓ Successfully restored %s to local\n", backupRestore)
+	fmt.Printf("  Target: %s\n", localPath)
+
+	return nil
+}
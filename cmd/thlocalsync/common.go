@@ -2,22 +2,605 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/hook"
+	"github.com/otagao/touhou-local-sync/pkg/lock"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/process"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
+// exitInterrupted is the exit code after an interrupt or termination signal
+const exitInterrupted = 130
+
+// acquireRunLock takes data/.lock for a command that writes to data/ or the vault and returns
+// the function that releases it. Read-only commands and dry runs do not take the lock.
+// An interrupt or termination signal releases the lock and exits, unless handleSignals is
+// false because the command stops gracefully on those signals and releases the lock itself.
+func acquireRunLock(cmd *cobra.Command, handleSignals bool) (func(), error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	l, err := lock.Acquire(configDir, cmd.CommandPath())
+	if err != nil {
+		if errors.Is(err, lock.ErrLocked) {
+			// Another run in progress is a result, not a usage mistake
+			cmd.SilenceUsage = true
+			return nil, fmt.Errorf("%w; wait for it to finish (a lock older than %s is taken over automatically)", err, lock.StaleAfter)
+		}
+		return nil, err
+	}
+	if l.Stale != nil {
+		fmt.Fprintf(os.Stderr, "warning: took over a stale lock left by '%s' (PID %d)\n", l.Stale.Command, l.Stale.PID)
+	}
+
+	release := func() {
+		if err := l.Release(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	if !handleSignals {
+		return release, nil
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if _, ok := <-signals; ok {
+			release()
+			os.Exit(exitInterrupted)
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+		release()
+	}, nil
+}
+
+// readOnlyStorage is set by checkReadOnlyStorage when the portable storage is write-protected.
+// Nothing is written then: no log cleanup, log entries or hash cache.
+var readOnlyStorage bool
+
+// checkReadOnlyStorage detects write-protected portable storage before a read-only command
+// (status, backup --list, verify) runs, so it can skip every write instead of failing on one.
+// The note goes to stderr to keep --json output intact.
+func checkReadOnlyStorage() {
+	// Logs go to the log directory and the hash cache to data/, which may live apart
+	for _, resolve := range []func() (string, error){paths.GetLogDir, paths.GetConfigDir} {
+		dir, err := resolve()
+		if err != nil {
+			continue
+		}
+		// A directory not created yet is written to through its parent
+		if !utils.DirExists(dir) {
+			dir = filepath.Dir(dir)
+		}
+		if !utils.DirExists(dir) || utils.IsDirWritable(dir) {
+			continue
+		}
+
+		readOnlyStorage = true
+		fmt.Fprintf(os.Stderr, "Note: %s is write-protected; running read-only (logs and the hash cache are not updated)\n", dir)
+		return
+	}
+}
+
+// withRunLock wraps the RunE of a command that always writes, holding the run lock while it runs.
+func withRunLock(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return run(cmd, args)
+	}
+}
+
 // getCurrentTime returns the current time in UTC.
 func getCurrentTime() time.Time {
 	return time.Now().UTC()
 }
 
+// errStorageRemoved is returned when the vault disappears or stops accepting writes
+// in the middle of a run, typically because the portable storage was unplugged.
+var errStorageRemoved = errors.New("portable storage removed")
+
+// checkVaultAvailable checks that the vault directory still exists and is writable, so that
+// a run stops cleanly when the storage goes away instead of failing every remaining title.
+// Before the first write the vault may not exist yet, in which case its parent is checked.
+func checkVaultAvailable() error {
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errStorageRemoved, err)
+	}
+
+	dir := vaultDir
+	if !utils.DirExists(dir) {
+		dir = filepath.Dir(vaultDir)
+		if !utils.DirExists(dir) {
+			return fmt.Errorf("%w: %s is no longer available", errStorageRemoved, vaultDir)
+		}
+	}
+
+	probe, err := os.CreateTemp(dir, ".tmp-probe-*")
+	if err != nil {
+		return fmt.Errorf("%w: %s is not writable (%v)", errStorageRemoved, dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// checkVaultBeforeTitle runs checkVaultAvailable before titles[i] is processed and, if the
+// storage is gone, reports it together with the titles that are left unprocessed.
+func checkVaultBeforeTitle(command string, titles []string, i int, deviceID string, log *logger.Logger) error {
+	err := checkVaultAvailable()
+	if err == nil {
+		return nil
+	}
+
+	fmt.Printf("✗ %v\n", err)
+	fmt.Printf("  Skipped %d remaining title(s): %s\n", len(titles)-i, strings.Join(titles[i:], ", "))
+	log.Error(command+"_storage_removed", map[string]interface{}{
+		"device":  deviceID,
+		"error":   err.Error(),
+		"skipped": titles[i:],
+	})
+	return err
+}
+
+// removeStaleTempFiles deletes the temporary files an interrupted run left in data/ and the vault.
+func removeStaleTempFiles(log *logger.Logger) {
+	var dirs []string
+	if configDir, err := config.GetConfigDir(); err == nil {
+		dirs = append(dirs, configDir)
+	}
+	if vaultDir, err := backup.GetVaultDir(); err == nil {
+		dirs = append(dirs, vaultDir)
+	}
+
+	var removed []string
+	for _, dir := range dirs {
+		files, err := utils.CleanupTempFiles(dir)
+		removed = append(removed, files...)
+		if err != nil {
+			log.Warn("temp_cleanup_failed", map[string]interface{}{
+				"dir":   dir,
+				"error": err.Error(),
+			})
+		}
+	}
+	if len(removed) > 0 {
+		console.Printf("Removed %d stale temporary file(s) left by an interrupted run\n\n", len(removed))
+		log.Info("temp_cleanup", map[string]interface{}{
+			"files": removed,
+		})
+	}
+}
+
+// formatTimeAgo renders t relative to now ("2 hours ago"). With --verbose the absolute
+// time in layout is shown as well, so no precision is lost.
+func formatTimeAgo(t time.Time, layout string) string {
+	if console.IsVerbose() {
+		return fmt.Sprintf("%s (%s)", t.Format(layout), utils.HumanizeTime(t))
+	}
+	return utils.HumanizeTime(t)
+}
+
+// recordDeviceSeen updates this device's entry in devices.json (last seen, OS, tool version).
+// If the device ID changed, the entry and its paths.json entries are migrated first.
+// Returns the updated device record. Failures are non-fatal for the calling command.
+func recordDeviceSeen(deviceID, macHash, hostname string) (*models.Device, error) {
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateDeviceID(devicesConfig, deviceID, hostname); err != nil {
+		return nil, err
+	}
+
+	updateDeviceConfig(devicesConfig, deviceID, hostname, macHash)
+
+	if err := config.SaveDevices(devicesConfig); err != nil {
+		return nil, err
+	}
+
+	for i := range devicesConfig.Devices {
+		if devicesConfig.Devices[i].ID == deviceID {
+			return &devicesConfig.Devices[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// migrateDeviceID re-keys this machine's devices.json and paths.json entries
+// when its device ID has changed, saving paths.json if anything moved.
+// devicesConfig is updated in place; the caller saves it.
+func migrateDeviceID(devicesConfig *models.DeviceConfig, deviceID, hostname string) error {
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return err
+	}
+
+	oldID := device.MigrateDeviceID(devicesConfig, pathsConfig, deviceID, hostname)
+	if oldID == "" {
+		return nil
+	}
+
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return err
+	}
+
+	fmt.Printf("Device ID changed: %s → %s (registered paths migrated)\n", oldID, deviceID)
+	return nil
+}
+
+// findDevice returns this device's entry from devices.json without modifying it.
+// Returns nil if the device is not registered.
+func findDevice(deviceID string) (*models.Device, error) {
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range devicesConfig.Devices {
+		if devicesConfig.Devices[i].ID == deviceID {
+			return &devicesConfig.Devices[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applyRules loads rules.json and applies its comparison thresholds and include/exclude
+// lists to the sync package, its pre_sync/post_sync hooks, and its log limits to the
+// logger. An encrypted vault (encrypt_vault) is unlocked, prompting for the passphrase
+// if needed. It also enables the hash cache unless --no-cache is given.
+// Old log files are removed here, once the configured retention is known.
+func applyRules() error {
+	rules, err := thlocalsync.ApplyRules()
+	if err != nil {
+		return err
+	}
+
+	preSyncHook = rules.PreSync
+	postSyncHook = rules.PostSync
+
+	if err := unlockVault(rules); err != nil {
+		return err
+	}
+
+	// Reuse hashes of unchanged files; main saves the cache on exit
+	if !noHashCache {
+		if configDir, err := config.GetConfigDir(); err == nil {
+			sync.EnableHashCache(sync.LoadHashCache(filepath.Join(configDir, config.HashCacheFile)))
+		}
+	}
+
+	logger.MaxFileSize = int64(rules.LogMaxSizeKB) * 1024
+	if readOnlyStorage {
+		return nil
+	}
+	if log, err := logger.New(); err == nil {
+		if err := log.CleanupOldLogs(rules.LogRetentionDays); err != nil {
+			log.Warn("log_cleanup_failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// unlockVault applies rules.json encrypt_vault, asking for the passphrase of an
+// encrypted vault and unlocking its key.
+func unlockVault(rules *models.Rules) error {
+	passphrase, err := vaultPassphrase(rules)
+	if err != nil {
+		return err
+	}
+	return thlocalsync.EnableEncryption(rules, passphrase)
+}
+
+// vaultPassphrase returns the passphrase of an encrypted vault (rules.json encrypt_vault):
+// THLOCALSYNC_PASSPHRASE if set, otherwise typed at the terminal without echo. A vault
+// without a key file is about to get one, so the passphrase is asked for twice.
+func vaultPassphrase(rules *models.Rules) (string, error) {
+	if !rules.EncryptVault {
+		return "", nil
+	}
+	if passphrase := os.Getenv(vaultcrypt.EnvPassphrase); passphrase != "" {
+		return passphrase, nil
+	}
+	if !utils.IsInteractive() {
+		return "", fmt.Errorf("encrypt_vault is set in rules.json; set %s to run non-interactively", vaultcrypt.EnvPassphrase)
+	}
+
+	passphrase, err := readPassphrase("Vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	vaultDir, err := paths.GetVaultDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	if exists, _ := utils.FileExists(filepath.Join(vaultDir, vaultcrypt.KeyFile)); !exists {
+		fmt.Println("The vault has no key yet; this passphrase will encrypt it. It cannot be recovered if forgotten.")
+		confirm, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readPassphrase prompts for a line typed at the terminal without echoing it.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Print(prompt)
+	defer fmt.Println()
+	line, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(line), nil
+}
+
+// loadUserTitles registers the titles.json definitions so every command sees the merged title list,
+// and the executable names of every title for the game-running check. The built-in names are
+// registered even when titles.json cannot be loaded.
+func loadUserTitles() error {
+	titles, err := config.LoadTitles()
+	if err == nil {
+		pathdetect.SetUserTitles(titles.Titles)
+	} else {
+		titles = &models.TitlesConfig{}
+	}
+
+	process.SetGameProcessNames(gameProcessNames(pathdetect.GetKnownTitles(), titles.ProcessNames))
+	return err
+}
+
+// gameProcessNames collects the extra executable names per title from the known titles
+// and the process_names overrides in titles.json.
+func gameProcessNames(known []pathdetect.KnownTitle, overrides map[string][]string) map[string][]string {
+	names := make(map[string][]string)
+	for _, title := range known {
+		if len(title.ProcessNames) > 0 {
+			names[title.Code] = append(names[title.Code], title.ProcessNames...)
+		}
+	}
+	for code, extra := range overrides {
+		names[code] = append(names[code], extra...)
+	}
+	return names
+}
+
+// vaultFileNames are the vault file names set per title in paths.json. Set via loadVaultFileNames.
+var vaultFileNames map[string]string
+
+// loadVaultFileNames reads the vault file names set in paths.json, so every command
+// (including those that never load paths.json) stores titles under the same name.
+func loadVaultFileNames() error {
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return err
+	}
+	vaultFileNames = pathsConfig.VaultFileNames
+	return nil
+}
+
+// warnedUnknownTitles records titles already reported by getVaultFileName, so each is warned about once.
+var warnedUnknownTitles = make(map[string]bool)
+
+// getVaultFileName returns the save file name stored in the vault for a title: the
+// vault_file_names entry in paths.json if set, otherwise the title's save file name.
+// Unknown titles are assumed to use score<title>.dat, with a one-time warning.
+func getVaultFileName(title string) string {
+	if fileName := vaultFileNames[title]; fileName != "" {
+		return fileName
+	}
+
+	fileName, known := pathdetect.ExpectedFileName(title)
+	if !known && !warnedUnknownTitles[title] {
+		warnedUnknownTitles[title] = true
+		// stderr keeps machine-readable output (status --json) on stdout valid
+		fmt.Fprintf(os.Stderr, "⚠ %s is not a recognized title; assuming save file %s (add it to data/titles.json to configure)\n", title, fileName)
+	}
+	return fileName
+}
+
+// errTitlesWithTitleArg is returned when --titles is combined with a single title argument.
+var errTitlesWithTitleArg = errors.New("--titles cannot be combined with a title argument (use 'all' or omit it)")
+
+// logVaultWrite records the vault file's hash after a command other than pull/push/sync
+// replaced it, in the log and the vault manifest, so that verify compares against the
+// current contents.
+func logVaultWrite(message, title, slot, vaultPath string) {
+	log, err := logger.New()
+	if err != nil {
+		return
+	}
+
+	hash, err := utils.CalculateFileHash(vaultPath)
+	if err != nil {
+		log.Warn(message, map[string]interface{}{
+			"title": title,
+			"slot":  slot,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	log.Info(message, map[string]interface{}{
+		"title":      title,
+		"slot":       slot,
+		"vault_hash": hash,
+	})
+	thlocalsync.UpdateManifest(title, slot, vaultPath, hash, log)
+}
+
+// printEmptyLocal reports a 0-byte local save file that the comparison treated as
+// missing, typically left by a game that crashed while writing it.
+func printEmptyLocal(title string) {
+	fmt.Printf("⚠ %s: Local save file is empty (0 bytes), treated as missing\n", title)
+}
+
+// printEmptyRemote reports a 0-byte vault file that the comparison treated as missing.
+func printEmptyRemote(title string) {
+	fmt.Printf("⚠ %s: USB save file is empty (0 bytes), treated as missing\n", title)
+}
+
+// printWrite prints a pull or push that wrote a save file.
+func printWrite(syncer *thlocalsync.Syncer, result *sync.TitleSyncResult, reason string) {
+	comparison := result.Comparison
+	overwritten := comparison.RemoteMeta
+	if result.Action == sync.ActionPull {
+		console.Printf("✓ %s: Pulled to USB (%s)\n", result.Title, reason)
+		printCopyDetails(result.LocalPath, result.VaultPath, comparison.LocalMeta.Hash)
+	} else {
+		console.Printf("✓ %s: Pushed to local (%s)\n", result.Title, reason)
+		printCopyDetails(result.VaultPath, result.LocalPath, comparison.RemoteMeta.Hash)
+		overwritten = comparison.LocalMeta
+	}
+	if syncer.NoBackup && overwritten.Exists {
+		console.Printf("    (no backup of the overwritten file: --no-backup)\n")
+	}
+}
+
+// previewTitle compares a title like pulling or pushing it (direction) would, without
+// writing anything.
+func previewTitle(syncer *thlocalsync.Syncer, title, direction string) (*models.ComparisonResult, error) {
+	target, err := syncer.Target(title)
+	if err != nil {
+		return nil, err
+	}
+	return syncer.Preview(target, direction)
+}
+
+// syncFolders pulls or pushes (direction) the registered replay folder and title folder
+// of a title and prints the outcome. Failures never fail the title.
+func syncFolders(syncer *thlocalsync.Syncer, title, direction string) {
+	target, err := syncer.Target(title)
+	if err != nil {
+		// The save file of the title failed the same way and was reported
+		return
+	}
+
+	for _, folder := range syncer.SyncFolders(target, direction) {
+		if folder.Err != nil {
+			fmt.Printf("✗ %s/%s: %v\n", title, folder.Kind, folder.Err)
+			continue
+		}
+		if len(folder.Result.Files) == 0 {
+			continue
+		}
+
+		console.Printf("  %s/%s: %d %sed, %d skipped, %d error(s)\n", title, folder.Kind,
+			folder.Transferred, direction, folder.Result.Count("SKIP"), folder.Result.Errors())
+		for _, f := range folder.Result.Files {
+			if f.Err != nil {
+				fmt.Printf("    ✗ %s: %v\n", f.Name, f.Err)
+			}
+		}
+	}
+}
+
+// preSyncHook and postSyncHook are the pre_sync and post_sync commands from rules.json,
+// handed to every Syncer. Set via applyRules.
+var preSyncHook, postSyncHook []string
+
+// printHook prints a hook that ran (--verbose); it is the HookRan of every Syncer.
+func printHook(stage string, result *hook.Result) {
+	console.Verbosef("  %s hook: %s (%s)\n", stage, strings.Join(result.Args, " "), formatDuration(result.Duration))
+}
+
+// withHooks runs run between the pre_sync and post_sync hooks of a title with
+// Syncer.WithHooks and warns about a failed post_sync hook.
+func withHooks(syncer *thlocalsync.Syncer, direction, title string, run func() error) error {
+	postErr, err := syncer.WithHooks(direction, title, run)
+	if postErr != nil {
+		fmt.Printf("⚠ %s: %v\n", title, postErr)
+	}
+	return err
+}
+
+// printFileDetails prints the compared files of a title with full paths and hashes (--verbose).
+func printFileDetails(title string, comparison *models.ComparisonResult) {
+	if !console.IsVerbose() || comparison == nil {
+		return
+	}
+
+	sides := []struct {
+		label string
+		meta  *models.FileMetadata
+	}{
+		{"local ", comparison.LocalMeta},
+		{"remote", comparison.RemoteMeta},
+	}
+	for _, side := range sides {
+		if side.meta == nil {
+			continue
+		}
+		if !side.meta.Exists {
+			console.Verbosef("    %s %s: %s [NOT EXIST]\n", title, side.label, side.meta.Path)
+			continue
+		}
+		console.Verbosef("    %s %s: %s size=%d mtime=%s hash=%s\n", title, side.label, side.meta.Path,
+			side.meta.Size, side.meta.ModTime.Format(time.RFC3339), side.meta.Hash)
+	}
+}
+
+// printCopyDetails prints a completed copy and the verified hash of its destination (--verbose).
+func printCopyDetails(src, dest, hash string) {
+	console.Verbosef("    copied %s → %s (verified hash=%s)\n", src, dest, hash)
+}
+
+// printSlot prints the vault slot in a command header unless it is the default slot.
+func printSlot(slot string) {
+	if slot != backup.DefaultSlot {
+		console.Printf("Slot: %s\n", slot)
+	}
+}
+
 // promptUserForConflictResolution asks the user to choose between local, remote, or cancel when a conflict is detected.
+// The user can also view a byte diff of the two files, after which the menu is shown again.
+// Cancels without prompting when stdin is not interactive.
 // Returns: "local", "remote", or "cancel"
 func promptUserForConflictResolution(title string, comparison *models.ComparisonResult, operation string) string {
+	if !utils.IsInteractive() {
+		fmt.Printf("⚠ Conflict detected for %s: %s\n", title, comparison.Reason)
+		fmt.Println("   stdin is not interactive, cancelling.")
+		return "cancel"
+	}
+
 	fmt.Printf("\n⚠ Conflict detected for %s:\n", title)
 	fmt.Printf("   %s\n\n", comparison.Reason)
 
@@ -31,41 +614,330 @@ func promptUserForConflictResolution(title string, comparison *models.Comparison
 		comparison.RemoteMeta.ModTime.Format("2006-01-02 15:04:05"),
 		truncateHash(comparison.RemoteMeta.Hash))
 
-	fmt.Println("\nWhich file should be used?")
-	if operation == "pull" {
-		fmt.Println("  [l] Use local file (pull to USB)")
-		fmt.Println("  [r] Use remote file (keep USB version)")
-	} else {
-		fmt.Println("  [l] Use local file (keep local version)")
-		fmt.Println("  [r] Use remote file (push from USB)")
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Println("\nWhich file should be used?")
+		if operation == "pull" {
+			fmt.Println("  [l] Use local file (pull to USB)")
+			fmt.Println("  [r] Use remote file (keep USB version)")
+		} else if operation == "sync" {
+			fmt.Println("  [l] Use local file (pull to USB)")
+			fmt.Println("  [r] Use remote file (push to local)")
+		} else if operation == "merge" {
+			fmt.Println("  [l] Use current vault file (keep current version)")
+			fmt.Println("  [r] Use other vault file (take other version)")
+		} else {
+			fmt.Println("  [l] Use local file (keep local version)")
+			fmt.Println("  [r] Use remote file (push from USB)")
+		}
+		fmt.Println("  [d] Show byte diff")
+		fmt.Println("  [c] Cancel this operation")
+		fmt.Print("\nYour choice [l/r/d/c]: ")
+
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "cancel"
+		}
+
+		input = strings.ToLower(strings.TrimSpace(input))
+		switch input {
+		case "l", "local":
+			return "local"
+		case "r", "remote":
+			return "remote"
+		case "d", "diff":
+			// Inspect, then ask again
+			printByteDiff(comparison.LocalMeta, comparison.RemoteMeta)
+		case "c", "cancel":
+			return "cancel"
+		default:
+			fmt.Println("Invalid choice, cancelling.")
+			return "cancel"
+		}
 	}
-	fmt.Println("  [c] Cancel this operation")
-	fmt.Print("\nYour choice [l/r/c]: ")
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+const (
+	// maxDiffOffsets is the number of differing byte offsets shown by printByteDiff.
+	maxDiffOffsets = 8
+	// hexdumpWidth is the number of bytes per hexdump row.
+	hexdumpWidth = 16
+)
+
+// printByteDiff prints the first differing byte offsets between the local and remote files,
+// followed by a hexdump row of both sides around each of them.
+func printByteDiff(localMeta, remoteMeta *models.FileMetadata) {
+	local, err := utils.ReadContents(localMeta.Path)
 	if err != nil {
-		return "cancel"
+		fmt.Printf("\nFailed to read local file: %v\n", err)
+		return
+	}
+	remote, err := utils.ReadContents(remoteMeta.Path)
+	if err != nil {
+		fmt.Printf("\nFailed to read remote file: %v\n", err)
+		return
 	}
 
-	input = strings.ToLower(strings.TrimSpace(input))
-	switch input {
-	case "l", "local":
-		return "local"
-	case "r", "remote":
-		return "remote"
-	case "c", "cancel":
-		return "cancel"
-	default:
-		fmt.Println("Invalid choice, cancelling.")
-		return "cancel"
+	offsets, total := utils.DiffOffsets(local, remote, maxDiffOffsets)
+	if total == 0 {
+		fmt.Println("\nFiles are byte-identical.")
+		return
 	}
+
+	fmt.Printf("\nByte diff: %d differing byte(s)", total)
+	if int64(len(offsets)) < total {
+		fmt.Printf(", showing first %d", len(offsets))
+	}
+	fmt.Println()
+	if len(local) != len(remote) {
+		fmt.Printf("  Sizes differ: local=%d, remote=%d\n", len(local), len(remote))
+	}
+
+	hexOffsets := make([]string, len(offsets))
+	for i, offset := range offsets {
+		hexOffsets[i] = fmt.Sprintf("0x%08x", offset)
+	}
+	fmt.Printf("  Offsets: %s\n\n", strings.Join(hexOffsets, ", "))
+
+	// One row per window; nearby offsets share a row
+	lastRow := int64(-1)
+	for _, offset := range offsets {
+		row := offset - offset%hexdumpWidth
+		if row == lastRow {
+			continue
+		}
+		lastRow = row
+		printHexdumpRow(row, local, remote)
+	}
+}
+
+// printHexdumpRow prints the hexdump row starting at offset row for both sides,
+// with a marker line under the bytes that differ.
+func printHexdumpRow(row int64, local, remote []byte) {
+	var localHex, remoteHex, marker strings.Builder
+	for i := row; i < row+hexdumpWidth; i++ {
+		localHex.WriteString(hexdumpByte(local, i))
+		remoteHex.WriteString(hexdumpByte(remote, i))
+		inLocal, inRemote := i < int64(len(local)), i < int64(len(remote))
+		if !inLocal && !inRemote || inLocal && inRemote && local[i] == remote[i] {
+			marker.WriteString("   ")
+		} else {
+			marker.WriteString("^^ ")
+		}
+	}
+
+	fmt.Printf("  %08x  local  %s\n", row, strings.TrimRight(localHex.String(), " "))
+	fmt.Printf("            remote %s\n", strings.TrimRight(remoteHex.String(), " "))
+	fmt.Printf("                   %s\n", strings.TrimRight(marker.String(), " "))
+}
+
+// hexdumpByte formats data[i] as two hex digits, or blanks when i is past the end.
+func hexdumpByte(data []byte, i int64) string {
+	if i >= int64(len(data)) {
+		return "   "
+	}
+	return fmt.Sprintf("%02x ", data[i])
 }
 
-// truncateHash returns the first 12 characters of a hash for display.
+// truncateHash returns the first 12 characters of a hash digest for display.
 func truncateHash(hash string) string {
+	hash = utils.HashHex(hash)
 	if len(hash) > 12 {
 		return hash[:12]
 	}
 	return hash
 }
+
+// previewTitles prints the dry-run comparison for each title and a summary of what
+// would happen. writeRecommendation is "PULL" or "PUSH" depending on the command.
+func previewTitles(titles []string, writeRecommendation string, preview func(title string) (*models.ComparisonResult, error)) {
+	writeCount := 0
+	skipCount := 0
+	conflictCount := 0
+	errorCount := 0
+
+	for _, title := range titles {
+		comparison, err := preview(title)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", title, err)
+			errorCount++
+			continue
+		}
+
+		fmt.Printf("[dry-run] %s: %s (%s)\n", title, comparison.Recommendation, comparison.Reason)
+		switch comparison.Recommendation {
+		case writeRecommendation:
+			writeCount++
+		case "CONFLICT":
+			conflictCount++
+		default:
+			skipCount++
+		}
+	}
+
+	fmt.Printf("\n=== Summary (dry-run) ===\n")
+	fmt.Printf("Would %s: %d, Would skip: %d, Conflicts: %d, Errors: %d\n",
+		strings.ToLower(writeRecommendation), writeCount, skipCount, conflictCount, errorCount)
+}
+
+// confirm asks a yes/no question on stdin. Anything but y/yes counts as no,
+// as does a stdin that is not interactive.
+func confirm(question string) bool {
+	if !utils.IsInteractive() {
+		fmt.Printf("%s [y/N]: N (stdin is not interactive)\n", question)
+		return false
+	}
+
+	fmt.Printf("%s [y/N]: ", question)
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes"
+}
+
+// logRetries records each pull/push stat or copy retried after a transient I/O error,
+// such as a sharing violation on a USB stick, and mentions it with --verbose.
+func logRetries(log *logger.Logger) {
+	sync.SetRetryReporter(func(op, path string, attempt int, err error) {
+		console.Verbosef("  retrying %s of %s after attempt %d: %v\n", op, path, attempt, err)
+		log.Warn("retry", map[string]interface{}{
+			"op":      op,
+			"path":    path,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+	})
+}
+
+// logPrunes records the backups that pull/push trim from a slot's history after
+// backing up a file, and mentions them with --verbose.
+func logPrunes(log *logger.Logger) {
+	sync.SetPruneReporter(func(title, slot string, result backup.PruneResult, err error) {
+		if len(result.Removed) > 0 {
+			console.Verbosef("  %s: removed %d old backups (%s freed)\n", title, len(result.Removed), formatBytes(result.FreedBytes))
+			log.Info("prune", map[string]interface{}{
+				"title":       title,
+				"slot":        slot,
+				"removed":     len(result.Removed),
+				"freed_bytes": result.FreedBytes,
+			})
+		}
+		if err != nil {
+			log.Warn("prune_failed", map[string]interface{}{
+				"title": title,
+				"slot":  slot,
+				"error": err.Error(),
+			})
+		}
+	})
+}
+
+// newCopyProgress returns a progress reporter that redraws one line with the
+// percentage and throughput of a copy to dest, at most every 200ms.
+func newCopyProgress(dest string) utils.ProgressFunc {
+	name := filepath.Base(dest)
+	start := time.Now()
+	var last time.Time
+	finished := false
+
+	return func(copied, total int64) {
+		if finished {
+			return
+		}
+
+		now := time.Now()
+		done := copied >= total
+		if !done && now.Sub(last) < 200*time.Millisecond {
+			return
+		}
+		last = now
+
+		percent := int64(100)
+		if total > 0 {
+			percent = copied * 100 / total
+		}
+		var rate float64
+		if elapsed := now.Sub(start).Seconds(); elapsed > 0 {
+			rate = float64(copied) / elapsed
+		}
+
+		fmt.Printf("\r  %s: %3d%% (%s / %s, %s/s)", name, percent, formatBytes(copied), formatBytes(total), formatBytes(int64(rate)))
+		if done {
+			finished = true
+			fmt.Println()
+		}
+	}
+}
+
+// titleIO tracks how long each title of a pull, push or sync took and the throughput of
+// its hashing and copying, for --verbose.
+type titleIO struct {
+	elapsed time.Duration
+	stats   sync.IOStats
+}
+
+// start begins timing title. The returned function prints the title's duration and
+// throughput with --verbose and adds them to the totals.
+func (t *titleIO) start(title string) func() {
+	sync.TakeIOStats() // work done between titles is not counted
+	start := time.Now()
+
+	return func() {
+		elapsed := time.Since(start)
+		stats := sync.TakeIOStats()
+		t.elapsed += elapsed
+		t.stats = t.stats.Add(stats)
+		console.Verbosef("    %s took %s%s\n", title, formatDuration(elapsed), formatIOStats(stats))
+	}
+}
+
+// printTotal prints the time and throughput of every title with --verbose, below the summary.
+func (t *titleIO) printTotal() {
+	console.Verbosef("Total time: %s%s\n", formatDuration(t.elapsed), formatIOStats(t.stats))
+}
+
+// formatIOStats describes the hashing and copying in stats, e.g.
+// " (hashed 2.0 MiB in 15ms at 133.3 MiB/s, copied 1.0 MiB in 80ms at 12.5 MiB/s)".
+func formatIOStats(stats sync.IOStats) string {
+	var parts []string
+	if stats.HashBytes > 0 {
+		parts = append(parts, fmt.Sprintf("hashed %s in %s at %s/s", formatBytes(stats.HashBytes), formatDuration(stats.HashTime), formatBytes(int64(stats.HashRate()))))
+	}
+	if stats.CopyBytes > 0 {
+		parts = append(parts, fmt.Sprintf("copied %s in %s at %s/s", formatBytes(stats.CopyBytes), formatDuration(stats.CopyTime), formatBytes(int64(stats.CopyRate()))))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// formatDuration rounds d for display: to the millisecond, or the microsecond below 1ms.
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// formatBytes renders a byte count with a binary unit (B, KiB, MiB, GiB).
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n) / unit
+	for _, suffix := range []string{"KiB", "MiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f GiB", value)
+}
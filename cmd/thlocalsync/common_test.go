@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+func TestCheckVaultAvailable(t *testing.T) {
+	home := t.TempDir()
+	paths.HomeOverride = home
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	// Before the first write only the parent has to exist
+	if err := checkVaultAvailable(); err != nil {
+		t.Fatalf("Expected a missing vault under an existing home to be available, got %v", err)
+	}
+
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(vaultDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkVaultAvailable(); err != nil {
+		t.Fatalf("Expected the vault to be available, got %v", err)
+	}
+	entries, err := os.ReadDir(vaultDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected the write probe to be removed, got %v, %v", entries, err)
+	}
+
+	// Unplugging the storage takes the whole home with it
+	paths.HomeOverride = filepath.Join(home, "unplugged")
+	if err := checkVaultAvailable(); !errors.Is(err, errStorageRemoved) {
+		t.Errorf("Expected errStorageRemoved, got %v", err)
+	}
+}
+
+func TestGetVaultFileName(t *testing.T) {
+	vaultFileNames = map[string]string{"th16": "scoreth16-main.dat"}
+	t.Cleanup(func() { vaultFileNames = nil })
+
+	if got := getVaultFileName("th16"); got != "scoreth16-main.dat" {
+		t.Errorf("Expected the paths.json vault file name, got %s", got)
+	}
+	if got := getVaultFileName("th08"); got != "score.dat" {
+		t.Errorf("Expected the title's save file name when none is set, got %s", got)
+	}
+}
+
+func TestFormatIOStats(t *testing.T) {
+	tests := []struct {
+		stats sync.IOStats
+		want  string
+	}{
+		{sync.IOStats{}, ""},
+		{sync.IOStats{CopyTime: 500 * time.Millisecond, CopyBytes: 1024 * 1024}, " (copied 1.0 MiB in 500ms at 2.0 MiB/s)"},
+		{sync.IOStats{HashTime: 250 * time.Microsecond, HashBytes: 512, CopyTime: time.Second, CopyBytes: 2048},
+			" (hashed 512 B in 250µs at 2.0 MiB/s, copied 2.0 KiB in 1s at 2.0 KiB/s)"},
+	}
+
+	for _, tt := range tests {
+		if got := formatIOStats(tt.stats); got != tt.want {
+			t.Errorf("formatIOStats(%+v) = %q, want %q", tt.stats, got, tt.want)
+		}
+	}
+}
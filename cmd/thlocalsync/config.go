@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configValidateSchema bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "設定の表示・編集・検証",
+	Long: `data/ 以下の設定ファイル（devices.json / paths.json / rules.json）を扱います。
+
+--validate-schema を指定すると、各設定ファイルを組み込みのJSON Schemaで検証し、
+型の誤り・必須キーの欠落・未知のキーを行番号付きで報告します。
+
+使用例:
+  thlocalsync config show                      現在の設定を表示
+  thlocalsync config set-history-limit 30      履歴保存上限を変更
+  thlocalsync config add-include "*.rpy"       同期対象パターンを追加
+  thlocalsync config add-exclude "*.bak"       除外パターンを追加
+  thlocalsync config clear-hash-cache          ハッシュキャッシュを削除
+  thlocalsync config --validate-schema`,
+	Args: cobra.NoArgs,
+	RunE: runConfig,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "rules / devices / paths の内容を表示",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigShow,
+}
+
+var configSetHistoryLimitCmd = &cobra.Command{
+	Use:   "set-history-limit <N>",
+	Short: "履歴保存上限（history_limit）を変更",
+	Args:  cobra.ExactArgs(1),
+	RunE:  withRunLock(runConfigSetHistoryLimit),
+}
+
+var configClearHashCacheCmd = &cobra.Command{
+	Use:   "clear-hash-cache",
+	Short: "ハッシュキャッシュ（hashcache.json）を削除",
+	Args:  cobra.NoArgs,
+	RunE:  withRunLock(runConfigClearHashCache),
+}
+
+var configAddIncludeCmd = &cobra.Command{
+	Use:   "add-include <pattern>",
+	Short: "同期対象のglobパターンを追加",
+	Args:  cobra.ExactArgs(1),
+	RunE: withRunLock(func(cmd *cobra.Command, args []string) error {
+		return addRulePattern("include", args[0])
+	}),
+}
+
+var configAddExcludeCmd = &cobra.Command{
+	Use:   "add-exclude <pattern>",
+	Short: "除外するglobパターンを追加",
+	Args:  cobra.ExactArgs(1),
+	RunE: withRunLock(func(cmd *cobra.Command, args []string) error {
+		return addRulePattern("exclude", args[0])
+	}),
+}
+
+func init() {
+	configCmd.Flags().BoolVar(&configValidateSchema, "validate-schema", false, "設定ファイルをJSON Schemaで検証")
+
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSetHistoryLimitCmd)
+	configCmd.AddCommand(configAddIncludeCmd)
+	configCmd.AddCommand(configAddExcludeCmd)
+	configCmd.AddCommand(configClearHashCacheCmd)
+}
+
+func runConfig(cmd *cobra.Command, args []string) error {
+	if !configValidateSchema {
+		return cmd.Help()
+	}
+
+	fmt.Printf("=== thlocalsync config --validate-schema ===\n\n")
+
+	results, err := config.ValidateConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No config files found. Run 'thlocalsync detect' first.")
+		return nil
+	}
+
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	errorCount := 0
+	for _, file := range files {
+		schemaErrors := results[file]
+		if len(schemaErrors) == 0 {
+			fmt.Printf("✓ %s: OK\n", file)
+			continue
+		}
+
+		fmt.Printf("✗ %s: %d error(s)\n", file, len(schemaErrors))
+		for _, e := range schemaErrors {
+			fmt.Printf("    %s:%d:%d: %s: %s\n", file, e.Line, e.Column, e.Path, e.Message)
+		}
+		errorCount += len(schemaErrors)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d schema error(s) found", errorCount)
+	}
+
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	fmt.Printf("=== thlocalsync config show ===\n\n")
+
+	rules, err := config.LoadRules()
+	if err != nil {
+		return fmt.Errorf("failed to load rules config: %w", err)
+	}
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return fmt.Errorf("failed to load devices config: %w", err)
+	}
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	fmt.Printf("[%s]\n", config.RulesFile)
+	printRules(rules)
+
+	fmt.Printf("\n[%s]\n", config.DevicesFile)
+	if len(devicesConfig.Devices) == 0 {
+		fmt.Println("  (no devices)")
+	}
+	for _, d := range devicesConfig.Devices {
+		fmt.Printf("  %-12s %-20s %s\n", d.ID, d.Hostname, formatPlatform(d))
+	}
+
+	fmt.Printf("\n[%s]\n", config.PathsFile)
+	if len(pathsConfig.Paths) == 0 {
+		fmt.Println("  (no paths)")
+	}
+	titles := make([]string, 0, len(pathsConfig.Paths))
+	for title := range pathsConfig.Paths {
+		titles = append(titles, title)
+	}
+	for _, title := range pathdetect.SortTitlesByRelease(titles) {
+		fmt.Printf("  %s:\n", title)
+		deviceIDs := make([]string, 0, len(pathsConfig.Paths[title]))
+		for deviceID := range pathsConfig.Paths[title] {
+			deviceIDs = append(deviceIDs, deviceID)
+		}
+		sort.Strings(deviceIDs)
+		for _, deviceID := range deviceIDs {
+			entry := pathsConfig.Paths[title][deviceID]
+			fmt.Printf("    %s\n", deviceID)
+			for i, path := range entry.Paths {
+				marker := " "
+				if i == entry.Preferred {
+					marker = "*"
+				}
+				fmt.Printf("      %s %s\n", marker, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// printRules prints the rules.json settings.
+func printRules(rules *models.Rules) {
+	fmt.Printf("  include:                 %s\n", strings.Join(rules.Include, ", "))
+	fmt.Printf("  exclude:                 %s\n", strings.Join(rules.Exclude, ", "))
+	fmt.Printf("  history_limit:           %d\n", rules.HistoryLimit)
+	fmt.Printf("  history_max_age_days:    %d\n", rules.HistoryMaxAgeDays)
+	fmt.Printf("  compress_backups:        %t\n", rules.CompressBackups)
+	fmt.Printf("  encrypt_vault:           %t\n", rules.EncryptVault)
+	fmt.Printf("  size_ratio_threshold:    %g\n", rules.SizeRatioThreshold)
+	fmt.Printf("  drift_tolerance_seconds: %d\n", rules.DriftToleranceSeconds)
+	fmt.Printf("  log_retention_days:      %d\n", rules.LogRetentionDays)
+	fmt.Printf("  log_max_size_kb:         %d\n", rules.LogMaxSizeKB)
+	fmt.Printf("  hash_algo:               %s\n", rules.HashAlgo)
+	fmt.Printf("  retry_attempts:          %d\n", rules.RetryAttempts)
+	fmt.Printf("  retry_backoff_ms:        %d\n", rules.RetryBackoffMS)
+}
+
+func runConfigSetHistoryLimit(cmd *cobra.Command, args []string) error {
+	limit, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid history limit: %s", args[0])
+	}
+
+	rules, err := config.LoadRules()
+	if err != nil {
+		return fmt.Errorf("failed to load rules config: %w", err)
+	}
+
+	old := rules.HistoryLimit
+	rules.HistoryLimit = limit
+	if err := config.SaveRules(rules); err != nil {
+		return fmt.Errorf("failed to save rules config: %w", err)
+	}
+
+	fmt.Printf("✓ history_limit: %d → %d\n", old, limit)
+	return nil
+}
+
+// addRulePattern appends a glob pattern to the include or exclude list in rules.json.
+func addRulePattern(list string, pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	rules, err := config.LoadRules()
+	if err != nil {
+		return fmt.Errorf("failed to load rules config: %w", err)
+	}
+
+	patterns := &rules.Include
+	if list == "exclude" {
+		patterns = &rules.Exclude
+	}
+
+	for _, p := range *patterns {
+		if p == pattern {
+			fmt.Printf("- %s: %q already present, no change\n", list, pattern)
+			return nil
+		}
+	}
+
+	*patterns = append(*patterns, pattern)
+	if err := config.SaveRules(rules); err != nil {
+		return fmt.Errorf("failed to save rules config: %w", err)
+	}
+
+	fmt.Printf("✓ %s: added %q\n", list, pattern)
+	fmt.Printf("  %s: %s\n", list, strings.Join(*patterns, ", "))
+	return nil
+}
+
+func runConfigClearHashCache(cmd *cobra.Command, args []string) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return err
+	}
+
+	cachePath := filepath.Join(configDir, config.HashCacheFile)
+	if err := sync.ClearHashCache(cachePath); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Cleared %s\n", cachePath)
+	return nil
+}
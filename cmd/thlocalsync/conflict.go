@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Conflict policies accepted by --on-conflict on pull and push.
+// An empty policy prompts the user, or skips when stdin is not a terminal.
+const (
+	conflictLocal  = "local"  // use the local file
+	conflictRemote = "remote" // use the vault file
+	conflictNewer  = "newer"  // use the file with the newer mtime
+	conflictLarger = "larger" // use the bigger file
+	conflictSkip   = "skip"   // leave both files untouched
+	conflictAbort  = "abort"  // stop the whole run
+)
+
+// errConflictAbort is returned by settleConflict when --on-conflict=abort stops the run.
+var errConflictAbort = errors.New("aborted on conflict (--on-conflict=abort)")
+
+// validateConflictPolicy checks an --on-conflict value.
+func validateConflictPolicy(policy string) error {
+	switch policy {
+	case "", conflictLocal, conflictRemote, conflictNewer, conflictLarger, conflictSkip, conflictAbort:
+		return nil
+	default:
+		return fmt.Errorf("invalid --on-conflict policy: %s", policy)
+	}
+}
+
+// resolveConflict decides a CONFLICT for pull/push by policy, prompting only when no policy is set
+// and stdin is a terminal. Without a terminal it skips and logs a warning instead of blocking.
+// Returns "local", "remote", "skip", "abort", or "cancel", and the reason to report and log.
+func resolveConflict(title string, comparison *models.ComparisonResult, operation, policy string, log *logger.Logger) (string, string) {
+	if policy != "" {
+		return conflictPolicyChoice(comparison, policy)
+	}
+
+	if !utils.IsInteractive() {
+		reason := "stdin is not a terminal - skipped conflict (set --on-conflict to resolve)"
+		log.Warn(operation+"_conflict_skipped", map[string]interface{}{
+			"title":  title,
+			"reason": reason,
+		})
+		return conflictSkip, reason
+	}
+
+	switch choice := promptUserForConflictResolution(title, comparison, operation); choice {
+	case "cancel":
+		return choice, "user cancelled conflict resolution"
+	default:
+		return choice, "user resolved conflict - chose " + choice
+	}
+}
+
+// settleConflict resolves the conflict of a title with choice, prints the outcome and
+// returns the title's net effect. The choice "abort" stops the run with errConflictAbort.
+func settleConflict(syncer *thlocalsync.Syncer, conflict *sync.TitleSyncResult, choice, reason string) (string, error) {
+	if choice == conflictAbort {
+		return "", errConflictAbort
+	}
+	result, err := syncer.Resolve(conflict, choice, reason)
+	if err != nil {
+		return "", err
+	}
+
+	title := result.Title
+	switch {
+	case result.Action == sync.ActionPull || result.Action == sync.ActionPush:
+		printWrite(syncer, result, reason)
+	case result.Action == sync.ActionSkip && choice == conflictLocal:
+		console.Printf("- %s: Kept local version (%s)\n", title, reason)
+	case result.Action == sync.ActionSkip:
+		console.Printf("- %s: Kept USB version (%s)\n", title, reason)
+	case choice == thlocalsync.ChoiceCancel:
+		console.Printf("- %s: Cancelled by user\n", title)
+	default:
+		console.Printf("- %s: Conflict skipped (%s)\n", title, reason)
+	}
+	return result.Action, nil
+}
+
+// conflictPolicyChoice applies a non-empty --on-conflict policy to a conflict.
+// newer and larger fall back to skip when both files are equal in that respect.
+func conflictPolicyChoice(comparison *models.ComparisonResult, policy string) (string, string) {
+	local, remote := comparison.LocalMeta, comparison.RemoteMeta
+
+	switch policy {
+	case conflictLocal, conflictRemote, conflictSkip, conflictAbort:
+		return policy, "conflict policy " + policy
+	case conflictNewer:
+		switch {
+		case local.ModTime.After(remote.ModTime):
+			return conflictLocal, "conflict policy newer - local is newer"
+		case remote.ModTime.After(local.ModTime):
+			return conflictRemote, "conflict policy newer - remote is newer"
+		}
+		return conflictSkip, "conflict policy newer - same mtime, skipped"
+	case conflictLarger:
+		switch {
+		case local.Size > remote.Size:
+			return conflictLocal, "conflict policy larger - local is larger"
+		case remote.Size > local.Size:
+			return conflictRemote, "conflict policy larger - remote is larger"
+		}
+		return conflictSkip, "conflict policy larger - same size, skipped"
+	}
+
+	return conflictSkip, "unknown conflict policy " + policy
+}
+
+// runResultError turns the per-title outcome of pull/push into the command's error:
+// any failed title exits with exitErrors, and with strict set, conflicts left
+// unresolved exit with exitConflicts.
+func runResultError(cmd *cobra.Command, errorCount, conflictCount int, strict bool) error {
+	switch {
+	case errorCount > 0:
+		// Failed titles are a result, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d title(s) failed", errorCount)
+	case strict && conflictCount > 0:
+		cmd.SilenceUsage = true
+		return &exitCodeError{code: exitConflicts, err: fmt.Errorf("%d conflict(s) left unresolved (--strict)", conflictCount)}
+	}
+	return nil
+}
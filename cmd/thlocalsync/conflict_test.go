@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func TestConflictPolicyChoice(t *testing.T) {
+	older := time.Date(2025, 11, 11, 6, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	comparison := func(localSize int64, localTime time.Time, remoteSize int64, remoteTime time.Time) *models.ComparisonResult {
+		return &models.ComparisonResult{
+			LocalMeta:  &models.FileMetadata{Size: localSize, ModTime: localTime},
+			RemoteMeta: &models.FileMetadata{Size: remoteSize, ModTime: remoteTime},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		comparison *models.ComparisonResult
+		policy     string
+		want       string
+	}{
+		{"Local", comparison(10, older, 20, newer), "local", "local"},
+		{"Remote", comparison(10, older, 20, newer), "remote", "remote"},
+		{"Skip", comparison(10, older, 20, newer), "skip", "skip"},
+		{"Abort", comparison(10, older, 20, newer), "abort", "abort"},
+		{"Newer local", comparison(10, newer, 20, older), "newer", "local"},
+		{"Newer remote", comparison(20, older, 10, newer), "newer", "remote"},
+		{"Newer tie", comparison(10, older, 20, older), "newer", "skip"},
+		{"Larger local", comparison(20, older, 10, newer), "larger", "local"},
+		{"Larger remote", comparison(10, newer, 20, older), "larger", "remote"},
+		{"Larger tie", comparison(10, older, 10, newer), "larger", "skip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := conflictPolicyChoice(tt.comparison, tt.policy)
+			if got != tt.want {
+				t.Errorf("conflictPolicyChoice() = %q (%s), want %q", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConflictPolicy(t *testing.T) {
+	for _, policy := range []string{"", "local", "remote", "newer", "larger", "skip", "abort"} {
+		if err := validateConflictPolicy(policy); err != nil {
+			t.Errorf("validateConflictPolicy(%q) = %v, want nil", policy, err)
+		}
+	}
+	for _, policy := range []string{"prompt", "current", "LOCAL"} {
+		if err := validateConflictPolicy(policy); err == nil {
+			t.Errorf("validateConflictPolicy(%q) = nil, want error", policy)
+		}
+	}
+}
+
+func TestRunResultError(t *testing.T) {
+	tests := []struct {
+		name          string
+		errorCount    int
+		conflictCount int
+		strict        bool
+		wantCode      int // 0 means no error
+	}{
+		{"clean run", 0, 0, true, 0},
+		{"conflicts without --strict", 0, 2, false, 0},
+		{"conflicts with --strict", 0, 2, true, exitConflicts},
+		{"errors", 1, 0, false, exitErrors},
+		{"errors win over conflicts", 1, 2, true, exitErrors},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runResultError(&cobra.Command{}, tt.errorCount, tt.conflictCount, tt.strict)
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d (%v)", tt.wantCode, code, err)
+			}
+		})
+	}
+
+	if code := exitCode(errConflictAbort); code != exitErrors {
+		t.Errorf("Expected exit code %d for an abort, got %d", exitErrors, code)
+	}
+}
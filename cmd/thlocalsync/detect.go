@@ -1,17 +1,28 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"runtime"
+	"strings"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
 	"github.com/otagao/touhou-local-sync/pkg/config"
 	"github.com/otagao/touhou-local-sync/pkg/device"
 	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
 	"github.com/spf13/cobra"
 )
 
 var (
 	detectGameDir string
+	detectYes     bool
+	detectImport  string
+	detectDepth   int
+	detectJobs    int
+	detectDryRun  bool
+	detectJSON    bool
 )
 
 var detectCmd = &cobra.Command{
@@ -25,19 +36,72 @@ var detectCmd = &cobra.Command{
 
 検出ステップ:
   1. 既知パターンでセーブデータを探索
+     ゲームディレクトリを指定した場合は、その配下を --depth の階層（既定3）まで探索し、
+     見つかった thNN.exe と同じフォルダ（またはその thNN サブフォルダ）のスコアファイルも候補にします。
+     _history・.git・node_modules は探索しません。
+     タイトルごとの探索は --jobs の数（既定はCPU数）まで並列に行い、結果はリリース順に並べます。
+     ゲームディレクトリの入力は探索の開始前に求めます。
   2. 見つかった候補を一覧表示
   3. ユーザーが登録するものを選択
 
 未検出タイトルの手動登録:
-  検出されなかったタイトルを対話的に追加できます。`,
+  検出されなかったタイトルを対話的に追加できます。
+
+--yes を指定すると、見つかった候補をすべて登録し、手動登録の y/N 確認にも
+自動で yes と答えます（パスの入力は必要です。空欄でそのタイトルを飛ばします）。
+
+--import（別名 --paths-file）を指定すると、探索は行わず、ファイルに書かれたパスを
+このデバイスに対話なしで登録します。1行に1つ "タイトル=パス" の形式で書きます
+（空行と # で始まる行は無視、環境変数は展開）。複数のPCの初回セットアップをスクリプト化できます:
+  th08=D:\Games\th08\score.dat
+  th10=${APPDATA}\ShanghaiAlice\th10\scoreth10.dat
+書式の誤った行や存在しないファイルは報告してスキップし、残りの行は登録します。
+スキップした行があれば、設定を保存したうえで終了コード1で終了します。
+
+--dry-run を指定すると、探索だけを行い、見つかった候補（登録済みかどうか）と
+見つからなかったタイトルを表示します。ゲームディレクトリの入力などの質問はせず
+（--gamedir は使えます）、paths.json・devices.json は変更しません。
+新しいPCのフォルダ構成を、登録前に確認するときに使います。
+--json を併せて指定すると、結果をJSONで標準出力に出力します。`,
 	RunE: runDetect,
 }
 
 func init() {
 	detectCmd.Flags().StringVarP(&detectGameDir, "gamedir", "g", "", "ゲームディレクトリのパス（省略可）")
+	detectCmd.Flags().IntVar(&detectDepth, "depth", pathdetect.DefaultGameDirSearchDepth, "ゲームディレクトリ配下を探索する階層の深さ")
+	detectCmd.Flags().IntVar(&detectJobs, "jobs", runtime.NumCPU(), "同時に探索するタイトルの最大数")
+	detectCmd.Flags().BoolVarP(&detectYes, "yes", "y", false, "候補をすべて登録し、手動登録の確認に自動で yes と答える")
+	detectCmd.Flags().StringVar(&detectImport, "import", "", "\"タイトル=パス\" 形式のファイルからパスを一括登録（探索・対話なし）")
+	detectCmd.Flags().StringVar(&detectImport, "paths-file", "", "--import の別名")
+	detectCmd.Flags().BoolVar(&detectDryRun, "dry-run", false, "探索結果のみ表示し、質問・設定の変更を行わない")
+	detectCmd.Flags().BoolVar(&detectJSON, "json", false, "--dry-run の結果をJSON形式で出力")
+	detectCmd.MarkFlagsMutuallyExclusive("import", "paths-file", "gamedir")
+	detectCmd.MarkFlagsMutuallyExclusive("import", "dry-run")
+	detectCmd.MarkFlagsMutuallyExclusive("paths-file", "dry-run")
+	detectCmd.MarkFlagsMutuallyExclusive("yes", "dry-run")
 }
 
 func runDetect(cmd *cobra.Command, args []string) error {
+	if detectDepth < 0 {
+		return fmt.Errorf("--depth must not be negative: %d", detectDepth)
+	}
+	if detectJSON && !detectDryRun {
+		return fmt.Errorf("--json requires --dry-run")
+	}
+	pathdetect.GameDirSearchDepth = detectDepth
+	pathdetect.DetectJobs = detectJobs
+
+	if detectDryRun {
+		// A dry run writes nothing and may overlap with another run
+		return runDetectDryRun()
+	}
+
+	release, err := acquireRunLock(cmd, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	fmt.Println("=== thlocalsync detect ===")
 	fmt.Println()
 
@@ -62,12 +126,38 @@ func runDetect(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load paths config: %w", err)
 	}
 
+	// Apply include/exclude patterns from rules.json
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	// Carry over paths registered under a previous device ID
+	if oldID := device.MigrateDeviceID(devicesConfig, pathsConfig, deviceID, hostname); oldID != "" {
+		fmt.Printf("Device ID changed: %s → %s (registered paths migrated)\n\n", oldID, deviceID)
+	}
+
 	// Update device in config
 	updateDeviceConfig(devicesConfig, deviceID, hostname, macHash)
 
+	if detectImport != "" {
+		skipped, err := importPathsFile(detectImport, deviceID, pathsConfig)
+		if err != nil {
+			return err
+		}
+		if err := saveDetectConfig(devicesConfig, pathsConfig); err != nil {
+			return err
+		}
+		if skipped > 0 {
+			// The rest was imported; report the skipped lines through the exit code
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d line(s) could not be imported", skipped)
+		}
+		return nil
+	}
+
 	// Detect save files
 	fmt.Println("Searching for save files...")
-	detectResult, err := pathdetect.DetectSaveFiles(detectGameDir)
+	detectResult, err := pathdetect.DetectSaveFiles(detectGameDir, true)
 	if err != nil {
 		return fmt.Errorf("failed to detect save files: %w", err)
 	}
@@ -77,9 +167,17 @@ func runDetect(cmd *cobra.Command, args []string) error {
 
 	// Prompt for selection
 	if len(detectResult.Candidates) > 0 {
-		indices, err := pathdetect.PromptCandidateSelection(len(detectResult.Candidates))
-		if err != nil {
-			return fmt.Errorf("failed to read selection: %w", err)
+		var indices []int
+		if detectYes {
+			fmt.Println("Registering all candidates (--yes)")
+			for i := range detectResult.Candidates {
+				indices = append(indices, i)
+			}
+		} else {
+			indices, err = pathdetect.PromptCandidateSelection(len(detectResult.Candidates))
+			if err != nil {
+				return fmt.Errorf("failed to read selection: %w", err)
+			}
 		}
 
 		// Add selected candidates to config
@@ -104,7 +202,7 @@ func runDetect(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%d title(s) not found automatically.\n\n", len(detectResult.NotFound))
 
 		for _, title := range detectResult.NotFound {
-			path, err := pathdetect.PromptManualPath(title)
+			path, err := pathdetect.PromptManualPath(title, detectYes)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
 				continue
@@ -113,8 +211,11 @@ func runDetect(cmd *cobra.Command, args []string) error {
 			if path != "" {
 				// Add to config
 				candidate := models.DetectCandidate{
-					Title: title.Code,
-					Path:  path,
+					Title:     title.Code,
+					Path:      path,
+					ReplayDir: pathdetect.DetectReplayDir(title.Code, path),
+					SyncDir:   pathdetect.DetectSyncDir(title.Code, path),
+					SetFiles:  pathdetect.DetectSetFiles(title.Code),
 				}
 				pathdetect.AddCandidateToConfig(candidate, deviceID, pathsConfig)
 				fmt.Printf("Registered: %s -> %s\n", title.Code, path)
@@ -122,7 +223,76 @@ func runDetect(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Save configurations
+	return saveDetectConfig(devicesConfig, pathsConfig)
+}
+
+// runDetectDryRun searches for save files and reports the candidates without asking
+// anything or changing the configuration.
+func runDetectDryRun() error {
+	deviceID, _, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	// Apply include/exclude patterns from rules.json
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	if !detectJSON {
+		fmt.Println("=== thlocalsync detect (dry-run) ===")
+		fmt.Println()
+		fmt.Printf("Device ID: %s\n", deviceID)
+		fmt.Printf("Hostname: %s\n", hostname)
+		fmt.Println("Dry-run mode: no configuration will be changed")
+		fmt.Println()
+		fmt.Println("Searching for save files...")
+	}
+
+	detectResult, err := pathdetect.DetectSaveFiles(detectGameDir, false)
+	if err != nil {
+		return fmt.Errorf("failed to detect save files: %w", err)
+	}
+
+	report := thlocalsync.BuildDetectReport(detectResult, deviceID, pathsConfig)
+
+	if detectJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	pathdetect.DisplayCandidates(detectResult.Candidates)
+
+	newCount := 0
+	for i, candidate := range report.Candidates {
+		if candidate.Registered {
+			fmt.Printf("  [%d] %s: already registered\n", i+1, candidate.Title)
+		} else {
+			newCount++
+		}
+	}
+	if len(report.Candidates) > 0 {
+		fmt.Printf("Would register %d new path(s)\n", newCount)
+	}
+
+	if len(report.NotFound) > 0 {
+		fmt.Printf("\nNot found (%d): %s\n", len(report.NotFound), strings.Join(report.NotFound, ", "))
+	}
+
+	return nil
+}
+
+// saveDetectConfig saves the configurations updated by detect.
+func saveDetectConfig(devicesConfig *models.DeviceConfig, pathsConfig *models.PathsConfig) error {
 	if err := config.SaveDevices(devicesConfig); err != nil {
 		return fmt.Errorf("failed to save devices config: %w", err)
 	}
@@ -135,7 +305,35 @@ func runDetect(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// importPathsFile registers the title=path lines of an --import file for this device.
+// Invalid lines and files that are missing or unreadable are reported and skipped.
+// Returns the number of skipped lines.
+func importPathsFile(path, deviceID string, pathsConfig *models.PathsConfig) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer f.Close()
+
+	result, err := pathdetect.ImportPaths(f, deviceID, pathsConfig)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+
+	fmt.Printf("Importing paths from %s\n", path)
+	for _, problem := range result.Skipped {
+		fmt.Printf("✗ %s\n", problem)
+	}
+	for _, candidate := range result.Registered {
+		fmt.Printf("Registered: %s -> %s\n", candidate.Title, candidate.Path)
+	}
+
+	fmt.Printf("\nImported %d path(s), skipped %d\n", len(result.Registered), len(result.Skipped))
+	return len(result.Skipped), nil
+}
+
 // updateDeviceConfig updates or adds a device to the device configuration.
+// OS, architecture, and tool version are refreshed on every call.
 func updateDeviceConfig(config *models.DeviceConfig, deviceID, hostname, macHash string) {
 	// Check if device already exists
 	found := false
@@ -145,6 +343,9 @@ func updateDeviceConfig(config *models.DeviceConfig, deviceID, hostname, macHash
 			config.Devices[i].Hostname = hostname
 			config.Devices[i].MACHash = macHash
 			config.Devices[i].LastSeen = getCurrentTime()
+			config.Devices[i].OS = runtime.GOOS
+			config.Devices[i].Arch = runtime.GOARCH
+			config.Devices[i].ToolVersion = version
 			found = true
 			break
 		}
@@ -153,10 +354,13 @@ func updateDeviceConfig(config *models.DeviceConfig, deviceID, hostname, macHash
 	// Add new device if not found
 	if !found {
 		newDevice := models.Device{
-			ID:       deviceID,
-			Hostname: hostname,
-			MACHash:  macHash,
-			LastSeen: getCurrentTime(),
+			ID:          deviceID,
+			Hostname:    hostname,
+			MACHash:     macHash,
+			LastSeen:    getCurrentTime(),
+			OS:          runtime.GOOS,
+			Arch:        runtime.GOARCH,
+			ToolVersion: version,
 		}
 		config.Devices = append(config.Devices, newDevice)
 	}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestUpdateDeviceConfig_MigratesOldRecord(t *testing.T) {
+	// devices.json written before OS/arch/tool version were recorded
+	oldJSON := `{
+  "devices": [
+    {
+      "id": "abc123def456",
+      "hostname": "OLD-PC",
+      "mac_hash": "sha256:0000",
+      "last_seen": "2025-11-11T06:20:30Z"
+    }
+  ]
+}`
+
+	var cfg models.DeviceConfig
+	if err := json.Unmarshal([]byte(oldJSON), &cfg); err != nil {
+		t.Fatalf("failed to parse old devices.json: %v", err)
+	}
+
+	if cfg.Devices[0].OS != "" || cfg.Devices[0].ToolVersion != "" {
+		t.Fatalf("Expected new fields to be empty for old record, got %+v", cfg.Devices[0])
+	}
+
+	updateDeviceConfig(&cfg, "abc123def456", "OLD-PC", "sha256:0000")
+
+	if len(cfg.Devices) != 1 {
+		t.Fatalf("Expected 1 device after update, got %d", len(cfg.Devices))
+	}
+	d := cfg.Devices[0]
+	if d.OS != runtime.GOOS || d.Arch != runtime.GOARCH {
+		t.Errorf("Expected %s/%s, got %s/%s", runtime.GOOS, runtime.GOARCH, d.OS, d.Arch)
+	}
+	if d.ToolVersion != version {
+		t.Errorf("Expected tool version %s, got %s", version, d.ToolVersion)
+	}
+}
+
+func TestDevice_OmitsEmptyNewFields(t *testing.T) {
+	data, err := json.Marshal(models.Device{ID: "abc123def456", Hostname: "OLD-PC"})
+	if err != nil {
+		t.Fatalf("failed to marshal device: %v", err)
+	}
+
+	for _, key := range []string{`"os"`, `"arch"`, `"tool_version"`} {
+		if strings.Contains(string(data), key) {
+			t.Errorf("Expected %s to be omitted, got %s", key, data)
+		}
+	}
+}
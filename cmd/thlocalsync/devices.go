@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devicesList       bool
+	devicesStripPaths bool
+)
+
+var devicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "登録デバイスの一覧/削除",
+	Long: `devices.json に登録されているデバイスを一覧表示・削除します。
+
+使用例:
+  thlocalsync devices list                      登録デバイスを一覧表示
+  thlocalsync devices remove <id>               デバイスを削除
+  thlocalsync devices remove <id> --strip-paths paths.json からも削除`,
+	Args: cobra.NoArgs,
+	RunE: runDevices,
+}
+
+var devicesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "登録デバイスを一覧表示",
+	Args:  cobra.NoArgs,
+	RunE:  runDevices,
+}
+
+var devicesRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "登録デバイスを削除",
+	Args:  cobra.ExactArgs(1),
+	RunE:  withRunLock(runDevicesRemove),
+}
+
+func init() {
+	devicesCmd.Flags().BoolVarP(&devicesList, "list", "l", false, "登録デバイスを一覧表示")
+	devicesRemoveCmd.Flags().BoolVar(&devicesStripPaths, "strip-paths", false, "paths.json に登録されたこのデバイスのパスも削除")
+
+	devicesCmd.AddCommand(devicesListCmd)
+	devicesCmd.AddCommand(devicesRemoveCmd)
+}
+
+func runDevices(cmd *cobra.Command, args []string) error {
+	fmt.Printf("=== thlocalsync devices ===\n\n")
+
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return fmt.Errorf("failed to load devices config: %w", err)
+	}
+
+	if len(devicesConfig.Devices) == 0 {
+		fmt.Println("No devices registered. Run 'thlocalsync detect' first.")
+		return nil
+	}
+
+	// Current device is marked in the listing; failure to identify it is not fatal
+	currentID, _, _, _ := device.GetDeviceID()
+
+	fmt.Printf("  %-12s %-20s %-15s %-10s %-20s\n", "ID", "Hostname", "OS/Arch", "Version", "Last seen")
+	fmt.Println(strings.Repeat("-", 84))
+	for _, d := range devicesConfig.Devices {
+		marker := " "
+		if d.ID == currentID {
+			marker = "*"
+		}
+		fmt.Printf("%s %-12s %-20s %-15s %-10s %-20s\n",
+			marker, d.ID, d.Hostname, formatPlatform(d), orUnknown(d.ToolVersion),
+			d.LastSeen.Local().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Println("\n* = this device")
+
+	return nil
+}
+
+func runDevicesRemove(cmd *cobra.Command, args []string) error {
+	deviceID := args[0]
+
+	fmt.Printf("=== thlocalsync devices remove ===\n\n")
+
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return fmt.Errorf("failed to load devices config: %w", err)
+	}
+
+	index := -1
+	for i, d := range devicesConfig.Devices {
+		if d.ID == deviceID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("device not found: %s", deviceID)
+	}
+	removed := devicesConfig.Devices[index]
+
+	// Removing the running device orphans its paths until the next detect
+	if currentID, _, _, err := device.GetDeviceID(); err == nil && currentID == deviceID {
+		fmt.Printf("⚠ %s (%s) is the device you are running on.\n", deviceID, removed.Hostname)
+		if !confirm("Remove it anyway?") {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	devicesConfig.Devices = append(devicesConfig.Devices[:index], devicesConfig.Devices[index+1:]...)
+	if err := config.SaveDevices(devicesConfig); err != nil {
+		return fmt.Errorf("failed to save devices config: %w", err)
+	}
+	fmt.Printf("✓ Removed device %s (%s)\n", deviceID, removed.Hostname)
+
+	if !devicesStripPaths {
+		return nil
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	stripped := stripDevicePaths(pathsConfig, deviceID)
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+	fmt.Printf("✓ Removed paths for %d title(s)\n", stripped)
+
+	return nil
+}
+
+// stripDevicePaths removes a device's entries from paths.json, dropping titles left
+// with no devices. Returns the number of titles that had an entry for the device.
+func stripDevicePaths(pathsConfig *models.PathsConfig, deviceID string) int {
+	count := 0
+	for title, titlePaths := range pathsConfig.Paths {
+		if _, ok := titlePaths[deviceID]; !ok {
+			continue
+		}
+		delete(titlePaths, deviceID)
+		if len(titlePaths) == 0 {
+			delete(pathsConfig.Paths, title)
+		}
+		count++
+	}
+	return count
+}
+
+// formatPlatform returns "os/arch" for a device, or "unknown" for records from older versions.
+func formatPlatform(d models.Device) string {
+	if d.OS == "" && d.Arch == "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s/%s", orUnknown(d.OS), orUnknown(d.Arch))
+}
+
+// orUnknown returns s, or "unknown" if s is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
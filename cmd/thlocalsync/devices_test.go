@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestStripDevicePaths(t *testing.T) {
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {
+			"abc123def456": {Paths: []string{"C:\\th08\\score.dat"}},
+			"fff000fff000": {Paths: []string{"D:\\th08\\score.dat"}},
+		},
+		"th10": {
+			"abc123def456": {Paths: []string{"C:\\th10\\scoreth10.dat"}},
+		},
+		"th12": {
+			"fff000fff000": {Paths: []string{"D:\\th12\\scoreth12.dat"}},
+		},
+	}}
+
+	if got := stripDevicePaths(pathsConfig, "abc123def456"); got != 2 {
+		t.Errorf("Expected 2 titles stripped, got %d", got)
+	}
+
+	if _, ok := pathsConfig.Paths["th08"]["abc123def456"]; ok {
+		t.Error("Expected th08 entry for removed device to be gone")
+	}
+	if _, ok := pathsConfig.Paths["th08"]["fff000fff000"]; !ok {
+		t.Error("Expected other device's th08 entry to remain")
+	}
+	if _, ok := pathsConfig.Paths["th10"]; ok {
+		t.Error("Expected th10 to be dropped once it has no devices")
+	}
+	if _, ok := pathsConfig.Paths["th12"]; !ok {
+		t.Error("Expected th12 to be untouched")
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "実行環境を自己診断",
+	Long: `実行環境を確認し、項目ごとに PASS/WARN/FAIL と対処方法を表示します。
+
+確認内容:
+  - data/・vault/・logs/ を置くディレクトリと、その決定方法
+  - data/・vault/・logs/ に書き込めること
+  - 環境変数 APPDATA・LOCALAPPDATA が設定されていること
+  - 実行ファイルがリムーバブルドライブ上にあること（Windowsのみ）
+  - このデバイスに登録されたパスが解決できること
+
+FAIL が1つでもあれば終了コード1で終了します。`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+// Outcomes of a doctor check
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+	doctorSkip = "SKIP" // the check is not available on this platform
+)
+
+// doctorCheck is the outcome of one environment check.
+type doctorCheck struct {
+	Status string
+	Name   string
+	Detail string
+	Hint   string // how to fix a WARN/FAIL
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	fmt.Printf("=== thlocalsync doctor ===\n\n")
+
+	var checks []doctorCheck
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	logDir, err := paths.GetLogDir()
+	if err != nil {
+		return fmt.Errorf("failed to get log directory: %w", err)
+	}
+	homeDir, homeSource, err := paths.ResolveBaseDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	checks = append(checks,
+		checkBaseDir(homeDir, homeSource),
+		checkDirWritable("data directory", configDir),
+		checkDirWritable("vault directory", vaultDir),
+		checkDirWritable("log directory", logDir),
+		checkEnvVar("APPDATA"),
+		checkEnvVar("LOCALAPPDATA"),
+		checkRemovableDrive(),
+	)
+
+	deviceID, _, _, err := device.GetDeviceID()
+	if err != nil {
+		checks = append(checks, doctorCheck{
+			Status: doctorFail,
+			Name:   "device ID",
+			Detail: err.Error(),
+			Hint:   "set a fixed ID with --device-id or " + device.EnvDeviceID,
+		})
+	} else if pathsConfig, err := config.LoadPaths(); err != nil {
+		checks = append(checks, doctorCheck{
+			Status: doctorFail,
+			Name:   "paths.json",
+			Detail: err.Error(),
+			Hint:   "fix or remove data/paths.json, then run 'thlocalsync detect'",
+		})
+	} else {
+		checks = append(checks, checkRegisteredPaths(pathsConfig, deviceID)...)
+	}
+
+	counts := make(map[string]int)
+	for _, check := range checks {
+		counts[check.Status]++
+		fmt.Printf("[%s] %s", check.Status, check.Name)
+		if check.Detail != "" {
+			fmt.Printf(": %s", check.Detail)
+		}
+		fmt.Println()
+		if check.Hint != "" && check.Status != doctorPass {
+			fmt.Printf("       → %s\n", check.Hint)
+		}
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	fmt.Printf("PASS: %d, WARN: %d, FAIL: %d, SKIP: %d\n",
+		counts[doctorPass], counts[doctorWarn], counts[doctorFail], counts[doctorSkip])
+
+	if counts[doctorFail] > 0 {
+		// A failed check is a result, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d check(s) failed", counts[doctorFail])
+	}
+	return nil
+}
+
+// checkDirWritable checks that a file can be created in dir. A directory that does not
+// exist yet is a warning if its parent exists, since init or the first write creates it.
+func checkDirWritable(name, dir string) doctorCheck {
+	check := doctorCheck{Name: name + " writable", Detail: dir}
+
+	if !utils.DirExists(dir) {
+		if utils.DirExists(filepath.Dir(dir)) {
+			check.Status = doctorWarn
+			check.Detail = dir + " (does not exist yet)"
+			check.Hint = "run 'thlocalsync init'"
+			return check
+		}
+		check.Status = doctorFail
+		check.Detail = dir + " (parent directory does not exist)"
+		check.Hint = "reconnect the portable storage, or point --home / " + paths.EnvHome + " (or --config-dir, --vault-dir, --log-dir) at an existing directory"
+		return check
+	}
+
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("%s (%v)", dir, err)
+		check.Hint = "make the drive writable (read-only media, write protection switch, permissions) or move thlocalsync"
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	check.Status = doctorPass
+	return check
+}
+
+// checkBaseDir reports where data/, vault/ and logs/ live and which rule chose that directory.
+func checkBaseDir(dir string, source paths.Source) doctorCheck {
+	check := doctorCheck{
+		Status: doctorPass,
+		Name:   "base directory",
+		Detail: fmt.Sprintf("%s (from %s)", dir, source),
+	}
+	if source == paths.SourceWorkingDir {
+		// The executable runs from the temp directory, so the data follows the current directory
+		check.Status = doctorWarn
+		check.Hint = "the executable is in the temp directory; pin the base directory with --home or " + paths.EnvHome
+	}
+	return check
+}
+
+// checkEnvVar checks that a Windows environment variable used by save detection is set.
+// Outside Windows (e.g. Wine setups) it is only a warning.
+func checkEnvVar(name string) doctorCheck {
+	check := doctorCheck{Name: name + " set"}
+
+	if value := os.Getenv(name); value != "" {
+		check.Status = doctorPass
+		check.Detail = value
+		return check
+	}
+
+	check.Detail = "not set"
+	check.Hint = "saves under %" + name + "% cannot be detected; register them with 'thlocalsync detect --import'"
+	if runtime.GOOS == "windows" {
+		check.Status = doctorFail
+		check.Hint = "run thlocalsync from a normal user session, where " + name + " is always set"
+	} else {
+		check.Status = doctorWarn
+	}
+	return check
+}
+
+// checkRemovableDrive checks that the executable lives on removable storage, as intended.
+func checkRemovableDrive() doctorCheck {
+	check := doctorCheck{Name: "executable on removable drive"}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		check.Status = doctorWarn
+		check.Detail = err.Error()
+		return check
+	}
+	check.Detail = exePath
+
+	removable, err := utils.IsRemovableDrive(exePath)
+	switch {
+	case errors.Is(err, utils.ErrDriveTypeUnknown):
+		check.Status = doctorSkip
+		check.Detail = fmt.Sprintf("%s (%v)", exePath, err)
+	case err != nil:
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%s (%v)", exePath, err)
+	case removable:
+		check.Status = doctorPass
+	case isNetworkDrive(exePath):
+		// A network share is portable storage too: every device reaches the same vault
+		check.Status = doctorPass
+		check.Detail = exePath + " (network share)"
+	default:
+		check.Status = doctorWarn
+		check.Hint = "fine for a fixed or external drive; otherwise keep thlocalsync on the portable storage with the vault"
+	}
+	return check
+}
+
+// isNetworkDrive reports whether path is on a network share; false if unknown.
+func isNetworkDrive(path string) bool {
+	network, err := utils.IsNetworkDrive(path)
+	return err == nil && network
+}
+
+// checkRegisteredPaths checks the preferred path of each title registered for deviceID.
+// A missing save file is a warning (the game may not have written one yet), a missing
+// directory is a failure (the drive or game folder is gone).
+func checkRegisteredPaths(pathsConfig *models.PathsConfig, deviceID string) []doctorCheck {
+	var checks []doctorCheck
+
+	for _, title := range sortedPathTitles(pathsConfig) {
+		entry, ok := pathsConfig.Paths[title][deviceID]
+		if !ok || len(entry.Paths) == 0 {
+			continue
+		}
+
+		check := doctorCheck{Name: title + " path"}
+		preferred := entry.Preferred
+		if preferred < 0 || preferred >= len(entry.Paths) {
+			check.Status = doctorFail
+			check.Detail = fmt.Sprintf("preferred index %d is out of range", preferred)
+			check.Hint = fmt.Sprintf("run 'thlocalsync paths set-preferred %s <index>'", title)
+			checks = append(checks, check)
+			continue
+		}
+
+		path := utils.ExpandEnvPath(entry.Paths[preferred])
+		check.Detail = path
+		exists, readable := utils.FileExists(path)
+		switch {
+		case exists && readable:
+			check.Status = doctorPass
+		case exists:
+			check.Status = doctorFail
+			check.Detail = path + " (not readable)"
+			check.Hint = "check the file permissions, or close programs holding the file"
+		case utils.DirExists(filepath.Dir(path)):
+			check.Status = doctorWarn
+			check.Detail = path + " (no save file yet)"
+			check.Hint = "start the game once, or push to place the vault copy"
+		default:
+			check.Status = doctorFail
+			check.Detail = path + " (directory does not exist)"
+			check.Hint = fmt.Sprintf("run 'thlocalsync paths doctor' or 'thlocalsync paths set-preferred %s <index>'", title)
+		}
+		checks = append(checks, check)
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{
+			Status: doctorWarn,
+			Name:   "registered paths",
+			Detail: "no paths registered for this device",
+			Hint:   "run 'thlocalsync detect'",
+		})
+	}
+	return checks
+}
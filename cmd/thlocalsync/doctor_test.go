@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestCheckDirWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	if check := checkDirWritable("data directory", dir); check.Status != doctorPass {
+		t.Errorf("Expected PASS for a writable directory, got %+v", check)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the probe file to be removed, found %d entries", len(entries))
+	}
+
+	if check := checkDirWritable("vault directory", filepath.Join(dir, "vault")); check.Status != doctorWarn {
+		t.Errorf("Expected WARN for a directory not created yet, got %+v", check)
+	}
+	if check := checkDirWritable("log directory", filepath.Join(dir, "gone", "logs")); check.Status != doctorFail {
+		t.Errorf("Expected FAIL when the parent directory is missing, got %+v", check)
+	}
+}
+
+func TestCheckRegisteredPaths(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(existing, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th06": {"dev1": {Paths: []string{existing}}},
+		"th07": {"dev1": {Paths: []string{filepath.Join(dir, "scoreth07.dat")}}},
+		"th08": {"dev1": {Paths: []string{filepath.Join(dir, "gone", "score.dat")}}},
+		"th10": {"dev1": {Paths: []string{existing}, Preferred: 3}},
+		"th11": {"dev2": {Paths: []string{existing}}},
+	}}
+
+	checks := checkRegisteredPaths(pathsConfig, "dev1")
+	want := []string{doctorPass, doctorWarn, doctorFail, doctorFail}
+	if len(checks) != len(want) {
+		t.Fatalf("Expected %d checks (other devices skipped), got %+v", len(want), checks)
+	}
+	for i, status := range want {
+		if checks[i].Status != status {
+			t.Errorf("Check %d (%s): expected %s, got %s", i, checks[i].Name, status, checks[i].Status)
+		}
+	}
+
+	if checks := checkRegisteredPaths(pathsConfig, "dev3"); len(checks) != 1 || checks[0].Status != doctorWarn {
+		t.Errorf("Expected a single WARN when nothing is registered, got %+v", checks)
+	}
+}
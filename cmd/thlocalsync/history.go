@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySince string
+	historyLimit int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <title>",
+	Short: "タイトルの同期履歴（操作のタイムライン）を表示",
+	Long: `logs/ 以下のJSON Linesログ（日ごとのファイルすべて）から指定タイトルの
+pull/push・スキップ・キャンセル・競合・隔離の記録を抜き出し、古い順に表示します。
+各行には時刻、イベント、方向（local → usb など）、デバイス、理由を表示します。
+
+backup --list がファイル単位の履歴なのに対し、こちらは操作単位の履歴です。
+ログの保存期間より古い記録は表示されません。
+
+使用例:
+  thlocalsync history th08
+  thlocalsync history th08 --since 2025-01-01
+  thlocalsync history th08 --limit 20`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historySince, "since", "", "指定日以降の記録のみ（YYYY-MM-DD）")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 0, "表示する最大件数（直近から数える、0で無制限）")
+}
+
+// Events of the history timeline.
+const (
+	historyPull       = "PULL"
+	historyPush       = "PUSH"
+	historySkip       = "SKIP"
+	historyCancel     = "CANCEL"
+	historyConflict   = "CONFLICT"
+	historyQuarantine = "QUARANTINE"
+)
+
+// historyEvent is one sync operation of a title, taken from a log entry.
+type historyEvent struct {
+	Time      time.Time
+	Event     string
+	Direction string // "local → usb" for pulls, "usb → local" for pushes; empty if nothing was copied
+	Device    string
+	Reason    string
+}
+
+// historyEvents maps log messages to timeline events. Other messages are not part of
+// the timeline.
+var historyEvents = map[string]string{
+	"pull":                  historyPull,
+	"push":                  historyPush,
+	"pull_skip":             historySkip,
+	"push_skip":             historySkip,
+	"ui_skip":               historySkip,
+	"pull_cancel":           historyCancel,
+	"push_cancel":           historyCancel,
+	"pull_conflict_skipped": historyConflict,
+	"push_conflict_skipped": historyConflict,
+	"pull_quarantine":       historyQuarantine,
+	"push_quarantine":       historyQuarantine,
+}
+
+// historyEventFromRecord returns the timeline event of a log entry, and false if the
+// entry is not a sync operation.
+func historyEventFromRecord(r logger.Record) (historyEvent, bool) {
+	event, ok := historyEvents[r.Message]
+	if !ok {
+		return historyEvent{}, false
+	}
+
+	from, _ := r.Fields["from"].(string)
+	to, _ := r.Fields["to"].(string)
+	device, _ := r.Fields["device"].(string)
+	reason, _ := r.Fields["reason"].(string)
+
+	var direction string
+	if from != "" && to != "" {
+		direction = from + " → " + to
+	}
+
+	return historyEvent{
+		Time:      r.Time,
+		Event:     event,
+		Direction: direction,
+		Device:    device,
+		Reason:    reason,
+	}, true
+}
+
+// collectHistory reads the sync operations matching filter from every log file, oldest
+// first, keeping the most recent limit events (0 keeps all).
+func collectHistory(log *logger.Logger, filter logger.Filter, limit int) ([]historyEvent, []*logger.ParseError, error) {
+	records, parseErrors, err := log.ReadEntries(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	// Filter.Limit would count every entry, so the limit is applied to events only
+	var events []historyEvent
+	for _, r := range records {
+		if event, ok := historyEventFromRecord(r); ok {
+			events = append(events, event)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+
+	return events, parseErrors, nil
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	title := args[0]
+	if !pathdetect.IsValidTitleCode(title) {
+		return fmt.Errorf("invalid title code: %s", title)
+	}
+
+	filter := logger.Filter{Title: title}
+	if historySince != "" {
+		since, err := time.ParseInLocation("2006-01-02", historySince, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date: %s (use YYYY-MM-DD)", historySince)
+		}
+		filter.Since = since
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	events, parseErrors, err := collectHistory(log, filter, historyLimit)
+	if err != nil {
+		return err
+	}
+	for _, e := range parseErrors {
+		fmt.Fprintf(os.Stderr, "⚠ Skipped malformed line %v\n", e)
+	}
+
+	fmt.Printf("=== thlocalsync history %s ===\n\n", title)
+
+	if len(events) == 0 {
+		fmt.Println("No sync history recorded.")
+		return nil
+	}
+
+	fmt.Printf("%-19s %-10s %-13s %-12s %s\n", "Time", "Event", "Direction", "Device", "Reason")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, e := range events {
+		direction := e.Direction
+		if direction == "" {
+			direction = "-"
+		}
+		device := e.Device
+		if device == "" {
+			device = "-"
+		}
+		fmt.Printf("%-19s %-10s %-13s %-12s %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Event, direction, device, e.Reason)
+	}
+
+	fmt.Printf("\n%d event(s)\n", len(events))
+
+	return nil
+}
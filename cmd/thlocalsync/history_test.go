@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+func TestCollectHistory(t *testing.T) {
+	home := t.TempDir()
+	paths.HomeOverride = home
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	logDir := filepath.Join(home, logger.LogDir)
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]string{
+		"2025-11-10.log": {
+			`{"level":"INFO","time":"2025-11-10T10:00:00Z","msg":"pull","title":"th08","device":"dev1","from":"local","to":"usb","reason":"local is newer"}`,
+			`{"level":"INFO","time":"2025-11-10T10:00:01Z","msg":"replay_archive_complete","title":"th08"}`,
+			`{"level":"INFO","time":"2025-11-10T10:00:02Z","msg":"pull","title":"th10","device":"dev1","from":"local","to":"usb","reason":"local is newer"}`,
+		},
+		"2025-11-11.log": {
+			`{"level":"WARN","time":"2025-11-11T09:00:00Z","msg":"push_conflict_skipped","title":"th08","reason":"stdin is not a terminal"}`,
+			`{"level":"INFO","time":"2025-11-11T09:30:00Z","msg":"push","title":"th08","device":"dev2","from":"usb","to":"local","reason":"remote is newer"}`,
+		},
+	}
+	for name, lines := range files {
+		if err := os.WriteFile(filepath.Join(logDir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, parseErrors, err := collectHistory(log, logger.Filter{Title: "th08"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parseErrors) != 0 {
+		t.Errorf("Unexpected parse errors: %v", parseErrors)
+	}
+
+	want := []struct{ event, direction, device string }{
+		{historyPull, "local → usb", "dev1"},
+		{historyConflict, "", ""},
+		{historyPush, "usb → local", "dev2"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events across both log files, got %d: %+v", len(want), len(events), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Event != w.event || e.Direction != w.direction || e.Device != w.device {
+			t.Errorf("events[%d] = %+v, want %s %q %q", i, e, w.event, w.direction, w.device)
+		}
+	}
+
+	// The limit keeps the most recent events
+	events, _, err = collectHistory(log, logger.Filter{Title: "th08"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Event != historyPush {
+		t.Errorf("Expected only the last push with --limit 1, got %+v", events)
+	}
+}
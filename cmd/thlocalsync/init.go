@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "ポータブルストレージ上にディレクトリ構成を作成",
+	Long: `実行ファイルの隣に data/（設定ファイル）、vault/、logs/ を作成します。
+
+devices.json / paths.json / rules.json は存在しない場合のみ既定値で作成され、
+既存のファイルは上書きしません。何度実行しても安全です。
+取り外し可能なドライブ以外から実行した場合は警告を表示します。
+
+使用例:
+  thlocalsync init`,
+	Args: cobra.NoArgs,
+	RunE: withRunLock(runInit),
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	fmt.Printf("=== thlocalsync init ===\n\n")
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	// Warn when not running from portable storage
+	if removable, err := utils.IsRemovableDrive(configDir); err == nil && !removable {
+		fmt.Println("⚠ Warning: this does not appear to be a removable drive.")
+		fmt.Println("  thlocalsync is meant to live on portable storage next to its vault.")
+		fmt.Println()
+	}
+
+	if err := utils.EnsureDir(configDir); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Create config files with defaults, never overwriting existing ones
+	configFiles := []struct {
+		name string
+		save func() error
+	}{
+		{config.DevicesFile, func() error {
+			return config.SaveDevices(&models.DeviceConfig{Devices: []models.Device{}})
+		}},
+		{config.PathsFile, func() error {
+			return config.SavePaths(&models.PathsConfig{Paths: make(map[string]map[string]models.PathEntry)})
+		}},
+		{config.RulesFile, func() error {
+			return config.SaveRules(config.DefaultRules())
+		}},
+	}
+
+	for _, f := range configFiles {
+		if exists, _ := utils.FileExists(filepath.Join(configDir, f.name)); exists {
+			fmt.Printf("- %s: exists\n", f.name)
+			continue
+		}
+		if err := f.save(); err != nil {
+			return fmt.Errorf("failed to create %s: %w", f.name, err)
+		}
+		fmt.Printf("✓ %s: created\n", f.name)
+	}
+
+	// Write JSON Schemas for editor support
+	schemaDir := filepath.Join(configDir, "schemas")
+	if err := config.WriteSchemas(schemaDir); err != nil {
+		return fmt.Errorf("failed to write schemas: %w", err)
+	}
+
+	// Create vault directory
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	if err := utils.EnsureDir(vaultDir); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	// Create log directory
+	if _, err := logger.New(); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	logDir, err := paths.GetLogDir()
+	if err != nil {
+		return fmt.Errorf("failed to get log directory: %w", err)
+	}
+
+	fmt.Printf("\nConfig:  %s\n", configDir)
+	fmt.Printf("Schemas: %s\n", schemaDir)
+	fmt.Printf("Vault:   %s\n", vaultDir)
+	fmt.Printf("Logs:    %s\n", logDir)
+	fmt.Println("\nNext: run 'thlocalsync detect' to register save file paths.")
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectOtherVault string
+	inspectJSON       bool
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect --other-vault <path> [title|all]",
+	Short: "別のvaultと比較（読み取り専用）",
+	Long: `現在のvaultと別ドライブ上のvaultを比較します。
+
+各タイトルの vault/<title>/main を比較し、どちらが新しい/大きい/同一かを表示します。
+ファイルの書き込みは一切行いません。どちらのポータブルストレージを正とするか
+判断する際に使用してください。
+
+使用例:
+  thlocalsync inspect --other-vault E:\thlocalsync\vault all
+  thlocalsync inspect --other-vault E:\thlocalsync\vault th08 --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().StringVar(&inspectOtherVault, "other-vault", "", "比較対象のvaultディレクトリ")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "JSON形式で出力")
+	inspectCmd.MarkFlagRequired("other-vault")
+}
+
+// inspectResult is the per-title result of comparing two vaults.
+type inspectResult struct {
+	Title          string `json:"title"`
+	Current        string `json:"current"`
+	Other          string `json:"other"`
+	Verdict        string `json:"verdict"` // "current", "other", "identical", "skip", "conflict", "error"
+	Recommendation string `json:"recommendation,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	// Determine target title
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+
+	otherVault := strings.Trim(inspectOtherVault, "\"")
+	if info, err := os.Stat(otherVault); err != nil || !info.IsDir() {
+		return fmt.Errorf("other vault not found: %s", otherVault)
+	}
+
+	currentVault, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	titles, err := collectVaultTitles(targetTitle, currentVault, otherVault)
+	if err != nil {
+		return err
+	}
+
+	var results []inspectResult
+	for _, title := range titles {
+		results = append(results, inspectTitle(title, currentVault, otherVault))
+	}
+
+	if inspectJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("=== thlocalsync inspect ===\n")
+	fmt.Printf("Current: %s\n", currentVault)
+	fmt.Printf("Other:   %s\n\n", otherVault)
+
+	if len(results) == 0 {
+		fmt.Println("No titles found in either vault.")
+		return nil
+	}
+
+	fmt.Printf("%-8s %-35s %-35s %-25s\n",
+		"Title", "Current", "Other", "Verdict")
+	fmt.Println(strings.Repeat("-", 110))
+
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Printf("%-8s ERROR: %s\n", r.Title, r.Error)
+			continue
+		}
+		fmt.Printf("%-8s %-35s %-35s %-25s\n", r.Title, r.Current, r.Other, formatInspectVerdict(r))
+	}
+
+	return nil
+}
+
+// collectVaultTitles returns the titles to compare between two vaults.
+// For "all", this is the union of titles present in either vault, in release order.
+func collectVaultTitles(targetTitle, currentVault, otherVault string) ([]string, error) {
+	if targetTitle != "all" {
+		if !pathdetect.IsValidTitleCode(targetTitle) {
+			return nil, fmt.Errorf("invalid title code: %s", targetTitle)
+		}
+		return []string{targetTitle}, nil
+	}
+
+	seen := make(map[string]bool)
+	var titles []string
+	for _, vaultDir := range []string{currentVault, otherVault} {
+		vaultTitles, err := backup.ListVaultTitles(vaultDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, title := range vaultTitles {
+			if !pathdetect.IsValidTitleCode(title) || seen[title] {
+				continue
+			}
+			seen[title] = true
+			titles = append(titles, title)
+		}
+	}
+
+	return pathdetect.SortTitlesByRelease(titles), nil
+}
+
+// inspectTitle compares one title between the current vault and another vault.
+func inspectTitle(title, currentVault, otherVault string) inspectResult {
+	result := inspectResult{Title: title}
+	fileName := getVaultFileName(title)
+
+	currentMeta, err := sync.GetFileMetadata(filepath.Join(backup.GetTitleVaultPathIn(currentVault, title, backup.DefaultSlot), fileName))
+	if err != nil {
+		result.Verdict = "error"
+		result.Error = fmt.Sprintf("failed to get current metadata: %v", err)
+		return result
+	}
+
+	otherMeta, err := sync.GetFileMetadata(filepath.Join(backup.GetTitleVaultPathIn(otherVault, title, backup.DefaultSlot), fileName))
+	if err != nil {
+		result.Verdict = "error"
+		result.Error = fmt.Sprintf("failed to get other metadata: %v", err)
+		return result
+	}
+
+	// Current vault plays the "local" role, the other vault the "remote" role
+	comparison, err := sync.CompareWithRehash(currentMeta, otherMeta)
+	if err != nil {
+		result.Verdict = "error"
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Current = formatFileInfo(currentMeta)
+	result.Other = formatFileInfo(otherMeta)
+	result.Recommendation = comparison.Recommendation
+	result.Reason = comparison.Reason
+	result.Verdict = inspectVerdict(comparison)
+
+	return result
+}
+
+// inspectVerdict maps a comparison recommendation to which vault holds the preferred file.
+func inspectVerdict(comparison *models.ComparisonResult) string {
+	switch comparison.Recommendation {
+	case "PULL":
+		return "current"
+	case "PUSH":
+		return "other"
+	case "SKIP":
+		if comparison.HashMatch {
+			return "identical"
+		}
+		return "skip"
+	default:
+		return "conflict"
+	}
+}
+
+func formatInspectVerdict(r inspectResult) string {
+	switch r.Verdict {
+	case "current":
+		return fmt.Sprintf("← current (%s)", shortenReason(r.Reason))
+	case "other":
+		return fmt.Sprintf("→ other (%s)", shortenReason(r.Reason))
+	case "identical":
+		return "= identical"
+	case "conflict":
+		return fmt.Sprintf("⚠ CONFLICT (%s)", shortenReason(r.Reason))
+	default:
+		return fmt.Sprintf("- %s (%s)", r.Verdict, shortenReason(r.Reason))
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logTitle string
+	logLevel string
+	logSince string
+	logLimit int
+	logJSON  bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "操作ログの検索・表示",
+	Long: `logs/ 以下のJSON Linesログを読み込み、条件に合う記録を古い順に表示します。
+解析できない行は警告を出して読み飛ばします。
+
+使用例:
+  thlocalsync log                              直近50件を表示
+  thlocalsync log --title th08 --level ERROR   th08のエラーのみ
+  thlocalsync log --since 2025-01-01 --limit 0 指定日以降をすべて表示
+  thlocalsync log --json                       JSON Linesのまま出力`,
+	Args: cobra.NoArgs,
+	RunE: runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logTitle, "title", "", "タイトルで絞り込み（例: th08）")
+	logCmd.Flags().StringVar(&logLevel, "level", "", "ログレベルで絞り込み（INFO / WARN / ERROR）")
+	logCmd.Flags().StringVar(&logSince, "since", "", "指定日以降の記録のみ（YYYY-MM-DD）")
+	logCmd.Flags().IntVar(&logLimit, "limit", 50, "表示する最大件数（直近から数える、0で無制限）")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "JSON Linesのまま出力")
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	filter := logger.Filter{
+		Title: logTitle,
+		Level: logger.Level(strings.ToUpper(logLevel)),
+		Limit: logLimit,
+	}
+
+	switch filter.Level {
+	case "", logger.LevelInfo, logger.LevelWarn, logger.LevelError:
+	default:
+		return fmt.Errorf("invalid log level: %s (use INFO, WARN or ERROR)", logLevel)
+	}
+
+	if logSince != "" {
+		since, err := time.ParseInLocation("2006-01-02", logSince, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date: %s (use YYYY-MM-DD)", logSince)
+		}
+		filter.Since = since
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	records, parseErrors, err := log.ReadEntries(filter)
+	if err != nil {
+		return fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	// Warnings go to stderr so --json output stays pipeable
+	for _, e := range parseErrors {
+		fmt.Fprintf(os.Stderr, "⚠ Skipped malformed line %v\n", e)
+	}
+
+	if logJSON {
+		for _, r := range records {
+			fmt.Println(r.Raw)
+		}
+		return nil
+	}
+
+	fmt.Printf("=== thlocalsync log ===\n\n")
+
+	if len(records) == 0 {
+		fmt.Println("No matching log entries.")
+		return nil
+	}
+
+	fmt.Printf("%-19s %-5s %-24s %-8s %s\n", "Time", "Level", "Message", "Title", "Details")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, r := range records {
+		title, _ := r.Fields["title"].(string)
+		fmt.Printf("%-19s %-5s %-24s %-8s %s\n",
+			r.Time.Local().Format("2006-01-02 15:04:05"), r.Level, r.Message, title, formatLogDetails(r.Fields))
+	}
+
+	fmt.Printf("\n%d entries\n", len(records))
+
+	return nil
+}
+
+// formatLogDetails renders the fields other than title as sorted key=value pairs.
+func formatLogDetails(fields map[string]interface{}) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key == "title" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, fields[key]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -2,9 +2,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/process"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +24,20 @@ var (
 	date    = "unknown"
 )
 
+var (
+	profileTimings    bool
+	networkVault      bool
+	deviceIDOverride  string
+	homeOverride      string
+	configDirOverride string
+	vaultDirOverride  string
+	logDirOverride    string
+	noHashCache       bool
+	quietOutput       bool
+	verboseOutput     bool
+	copyThrottle      uint64
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "thlocalsync",
 	Short: "東方Project セーブデータ同期ツール",
@@ -24,23 +47,137 @@ var rootCmd = &cobra.Command{
 タイトル別の保存パスを半自動認識＋対話的に登録/編集。
 mtime・ハッシュ・サイズの三点で新旧/正誤判定。`,
 	Version: version,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if profileTimings {
+			timing.Enable()
+		}
+		if quietOutput {
+			console.SetVerbosity(console.Quiet)
+		} else if verboseOutput {
+			console.SetVerbosity(console.Verbose)
+		}
+		if networkVault {
+			process.LockProbeTimeout = process.NetworkLockProbeTimeout
+		}
+		if copyThrottle > 0 {
+			utils.CopyRateLimit = int64(copyThrottle)
+		}
+		if deviceIDOverride != "" {
+			device.OverrideID = deviceIDOverride
+		}
+		// Must be set before anything under data/ is read
+		if homeOverride != "" {
+			paths.HomeOverride = homeOverride
+		}
+		paths.ConfigDirOverride = configDirOverride
+		paths.VaultDirOverride = vaultDirOverride
+		paths.LogDirOverride = logDirOverride
+		// A broken titles.json must not lock users out of the built-in titles
+		if err := loadUserTitles(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		if err := loadVaultFileNames(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	},
 }
 
 func init() {
 	// Set custom version template
 	rootCmd.SetVersionTemplate(fmt.Sprintf("thlocalsync %s (commit: %s, built: %s)\n", version, commit, date))
 
+	// Global flags
+	rootCmd.PersistentFlags().BoolVar(&profileTimings, "profile-timings", false, "フェーズ別の所要時間を表示")
+	rootCmd.PersistentFlags().BoolVar(&networkVault, "network-vault", false, "vaultがネットワーク共有上にある（ロック確認の待機時間を延長）")
+	rootCmd.PersistentFlags().StringVar(&deviceIDOverride, "device-id", "", "デバイスIDを指定（12桁の16進数、環境変数 "+device.EnvDeviceID+" より優先）")
+	rootCmd.PersistentFlags().StringVar(&homeOverride, "home", "", "data/・vault/・logs/ を置くディレクトリ（既定は実行ファイルの場所、環境変数 "+paths.EnvHome+" より優先）")
+	rootCmd.PersistentFlags().StringVar(&configDirOverride, "config-dir", "", "data/ の代わりに使う設定ディレクトリ（既定は --home 以下の data/、環境変数 "+paths.EnvConfigDir+" より優先）")
+	rootCmd.PersistentFlags().StringVar(&vaultDirOverride, "vault-dir", "", "vault/ の代わりに使うvaultディレクトリ（既定は --home 以下の vault/、環境変数 "+paths.EnvVaultDir+" より優先）")
+	rootCmd.PersistentFlags().StringVar(&logDirOverride, "log-dir", "", "logs/ の代わりに使うログディレクトリ（既定は --home 以下の logs/、環境変数 "+paths.EnvLogDir+" より優先）")
+	rootCmd.PersistentFlags().BoolVar(&noHashCache, "no-cache", false, "ハッシュキャッシュを使わず全ファイルを再計算")
+	rootCmd.PersistentFlags().BoolVarP(&quietOutput, "quiet", "q", false, "エラーのみ表示（見出し・✓/-の結果行・集計を省略）")
+	rootCmd.PersistentFlags().BoolVar(&verboseOutput, "verbose", false, "ファイルごとのパス・ハッシュ、コピー内容、detectで確認したパスを表示")
+	rootCmd.PersistentFlags().Uint64Var(&copyThrottle, "throttle", 0, "ファイルコピーの書き込み速度の上限（バイト/秒、0は無制限）")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+
 	// Add subcommands
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(detectCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(pullCmd)
 	rootCmd.AddCommand(pushCmd)
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(uiCmd)
 	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(mergeVaultCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(devicesCmd)
+	rootCmd.AddCommand(pathsCmd)
+	rootCmd.AddCommand(quarantineCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(whereisCmd)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+
+	// Hashes computed on write-protected storage are simply not cached
+	if !readOnlyStorage {
+		if saveErr := sync.SaveHashCache(); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", saveErr)
+		}
+	}
+
+	if timing.Enabled() {
+		reportTimings()
+	}
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// Process exit codes; see the pull/push help for their meaning
+const (
+	exitErrors    = 1
+	exitConflicts = 2
+)
+
+// exitCodeError makes the process exit with a code other than exitErrors.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+// exitCode returns the process exit code for an error returned by a command.
+func exitCode(err error) int {
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitErrors
+}
+
+// reportTimings prints the phase breakdown and records it in the log.
+func reportTimings() {
+	timing.Report(os.Stdout)
+
+	if readOnlyStorage {
+		return
+	}
+	if log, err := logger.New(); err == nil {
+		log.Info("timings", timing.Fields())
 	}
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeOtherVault string
+	mergeOnConflict string
+)
+
+var mergeVaultCmd = &cobra.Command{
+	Use:   "merge-vault --other-vault <path> [title|all]",
+	Short: "別のvaultを現在のvaultへ統合",
+	Long: `別ドライブ上のvaultを現在のvaultへ統合します。
+
+タイトルごとに比較し、優先されるファイルを現在のvaultの正本とします。
+採用されなかった側のファイルは必ず現在のvaultの履歴へバックアップされます。
+履歴ディレクトリもファイル名・ハッシュで重複を除いて統合します。
+
+競合時のポリシー (--on-conflict):
+  prompt   対話的に選択（既定）
+  current  現在のvaultを優先
+  other    別のvaultを優先
+  skip     そのタイトルを変更しない`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: withRunLock(runMergeVault),
+}
+
+func init() {
+	mergeVaultCmd.Flags().StringVar(&mergeOtherVault, "other-vault", "", "統合元のvaultディレクトリ")
+	mergeVaultCmd.Flags().StringVar(&mergeOnConflict, "on-conflict", "prompt", "競合時のポリシー (prompt|current|other|skip)")
+	mergeVaultCmd.MarkFlagRequired("other-vault")
+}
+
+func runMergeVault(cmd *cobra.Command, args []string) error {
+	// Determine target title
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+
+	switch mergeOnConflict {
+	case "prompt", "current", "other", "skip":
+	default:
+		return fmt.Errorf("invalid --on-conflict policy: %s", mergeOnConflict)
+	}
+
+	otherVault := strings.Trim(mergeOtherVault, "\"")
+	if info, err := os.Stat(otherVault); err != nil || !info.IsDir() {
+		return fmt.Errorf("other vault not found: %s", otherVault)
+	}
+
+	currentVault, err := backup.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	fmt.Printf("=== thlocalsync merge-vault ===\n")
+	fmt.Printf("Current: %s\n", currentVault)
+	fmt.Printf("Other:   %s\n\n", otherVault)
+
+	// Initialize logger
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	titles, err := collectVaultTitles(targetTitle, currentVault, otherVault)
+	if err != nil {
+		return err
+	}
+
+	if len(titles) == 0 {
+		fmt.Println("No titles found in either vault.")
+		return nil
+	}
+
+	successCount := 0
+	conflictCount := 0
+	errorCount := 0
+
+	for _, title := range titles {
+		result, err := mergeVaultTitle(title, currentVault, otherVault)
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", title, err)
+			errorCount++
+			log.Error("merge_vault_error", map[string]interface{}{
+				"title": title,
+				"other": otherVault,
+				"error": err.Error(),
+			})
+			continue
+		}
+
+		switch result.Action {
+		case "took_other":
+			fmt.Printf("✓ %s: Took other vault (%s)\n", title, result.Comparison.Reason)
+		case "kept_current":
+			fmt.Printf("✓ %s: Kept current vault, other saved to history (%s)\n", title, result.Comparison.Reason)
+		case "identical":
+			fmt.Printf("- %s: Identical\n", title)
+		case "conflict":
+			fmt.Printf("⚠ %s: Conflict left unresolved (%s)\n", title, result.Comparison.Reason)
+			conflictCount++
+		default:
+			fmt.Printf("- %s: Skipped (%s)\n", title, result.Comparison.Reason)
+		}
+		if result.HistoryMerged > 0 || result.HistorySkipped > 0 {
+			fmt.Printf("    History: merged=%d, skipped=%d\n", result.HistoryMerged, result.HistorySkipped)
+		}
+
+		fields := map[string]interface{}{
+			"title":           title,
+			"other":           otherVault,
+			"action":          result.Action,
+			"reason":          result.Comparison.Reason,
+			"history_merged":  result.HistoryMerged,
+			"history_skipped": result.HistorySkipped,
+		}
+		// Record the resulting vault hash for verify
+		switch result.Action {
+		case "took_other":
+			fields["slot"] = backup.DefaultSlot
+			fields["vault_hash"] = result.Comparison.RemoteMeta.Hash
+		case "kept_current", "identical":
+			fields["slot"] = backup.DefaultSlot
+			fields["vault_hash"] = result.Comparison.LocalMeta.Hash
+		}
+		log.Info("merge_vault", fields)
+		if result.Action == "took_other" {
+			vaultPath := filepath.Join(backup.GetTitleVaultPathIn(currentVault, title, backup.DefaultSlot), getVaultFileName(title))
+			thlocalsync.UpdateManifest(title, backup.DefaultSlot, vaultPath, result.Comparison.RemoteMeta.Hash, log)
+		}
+
+		if result.Action != "conflict" {
+			successCount++
+		}
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	fmt.Printf("Success: %d, Conflicts: %d, Errors: %d\n", successCount, conflictCount, errorCount)
+
+	return nil
+}
+
+// mergeVaultTitle merges one title, asking the user on conflict when the policy is "prompt".
+func mergeVaultTitle(title, currentVault, otherVault string) (*sync.MergeResult, error) {
+	fileName := getVaultFileName(title)
+
+	result, err := sync.MergeVaultTitle(title, fileName, currentVault, otherVault, mergeOnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Action != "conflict" || mergeOnConflict != "prompt" {
+		return result, nil
+	}
+
+	// Current vault plays the "local" role, the other vault the "remote" role
+	var policy string
+	switch promptUserForConflictResolution(title, result.Comparison, "merge") {
+	case "local":
+		policy = "current"
+	case "remote":
+		policy = "other"
+	default:
+		return result, nil
+	}
+
+	resolved, err := sync.MergeVaultTitle(title, fileName, currentVault, otherVault, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// History was already merged by the first pass
+	resolved.HistoryMerged = result.HistoryMerged
+	resolved.HistorySkipped = result.HistorySkipped
+	return resolved, nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pathsDoctorFix bool
+)
+
+var pathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "paths.json の点検",
+	Long: `paths.json に登録されたセーブファイルのパスを点検します。
+
+使用例:
+  thlocalsync paths list th08             このデバイスの候補パスを一覧表示
+  thlocalsync paths set-preferred th08 1  優先パスを変更（番号は list の表示）
+  thlocalsync paths doctor                問題のあるパスを一覧表示（変更なし）
+  thlocalsync paths doctor --fix          見つからないパスと重複パスを削除`,
+	Args: cobra.NoArgs,
+}
+
+var pathsListCmd = &cobra.Command{
+	Use:   "list <title>",
+	Short: "このデバイスの候補パスを一覧表示",
+	Long: `このデバイスに登録されたタイトルの候補パスを、番号・存在状況とともに表示します。
+* が付いているのが pull/push で使われる優先パスです。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPathsList,
+}
+
+var pathsSetPreferredCmd = &cobra.Command{
+	Use:   "set-preferred <title> <index>",
+	Short: "優先パスを変更",
+	Long: `このデバイスで pull/push に使う候補パスを番号で指定します。
+番号は 'thlocalsync paths list <title>' で表示されるものです（0始まり）。`,
+	Args: cobra.ExactArgs(2),
+	RunE: withRunLock(runPathsSetPreferred),
+}
+
+var pathsDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "登録パスの問題を検出",
+	Long: `paths.json を走査し、次の問題を報告します。
+
+  - このデバイスのエントリで、環境変数を展開したパスが存在しないもの
+  - 同じデバイス内で同じファイルを指す重複パス
+  - このデバイスと同じファイルが別のデバイスIDにも登録されているもの
+    （ドライブ文字が同じ環境や、デバイスIDが変わった後の古いエントリ）
+
+既定では何も変更しません。--fix を指定すると、見つからないパスと重複パスを削除します。
+別デバイスとの重複は自動では直さないため、古いデバイスであれば
+'thlocalsync devices remove <id> --strip-paths' で削除してください。`,
+	Args: cobra.NoArgs,
+	RunE: runPathsDoctor,
+}
+
+func init() {
+	pathsDoctorCmd.Flags().BoolVar(&pathsDoctorFix, "fix", false, "見つからないパスと重複パスを paths.json から削除")
+
+	pathsCmd.AddCommand(pathsListCmd)
+	pathsCmd.AddCommand(pathsSetPreferredCmd)
+	pathsCmd.AddCommand(pathsDoctorCmd)
+}
+
+// Kinds of problems reported by paths doctor
+const (
+	pathIssueMissing   = "missing"   // path does not exist on this device
+	pathIssueDuplicate = "duplicate" // same file as an earlier path of the same device
+	pathIssueShared    = "shared"    // same file as a path registered for another device
+)
+
+// pathIssue is a problem found in one path of paths.json.
+type pathIssue struct {
+	Title  string
+	Device string
+	Path   string // as stored in paths.json
+	Kind   string
+	Other  string // duplicate: the path kept instead; shared: the other device ID
+}
+
+func runPathsList(cmd *cobra.Command, args []string) error {
+	title := args[0]
+	if !pathdetect.IsValidTitleCode(title) {
+		return fmt.Errorf("invalid title code: %s", title)
+	}
+
+	deviceID, _, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	fmt.Printf("=== thlocalsync paths list ===\n")
+	fmt.Printf("Device: %s (%s)\n\n", deviceID, hostname)
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	entry, ok := pathsConfig.Paths[title][deviceID]
+	if !ok || len(entry.Paths) == 0 {
+		fmt.Printf("No paths registered for %s on this device. Run 'thlocalsync detect' first.\n", title)
+		return nil
+	}
+
+	fmt.Printf("%s:\n", title)
+	for i, p := range entry.Paths {
+		marker := " "
+		if i == entry.Preferred {
+			marker = "*"
+		}
+		status := "OK"
+		if exists, readable := utils.FileExists(utils.ExpandEnvPath(p)); !exists {
+			status = "NOT EXIST"
+		} else if !readable {
+			status = "NOT READABLE"
+		}
+		fmt.Printf("%s [%d] %s [%s]\n", marker, i, p, status)
+	}
+	fmt.Println("\n* = preferred path")
+
+	return nil
+}
+
+func runPathsSetPreferred(cmd *cobra.Command, args []string) error {
+	title := args[0]
+	if !pathdetect.IsValidTitleCode(title) {
+		return fmt.Errorf("invalid title code: %s", title)
+	}
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid index: %s", args[1])
+	}
+
+	deviceID, _, _, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	if err := setPreferredPath(pathsConfig, title, deviceID, index); err != nil {
+		return err
+	}
+
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+
+	preferred := pathsConfig.Paths[title][deviceID].Paths[index]
+	fmt.Printf("✓ %s: preferred path set to [%d] %s\n", title, index, preferred)
+	if exists, _ := utils.FileExists(utils.ExpandEnvPath(preferred)); !exists {
+		fmt.Printf("⚠ %s does not exist on this device\n", preferred)
+	}
+
+	return nil
+}
+
+// setPreferredPath sets the preferred index of a title's entry for deviceID.
+// The index must refer to one of the registered paths.
+func setPreferredPath(pathsConfig *models.PathsConfig, title, deviceID string, index int) error {
+	entry, ok := pathsConfig.Paths[title][deviceID]
+	if !ok || len(entry.Paths) == 0 {
+		return fmt.Errorf("no paths registered for %s on this device", title)
+	}
+	if index < 0 || index >= len(entry.Paths) {
+		return fmt.Errorf("index out of range: %d (%s has %d path(s), 0-%d)", index, title, len(entry.Paths), len(entry.Paths)-1)
+	}
+
+	entry.Preferred = index
+	pathsConfig.Paths[title][deviceID] = entry
+	return nil
+}
+
+func runPathsDoctor(cmd *cobra.Command, args []string) error {
+	// Only --fix writes
+	if pathsDoctorFix {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	deviceID, _, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	fmt.Printf("=== thlocalsync paths doctor ===\n")
+	fmt.Printf("Device: %s (%s)\n\n", deviceID, hostname)
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	issues := diagnosePaths(pathsConfig, deviceID)
+	if len(issues) == 0 {
+		fmt.Println("✓ No problems found.")
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		printPathIssue(issue)
+		counts[issue.Kind]++
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	fmt.Printf("Missing: %d, Duplicates: %d, Shared with other devices: %d\n",
+		counts[pathIssueMissing], counts[pathIssueDuplicate], counts[pathIssueShared])
+
+	fixable := counts[pathIssueMissing] + counts[pathIssueDuplicate]
+	if fixable == 0 {
+		return nil
+	}
+	if !pathsDoctorFix {
+		fmt.Printf("\nRun 'thlocalsync paths doctor --fix' to remove %d missing/duplicate path(s).\n", fixable)
+		return nil
+	}
+
+	removed := fixPaths(pathsConfig, deviceID)
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+	fmt.Printf("\n✓ Removed %d path(s) from paths.json\n", removed)
+
+	return nil
+}
+
+// printPathIssue prints one problem and how to fix it.
+func printPathIssue(issue pathIssue) {
+	switch issue.Kind {
+	case pathIssueMissing:
+		fmt.Printf("✗ %s [%s]: not found on this device: %s\n", issue.Title, issue.Device, issue.Path)
+		fmt.Println("    → remove it with --fix, or run 'thlocalsync detect' to register the new location")
+	case pathIssueDuplicate:
+		fmt.Printf("⚠ %s [%s]: duplicate of %s: %s\n", issue.Title, issue.Device, issue.Other, issue.Path)
+		fmt.Println("    → remove it with --fix")
+	case pathIssueShared:
+		fmt.Printf("⚠ %s [%s]: also registered for device %s: %s\n", issue.Title, issue.Device, issue.Other, issue.Path)
+		fmt.Printf("    → if %s is this machine under an old ID, run 'thlocalsync devices remove %s --strip-paths'\n", issue.Other, issue.Other)
+	}
+}
+
+// diagnosePaths returns the problems in paths.json, ordered by title release and device ID.
+// Existence is only checked for deviceID's entries, since other devices' paths refer to their own disks.
+func diagnosePaths(pathsConfig *models.PathsConfig, deviceID string) []pathIssue {
+	var issues []pathIssue
+
+	for _, title := range sortedPathTitles(pathsConfig) {
+		titlePaths := pathsConfig.Paths[title]
+
+		devices := make([]string, 0, len(titlePaths))
+		for id := range titlePaths {
+			devices = append(devices, id)
+		}
+		sort.Strings(devices)
+
+		for _, id := range devices {
+			_, entryIssues := cleanPathEntry(titlePaths[id], id == deviceID)
+			for _, issue := range entryIssues {
+				issue.Title = title
+				issue.Device = id
+				issues = append(issues, issue)
+			}
+		}
+
+		// Another device pointing at the same file as this one; missing paths are already reported
+		own, _ := cleanPathEntry(titlePaths[deviceID], true)
+		for _, ownPath := range own.Paths {
+			for _, id := range devices {
+				if id == deviceID {
+					continue
+				}
+				for _, p := range titlePaths[id].Paths {
+					if utils.NormalizePath(p) == utils.NormalizePath(ownPath) {
+						issues = append(issues, pathIssue{Title: title, Device: deviceID, Path: ownPath, Kind: pathIssueShared, Other: id})
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// fixPaths removes duplicate paths from every entry and missing paths from deviceID's entries,
+// dropping entries and titles left empty. Returns the number of paths removed.
+func fixPaths(pathsConfig *models.PathsConfig, deviceID string) int {
+	removed := 0
+	for title, titlePaths := range pathsConfig.Paths {
+		for id, entry := range titlePaths {
+			cleaned, issues := cleanPathEntry(entry, id == deviceID)
+			if len(issues) == 0 {
+				continue
+			}
+			removed += len(issues)
+			if len(cleaned.Paths) == 0 {
+				delete(titlePaths, id)
+			} else {
+				titlePaths[id] = cleaned
+			}
+		}
+		if len(titlePaths) == 0 {
+			delete(pathsConfig.Paths, title)
+		}
+	}
+	return removed
+}
+
+// cleanPathEntry returns entry without duplicate paths, and without missing ones when checkExists
+// is set, along with an issue for each removed path (Title and Device are left to the caller).
+// The preferred index follows its path, or the path it duplicated, and resets to 0 if it was removed.
+func cleanPathEntry(entry models.PathEntry, checkExists bool) (models.PathEntry, []pathIssue) {
+	var issues []pathIssue
+	cleaned := entry
+	cleaned.Paths = nil
+	cleaned.Preferred = 0
+
+	seen := make(map[string]int) // normalized path -> index in cleaned.Paths
+	for i, p := range entry.Paths {
+		key := utils.NormalizePath(p)
+		if j, ok := seen[key]; ok {
+			issues = append(issues, pathIssue{Path: p, Kind: pathIssueDuplicate, Other: cleaned.Paths[j]})
+			if i == entry.Preferred {
+				cleaned.Preferred = j
+			}
+			continue
+		}
+		if checkExists {
+			if exists, _ := utils.FileExists(utils.ExpandEnvPath(p)); !exists {
+				issues = append(issues, pathIssue{Path: p, Kind: pathIssueMissing})
+				continue
+			}
+		}
+		seen[key] = len(cleaned.Paths)
+		if i == entry.Preferred {
+			cleaned.Preferred = len(cleaned.Paths)
+		}
+		cleaned.Paths = append(cleaned.Paths, p)
+	}
+
+	return cleaned, issues
+}
+
+// sortedPathTitles returns the titles in paths.json in release order.
+func sortedPathTitles(pathsConfig *models.PathsConfig) []string {
+	titles := make([]string, 0, len(pathsConfig.Paths))
+	for title := range pathsConfig.Paths {
+		titles = append(titles, title)
+	}
+	return pathdetect.SortTitlesByRelease(titles)
+}
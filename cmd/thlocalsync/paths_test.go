@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestCleanPathEntry(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(existing, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "gone", "score.dat")
+	duplicate := filepath.Join(dir, ".", "score.dat")
+
+	entry := models.PathEntry{
+		Paths:     []string{missing, existing, duplicate},
+		Preferred: 2,
+		ReplayDir: filepath.Join(dir, "replay"),
+	}
+
+	cleaned, issues := cleanPathEntry(entry, true)
+	if len(cleaned.Paths) != 1 || cleaned.Paths[0] != existing {
+		t.Fatalf("Expected only %s to remain, got %v", existing, cleaned.Paths)
+	}
+	if cleaned.Preferred != 0 {
+		t.Errorf("Expected preferred to follow the duplicated path, got %d", cleaned.Preferred)
+	}
+	if cleaned.ReplayDir != entry.ReplayDir {
+		t.Error("Expected replay_dir to be kept")
+	}
+	if len(issues) != 2 || issues[0].Kind != pathIssueMissing || issues[1].Kind != pathIssueDuplicate || issues[1].Other != existing {
+		t.Errorf("Unexpected issues: %+v", issues)
+	}
+
+	// Other devices' paths are not checked for existence
+	cleaned, issues = cleanPathEntry(entry, false)
+	if len(cleaned.Paths) != 2 || len(issues) != 1 || issues[0].Kind != pathIssueDuplicate {
+		t.Errorf("Expected only the duplicate to be removed, got %v / %+v", cleaned.Paths, issues)
+	}
+	if cleaned.Preferred != 1 {
+		t.Errorf("Expected preferred 1, got %d", cleaned.Preferred)
+	}
+	if len(entry.Paths) != 3 {
+		t.Error("Expected the original entry to be left unmodified")
+	}
+}
+
+func TestDiagnoseAndFixPaths(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "th08", "score.dat")
+	if err := os.MkdirAll(filepath.Dir(shared), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shared, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "th10", "scoreth10.dat")
+
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {
+			"abc123def456": {Paths: []string{shared}},
+			"fff000fff000": {Paths: []string{shared}},
+		},
+		"th10": {
+			"abc123def456": {Paths: []string{missing}},
+			"fff000fff000": {Paths: []string{missing}},
+		},
+	}}
+
+	issues := diagnosePaths(pathsConfig, "abc123def456")
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %+v", issues)
+	}
+	if issues[0].Title != "th08" || issues[0].Kind != pathIssueShared || issues[0].Other != "fff000fff000" {
+		t.Errorf("Expected th08 shared with fff000fff000, got %+v", issues[0])
+	}
+	if issues[1].Title != "th10" || issues[1].Kind != pathIssueMissing || issues[1].Device != "abc123def456" {
+		t.Errorf("Expected th10 missing on this device, got %+v", issues[1])
+	}
+
+	if removed := fixPaths(pathsConfig, "abc123def456"); removed != 1 {
+		t.Errorf("Expected 1 path removed, got %d", removed)
+	}
+	if _, ok := pathsConfig.Paths["th10"]["abc123def456"]; ok {
+		t.Error("Expected the emptied th10 entry to be dropped")
+	}
+	if _, ok := pathsConfig.Paths["th10"]["fff000fff000"]; !ok {
+		t.Error("Expected the other device's th10 entry to be kept")
+	}
+	if len(pathsConfig.Paths["th08"]) != 2 {
+		t.Error("Expected shared th08 entries to be left alone")
+	}
+}
+
+func TestSetPreferredPath(t *testing.T) {
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {
+			"abc123def456": {Paths: []string{"C:\\th08\\score.dat", "D:\\th08\\score.dat"}},
+		},
+	}}
+
+	if err := setPreferredPath(pathsConfig, "th08", "abc123def456", 1); err != nil {
+		t.Fatalf("setPreferredPath failed: %v", err)
+	}
+	if got := pathsConfig.Paths["th08"]["abc123def456"].Preferred; got != 1 {
+		t.Errorf("Expected preferred 1, got %d", got)
+	}
+
+	for _, index := range []int{-1, 2} {
+		if err := setPreferredPath(pathsConfig, "th08", "abc123def456", index); err == nil {
+			t.Errorf("Expected index %d to be rejected", index)
+		}
+	}
+	if got := pathsConfig.Paths["th08"]["abc123def456"].Preferred; got != 1 {
+		t.Errorf("Expected preferred to stay 1 after rejected changes, got %d", got)
+	}
+
+	if err := setPreferredPath(pathsConfig, "th10", "abc123def456", 0); err == nil {
+		t.Error("Expected an error for a title with no paths on this device")
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [title|all]",
+	Short: "履歴の保存上限を今すぐ適用",
+	Long: `rules.json の history_limit と history_max_age_days を各タイトルの履歴に適用し、
+上限を超えたバックアップと期限切れのバックアップを削除します。
+削除した件数と解放した容量を表示します。
+
+ピン留めしたバックアップは削除せず、上限の件数にも数えません。
+各タイトルの最新のバックアップは上限に関わらず必ず残します。
+何度実行しても、上限を満たした履歴からはそれ以上削除しません。
+
+使用例:
+  thlocalsync prune
+  thlocalsync prune th08 --slot hard`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPrune,
+}
+
+var pruneSlot string
+
+func init() {
+	pruneCmd.Flags().StringVar(&pruneSlot, "slot", backup.DefaultSlot, "対象のvaultスロット")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+	if targetTitle != "all" && !pathdetect.IsValidTitleCode(targetTitle) {
+		return fmt.Errorf("invalid title code: %s", targetTitle)
+	}
+	if err := backup.ValidateSlot(pruneSlot); err != nil {
+		return err
+	}
+
+	release, err := acquireRunLock(cmd, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Load history limits from rules.json
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	fmt.Printf("=== thlocalsync prune ===\n")
+	printSlot(pruneSlot)
+	fmt.Println()
+
+	policy := sync.CurrentHistoryPolicy()
+	if policy.Limit <= 0 && policy.MaxAge <= 0 {
+		fmt.Println("No history limits configured (history_limit and history_max_age_days are 0).")
+		return nil
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	titles := []string{targetTitle}
+	if targetTitle == "all" {
+		vaultDir, err := backup.GetVaultDir()
+		if err != nil {
+			return fmt.Errorf("failed to get vault directory: %w", err)
+		}
+		vaultTitles, err := backup.ListVaultTitles(vaultDir)
+		if err != nil {
+			return err
+		}
+
+		titles = nil
+		for _, title := range vaultTitles {
+			if pathdetect.IsValidTitleCode(title) {
+				titles = append(titles, title)
+			}
+		}
+		if len(titles) == 0 {
+			fmt.Println("No titles in the vault.")
+			return nil
+		}
+		titles = pathdetect.SortTitlesByRelease(titles)
+	}
+
+	var totalRemoved, errorCount int
+	var totalFreed int64
+	for _, title := range titles {
+		result, err := sync.PruneHistory(title, pruneSlot)
+		// Backups removed before a failure are still reported
+		totalRemoved += len(result.Removed)
+		totalFreed += result.FreedBytes
+		if len(result.Removed) > 0 {
+			log.Info("prune", map[string]interface{}{
+				"title":       title,
+				"slot":        pruneSlot,
+				"removed":     len(result.Removed),
+				"freed_bytes": result.FreedBytes,
+			})
+		}
+
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", title, err)
+			log.Error("prune_error", map[string]interface{}{
+				"title": title,
+				"slot":  pruneSlot,
+				"error": err.Error(),
+			})
+			errorCount++
+		case len(result.Removed) > 0:
+			fmt.Printf("✓ %s: Removed %d backups (%s freed)\n", title, len(result.Removed), formatBytes(result.FreedBytes))
+		default:
+			fmt.Printf("- %s: Nothing to prune\n", title)
+		}
+	}
+
+	fmt.Printf("\nRemoved: %d, Freed: %s, Errors: %d\n", totalRemoved, formatBytes(totalFreed), errorCount)
+
+	if errorCount > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d title(s) failed", errorCount)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,25 +9,67 @@ import (
 	"github.com/otagao/touhou-local-sync/internal/models"
 	"github.com/otagao/touhou-local-sync/pkg/backup"
 	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
 	"github.com/otagao/touhou-local-sync/pkg/device"
 	"github.com/otagao/touhou-local-sync/pkg/logger"
 	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
 	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pullQuarantine bool
+	pullDryRun     bool
+	pullSlot       string
+	pullOnConflict string
+	pullTitles     string
+	pullStrict     bool
+	pullNoBackup   bool
+)
+
 var pullCmd = &cobra.Command{
 	Use:   "pull [title|all]",
 	Short: "ローカル → ポータブルストレージ（正本へ吸い上げ）",
 	Long: `ローカルのセーブデータをポータブルストレージの正本へ吸い上げます。
 
 ローカルがポータブルストレージより新しい/大きい場合に上書きします。
-上書き前にポータブルストレージ側のファイルはバックアップされます。`,
+上書き前にポータブルストレージ側のファイルはバックアップされます。
+--no-backup を指定するとバックアップを作成せずに上書きします（空き容量が少ない場合向け）。
+
+--dry-run を指定すると、比較結果（推奨動作と理由）を表示するだけで、
+コピー・バックアップ・設定の更新は一切行いません。
+
+競合時のポリシー (--on-conflict):
+  local    ローカルを吸い上げる
+  remote   ポータブルストレージ側を残す
+  newer    更新時刻が新しい方を採用
+  larger   サイズが大きい方を採用
+  skip     そのタイトルを変更しない
+  abort    実行全体を中止し、終了コード1で終了
+未指定時は対話的に選択します。標準入力が端末でない場合（スクリプト・タスク実行）は
+skip として扱い、警告をログに記録します。
+
+終了コード:
+  0  正常終了（--strict 指定時は未解決の競合もないこと）
+  1  いずれかのタイトルでエラー、または --on-conflict=abort で中止
+  2  --strict 指定時、skip・キャンセルなどで未解決の競合が残った`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runPull,
 }
 
+func init() {
+	pullCmd.Flags().BoolVar(&pullQuarantine, "quarantine", false, "疑わしいファイル（サイズ比）を隔離してCONFLICTを回避")
+	pullCmd.PersistentFlags().BoolVar(&pullDryRun, "dry-run", false, "比較結果のみ表示し、書き込みを行わない")
+	pullCmd.Flags().StringVar(&pullSlot, "slot", backup.DefaultSlot, "吸い上げ先のvaultスロット")
+	pullCmd.Flags().StringVar(&pullOnConflict, "on-conflict", "", "競合時のポリシー (local|remote|newer|larger|skip|abort)")
+	pullCmd.Flags().StringVar(&pullTitles, "titles", "", "吸い上げするタイトルをカンマ区切りまたはglobで指定 (例: th06,th1*)")
+	pullCmd.Flags().BoolVar(&pullStrict, "strict", false, "未解決の競合が残ったら終了コード2で終了")
+	pullCmd.Flags().BoolVar(&pullNoBackup, "no-backup", false, "上書き前のバックアップ（履歴）を作成しない")
+}
+
 func runPull(cmd *cobra.Command, args []string) error {
 	// Determine target title
 	targetTitle := "all"
@@ -34,20 +77,55 @@ func runPull(cmd *cobra.Command, args []string) error {
 		targetTitle = args[0]
 	}
 
+	if err := backup.ValidateSlot(pullSlot); err != nil {
+		return err
+	}
+	if err := validateConflictPolicy(pullOnConflict); err != nil {
+		return err
+	}
+
+	// A dry run writes nothing and may overlap with another run
+	if !pullDryRun {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
 	// Get device ID
-	deviceID, _, hostname, err := device.GetDeviceID()
+	deviceID, macHash, hostname, err := device.GetDeviceID()
 	if err != nil {
 		return fmt.Errorf("failed to get device ID: %w", err)
 	}
 
-	fmt.Printf("=== thlocalsync pull ===\n")
-	fmt.Printf("Device: %s (%s)\n\n", deviceID, hostname)
+	console.Printf("=== thlocalsync pull ===\n")
+	console.Printf("Device: %s (%s)\n", deviceID, hostname)
+	printSlot(pullSlot)
+	if pullDryRun {
+		console.Println("Dry-run mode: no files will be written")
+	} else if pullNoBackup {
+		fmt.Println("⚠ No-backup mode: overwritten vault files will not be backed up")
+	}
+	console.Println()
 
 	// Initialize logger
 	log, err := logger.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
+	logRetries(log)
+	logPrunes(log)
+
+	// Record this device as seen
+	if !pullDryRun {
+		if _, err := recordDeviceSeen(deviceID, macHash, hostname); err != nil {
+			log.Warn("device_update_failed", map[string]interface{}{
+				"device": deviceID,
+				"error":  err.Error(),
+			})
+		}
+	}
 
 	// Load configurations
 	pathsConfig, err := config.LoadPaths()
@@ -55,6 +133,15 @@ func runPull(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load paths config: %w", err)
 	}
 
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	// Show progress for large copies
+	if !console.IsQuiet() {
+		sync.SetCopyProgress(newCopyProgress)
+	}
+
 	// Get titles to pull
 	var titles []string
 	if targetTitle == "all" {
@@ -63,12 +150,17 @@ func runPull(cmd *cobra.Command, args []string) error {
 			titles = append(titles, title)
 		}
 		if len(titles) == 0 {
-			fmt.Println("No titles configured. Run 'thlocalsync detect' first.")
+			console.Println("No titles configured. Run 'thlocalsync detect' first.")
 			return nil
 		}
-		// Sort by release order
-		titles = pathdetect.SortTitlesByRelease(titles)
+		// Narrow down with --titles, sorted by release order
+		if titles, err = thlocalsync.FilterTitles(titles, pullTitles); err != nil {
+			return err
+		}
 	} else {
+		if pullTitles != "" {
+			return errTitlesWithTitleArg
+		}
 		// Validate title code
 		if !pathdetect.IsValidTitleCode(targetTitle) {
 			return fmt.Errorf("invalid title code: %s", targetTitle)
@@ -76,120 +168,163 @@ func runPull(cmd *cobra.Command, args []string) error {
 		titles = []string{targetTitle}
 	}
 
+	syncer := newPullSyncer(deviceID, pathsConfig, log)
+
+	if pullDryRun {
+		previewTitles(titles, "PULL", func(title string) (*models.ComparisonResult, error) {
+			return previewTitle(syncer, title, sync.ActionPull)
+		})
+		return nil
+	}
+
+	removeStaleTempFiles(log)
+
 	// Pull each title
 	successCount := 0
 	skipCount := 0
+	conflictCount := 0
 	errorCount := 0
+	aborted := false
+	var storageErr error
+	var titleStats titleIO
 
-	for _, title := range titles {
-		err := pullTitle(title, deviceID, pathsConfig, log)
-		if err != nil {
+	for i, title := range titles {
+		if storageErr = checkVaultBeforeTitle("pull", titles, i, deviceID, log); storageErr != nil {
+			break
+		}
+		stop := timing.Start("title:" + title)
+		done := titleStats.start(title)
+		var action string
+		err := withHooks(syncer, sync.ActionPull, title, func() (err error) {
+			if action, err = pullTitle(syncer, title); err == nil {
+				syncFolders(syncer, title, sync.ActionPull)
+			}
+			return err
+		})
+		stop()
+		done()
+		if errors.Is(err, errConflictAbort) {
 			fmt.Printf("✗ %s: %v\n", title, err)
-			errorCount++
-			// Log error
-			log.Error("pull_error", map[string]interface{}{
+			log.Warn("pull_abort", map[string]interface{}{
 				"title":  title,
 				"device": deviceID,
-				"error":  err.Error(),
+				"reason": err.Error(),
 			})
+			aborted = true
+			break
+		}
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", title, err)
+			errorCount++
+			syncer.LogError(title, err)
 		} else {
-			// Check if actually pulled or skipped
-			// We'll track this in pullTitle
-			successCount++
+			switch action {
+			case sync.ActionConflict:
+				conflictCount++
+			case sync.ActionSkip:
+				skipCount++
+			default:
+				successCount++
+			}
 		}
 	}
 
-	fmt.Printf("\n=== Summary ===\n")
-	fmt.Printf("Success: %d, Skipped: %d, Errors: %d\n", successCount, skipCount, errorCount)
+	if storageErr != nil {
+		// data/ lives on the same storage, so the paths config cannot be saved either
+		console.Printf("\n=== Summary ===\n")
+		console.Printf("Success: %d, Skipped: %d, Conflicts: %d, Errors: %d\n", successCount, skipCount, conflictCount, errorCount)
+		titleStats.printTotal()
+		cmd.SilenceUsage = true
+		return storageErr
+	}
 
-	return nil
-}
+	// Persist last-sync times
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
 
-func pullTitle(title, deviceID string, pathsConfig *models.PathsConfig, log *logger.Logger) error {
-	// Get local path
-	localPath, err := sync.GetPreferredLocalPath(pathsConfig, title, deviceID)
-	if err != nil {
-		return fmt.Errorf("no path configured")
+	console.Printf("\n=== Summary ===\n")
+	console.Printf("Success: %d, Skipped: %d, Conflicts: %d, Errors: %d\n", successCount, skipCount, conflictCount, errorCount)
+	titleStats.printTotal()
+
+	if aborted {
+		// Stopping on a conflict is a result, not a usage mistake
+		cmd.SilenceUsage = true
+		return errConflictAbort
 	}
+	return runResultError(cmd, errorCount, conflictCount, pullStrict)
+}
 
-	// Determine vault file name
-	titleInfo := pathdetect.GetTitleByCode(title)
-	var fileName string
-	if titleInfo != nil {
-		fileName = titleInfo.FileName
-	} else {
-		fileName = "score.dat"
+// newPullSyncer returns the Syncer of a pull run, set up from the pull flags.
+func newPullSyncer(deviceID string, pathsConfig *models.PathsConfig, log *logger.Logger) *thlocalsync.Syncer {
+	return &thlocalsync.Syncer{
+		PathsConfig:   pathsConfig,
+		DeviceID:      deviceID,
+		Slot:          pullSlot,
+		Log:           log,
+		Operation:     thlocalsync.OperationPull,
+		NoBackup:      pullNoBackup,
+		Quarantine:    pullQuarantine,
+		VaultFileName: getVaultFileName,
+		PreSync:       preSyncHook,
+		PostSync:      postSyncHook,
+		HookRan:       printHook,
 	}
+}
 
-	// Get vault path
-	vaultPath, err := sync.GetVaultFilePath(title, fileName)
+// pullTitle pulls a single title and returns its net effect: sync.ActionPull,
+// sync.ActionSkip, or sync.ActionConflict for a conflict left unresolved.
+func pullTitle(syncer *thlocalsync.Syncer, title string) (string, error) {
+	target, err := syncer.Target(title)
 	if err != nil {
-		return fmt.Errorf("failed to get vault path: %w", err)
+		return "", err
 	}
 
-	// Pull file
-	comparison, err := sync.PullFile(title, localPath, vaultPath)
+	result, err := syncer.Pull(target)
 	if err != nil {
-		return err
+		return "", err
 	}
+	comparison := result.Comparison
+	printFileDetails(title, comparison)
 
-	// Handle CONFLICT - ask user for resolution
-	if comparison.Recommendation == "CONFLICT" {
-		choice := promptUserForConflictResolution(title, comparison, "pull")
-		switch choice {
-		case "local":
-			// User chose local - force pull
-			comparison, err = sync.ForcePullFile(title, localPath, vaultPath)
-			if err != nil {
-				return fmt.Errorf("failed to force pull: %w", err)
-			}
-			fmt.Printf("✓ %s: Pulled to USB (user chose local)\n", title)
-			log.Info("pull", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"action": "update",
-				"from":   "local",
-				"to":     "usb",
-				"reason": "user resolved conflict - chose local",
-			})
-		case "remote":
-			// User chose remote - skip (keep USB version)
-			fmt.Printf("- %s: Kept USB version (user choice)\n", title)
-			log.Info("pull_skip", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"reason": "user resolved conflict - chose remote",
-			})
-		case "cancel":
-			fmt.Printf("- %s: Cancelled by user\n", title)
-			log.Info("pull_cancel", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"reason": "user cancelled conflict resolution",
-			})
-		}
-		return nil
+	if comparison.EmptyLocal {
+		printEmptyLocal(title)
+	}
+	if comparison.EmptyRemote {
+		printEmptyRemote(title)
+	}
+
+	// A suspicious local file was quarantined instead of blocking on the conflict
+	if result.QuarantinePath != "" {
+		fmt.Printf("⚠ %s: Quarantined suspicious local file (%s)\n", title, comparison.Reason)
+		fmt.Printf("    → %s\n", result.QuarantinePath)
+		return sync.ActionSkip, nil
+	}
+
+	// Handle CONFLICT - resolve by --on-conflict or ask the user
+	if result.Action == sync.ActionConflict {
+		choice, reason := resolveConflict(title, comparison, "pull", pullOnConflict, syncer.Log)
+		return settleConflict(syncer, result, choice, reason)
 	}
 
 	// Report result
-	switch comparison.Recommendation {
-	case "PULL":
-		fmt.Printf("✓ %s: Pulled to USB (%s)\n", title, comparison.Reason)
-		// Log operation
-		log.Info("pull", map[string]interface{}{
-			"title":  title,
-			"device": deviceID,
-			"action": "update",
-			"from":   "local",
-			"to":     "usb",
-			"reason": comparison.Reason,
-		})
-	case "SKIP":
-		fmt.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
-	case "PUSH":
-		fmt.Printf("- %s: USB is newer, skipped (%s)\n", title, comparison.Reason)
+	switch {
+	case result.Action == sync.ActionPull:
+		printWrite(syncer, result, comparison.Reason)
+	case comparison.Recommendation == "PUSH":
+		console.Printf("- %s: USB is newer, skipped (%s)\n", title, comparison.Reason)
+	default:
+		console.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
 	}
 
+	archiveTitleFiles(title, target.LocalPath, syncer.Log)
+
+	return result.Action, nil
+}
+
+// archiveTitleFiles archives the replays, snapshots and bestshots next to a title's
+// local save file. Archiving is optional, so failures are only logged.
+func archiveTitleFiles(title, localPath string, log *logger.Logger) {
 	// Archive replays if present
 	if err := archiveReplaysIfPresent(title, localPath, log); err != nil {
 		log.Error("replay_archive_error", map[string]interface{}{
@@ -216,8 +351,6 @@ func pullTitle(title, deviceID string, pathsConfig *models.PathsConfig, log *log
 		})
 		// Don't return error - bestshot archiving is optional
 	}
-
-	return nil
 }
 
 // hashExistsInArchive checks if a file with the given hash already exists in the archive directory.
@@ -249,7 +382,7 @@ func hashExistsInArchive(archiveDir, targetHash string) bool {
 // archiveReplaysIfPresent archives replay files if the replay directory exists.
 func archiveReplaysIfPresent(title, localPath string, log *logger.Logger) error {
 	// Detect replay directory
-	replayDir := pathdetect.DetectReplayDir(localPath)
+	replayDir := pathdetect.DetectReplayDir(title, localPath)
 	if replayDir == "" {
 		log.Info("replay_dir_not_found", map[string]interface{}{
 			"title": title,
@@ -1,19 +1,36 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
 	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
 	"github.com/otagao/touhou-local-sync/pkg/device"
 	"github.com/otagao/touhou-local-sync/pkg/logger"
 	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/process"
 	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/spf13/cobra"
 )
 
 var (
-	pushForce bool
+	pushForce       bool
+	pushQuarantine  bool
+	pushPreferLocal bool
+	pushDryRun      bool
+	pushSlot        string
+	pushOnConflict  string
+	pushWait        int
+	pushTitles      string
+	pushYes         bool
+	pushStrict      bool
+	pushNoBackup    bool
 )
 
 var pushCmd = &cobra.Command{
@@ -23,13 +40,58 @@ var pushCmd = &cobra.Command{
 
 ポータブルストレージがローカルより新しい/大きい場合に上書きします。
 ゲーム実行中やファイルロック中は書き込みを禁止します。
-上書き前にローカル側のファイルはバックアップされます。`,
+--wait <秒> を指定すると、ゲームの終了処理中などで書き込めない間は最大その秒数まで
+待ってから書き込みます。時間切れの場合は、ゲーム実行中・ファイルロックのどちらが
+原因だったかを表示します。
+上書き前にローカル側のファイルはバックアップされます。
+--no-backup を指定するとバックアップを作成せずに上書きします（空き容量が少ない場合向け）。
+
+書き込みの前に対象タイトルをすべて比較し、上書きされるローカルのセーブデータを
+理由とともに一覧表示して、一度だけ確認（y/N）します。--yes で確認を省略します
+（--force 時は確認しません）。標準入力が端末でない場合は --yes がなければ中止します。
+
+vaultのファイルが0バイト（失敗したpullの残りなど）で、ローカルのセーブデータが
+空でない場合は、上書きを拒否してエラーにします（理由 "vault file is empty"）。
+--force を指定した場合も、タイトルごとに確認（y/N）してから上書きします。
+標準入力が端末でない場合は --force があっても上書きしません。
+
+--prefer-existing-local を指定すると（またはdevices.jsonでデバイスの既定値として
+prefer_existing_local を有効にすると）、前回push以降に更新されたローカルファイルは
+上書きしません。共有PCで他の人の進行を消さないための安全策です。
+
+--dry-run を指定すると、比較結果（推奨動作と理由）を表示するだけで、
+コピー・バックアップ・設定の更新は一切行いません。
+
+競合時のポリシー (--on-conflict):
+  local    ローカル側を残す
+  remote   ポータブルストレージ側を配布する
+  newer    更新時刻が新しい方を採用
+  larger   サイズが大きい方を採用
+  skip     そのタイトルを変更しない
+  abort    実行全体を中止し、終了コード1で終了
+未指定時は対話的に選択します。標準入力が端末でない場合（スクリプト・タスク実行）は
+skip として扱い、警告をログに記録します。
+
+終了コード:
+  0  正常終了（--strict 指定時は未解決の競合もないこと）
+  1  いずれかのタイトルでエラー、または --on-conflict=abort で中止
+  2  --strict 指定時、skip・キャンセルなどで未解決の競合が残った`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runPush,
 }
 
 func init() {
 	pushCmd.Flags().BoolVarP(&pushForce, "force", "f", false, "強制的に上書き（警告を無視）")
+	pushCmd.Flags().BoolVar(&pushQuarantine, "quarantine", false, "疑わしいファイル（サイズ比）を隔離してCONFLICTを回避")
+	pushCmd.Flags().BoolVar(&pushPreferLocal, "prefer-existing-local", false, "前回push以降に更新されたローカルファイルを上書きしない")
+	pushCmd.PersistentFlags().BoolVar(&pushDryRun, "dry-run", false, "比較結果のみ表示し、書き込みを行わない")
+	pushCmd.Flags().StringVar(&pushSlot, "slot", backup.DefaultSlot, "配布元のvaultスロット")
+	pushCmd.Flags().StringVar(&pushOnConflict, "on-conflict", "", "競合時のポリシー (local|remote|newer|larger|skip|abort)")
+	pushCmd.Flags().IntVar(&pushWait, "wait", 0, "ゲーム実行中/ファイルロック中なら最大N秒待ってから書き込む")
+	pushCmd.Flags().StringVar(&pushTitles, "titles", "", "配布するタイトルをカンマ区切りまたはglobで指定 (例: th06,th1*)")
+	pushCmd.Flags().BoolVarP(&pushYes, "yes", "y", false, "上書き前の確認を省略")
+	pushCmd.Flags().BoolVar(&pushStrict, "strict", false, "未解決の競合が残ったら終了コード2で終了")
+	pushCmd.Flags().BoolVar(&pushNoBackup, "no-backup", false, "上書き前のバックアップ（履歴）を作成しない")
 }
 
 func runPush(cmd *cobra.Command, args []string) error {
@@ -39,24 +101,78 @@ func runPush(cmd *cobra.Command, args []string) error {
 		targetTitle = args[0]
 	}
 
+	if err := backup.ValidateSlot(pushSlot); err != nil {
+		return err
+	}
+	if err := validateConflictPolicy(pushOnConflict); err != nil {
+		return err
+	}
+	if pushWait < 0 {
+		return fmt.Errorf("invalid --wait: %d (must be 0 or more)", pushWait)
+	}
+
+	// A dry run writes nothing and may overlap with another run
+	if !pushDryRun {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
 	// Get device ID
-	deviceID, _, hostname, err := device.GetDeviceID()
+	deviceID, macHash, hostname, err := device.GetDeviceID()
 	if err != nil {
 		return fmt.Errorf("failed to get device ID: %w", err)
 	}
 
-	fmt.Printf("=== thlocalsync push ===\n")
-	fmt.Printf("Device: %s (%s)\n", deviceID, hostname)
+	console.Printf("=== thlocalsync push ===\n")
+	console.Printf("Device: %s (%s)\n", deviceID, hostname)
+	printSlot(pushSlot)
 	if pushForce {
 		fmt.Println("⚠ Force mode enabled")
 	}
-	fmt.Println()
+	if pushDryRun {
+		console.Println("Dry-run mode: no files will be written")
+	} else if pushNoBackup {
+		fmt.Println("⚠ No-backup mode: overwritten local files will not be backed up")
+	}
+	// Waiting only matters when the safety check can block the write
+	if pushWait > 0 && !pushForce && !pushDryRun {
+		process.SafeWriteWait = time.Duration(pushWait) * time.Second
+		console.Printf("Waiting up to %ds for running games and locked files\n", pushWait)
+	}
+	console.Println()
 
 	// Initialize logger
 	log, err := logger.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
+	logRetries(log)
+	logPrunes(log)
+
+	// Record this device as seen (dry-run only reads the device record)
+	var dev *models.Device
+	if pushDryRun {
+		dev, err = findDevice(deviceID)
+	} else {
+		dev, err = recordDeviceSeen(deviceID, macHash, hostname)
+	}
+	if err != nil {
+		log.Warn("device_update_failed", map[string]interface{}{
+			"device": deviceID,
+			"error":  err.Error(),
+		})
+	}
+
+	// Device default for prefer-existing-local
+	if dev != nil && dev.PreferExistingLocal {
+		pushPreferLocal = true
+	}
+	if pushPreferLocal {
+		console.Printf("Prefer-existing-local mode enabled\n\n")
+	}
 
 	// Load configurations
 	pathsConfig, err := config.LoadPaths()
@@ -64,6 +180,15 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load paths config: %w", err)
 	}
 
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	// Show progress for large copies
+	if !console.IsQuiet() {
+		sync.SetCopyProgress(newCopyProgress)
+	}
+
 	// Get titles to push
 	var titles []string
 	if targetTitle == "all" {
@@ -72,12 +197,17 @@ func runPush(cmd *cobra.Command, args []string) error {
 			titles = append(titles, title)
 		}
 		if len(titles) == 0 {
-			fmt.Println("No titles configured. Run 'thlocalsync detect' first.")
+			console.Println("No titles configured. Run 'thlocalsync detect' first.")
 			return nil
 		}
-		// Sort by release order
-		titles = pathdetect.SortTitlesByRelease(titles)
+		// Narrow down with --titles, sorted by release order
+		if titles, err = thlocalsync.FilterTitles(titles, pushTitles); err != nil {
+			return err
+		}
 	} else {
+		if pushTitles != "" {
+			return errTitlesWithTitleArg
+		}
 		// Validate title code
 		if !pathdetect.IsValidTitleCode(targetTitle) {
 			return fmt.Errorf("invalid title code: %s", targetTitle)
@@ -85,117 +215,196 @@ func runPush(cmd *cobra.Command, args []string) error {
 		titles = []string{targetTitle}
 	}
 
+	syncer := newPushSyncer(deviceID, pathsConfig, log)
+
+	if pushDryRun {
+		previewTitles(titles, "PUSH", func(title string) (*models.ComparisonResult, error) {
+			return previewTitle(syncer, title, sync.ActionPush)
+		})
+		return nil
+	}
+
+	// Ask once before overwriting local saves, so a stale vault cannot clobber fresh progress
+	if !pushForce && !pushYes && !confirmPushOverwrites(syncer, titles) {
+		console.Println("Cancelled, nothing was written (use --yes to push without confirmation)")
+		log.Info("push_cancel", map[string]interface{}{
+			"device": deviceID,
+			"reason": "user declined overwriting local files",
+		})
+		return nil
+	}
+
+	removeStaleTempFiles(log)
+
 	// Push each title
 	successCount := 0
 	skipCount := 0
+	conflictCount := 0
 	errorCount := 0
+	aborted := false
+	var storageErr error
+	var titleStats titleIO
 
-	for _, title := range titles {
-		err := pushTitle(title, deviceID, pathsConfig, log, pushForce)
-		if err != nil {
+	for i, title := range titles {
+		if storageErr = checkVaultBeforeTitle("push", titles, i, deviceID, log); storageErr != nil {
+			break
+		}
+		stop := timing.Start("title:" + title)
+		done := titleStats.start(title)
+		var action string
+		err := withHooks(syncer, sync.ActionPush, title, func() (err error) {
+			if action, err = pushTitle(syncer, title); err == nil {
+				syncFolders(syncer, title, sync.ActionPush)
+			}
+			return err
+		})
+		stop()
+		done()
+		if errors.Is(err, errConflictAbort) {
 			fmt.Printf("✗ %s: %v\n", title, err)
-			errorCount++
-			// Log error
-			log.Error("push_error", map[string]interface{}{
+			log.Warn("push_abort", map[string]interface{}{
 				"title":  title,
 				"device": deviceID,
-				"error":  err.Error(),
+				"reason": err.Error(),
 			})
+			aborted = true
+			break
+		}
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", title, err)
+			errorCount++
+			syncer.LogError(title, err)
 		} else {
-			successCount++
+			switch action {
+			case sync.ActionConflict:
+				conflictCount++
+			case sync.ActionSkip:
+				skipCount++
+			default:
+				successCount++
+			}
 		}
 	}
 
-	fmt.Printf("\n=== Summary ===\n")
-	fmt.Printf("Success: %d, Skipped: %d, Errors: %d\n", successCount, skipCount, errorCount)
+	if storageErr != nil {
+		// data/ lives on the same storage, so the paths config cannot be saved either
+		console.Printf("\n=== Summary ===\n")
+		console.Printf("Success: %d, Skipped: %d, Conflicts: %d, Errors: %d\n", successCount, skipCount, conflictCount, errorCount)
+		titleStats.printTotal()
+		cmd.SilenceUsage = true
+		return storageErr
+	}
 
-	return nil
+	// Persist last-push and last-sync times
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+
+	console.Printf("\n=== Summary ===\n")
+	console.Printf("Success: %d, Skipped: %d, Conflicts: %d, Errors: %d\n", successCount, skipCount, conflictCount, errorCount)
+	titleStats.printTotal()
+
+	if aborted {
+		// Stopping on a conflict is a result, not a usage mistake
+		cmd.SilenceUsage = true
+		return errConflictAbort
+	}
+	return runResultError(cmd, errorCount, conflictCount, pushStrict)
 }
 
-func pushTitle(title, deviceID string, pathsConfig *models.PathsConfig, log *logger.Logger, force bool) error {
-	// Get local path
-	localPath, err := sync.GetPreferredLocalPath(pathsConfig, title, deviceID)
-	if err != nil {
-		return fmt.Errorf("no path configured")
+// newPushSyncer returns the Syncer of a push run, set up from the push flags. A forced
+// push of an empty vault file asks first.
+func newPushSyncer(deviceID string, pathsConfig *models.PathsConfig, log *logger.Logger) *thlocalsync.Syncer {
+	return &thlocalsync.Syncer{
+		PathsConfig:         pathsConfig,
+		DeviceID:            deviceID,
+		Slot:                pushSlot,
+		Log:                 log,
+		Operation:           thlocalsync.OperationPush,
+		NoBackup:            pushNoBackup,
+		Quarantine:          pushQuarantine,
+		Force:               pushForce,
+		PreferExistingLocal: pushPreferLocal,
+		VaultFileName:       getVaultFileName,
+		PreSync:             preSyncHook,
+		PostSync:            postSyncHook,
+		HookRan:             printHook,
+		ConfirmEmptyVault: func(target sync.TitleTarget) bool {
+			return confirm(fmt.Sprintf("⚠ %s: The vault file is empty (0 bytes). Overwrite the local save %s with it?", target.Title, target.LocalPath))
+		},
 	}
+}
 
-	// Determine vault file name
-	titleInfo := pathdetect.GetTitleByCode(title)
-	var fileName string
-	if titleInfo != nil {
-		fileName = titleInfo.FileName
-	} else {
-		fileName = "score.dat"
+// confirmPushOverwrites compares every title before anything is written and, if existing
+// local save files would be overwritten, lists them with the reasons and asks once.
+// Returns true when nothing would be overwritten or the user agreed. Titles that fail to
+// compare are left out; the push itself reports them.
+func confirmPushOverwrites(syncer *thlocalsync.Syncer, titles []string) bool {
+	var overwrites []string
+	for _, title := range titles {
+		comparison, err := previewTitle(syncer, title, sync.ActionPush)
+		if err != nil || comparison.Recommendation != "PUSH" || !comparison.LocalMeta.Exists {
+			continue
+		}
+		overwrites = append(overwrites, fmt.Sprintf("  %-8s %s", title, comparison.Reason))
+	}
+	if len(overwrites) == 0 {
+		return true
+	}
+
+	fmt.Printf("%d local save file(s) will be overwritten:\n", len(overwrites))
+	for _, line := range overwrites {
+		fmt.Println(line)
 	}
+	ok := confirm("Continue?")
+	fmt.Println()
+	return ok
+}
 
-	// Get vault path
-	vaultPath, err := sync.GetVaultFilePath(title, fileName)
+// pushTitle pushes a single title and returns its net effect: sync.ActionPush,
+// sync.ActionSkip, or sync.ActionConflict for a conflict left unresolved.
+func pushTitle(syncer *thlocalsync.Syncer, title string) (string, error) {
+	target, err := syncer.Target(title)
 	if err != nil {
-		return fmt.Errorf("failed to get vault path: %w", err)
+		return "", err
 	}
 
-	// Push file
-	comparison, err := sync.PushFile(title, vaultPath, localPath, force)
+	result, err := syncer.Push(target)
 	if err != nil {
-		return err
+		return "", err
 	}
+	comparison := result.Comparison
 
-	// Handle CONFLICT - ask user for resolution
-	if comparison.Recommendation == "CONFLICT" {
-		choice := promptUserForConflictResolution(title, comparison, "push")
-		switch choice {
-		case "local":
-			// User chose local - skip (keep local version)
-			fmt.Printf("- %s: Kept local version (user choice)\n", title)
-			log.Info("push_skip", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"reason": "user resolved conflict - chose local",
-			})
-		case "remote":
-			// User chose remote - force push
-			comparison, err = sync.ForcePushFile(title, vaultPath, localPath)
-			if err != nil {
-				return fmt.Errorf("failed to force push: %w", err)
-			}
-			fmt.Printf("✓ %s: Pushed to local (user chose remote)\n", title)
-			log.Info("push", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"action": "update",
-				"from":   "usb",
-				"to":     "local",
-				"reason": "user resolved conflict - chose remote",
-			})
-		case "cancel":
-			fmt.Printf("- %s: Cancelled by user\n", title)
-			log.Info("push_cancel", map[string]interface{}{
-				"title":  title,
-				"device": deviceID,
-				"reason": "user cancelled conflict resolution",
-			})
-		}
-		return nil
+	// A suspicious vault file was quarantined instead of blocking on the conflict
+	if result.QuarantinePath != "" {
+		fmt.Printf("⚠ %s: Quarantined suspicious vault file (%s)\n", title, comparison.Reason)
+		fmt.Printf("    → %s\n", result.QuarantinePath)
+		return sync.ActionSkip, nil
+	}
+	printFileDetails(title, comparison)
+
+	if comparison.EmptyLocal {
+		printEmptyLocal(title)
+	}
+	if comparison.EmptyRemote {
+		printEmptyRemote(title)
+	}
+
+	// Handle CONFLICT - resolve by --on-conflict or ask the user
+	if result.Action == sync.ActionConflict {
+		choice, reason := resolveConflict(title, comparison, "push", pushOnConflict, syncer.Log)
+		return settleConflict(syncer, result, choice, reason)
 	}
 
 	// Report result
-	switch comparison.Recommendation {
-	case "PUSH":
-		fmt.Printf("✓ %s: Pushed to local (%s)\n", title, comparison.Reason)
-		// Log operation
-		log.Info("push", map[string]interface{}{
-			"title":  title,
-			"device": deviceID,
-			"action": "update",
-			"from":   "usb",
-			"to":     "local",
-			"reason": comparison.Reason,
-		})
-	case "SKIP":
-		fmt.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
-	case "PULL":
-		fmt.Printf("- %s: Local is newer, skipped (%s)\n", title, comparison.Reason)
+	switch {
+	case result.Action == sync.ActionPush:
+		printWrite(syncer, result, comparison.Reason)
+	case comparison.Recommendation == "PULL":
+		console.Printf("- %s: Local is newer, skipped (%s)\n", title, comparison.Reason)
+	default:
+		console.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
 	}
 
-	return nil
+	return result.Action, nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	quarantineList    bool
+	quarantinePromote string
+	quarantineDiscard string
+)
+
+var quarantineCmd = &cobra.Command{
+	Use:   "quarantine [title]",
+	Short: "隔離ファイルの表示/採用/破棄",
+	Long: `pull/push の --quarantine で隔離された疑わしいファイルを管理します。
+
+使用例:
+  thlocalsync quarantine th08 --list            隔離ファイルを一覧表示
+  thlocalsync quarantine th08 --promote <name>  隔離ファイルをvaultの正本として採用
+  thlocalsync quarantine th08 --discard <name>  隔離ファイルを削除`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQuarantine,
+}
+
+func init() {
+	quarantineCmd.Flags().BoolVarP(&quarantineList, "list", "l", false, "隔離ファイルを一覧表示")
+	quarantineCmd.Flags().StringVar(&quarantinePromote, "promote", "", "指定ファイルをvaultの正本として採用")
+	quarantineCmd.Flags().StringVar(&quarantineDiscard, "discard", "", "指定ファイルを削除")
+}
+
+func runQuarantine(cmd *cobra.Command, args []string) error {
+	title := args[0]
+
+	// Validate title code
+	if !pathdetect.IsValidTitleCode(title) {
+		return fmt.Errorf("invalid title code: %s", title)
+	}
+
+	if quarantinePromote != "" && quarantineDiscard != "" {
+		return fmt.Errorf("--promote and --discard cannot be used together")
+	}
+
+	// Listing is read-only
+	if quarantinePromote != "" || quarantineDiscard != "" {
+		release, err := acquireRunLock(cmd, true)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	fmt.Printf("=== thlocalsync quarantine: %s ===\n\n", title)
+
+	// Promote quarantined file to vault
+	if quarantinePromote != "" {
+		vaultPath, err := sync.GetVaultFilePath(title, backup.DefaultSlot, getVaultFileName(title))
+		if err != nil {
+			return fmt.Errorf("failed to get vault path: %w", err)
+		}
+
+		if err := backup.PromoteQuarantine(title, backup.DefaultSlot, quarantinePromote, vaultPath); err != nil {
+			return fmt.Errorf("failed to promote: %w", err)
+		}
+
+		logVaultWrite("quarantine_promote", title, backup.DefaultSlot, vaultPath)
+
+		fmt.Printf("✓ Promoted %s to vault\n", quarantinePromote)
+		fmt.Printf("  Target: %s\n", vaultPath)
+		return nil
+	}
+
+	// Discard quarantined file
+	if quarantineDiscard != "" {
+		if err := backup.DiscardQuarantine(title, quarantineDiscard); err != nil {
+			return fmt.Errorf("failed to discard: %w", err)
+		}
+
+		fmt.Printf("✓ Discarded %s\n", quarantineDiscard)
+		return nil
+	}
+
+	// List quarantined files
+	infos, err := backup.ListQuarantine(title)
+	if err != nil {
+		return fmt.Errorf("failed to list quarantine: %w", err)
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No quarantined files.")
+		return nil
+	}
+
+	fmt.Printf("Found %d quarantined file(s):\n\n", len(infos))
+	for i, info := range infos {
+		fmt.Printf("[%d] %s\n", i+1, info.Name)
+		if !info.Timestamp.IsZero() {
+			fmt.Printf("    Time: %s\n", info.Timestamp.Format("2006-01-02 15:04:05 MST"))
+		}
+		fmt.Printf("    Size: %d bytes\n", info.Size)
+		if info.Error != nil {
+			fmt.Printf("    Error: %v\n", info.Error)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
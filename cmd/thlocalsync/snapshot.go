@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importForce bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <archive.zip>",
+	Short: "vault と設定を1つのzipに書き出す",
+	Long: `vault/（正本・バックアップ履歴・隔離ファイル・manifest）と data/ の設定ファイルを
+1つのzipにまとめます。新しいポータブルストレージへの移行やクラウドへの保管に使えます。
+
+各ファイルの更新時刻もzipに記録されます。ハッシュキャッシュはPCごとの情報のため含めません。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <archive.zip>",
+	Short: "export したzipから vault と設定を復元",
+	Long: `'thlocalsync export' で作成したzipから vault/ と data/ を復元します。
+zip内のファイルは同名のファイルを上書きし、zipにないファイルはそのまま残ります。
+更新時刻はzipに記録されたもの（秒単位）に戻すため、復元後も新旧判定が正しく働きます。
+
+vault にすでにファイルがある場合は、--force を指定しない限り中止します。`,
+	Args: cobra.ExactArgs(1),
+	RunE: withRunLock(runImport),
+}
+
+func init() {
+	importCmd.Flags().BoolVarP(&importForce, "force", "f", false, "vault にファイルがあっても上書きして復元")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	archivePath := args[0]
+
+	fmt.Printf("=== thlocalsync export ===\n\n")
+
+	count, err := snapshot.Export(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to export: %w", err)
+	}
+	fmt.Printf("✓ Exported %d file(s) to %s\n", count, archivePath)
+
+	if log, err := logger.New(); err == nil {
+		log.Info("export", map[string]interface{}{
+			"archive": archivePath,
+			"files":   count,
+		})
+	}
+	return nil
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	archivePath := args[0]
+
+	fmt.Printf("=== thlocalsync import ===\n\n")
+
+	count, err := snapshot.Import(archivePath, importForce)
+	if errors.Is(err, snapshot.ErrVaultNotEmpty) {
+		return fmt.Errorf("%w (use --force to overwrite it with the archive contents)", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import after %d file(s): %w", count, err)
+	}
+	fmt.Printf("✓ Imported %d file(s) from %s\n", count, archivePath)
+
+	if log, err := logger.New(); err == nil {
+		log.Info("import", map[string]interface{}{
+			"archive": archivePath,
+			"files":   count,
+			"force":   importForce,
+		})
+	}
+	return nil
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
 	"github.com/otagao/touhou-local-sync/pkg/config"
 	"github.com/otagao/touhou-local-sync/pkg/device"
 	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
 	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
 	"github.com/spf13/cobra"
 )
 
@@ -18,11 +26,47 @@ var statusCmd = &cobra.Command{
 	Long: `ポータブルストレージとローカルの差分を一覧表示します。
 
 各ファイルのサイズ、更新時刻、ハッシュを比較し、
-推奨アクション（PULL/PUSH/SKIP）を表示します。`,
+推奨アクション（PULL/PUSH/SKIP）を表示します。
+Last sync 列には、このデバイスで最後にpull/pushして一致した時刻を表示します。
+更新時刻は "2 hours ago" のような相対表示です。--verbose で絶対時刻も表示します。
+
+--quick を指定するとハッシュを計算せず、サイズが同じファイルだけ内容を先頭から比較し、
+最初に異なるバイトで打ち切ります。一致するかどうかだけを知りたいときに速く、
+ハッシュ列は表示されません。
+
+--format で出力形式を選べます（既定は table）:
+  table  人が読むための固定幅の表
+  json   タイトルごとの比較結果（local/remoteのメタデータ、推奨動作、理由、サイズ差、
+         時間差、このデバイスで最後に同期した時刻）のJSON配列。remote はポータブルストレージ（vault）側です
+  csv    1行目が見出しのCSV（表計算ソフト向け）
+  tsv    1行目が見出しのタブ区切り（スクリプト向け）
+csv/tsv の列は title, local_size, local_mtime, local_hash, vault_size, vault_mtime,
+vault_hash, recommendation, reason です。更新時刻はRFC 3339（UTC）、ファイルがなければ
+サイズ・時刻・ハッシュは空欄です。除外したタイトルは EXCLUDED、比較できなかったタイトルは
+ERROR（reason にエラー内容）になります。
+table 以外では見出しなどは出力しません。--json は --format=json の別名です。`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runStatus,
 }
 
+var (
+	statusJobs   int
+	statusSlot   string
+	statusJSON   bool
+	statusFormat string
+	statusTitles string
+	statusQuick  bool
+)
+
+func init() {
+	statusCmd.Flags().IntVar(&statusJobs, "jobs", runtime.NumCPU(), "ハッシュ計算の最大並列数")
+	statusCmd.Flags().StringVar(&statusSlot, "slot", backup.DefaultSlot, "比較するvaultスロット")
+	statusCmd.Flags().StringVar(&statusFormat, "format", statusFormatTable, "出力形式 (table|csv|tsv|json)")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "--format=json の別名")
+	statusCmd.Flags().StringVar(&statusTitles, "titles", "", "確認するタイトルをカンマ区切りまたはglobで指定 (例: th06,th1*)")
+	statusCmd.Flags().BoolVar(&statusQuick, "quick", false, "ハッシュを計算せず内容を直接比較（最初の差分で打ち切る）")
+}
+
 func runStatus(cmd *cobra.Command, args []string) error {
 	// Determine target title
 	targetTitle := "all"
@@ -30,14 +74,27 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		targetTitle = args[0]
 	}
 
+	if err := backup.ValidateSlot(statusSlot); err != nil {
+		return err
+	}
+	format, err := statusOutputFormat(statusFormat, statusJSON, cmd.Flags().Changed("format"))
+	if err != nil {
+		return err
+	}
+	checkReadOnlyStorage()
+
 	// Get device ID
 	deviceID, _, hostname, err := device.GetDeviceID()
 	if err != nil {
 		return fmt.Errorf("failed to get device ID: %w", err)
 	}
 
-	fmt.Printf("=== thlocalsync status ===\n")
-	fmt.Printf("Device: %s (%s)\n\n", deviceID, hostname)
+	if format == statusFormatTable {
+		fmt.Printf("=== thlocalsync status ===\n")
+		fmt.Printf("Device: %s (%s)\n", deviceID, hostname)
+		printSlot(statusSlot)
+		fmt.Println()
+	}
 
 	// Load configurations
 	pathsConfig, err := config.LoadPaths()
@@ -45,6 +102,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load paths config: %w", err)
 	}
 
+	if err := applyRules(); err != nil {
+		return err
+	}
+
 	// Get titles to check
 	var titles []string
 	if targetTitle == "all" {
@@ -53,12 +114,20 @@ func runStatus(cmd *cobra.Command, args []string) error {
 			titles = append(titles, title)
 		}
 		if len(titles) == 0 {
+			if format != statusFormatTable {
+				return writeStatus(os.Stdout, format, nil)
+			}
 			fmt.Println("No titles configured. Run 'thlocalsync detect' first.")
 			return nil
 		}
-		// Sort by release order
-		titles = pathdetect.SortTitlesByRelease(titles)
+		// Narrow down with --titles, sorted by release order
+		if titles, err = thlocalsync.FilterTitles(titles, statusTitles); err != nil {
+			return err
+		}
 	} else {
+		if statusTitles != "" {
+			return errTitlesWithTitleArg
+		}
 		// Validate title code
 		if !pathdetect.IsValidTitleCode(targetTitle) {
 			return fmt.Errorf("invalid title code: %s", targetTitle)
@@ -66,70 +135,148 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		titles = []string{targetTitle}
 	}
 
+	// Compare in release order
+	statusResults := sync.Status(pathsConfig, titles, deviceID, sync.StatusOptions{
+		Slot:          statusSlot,
+		Quick:         statusQuick,
+		Jobs:          statusJobs,
+		VaultFileName: getVaultFileName,
+	})
+
+	if format != statusFormatTable {
+		return writeStatus(os.Stdout, format, statusResults)
+	}
+
 	// Print header
-	fmt.Printf("%-8s %-35s %-35s %-25s\n",
-		"Title", "Local(best)", "USB(main)", "Recommendation")
-	fmt.Println(strings.Repeat("-", 110))
+	fmt.Printf("%-8s %-35s %-35s %-16s %-25s\n",
+		"Title", "Local(best)", "USB("+statusSlot+")", "Last sync", "Recommendation")
+	fmt.Println(strings.Repeat("-", 127))
 
-	// Check each title
-	for _, title := range titles {
-		err := printTitleStatus(title, deviceID, pathsConfig)
-		if err != nil {
-			fmt.Printf("%-8s ERROR: %v\n", title, err)
-		}
+	for _, result := range statusResults {
+		printTitleStatus(result)
 	}
 
 	return nil
 }
 
-func printTitleStatus(title, deviceID string, pathsConfig *models.PathsConfig) error {
-	// Get local path
-	localPath, err := sync.GetPreferredLocalPath(pathsConfig, title, deviceID)
-	if err != nil {
-		return fmt.Errorf("no path configured")
+// Output formats of status --format.
+const (
+	statusFormatTable = "table"
+	statusFormatCSV   = "csv"
+	statusFormatTSV   = "tsv"
+	statusFormatJSON  = "json"
+)
+
+// statusOutputFormat validates --format and folds --json into it.
+// formatSet reports whether --format was given explicitly.
+func statusOutputFormat(format string, jsonFlag, formatSet bool) (string, error) {
+	switch format {
+	case statusFormatTable, statusFormatCSV, statusFormatTSV, statusFormatJSON:
+	default:
+		return "", fmt.Errorf("invalid --format: %s (use table, csv, tsv or json)", format)
+	}
+	if jsonFlag {
+		if formatSet && format != statusFormatJSON {
+			return "", fmt.Errorf("--json cannot be combined with --format=%s", format)
+		}
+		return statusFormatJSON, nil
 	}
+	return format, nil
+}
 
-	// Determine vault file name
-	titleInfo := pathdetect.GetTitleByCode(title)
-	var fileName string
-	if titleInfo != nil {
-		fileName = titleInfo.FileName
-	} else {
-		// Default to score.dat
-		fileName = "score.dat"
+// statusColumns are the header of the csv and tsv formats.
+var statusColumns = []string{
+	"title",
+	"local_size", "local_mtime", "local_hash",
+	"vault_size", "vault_mtime", "vault_hash",
+	"recommendation", "reason",
+}
+
+// writeStatus writes results to w in a machine-readable format (json, csv or tsv).
+func writeStatus(w io.Writer, format string, results []sync.TitleStatus) error {
+	if format == statusFormatJSON {
+		if results == nil {
+			results = []sync.TitleStatus{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
 	}
 
-	// Get vault path
-	vaultPath, err := sync.GetVaultFilePath(title, fileName)
-	if err != nil {
-		return fmt.Errorf("failed to get vault path: %w", err)
+	cw := csv.NewWriter(w)
+	if format == statusFormatTSV {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write(statusColumns); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := cw.Write(statusRecord(result)); err != nil {
+			return err
+		}
 	}
+	cw.Flush()
+	return cw.Error()
+}
 
-	// Get metadata for both files
-	localMeta, err := sync.GetFileMetadata(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to get local metadata: %w", err)
+// statusRecord returns the csv/tsv row of one title, in the order of statusColumns.
+func statusRecord(result sync.TitleStatus) []string {
+	switch {
+	case result.Error != "":
+		return []string{result.Title, "", "", "", "", "", "", "ERROR", result.Error}
+	case result.Excluded:
+		return []string{result.Title, "", "", "", "", "", "", "EXCLUDED", "rules.json"}
 	}
 
-	vaultMeta, err := sync.GetFileMetadata(vaultPath)
-	if err != nil {
-		return fmt.Errorf("failed to get vault metadata: %w", err)
+	record := []string{result.Title}
+	record = append(record, metadataFields(result.LocalMeta)...)
+	record = append(record, metadataFields(result.RemoteMeta)...)
+	return append(record, result.Recommendation, result.Reason)
+}
+
+// metadataFields returns the size, mtime and hash cells of a file; empty if it does not exist.
+func metadataFields(meta *models.FileMetadata) []string {
+	if meta == nil || !meta.Exists {
+		return []string{"", "", ""}
+	}
+	return []string{
+		fmt.Sprintf("%d", meta.Size),
+		meta.ModTime.UTC().Format(time.RFC3339),
+		meta.Hash,
 	}
+}
 
-	// Compare files
-	comparison := sync.CompareFiles(localMeta, vaultMeta)
+// printTitleStatus prints one row of the status listing.
+func printTitleStatus(result sync.TitleStatus) {
+	title := result.Title
+
+	if result.Error != "" {
+		fmt.Printf("%-8s ERROR: %s\n", title, result.Error)
+		return
+	}
+	if result.Excluded {
+		fmt.Printf("%-8s %-35s %-35s %-16s %-25s\n", title, "-", "-", "-", "- EXCLUDED (rules.json)")
+		return
+	}
 
 	// Format local info
-	localInfo := formatFileInfo(localMeta)
-	vaultInfo := formatFileInfo(vaultMeta)
+	localInfo := formatFileInfo(result.LocalMeta)
+	vaultInfo := formatFileInfo(result.RemoteMeta)
 
-	// Format recommendation
-	recommendation := formatRecommendation(comparison)
+	// Format last sync from this device
+	lastSynced := "never"
+	if result.LastSynced != nil {
+		lastSynced = formatTimeAgo(*result.LastSynced, "06-01-02 15:04")
+	}
 
-	fmt.Printf("%-8s %-35s %-35s %-25s\n",
-		title, localInfo, vaultInfo, recommendation)
+	// Format recommendation
+	recommendation := formatRecommendation(result.ComparisonResult)
 
-	return nil
+	fmt.Printf("%-8s %-35s %-35s %-16s %-25s\n",
+		title, localInfo, vaultInfo, lastSynced, recommendation)
 }
 
 func formatFileInfo(meta *models.FileMetadata) string {
@@ -140,10 +287,14 @@ func formatFileInfo(meta *models.FileMetadata) string {
 		return "[NOT READABLE]"
 	}
 
+	hash := meta.HashShort()
+	if hash == "" {
+		hash = "-" // not hashed (--quick)
+	}
 	return fmt.Sprintf("size=%d m=%s h=%s",
 		meta.Size,
-		meta.ModTime.Format("06-01-02 15:04"),
-		meta.HashShort())
+		formatTimeAgo(meta.ModTime, "06-01-02 15:04"),
+		hash)
 }
 
 func formatRecommendation(comparison *models.ComparisonResult) string {
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+func TestStatusOutputFormat(t *testing.T) {
+	tests := []struct {
+		format    string
+		jsonFlag  bool
+		formatSet bool
+		want      string
+		wantErr   bool
+	}{
+		{"table", false, false, "table", false},
+		{"csv", false, true, "csv", false},
+		{"tsv", false, true, "tsv", false},
+		{"table", true, false, "json", false}, // --json alone
+		{"json", true, true, "json", false},
+		{"csv", true, true, "", true},
+		{"xml", false, true, "", true},
+	}
+	for _, tt := range tests {
+		got, err := statusOutputFormat(tt.format, tt.jsonFlag, tt.formatSet)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("statusOutputFormat(%q, %v, %v) = %q, %v; want %q, error %v",
+				tt.format, tt.jsonFlag, tt.formatSet, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteStatus_CSV(t *testing.T) {
+	mtime := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC)
+	results := []sync.TitleStatus{
+		{Title: "th08", ComparisonResult: &models.ComparisonResult{
+			LocalMeta:      &models.FileMetadata{Exists: true, Readable: true, Size: 100, ModTime: mtime, Hash: "abc"},
+			RemoteMeta:     &models.FileMetadata{Exists: false},
+			Recommendation: "PULL",
+			Reason:         "remote does not exist, local exists",
+		}},
+		{Title: "th10", Excluded: true},
+		{Title: "th11", Error: "no path registered"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeStatus(&buf, statusFormatCSV, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"title,local_size,local_mtime,local_hash,vault_size,vault_mtime,vault_hash,recommendation,reason",
+		`th08,100,2025-11-11T06:20:30Z,abc,,,,PULL,"remote does not exist, local exists"`,
+		"th10,,,,,,,EXCLUDED,rules.json",
+		"th11,,,,,,,ERROR,no path registered",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("CSV output:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := writeStatus(&buf, statusFormatTSV, results[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(buf.String(), "\n")[1]; got != "th10\t\t\t\t\t\t\tEXCLUDED\trules.json" {
+		t.Errorf("Unexpected TSV row: %q", got)
+	}
+}
+
+func TestWriteStatus_JSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeStatus(&buf, statusFormatJSON, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("Expected an empty JSON array, got %q", got)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [title|all]",
+	Short: "pull → push を一度に実行",
+	Long: `ローカルの変更をポータブルストレージの正本へ吸い上げ（pull）、
+その後、正本をローカルへ配布（push）します。
+
+競合は対話的に解決します。pullで書き込んだタイトルは同じ実行内でpushしないため、
+同じファイルが二重にバックアップされることはありません。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSync,
+}
+
+var syncTitles string
+
+func init() {
+	syncCmd.Flags().StringVar(&syncTitles, "titles", "", "同期するタイトルをカンマ区切りまたはglobで指定 (例: th06,th1*)")
+}
+
+// syncTitleResult records the net effect of syncing one title.
+type syncTitleResult struct {
+	Title  string
+	Action string
+	Err    error
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	// Determine target title
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+
+	release, err := acquireRunLock(cmd, true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Get device ID
+	deviceID, macHash, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	console.Printf("=== thlocalsync sync ===\n")
+	console.Printf("Device: %s (%s)\n\n", deviceID, hostname)
+
+	// Initialize logger
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	logRetries(log)
+	logPrunes(log)
+
+	// Record this device as seen
+	if _, err := recordDeviceSeen(deviceID, macHash, hostname); err != nil {
+		log.Warn("device_update_failed", map[string]interface{}{
+			"device": deviceID,
+			"error":  err.Error(),
+		})
+	}
+
+	// Load configurations
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	// Show progress for large copies
+	if !console.IsQuiet() {
+		sync.SetCopyProgress(newCopyProgress)
+	}
+
+	// Get titles to sync
+	var titles []string
+	if targetTitle == "all" {
+		for title := range pathsConfig.Paths {
+			titles = append(titles, title)
+		}
+		if len(titles) == 0 {
+			console.Println("No titles configured. Run 'thlocalsync detect' first.")
+			return nil
+		}
+		if titles, err = thlocalsync.FilterTitles(titles, syncTitles); err != nil {
+			return err
+		}
+	} else {
+		if syncTitles != "" {
+			return errTitlesWithTitleArg
+		}
+		if !pathdetect.IsValidTitleCode(targetTitle) {
+			return fmt.Errorf("invalid title code: %s", targetTitle)
+		}
+		titles = []string{targetTitle}
+	}
+
+	removeStaleTempFiles(log)
+
+	syncer := &thlocalsync.Syncer{
+		PathsConfig:   pathsConfig,
+		DeviceID:      deviceID,
+		Slot:          backup.DefaultSlot,
+		Log:           log,
+		Operation:     thlocalsync.OperationSync,
+		VaultFileName: getVaultFileName,
+		PreSync:       preSyncHook,
+		PostSync:      postSyncHook,
+		HookRan:       printHook,
+	}
+
+	// Sync each title
+	var results []syncTitleResult
+	var storageErr error
+	var titleStats titleIO
+	for i, title := range titles {
+		if storageErr = checkVaultBeforeTitle("sync", titles, i, deviceID, log); storageErr != nil {
+			break
+		}
+		stop := timing.Start("title:" + title)
+		done := titleStats.start(title)
+		var action string
+		err := withHooks(syncer, thlocalsync.OperationSync, title, func() (err error) {
+			if action, err = syncTitle(syncer, title); err == nil {
+				syncFolders(syncer, title, sync.ActionPull)
+				syncFolders(syncer, title, sync.ActionPush)
+			}
+			return err
+		})
+		stop()
+		done()
+		if err != nil {
+			fmt.Printf("✗ %s: %v\n", title, err)
+			syncer.LogError(title, err)
+		}
+		results = append(results, syncTitleResult{Title: title, Action: action, Err: err})
+	}
+
+	if storageErr != nil {
+		// data/ lives on the same storage, so the paths config cannot be saved either
+		printSyncSummary(results)
+		titleStats.printTotal()
+		cmd.SilenceUsage = true
+		return storageErr
+	}
+
+	// Persist last-push and last-sync times
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+
+	printSyncSummary(results)
+	titleStats.printTotal()
+
+	return nil
+}
+
+// syncTitle pulls then pushes a single title and returns its net effect.
+// A title written by the pull phase is not pushed back, so the vault copy
+// is never backed up twice in one run.
+func syncTitle(syncer *thlocalsync.Syncer, title string) (string, error) {
+	target, err := syncer.Target(title)
+	if err != nil {
+		return "", err
+	}
+
+	// Pull phase
+	result, err := syncer.Pull(target)
+	if err != nil {
+		return "", err
+	}
+	comparison := result.Comparison
+	printFileDetails(title, comparison)
+
+	switch result.Action {
+	case sync.ActionPull:
+		printWrite(syncer, result, comparison.Reason)
+		return sync.ActionPull, nil
+
+	case sync.ActionConflict:
+		choice := promptUserForConflictResolution(title, comparison, "sync")
+		reason := "user resolved conflict - chose " + choice
+		if choice == thlocalsync.ChoiceCancel {
+			reason = "user cancelled conflict resolution"
+		}
+		return settleConflict(syncer, result, choice, reason)
+	}
+
+	if comparison.Recommendation == "SKIP" {
+		if comparison.HashMatch {
+			sync.RecordPush(syncer.PathsConfig, title, syncer.DeviceID, getCurrentTime())
+		}
+		console.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
+		return sync.ActionSkip, nil
+	}
+
+	// Push phase: the vault is newer than local
+	result, err = syncer.Push(target)
+	if err != nil {
+		return "", err
+	}
+	comparison = result.Comparison
+
+	if result.Action != sync.ActionPush {
+		console.Printf("- %s: Skipped (%s)\n", title, comparison.Reason)
+		return sync.ActionSkip, nil
+	}
+
+	printWrite(syncer, result, comparison.Reason)
+	return sync.ActionPush, nil
+}
+
+// printSyncSummary prints the net effect per title and the totals.
+func printSyncSummary(results []syncTitleResult) {
+	counts := make(map[string]int)
+	errorCount := 0
+
+	console.Printf("\n=== Summary ===\n")
+	for _, r := range results {
+		if r.Err != nil {
+			console.Printf("  %-8s error\n", r.Title)
+			errorCount++
+			continue
+		}
+		console.Printf("  %-8s %s\n", r.Title, r.Action)
+		counts[r.Action]++
+	}
+
+	console.Printf("Pulled: %d, Pushed: %d, Skipped: %d, Conflicts: %d, Errors: %d\n",
+		counts[sync.ActionPull], counts[sync.ActionPush], counts[sync.ActionSkip], counts[sync.ActionConflict], errorCount)
+}
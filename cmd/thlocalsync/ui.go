@@ -0,0 +1,558 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/thlocalsync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "タイトル一覧から対話的にpull/pushと競合解決",
+	Long: `全タイトルの比較結果を推奨アクションごとに色分けして一覧表示し、
+キー操作でpull/pushや競合の解決を行います。通常のCLIコマンドはそのまま使えます。
+
+キー操作:
+  ↑/↓ (k/j)  カーソル移動
+  Space      選択の切り替え（未選択ならカーソル行が対象）
+  p          選択したタイトルをpull
+  u          選択したタイトルをpush
+  Enter      カーソル行の競合を解決
+  r          再読み込み
+  q / Esc    終了
+
+競合はパネルに両側のサイズ・更新時刻・ハッシュを表示し、
+l（ローカルを採用してpull）、r（USBを採用してpush）、s（スキップ）で解決します。
+履歴は通常のpull/pushと同様に作成されます。`,
+	Args: cobra.NoArgs,
+	RunE: runUI,
+}
+
+var uiTitles string
+
+func init() {
+	uiCmd.Flags().StringVar(&uiTitles, "titles", "", "表示するタイトルをカンマ区切りまたはglobで指定 (例: th06,th1*)")
+}
+
+// Styles of the ui screen. lipgloss drops the colors when the terminal has none.
+var (
+	uiHeaderStyle   = lipgloss.NewStyle().Bold(true)
+	uiHelpStyle     = lipgloss.NewStyle().Faint(true)
+	uiSkipStyle     = lipgloss.NewStyle().Faint(true)
+	uiErrorStyle    = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(1))
+	uiPullStyle     = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(2))
+	uiConflictStyle = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(3))
+	uiPushStyle     = lipgloss.NewStyle().Foreground(lipgloss.ANSIColor(6))
+)
+
+// uiOp is a pull or push of one title queued from the ui.
+type uiOp struct {
+	title  string
+	action string // sync.ActionPull or sync.ActionPush
+}
+
+// uiConflict is the conflict shown in the resolution panel.
+type uiConflict struct {
+	title      string
+	comparison *models.ComparisonResult
+	direction  string // of the queued pull or push that stopped on it, whose pre_sync hook ran; empty when opened from the list
+}
+
+// uiModel is the state of the ui screen, independent of the terminal.
+type uiModel struct {
+	statuses []sync.TitleStatus
+	cursor   int
+	selected map[string]bool
+	messages []string    // results of the last action
+	conflict *uiConflict // open resolution panel, or nil
+	queue    []uiOp      // queued pulls/pushes waiting behind the conflict
+}
+
+func newUIModel(statuses []sync.TitleStatus) *uiModel {
+	return &uiModel{statuses: statuses, selected: make(map[string]bool)}
+}
+
+// move moves the cursor by delta, staying on the list.
+func (m *uiModel) move(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.statuses) {
+		m.cursor = len(m.statuses) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+// current returns the status under the cursor, or nil for an empty list.
+func (m *uiModel) current() *sync.TitleStatus {
+	if m.cursor < 0 || m.cursor >= len(m.statuses) {
+		return nil
+	}
+	return &m.statuses[m.cursor]
+}
+
+// toggle selects or deselects the title under the cursor.
+func (m *uiModel) toggle() {
+	if status := m.current(); status != nil {
+		m.selected[status.Title] = !m.selected[status.Title]
+	}
+}
+
+// targets returns action for the selected titles in list order, or for the
+// title under the cursor when nothing is selected.
+func (m *uiModel) targets(action string) []uiOp {
+	var ops []uiOp
+	for _, status := range m.statuses {
+		if m.selected[status.Title] {
+			ops = append(ops, uiOp{title: status.Title, action: action})
+		}
+	}
+	if len(ops) == 0 {
+		if status := m.current(); status != nil {
+			ops = append(ops, uiOp{title: status.Title, action: action})
+		}
+	}
+	return ops
+}
+
+// setStatuses replaces the list after a refresh, keeping the cursor on the same title.
+func (m *uiModel) setStatuses(statuses []sync.TitleStatus) {
+	title := ""
+	if status := m.current(); status != nil {
+		title = status.Title
+	}
+	m.statuses = statuses
+	m.cursor = 0
+	for i, status := range statuses {
+		if status.Title == title {
+			m.cursor = i
+		}
+	}
+}
+
+// view draws the whole screen.
+func (m *uiModel) view(header string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", uiHeaderStyle.Render(header))
+
+	fmt.Fprintf(&b, "      %-8s %-35s %-35s %s\n", "Title", "Local(best)", "USB("+backup.DefaultSlot+")", "Recommendation")
+	fmt.Fprintln(&b, strings.Repeat("-", 115))
+	for i, status := range m.statuses {
+		cursor, plain := "  ", lipgloss.NewStyle()
+		columns, recommendation, style := formatUIStatus(status)
+		if i == m.cursor {
+			cursor, plain, style = "> ", plain.Reverse(true), style.Reverse(true)
+		}
+		mark := "[ ]"
+		if m.selected[status.Title] {
+			mark = "[x]"
+		}
+		fmt.Fprintf(&b, "%s%s\n", plain.Render(fmt.Sprintf("%s%s %-8s %s", cursor, mark, status.Title, columns)), style.Render(recommendation))
+	}
+	if len(m.statuses) == 0 {
+		fmt.Fprintln(&b, "  No titles configured. Run 'thlocalsync detect' first.")
+	}
+
+	if len(m.messages) > 0 {
+		fmt.Fprintln(&b)
+		for _, message := range m.messages {
+			fmt.Fprintln(&b, message)
+		}
+	}
+
+	fmt.Fprintln(&b)
+	if c := m.conflict; c != nil {
+		fmt.Fprintln(&b, uiConflictStyle.Render("⚠ Conflict: "+c.title))
+		fmt.Fprintf(&b, "   %s\n", c.comparison.Reason)
+		fmt.Fprintf(&b, "   Local:  %s\n", formatConflictSide(c.comparison.LocalMeta))
+		fmt.Fprintf(&b, "   USB:    %s\n\n", formatConflictSide(c.comparison.RemoteMeta))
+		fmt.Fprintln(&b, "[l] use local (pull to USB)  [r] use USB (push to local)  [s] skip")
+		return b.String()
+	}
+	fmt.Fprintln(&b, uiHelpStyle.Render("↑/↓ move  space select  p pull  u push  enter resolve conflict  r refresh  q quit"))
+	return b.String()
+}
+
+// formatUIStatus formats one row after the title: the file columns, and the
+// recommendation with the style it is colored in. Errors and excluded titles fill the
+// row with the recommendation alone.
+func formatUIStatus(status sync.TitleStatus) (columns, recommendation string, style lipgloss.Style) {
+	if status.Error != "" {
+		return "", "ERROR: " + status.Error, uiErrorStyle
+	}
+	if status.Excluded {
+		return "", fmt.Sprintf("%-35s %-35s %s", "-", "-", "- EXCLUDED (rules.json)"), uiSkipStyle
+	}
+
+	style = lipgloss.NewStyle()
+	switch status.Recommendation {
+	case "PULL":
+		style = uiPullStyle
+	case "PUSH":
+		style = uiPushStyle
+	case "SKIP":
+		style = uiSkipStyle
+	case "CONFLICT":
+		style = uiConflictStyle
+	}
+	columns = fmt.Sprintf("%-35s %-35s ", formatFileInfo(status.LocalMeta), formatFileInfo(status.RemoteMeta))
+	return columns, formatRecommendation(status.ComparisonResult), style
+}
+
+// formatConflictSide formats one side of a conflict for the resolution panel.
+func formatConflictSide(meta *models.FileMetadata) string {
+	if !meta.Exists {
+		return "[NOT EXIST]"
+	}
+	return fmt.Sprintf("size=%d, mtime=%s, hash=%s",
+		meta.Size, meta.ModTime.Local().Format("2006-01-02 15:04:05"), truncateHash(meta.Hash))
+}
+
+// uiApp runs the actions chosen in the ui against the vault and this device.
+type uiApp struct {
+	model       *uiModel
+	deviceID    string
+	pathsConfig *models.PathsConfig
+	log         *logger.Logger
+	syncer      *thlocalsync.Syncer
+	titles      []string
+	header      string
+	counts      map[string]int // writes per action, for the closing summary
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	if !utils.IsInteractive() {
+		return fmt.Errorf("ui requires an interactive terminal (use status, pull and push instead)")
+	}
+
+	release, err := acquireRunLock(cmd, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Get device ID
+	deviceID, macHash, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	// Initialize logger
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	logRetries(log)
+	logPrunes(log)
+
+	// Record this device as seen
+	if _, err := recordDeviceSeen(deviceID, macHash, hostname); err != nil {
+		log.Warn("device_update_failed", map[string]interface{}{
+			"device": deviceID,
+			"error":  err.Error(),
+		})
+	}
+
+	// Load configurations
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	var titles []string
+	for title := range pathsConfig.Paths {
+		titles = append(titles, title)
+	}
+	if titles, err = thlocalsync.FilterTitles(titles, uiTitles); err != nil {
+		return err
+	}
+
+	removeStaleTempFiles(log)
+
+	app := &uiApp{
+		deviceID:    deviceID,
+		pathsConfig: pathsConfig,
+		log:         log,
+		titles:      titles,
+		counts:      make(map[string]int),
+		syncer: &thlocalsync.Syncer{
+			PathsConfig:   pathsConfig,
+			DeviceID:      deviceID,
+			Slot:          backup.DefaultSlot,
+			Log:           log,
+			Operation:     "ui",
+			VaultFileName: getVaultFileName,
+			PreSync:       preSyncHook,
+			PostSync:      postSyncHook,
+			HookRan:       printHook,
+		},
+	}
+	app.model = newUIModel(app.status())
+
+	// Pull and push report through the screen instead of routine output
+	console.SetVerbosity(console.Quiet)
+	log.Info("ui_start", map[string]interface{}{
+		"device": deviceID,
+		"titles": titles,
+	})
+
+	// A termination signal ends the program like q does, so the lock is released and
+	// the times of finished writes are saved
+	app.header = fmt.Sprintf("=== thlocalsync ui ===  Device: %s (%s)", deviceID, hostname)
+	if _, err := tea.NewProgram(app, tea.WithAltScreen()).Run(); err != nil && !errors.Is(err, tea.ErrInterrupted) {
+		return fmt.Errorf("failed to run the ui: %w", err)
+	}
+
+	log.Info("ui_stop", map[string]interface{}{
+		"device": deviceID,
+		"pulls":  app.counts[sync.ActionPull],
+		"pushes": app.counts[sync.ActionPush],
+	})
+	fmt.Printf("Pulled: %d, Pushed: %d\n", app.counts[sync.ActionPull], app.counts[sync.ActionPush])
+
+	// Persist last-push and last-sync times
+	if err := config.SavePaths(pathsConfig); err != nil {
+		return fmt.Errorf("failed to save paths config: %w", err)
+	}
+	return nil
+}
+
+// status compares every title shown in the ui.
+func (a *uiApp) status() []sync.TitleStatus {
+	return sync.Status(a.pathsConfig, a.titles, a.deviceID, sync.StatusOptions{
+		Slot:          backup.DefaultSlot,
+		Jobs:          runtime.NumCPU(),
+		VaultFileName: getVaultFileName,
+	})
+}
+
+// Init implements tea.Model; the list is compared before the program starts.
+func (a *uiApp) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model. Pulls and pushes run synchronously, so the screen
+// shows their results once they are done.
+func (a *uiApp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if key, ok := msg.(tea.KeyMsg); ok && a.handleKey(key) {
+		return a, tea.Quit
+	}
+	return a, nil
+}
+
+// View implements tea.Model.
+func (a *uiApp) View() string {
+	return a.model.view(a.header)
+}
+
+// handleKey applies one key press and reports whether the ui should close. Letters
+// are matched case-insensitively.
+func (a *uiApp) handleKey(msg tea.KeyMsg) (quit bool) {
+	m := a.model
+
+	key := msg.String()
+	if msg.Type == tea.KeyRunes {
+		key = strings.ToLower(key)
+	}
+	if key == "ctrl+c" {
+		return true
+	}
+	if m.conflict != nil {
+		switch key {
+		case "l":
+			a.resolve("local")
+		case "r":
+			a.resolve("remote")
+		case "s", "esc":
+			a.resolve("skip")
+		}
+		return false
+	}
+
+	switch key {
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case " ":
+		m.toggle()
+	case "p":
+		a.runOps(m.targets(sync.ActionPull))
+	case "u":
+		a.runOps(m.targets(sync.ActionPush))
+	case "enter":
+		if status := m.current(); status != nil && status.ComparisonResult != nil && status.Recommendation == "CONFLICT" {
+			m.messages = nil
+			m.conflict = &uiConflict{title: status.Title, comparison: status.ComparisonResult}
+		}
+	case "r":
+		m.messages = nil
+		m.setStatuses(a.status())
+	case "q", "esc":
+		return true
+	}
+	return false
+}
+
+// runOps queues ops and runs them until one stops on a conflict.
+func (a *uiApp) runOps(ops []uiOp) {
+	a.model.messages = nil
+	if err := checkVaultAvailable(); err != nil {
+		a.model.messages = append(a.model.messages, uiErrorStyle.Render(fmt.Sprintf("✗ %v", err)))
+		return
+	}
+	a.model.queue = append(a.model.queue, ops...)
+	a.drain()
+}
+
+// drain runs queued ops until the queue is empty or a conflict needs resolving, then
+// refreshes the list.
+func (a *uiApp) drain() {
+	m := a.model
+	for len(m.queue) > 0 && m.conflict == nil {
+		op := m.queue[0]
+		m.queue = m.queue[1:]
+		a.run(op)
+	}
+	if m.conflict == nil {
+		m.selected = make(map[string]bool)
+		m.setStatuses(a.status())
+	}
+}
+
+// run pulls or pushes one title between its pre_sync and post_sync hooks; a conflict
+// opens the resolution panel, and the post_sync hook waits for its resolution.
+func (a *uiApp) run(op uiOp) {
+	target, err := a.syncer.Target(op.title)
+	if err != nil {
+		a.fail(op, err)
+		return
+	}
+	if _, err := a.syncer.RunHook(thlocalsync.HookPreSync, op.action, op.title); err != nil {
+		a.fail(op, err)
+		return
+	}
+
+	var result *sync.TitleSyncResult
+	if op.action == sync.ActionPull {
+		result, err = a.syncer.Pull(target)
+	} else {
+		result, err = a.syncer.Push(target)
+	}
+	if err != nil {
+		a.fail(op, err)
+		return
+	}
+
+	if result.Action == sync.ActionConflict {
+		a.model.conflict = &uiConflict{title: op.title, comparison: result.Comparison, direction: op.action}
+		return
+	}
+	a.finish(op.action, result, result.Comparison.Reason)
+	a.postSync(op)
+}
+
+// resolve applies the choice made in the resolution panel and resumes the queue. A
+// conflict opened from the list runs the hooks around the write it resolves to.
+func (a *uiApp) resolve(choice string) {
+	c := a.model.conflict
+	a.model.conflict = nil
+
+	op := uiOp{title: c.title, action: c.direction}
+	if op.action == "" {
+		if choice == thlocalsync.ChoiceSkip {
+			a.model.messages = append(a.model.messages, fmt.Sprintf("- %s: Conflict skipped", c.title))
+			a.drain()
+			return
+		}
+		op.action = sync.ActionPull
+		if choice == thlocalsync.ChoiceRemote {
+			op.action = sync.ActionPush
+		}
+		if _, err := a.syncer.RunHook(thlocalsync.HookPreSync, op.action, op.title); err != nil {
+			a.fail(op, err)
+			a.drain()
+			return
+		}
+	}
+
+	target, err := a.syncer.Target(c.title)
+	if err != nil {
+		a.fail(op, err)
+		a.drain()
+		return
+	}
+	conflict := &sync.TitleSyncResult{TitleTarget: target, Action: sync.ActionConflict, Comparison: c.comparison}
+
+	reason := "user resolved conflict - chose " + choice
+	if choice == thlocalsync.ChoiceSkip {
+		reason = "user skipped conflict"
+	}
+	result, err := a.syncer.Resolve(conflict, choice, reason)
+	switch {
+	case err != nil:
+		a.fail(op, err)
+	case result.Action == sync.ActionConflict:
+		a.model.messages = append(a.model.messages, fmt.Sprintf("- %s: Conflict skipped", c.title))
+		a.postSync(op)
+	default:
+		a.finish(result.Action, result, reason)
+		a.postSync(op)
+	}
+	a.drain()
+}
+
+// postSync runs the post_sync hook of op, whose failure only warns.
+func (a *uiApp) postSync(op uiOp) {
+	if _, err := a.syncer.RunHook(thlocalsync.HookPostSync, op.action, op.title); err != nil {
+		a.model.messages = append(a.model.messages, uiConflictStyle.Render(fmt.Sprintf("⚠ %s: %v", op.title, err)))
+	}
+}
+
+// finish reports a pull or push that completed without a conflict and syncs the
+// title's replays and folder files.
+func (a *uiApp) finish(action string, result *sync.TitleSyncResult, reason string) {
+	switch result.Action {
+	case sync.ActionPull:
+		a.model.messages = append(a.model.messages, uiPullStyle.Render(fmt.Sprintf("✓ %s: Pulled to USB (%s)", result.Title, reason)))
+		a.counts[sync.ActionPull]++
+	case sync.ActionPush:
+		a.model.messages = append(a.model.messages, uiPushStyle.Render(fmt.Sprintf("✓ %s: Pushed to local (%s)", result.Title, reason)))
+		a.counts[sync.ActionPush]++
+	default:
+		a.model.messages = append(a.model.messages, fmt.Sprintf("- %s: Skipped (%s)", result.Title, reason))
+	}
+
+	if action == sync.ActionPull {
+		archiveTitleFiles(result.Title, result.LocalPath, a.log)
+	}
+	for _, folder := range a.syncer.SyncFolders(result.TitleTarget, action) {
+		if folder.Err != nil {
+			a.model.messages = append(a.model.messages, uiErrorStyle.Render(fmt.Sprintf("✗ %s/%s: %v", result.Title, folder.Kind, folder.Err)))
+		}
+	}
+}
+
+// fail reports an op that failed.
+func (a *uiApp) fail(op uiOp, err error) {
+	a.model.messages = append(a.model.messages, uiErrorStyle.Render(fmt.Sprintf("✗ %s: %v", op.title, err)))
+	a.syncer.LogError(op.title, err)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+func TestUIApp_Update(t *testing.T) {
+	comparison := &models.ComparisonResult{Recommendation: "CONFLICT", Reason: "evidence conflict"}
+	app := &uiApp{model: newUIModel([]sync.TitleStatus{
+		{Title: "th06"},
+		{Title: "th07", ComparisonResult: comparison},
+	})}
+	press := func(msg tea.KeyMsg) tea.Cmd {
+		t.Helper()
+		_, cmd := app.Update(msg)
+		return cmd
+	}
+
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if app.model.cursor != 1 || !app.model.selected["th07"] {
+		t.Errorf("Expected th07 under the cursor and selected, got cursor %d, %v", app.model.cursor, app.model.selected)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'K'}})
+	if app.model.cursor != 0 {
+		t.Errorf("Expected K to move up like k, got cursor %d", app.model.cursor)
+	}
+
+	// Enter opens the panel only on a conflict, and q does not quit while it is open
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if app.model.conflict != nil {
+		t.Errorf("Expected no panel for th06, got %+v", app.model.conflict)
+	}
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if c := app.model.conflict; c == nil || c.title != "th07" || c.direction != "" {
+		t.Fatalf("Expected the panel for th07 opened from the list, got %+v", c)
+	}
+	if cmd := press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}}); cmd != nil || app.model.conflict == nil {
+		t.Error("Expected q to be ignored while the panel is open")
+	}
+
+	if cmd := press(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("Expected Ctrl-C to quit even with the panel open")
+	}
+	app.model.conflict = nil
+	if cmd := press(tea.KeyMsg{Type: tea.KeyEsc}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("Expected Esc to quit from the list")
+	}
+}
+
+func TestUIModel_Targets(t *testing.T) {
+	m := newUIModel([]sync.TitleStatus{{Title: "th06"}, {Title: "th07"}, {Title: "th08"}})
+
+	m.move(-1)
+	if m.cursor != 0 {
+		t.Errorf("Expected the cursor to stay on the first row, got %d", m.cursor)
+	}
+	m.move(5)
+	if m.cursor != 2 {
+		t.Errorf("Expected the cursor to stop on the last row, got %d", m.cursor)
+	}
+
+	// Nothing selected: the cursor row is the target
+	if ops := m.targets(sync.ActionPull); len(ops) != 1 || ops[0].title != "th08" || ops[0].action != sync.ActionPull {
+		t.Errorf("Expected the cursor title, got %+v", ops)
+	}
+
+	m.toggle()
+	m.move(-2)
+	m.toggle()
+	ops := m.targets(sync.ActionPush)
+	if len(ops) != 2 || ops[0].title != "th06" || ops[1].title != "th08" {
+		t.Errorf("Expected the selected titles in list order, got %+v", ops)
+	}
+
+	// A refresh keeps the cursor on the same title
+	m.move(1)
+	m.setStatuses([]sync.TitleStatus{{Title: "th07"}, {Title: "th08"}})
+	if status := m.current(); status == nil || status.Title != "th07" {
+		t.Errorf("Expected the cursor to stay on th07, got %+v", status)
+	}
+}
+
+func TestUIModel_Render(t *testing.T) {
+	mtime := time.Now().Add(-time.Hour)
+	comparison := &models.ComparisonResult{
+		LocalMeta:      &models.FileMetadata{Exists: true, Readable: true, Size: 20, ModTime: mtime, Hash: "aaaaaaaaaaaaaaaaaaaa"},
+		RemoteMeta:     &models.FileMetadata{Exists: true, Readable: true, Size: 10, ModTime: mtime, Hash: "bbbbbbbbbbbbbbbbbbbb"},
+		Recommendation: "CONFLICT",
+		Reason:         "evidence conflict",
+	}
+	m := newUIModel([]sync.TitleStatus{
+		{Title: "th08", ComparisonResult: comparison},
+		{Title: "th10", Error: "no path configured"},
+	})
+
+	screen := m.view("header")
+	if !strings.Contains(screen, "⚠ CONFLICT") || !strings.Contains(screen, "ERROR: no path configured") {
+		t.Errorf("Expected the conflict and the error in the list:\n%q", screen)
+	}
+	if _, _, style := formatUIStatus(m.statuses[0]); style.GetForeground() != uiConflictStyle.GetForeground() {
+		t.Error("Expected the conflict to be colored like the conflict panel")
+	}
+	if _, _, style := formatUIStatus(m.statuses[1]); style.GetForeground() != uiErrorStyle.GetForeground() {
+		t.Error("Expected the error to be colored red")
+	}
+	if !strings.Contains(screen, "q quit") {
+		t.Errorf("Expected the key help without an open conflict:\n%q", screen)
+	}
+
+	m.conflict = &uiConflict{title: "th08", comparison: comparison}
+	screen = m.view("header")
+	for _, want := range []string{"Conflict: th08", "size=20", "size=10", "[l] use local"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Expected the conflict panel to contain %q:\n%q", want, screen)
+		}
+	}
+}
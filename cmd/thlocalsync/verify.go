@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/manifest"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifySlot string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [title|all]",
+	Short: "vaultの整合性を検証",
+	Long: `vaultの正本ファイルを検証し、タイトルごとに OK/FAIL を表示します。
+
+検証内容:
+  - 正本ファイルが存在し、読み取れること
+  - 0バイトでないこと
+  - vault/manifest.json に記録された最後の書き込み時の状態と一致すること
+    （サイズ・更新時刻が同じなのに内容だけ違う場合は破損の可能性として報告）
+  - manifest に記録がなければ、最後に記録された同期（ログの vault_hash）と一致すること
+  - 最新のバックアップが読み取れること
+
+ハッシュはキャッシュを使わず毎回計算し直します。
+1タイトルでも FAIL があれば終了コード1で終了するため、スクリプトからの定期確認に使えます。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifySlot, "slot", backup.DefaultSlot, "検証するvaultスロット")
+}
+
+// verifyResult is the outcome of verifying one title's vault file.
+type verifyResult struct {
+	title    string
+	failures []string // reasons the title failed
+	notes    []string // informational details for OK titles
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	// Determine target title
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+
+	if err := backup.ValidateSlot(verifySlot); err != nil {
+		return err
+	}
+	checkReadOnlyStorage()
+
+	fmt.Printf("=== thlocalsync verify ===\n")
+	printSlot(verifySlot)
+	fmt.Println()
+
+	// Rules are not applied on purpose: the hash cache must not stand in for a rehash.
+	// Only an encrypted vault needs its key to be read.
+	rules, err := config.LoadRules()
+	if err != nil {
+		return fmt.Errorf("failed to load rules config: %w", err)
+	}
+	if err := unlockVault(rules); err != nil {
+		return err
+	}
+
+	var titles []string
+	if targetTitle == "all" {
+		vaultDir, err := backup.GetVaultDir()
+		if err != nil {
+			return fmt.Errorf("failed to get vault directory: %w", err)
+		}
+		titles, err = listVaultSlotTitles(vaultDir, verifySlot)
+		if err != nil {
+			return err
+		}
+		if len(titles) == 0 {
+			fmt.Println("No titles found in the vault.")
+			return nil
+		}
+	} else {
+		// Validate title code
+		if !pathdetect.IsValidTitleCode(targetTitle) {
+			return fmt.Errorf("invalid title code: %s", targetTitle)
+		}
+		titles = []string{targetTitle}
+	}
+
+	loggedHashes := lastLoggedVaultHashes(verifySlot)
+
+	manifestPath, err := manifest.GetPath()
+	if err != nil {
+		return fmt.Errorf("failed to get manifest path: %w", err)
+	}
+	vaultManifest, err := manifest.Load(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	failCount := 0
+	for _, title := range titles {
+		result := verifyTitle(title, verifySlot, vaultManifest, loggedHashes[title])
+		if len(result.failures) > 0 {
+			failCount++
+			fmt.Printf("%-8s FAIL\n", title)
+			for _, reason := range result.failures {
+				fmt.Printf("    ✗ %s\n", reason)
+			}
+			continue
+		}
+		fmt.Printf("%-8s OK\n", title)
+		for _, note := range result.notes {
+			fmt.Printf("    - %s\n", note)
+		}
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	fmt.Printf("OK: %d, FAIL: %d\n", len(titles)-failCount, failCount)
+
+	if failCount > 0 {
+		// A failed check is a result, not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d title(s) failed verification", failCount)
+	}
+	return nil
+}
+
+// listVaultSlotTitles returns the known titles that have the given slot in the vault, in release order.
+func listVaultSlotTitles(vaultDir, slot string) ([]string, error) {
+	vaultTitles, err := backup.ListVaultTitles(vaultDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var titles []string
+	for _, title := range vaultTitles {
+		if !pathdetect.IsValidTitleCode(title) {
+			continue
+		}
+		if _, err := os.Stat(backup.GetTitleVaultPathIn(vaultDir, title, slot)); err != nil {
+			continue
+		}
+		titles = append(titles, title)
+	}
+
+	return pathdetect.SortTitlesByRelease(titles), nil
+}
+
+// lastLoggedVaultHashes returns, per title, the vault hash recorded by the most recent
+// logged write to the given slot. Entries without a slot field predate slots and
+// belong to the default slot.
+func lastLoggedVaultHashes(slot string) map[string]string {
+	log, err := logger.New()
+	if err != nil {
+		return map[string]string{}
+	}
+	records, _, err := log.ReadEntries(logger.Filter{})
+	if err != nil {
+		return map[string]string{}
+	}
+
+	return vaultHashesFromRecords(records, slot)
+}
+
+// vaultHashesFromRecords picks the last vault_hash per title for slot from records ordered oldest first.
+func vaultHashesFromRecords(records []logger.Record, slot string) map[string]string {
+	hashes := make(map[string]string)
+
+	// Later writes overwrite earlier ones
+	for _, record := range records {
+		title, _ := record.Fields["title"].(string)
+		hash, _ := record.Fields["vault_hash"].(string)
+		if title == "" || hash == "" {
+			continue
+		}
+		entrySlot, _ := record.Fields["slot"].(string)
+		if entrySlot == "" {
+			entrySlot = backup.DefaultSlot
+		}
+		if entrySlot != slot {
+			continue
+		}
+		hashes[title] = hash
+	}
+
+	return hashes
+}
+
+// verifyTitle rehashes a title's vault file and checks it against its manifest entry,
+// falling back to loggedHash (skipped when empty), and the most recent backup.
+func verifyTitle(title, slot string, vaultManifest *manifest.Manifest, loggedHash string) verifyResult {
+	result := verifyResult{title: title}
+
+	vaultPath, err := sync.GetVaultFilePath(title, slot, getVaultFileName(title))
+	if err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("failed to get vault path: %v", err))
+		return result
+	}
+
+	meta, err := sync.GetFileMetadata(vaultPath)
+	if err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("failed to read vault file: %v", err))
+		return result
+	}
+
+	switch {
+	case !meta.Exists:
+		result.failures = append(result.failures, fmt.Sprintf("vault file does not exist: %s", vaultPath))
+		return result
+	case !meta.Readable:
+		result.failures = append(result.failures, fmt.Sprintf("vault file is not readable: %s", vaultPath))
+		return result
+	case meta.Size == 0:
+		result.failures = append(result.failures, "vault file is zero bytes")
+	}
+
+	result.notes = append(result.notes, fmt.Sprintf("size=%d h=%s", meta.Size, meta.HashShort()))
+
+	if entry, ok := vaultManifest.Lookup(title, slot); ok {
+		if problem := manifest.Check(entry, meta); problem != "" {
+			result.failures = append(result.failures, fmt.Sprintf("%s (recorded=%s, actual=%s)",
+				problem, truncateHash(entry.Hash), truncateHash(meta.Hash)))
+		} else {
+			result.notes = append(result.notes, "matches manifest")
+		}
+	} else if loggedHash == "" {
+		result.notes = append(result.notes, "no logged sync to compare against")
+	} else if utils.RehashLike(meta.Path, meta.Hash, loggedHash) != loggedHash {
+		result.failures = append(result.failures, fmt.Sprintf("hash differs from last logged sync (logged=%s, actual=%s)",
+			truncateHash(loggedHash), truncateHash(meta.Hash)))
+	} else {
+		result.notes = append(result.notes, "matches last logged sync")
+	}
+
+	// The most recent backup must still be restorable
+	backups, err := backup.ListBackups(title, slot)
+	if err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("failed to list backups: %v", err))
+		return result
+	}
+	if len(backups) == 0 {
+		result.notes = append(result.notes, "no backups")
+		return result
+	}
+
+	historyDir, err := backup.GetHistoryDir(title, slot)
+	if err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("failed to get history directory: %v", err))
+		return result
+	}
+	backupHash, err := backup.HashBackup(filepath.Join(historyDir, backups[0]))
+	if err != nil {
+		result.failures = append(result.failures, fmt.Sprintf("latest backup %s is not readable: %v", backups[0], err))
+		return result
+	}
+	if backupHash == meta.Hash {
+		result.notes = append(result.notes, fmt.Sprintf("identical to latest backup %s", backups[0]))
+	} else {
+		result.notes = append(result.notes, fmt.Sprintf("latest backup %s is readable", backups[0]))
+	}
+
+	return result
+}
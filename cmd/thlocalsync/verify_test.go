@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+)
+
+func TestVaultHashesFromRecords(t *testing.T) {
+	record := func(fields map[string]interface{}) logger.Record {
+		return logger.Record{Entry: logger.Entry{Level: logger.LevelInfo, Message: "pull", Fields: fields}}
+	}
+	records := []logger.Record{
+		record(map[string]interface{}{"title": "th08", "vault_hash": "old"}),
+		record(map[string]interface{}{"title": "th08", "slot": "main", "vault_hash": "new"}),
+		record(map[string]interface{}{"title": "th08", "slot": "scoring", "vault_hash": "scoring"}),
+		record(map[string]interface{}{"title": "th10", "vault_hash": "th10"}),
+		record(map[string]interface{}{"title": "th10", "reason": "no hash recorded"}),
+		record(map[string]interface{}{"vault_hash": "untitled"}),
+	}
+
+	defaultSlot := vaultHashesFromRecords(records, "main")
+	if len(defaultSlot) != 2 || defaultSlot["th08"] != "new" || defaultSlot["th10"] != "th10" {
+		t.Errorf("Unexpected main slot hashes: %v", defaultSlot)
+	}
+
+	scoring := vaultHashesFromRecords(records, "scoring")
+	if len(scoring) != 1 || scoring["th08"] != "scoring" {
+		t.Errorf("Unexpected scoring slot hashes: %v", scoring)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/process"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval   time.Duration
+	watchDebounce   time.Duration
+	watchOnConflict string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [title|all]",
+	Short: "ゲーム終了を監視して自動でpull",
+	Long: `ゲームのプロセスを監視し、起動中だったゲームが終了したらそのタイトルを自動でpullします。
+プレイ中に起動しておけば、終了後に pull を実行し忘れることがなくなります。
+
+終了を検出してから --debounce の間待ってからpullします。その間にゲームが再起動した場合は
+pullしません（次に終了したときに改めて待ちます）。
+競合は --on-conflict で処理します（既定は skip、監視中は対話しません）。
+Ctrl-C で監視を終了します。`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 2*time.Second, "プロセスを確認する間隔")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 5*time.Second, "ゲーム終了からpullまでの待ち時間")
+	watchCmd.Flags().StringVar(&watchOnConflict, "on-conflict", conflictSkip, "競合時のポリシー (local|remote|newer|larger|skip|abort)")
+}
+
+// gameWatcher tracks the running state of each watched game and decides when to pull.
+type gameWatcher struct {
+	debounce time.Duration
+	running  map[string]bool
+	exitedAt map[string]time.Time // games that exited and are waiting for the debounce
+}
+
+func newGameWatcher(debounce time.Duration) *gameWatcher {
+	return &gameWatcher{
+		debounce: debounce,
+		running:  make(map[string]bool),
+		exitedAt: make(map[string]time.Time),
+	}
+}
+
+// observe records whether a title's game is running at now. It reports whether the game has just
+// exited (so the caller can announce it) and whether the debounce has passed and the title should
+// be pulled. A game already stopped when watching starts is never pulled.
+func (w *gameWatcher) observe(title string, running bool, now time.Time) (exited, pull bool) {
+	if running {
+		w.running[title] = true
+		delete(w.exitedAt, title)
+		return false, false
+	}
+
+	if w.running[title] {
+		w.running[title] = false
+		w.exitedAt[title] = now
+		exited = true
+	}
+
+	if at, ok := w.exitedAt[title]; ok && now.Sub(at) >= w.debounce {
+		delete(w.exitedAt, title)
+		return exited, true
+	}
+	return exited, false
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	// Determine target title
+	targetTitle := "all"
+	if len(args) > 0 {
+		targetTitle = args[0]
+	}
+
+	if watchInterval <= 0 {
+		return fmt.Errorf("invalid --interval: %s (must be positive)", watchInterval)
+	}
+	if watchDebounce < 0 {
+		return fmt.Errorf("invalid --debounce: %s (must not be negative)", watchDebounce)
+	}
+	if err := validateConflictPolicy(watchOnConflict); err != nil {
+		return err
+	}
+
+	// Held for the whole watch, so a manual pull cannot overlap an auto-pull.
+	// Interrupts stop the watch loop below, which then releases the lock.
+	release, err := acquireRunLock(cmd, false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Get device ID
+	deviceID, macHash, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	console.Printf("=== thlocalsync watch ===\n")
+	console.Printf("Device: %s (%s)\n", deviceID, hostname)
+
+	// Initialize logger
+	log, err := logger.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	logRetries(log)
+	logPrunes(log)
+
+	// Record this device as seen
+	if _, err := recordDeviceSeen(deviceID, macHash, hostname); err != nil {
+		log.Warn("device_update_failed", map[string]interface{}{
+			"device": deviceID,
+			"error":  err.Error(),
+		})
+	}
+
+	// Load configurations
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	if err := applyRules(); err != nil {
+		return err
+	}
+
+	// Get titles to watch: only those with a path on this device can be pulled
+	var titles []string
+	if targetTitle == "all" {
+		for title, titlePaths := range pathsConfig.Paths {
+			if _, ok := titlePaths[deviceID]; ok {
+				titles = append(titles, title)
+			}
+		}
+		if len(titles) == 0 {
+			console.Println("No titles configured. Run 'thlocalsync detect' first.")
+			return nil
+		}
+		titles = pathdetect.SortTitlesByRelease(titles)
+	} else {
+		if !pathdetect.IsValidTitleCode(targetTitle) {
+			return fmt.Errorf("invalid title code: %s", targetTitle)
+		}
+		titles = []string{targetTitle}
+	}
+
+	// Fail early where process detection is unavailable instead of never pulling
+	if _, _, err := process.IsGameRunning(titles[0]); errors.Is(err, process.ErrNotSupported) {
+		return fmt.Errorf("watch requires process detection: %w", err)
+	}
+
+	removeStaleTempFiles(log)
+
+	// Auto-pulls go through pullTitle with the default slot and never prompt
+	pullSlot = backup.DefaultSlot
+	pullOnConflict = watchOnConflict
+
+	console.Printf("Watching %d title(s) every %s, pulling %s after a game exits (Ctrl-C to stop)\n\n",
+		len(titles), watchInterval, watchDebounce)
+	log.Info("watch_start", map[string]interface{}{
+		"device": deviceID,
+		"titles": titles,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	syncer := newPullSyncer(deviceID, pathsConfig, log)
+	pulls, err := watchGames(ctx, titles, deviceID, log, func(title string) error {
+		err := withHooks(syncer, sync.ActionPull, title, func() error {
+			_, err := pullTitle(syncer, title)
+			if err == nil {
+				syncFolders(syncer, title, sync.ActionPull)
+			}
+			return err
+		})
+		if err == nil {
+			// Persist the last-sync time right away, watching may run for hours
+			if saveErr := config.SavePaths(pathsConfig); saveErr != nil {
+				fmt.Printf("✗ %s: failed to save paths config: %v\n", title, saveErr)
+			}
+		}
+		return err
+	})
+
+	console.Printf("\nStopped watching (%d auto-pull(s))\n", pulls)
+	log.Info("watch_stop", map[string]interface{}{
+		"device": deviceID,
+		"pulls":  pulls,
+	})
+
+	if errors.Is(err, errConflictAbort) {
+		// Stopping on a conflict is a result, not a usage mistake
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+// watchGames polls the games of titles every watchInterval until ctx is cancelled, calling pull
+// for a title once its game has been closed for watchDebounce. Returns the number of successful
+// pulls; only errConflictAbort stops the loop early, other pull errors are reported and logged.
+func watchGames(ctx context.Context, titles []string, deviceID string, log *logger.Logger, pull func(title string) error) (int, error) {
+	watcher := newGameWatcher(watchDebounce)
+	failedChecks := make(map[string]bool) // titles whose last process check failed, reported once
+	pulls := 0
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, title := range titles {
+			running, _, err := process.IsGameRunning(title)
+			if err != nil {
+				if !failedChecks[title] {
+					failedChecks[title] = true
+					fmt.Printf("✗ %s: failed to check process: %v\n", title, err)
+				}
+				continue
+			}
+			delete(failedChecks, title)
+
+			wasRunning := watcher.running[title]
+			exited, shouldPull := watcher.observe(title, running, time.Now())
+			if running && !wasRunning {
+				console.Printf("%s %s: game started\n", time.Now().Format("15:04:05"), title)
+			}
+			if exited {
+				console.Printf("%s %s: game exited\n", time.Now().Format("15:04:05"), title)
+			}
+			if !shouldPull {
+				continue
+			}
+
+			log.Info("watch_pull", map[string]interface{}{
+				"title":  title,
+				"device": deviceID,
+			})
+			err = pull(title)
+			if errors.Is(err, errConflictAbort) {
+				fmt.Printf("✗ %s: %v\n", title, err)
+				return pulls, err
+			}
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", title, err)
+				log.Error("pull_error", map[string]interface{}{
+					"title":  title,
+					"device": deviceID,
+					"error":  err.Error(),
+				})
+				continue
+			}
+			pulls++
+		}
+
+		select {
+		case <-ctx.Done():
+			return pulls, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameWatcher_Observe(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	w := newGameWatcher(5 * time.Second)
+
+	// Not running when watching starts: nothing to pull
+	if exited, pull := w.observe("th08", false, at(0)); exited || pull {
+		t.Error("Expected no pull for a game that was never seen running")
+	}
+
+	w.observe("th08", true, at(2))
+	if exited, pull := w.observe("th08", false, at(4)); !exited || pull {
+		t.Errorf("Expected exit without pull before the debounce, got exited=%v pull=%v", exited, pull)
+	}
+	if _, pull := w.observe("th08", false, at(8)); pull {
+		t.Error("Expected no pull before the debounce elapsed")
+	}
+	if exited, pull := w.observe("th08", false, at(9)); exited || !pull {
+		t.Errorf("Expected a pull once the debounce elapsed, got exited=%v pull=%v", exited, pull)
+	}
+	if _, pull := w.observe("th08", false, at(20)); pull {
+		t.Error("Expected a single pull per exit")
+	}
+
+	// A restart within the debounce cancels the pending pull
+	w.observe("th08", true, at(30))
+	w.observe("th08", false, at(31))
+	w.observe("th08", true, at(33))
+	if _, pull := w.observe("th08", true, at(40)); pull {
+		t.Error("Expected no pull while the game is running again")
+	}
+	w.observe("th08", false, at(41))
+	if _, pull := w.observe("th08", false, at(46)); !pull {
+		t.Error("Expected a pull after the restarted game exited")
+	}
+}
+
+func TestGameWatcher_ZeroDebounce(t *testing.T) {
+	w := newGameWatcher(0)
+	now := time.Now()
+
+	w.observe("th10", true, now)
+	if exited, pull := w.observe("th10", false, now); !exited || !pull {
+		t.Errorf("Expected an immediate pull without debounce, got exited=%v pull=%v", exited, pull)
+	}
+}
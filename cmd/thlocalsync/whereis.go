@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/process"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var whereisCmd = &cobra.Command{
+	Use:   "whereis <title>",
+	Short: "タイトルの解決済みパスを表示（読み取り専用）",
+	Long: `タイトルの同期に使われるパスを、内部で解決した結果のまま表示します。
+
+このデバイスに登録された生のパス、環境変数を展開した優先パス、
+シンボリックリンクやVirtualStoreを解決した実体のパス、ファイルの有無と読み取り可否、
+vaultのファイルパス、履歴ディレクトリ、ゲームの実行ファイル名を表示します。
+「セーブデータが見つからない」ときの原因調査に使用してください。
+
+使用例:
+  thlocalsync whereis th08
+  thlocalsync whereis th08 --slot hard`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWhereis,
+}
+
+var whereisSlot string
+
+func init() {
+	whereisCmd.Flags().StringVar(&whereisSlot, "slot", backup.DefaultSlot, "表示するvaultスロット")
+}
+
+// whereisInfo holds every path resolved for one title on this device.
+type whereisInfo struct {
+	Title        string
+	RawPaths     []string // as registered in paths.json
+	Preferred    int
+	Expanded     string // preferred path with environment variables expanded
+	Resolved     string // Expanded after utils.ResolvePath
+	SetFiles     []string
+	Local        *models.FileMetadata
+	LocalErr     error // no path registered, or the local file could not be read
+	VaultPath    string
+	Vault        *models.FileMetadata
+	HistoryDir   string
+	Backups      int
+	ProcessNames []string
+}
+
+func runWhereis(cmd *cobra.Command, args []string) error {
+	title := args[0]
+	if !pathdetect.IsValidTitleCode(title) {
+		return fmt.Errorf("invalid title code: %s", title)
+	}
+	if err := backup.ValidateSlot(whereisSlot); err != nil {
+		return err
+	}
+
+	// Get device ID
+	deviceID, _, hostname, err := device.GetDeviceID()
+	if err != nil {
+		return fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	info, err := collectWhereis(pathsConfig, title, deviceID, whereisSlot)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("=== thlocalsync whereis %s ===\n", title)
+	fmt.Printf("Device: %s (%s)\n", deviceID, hostname)
+	printSlot(whereisSlot)
+	fmt.Println()
+	printWhereis(info)
+
+	return nil
+}
+
+// collectWhereis resolves the local, vault and history paths of title without writing anything.
+func collectWhereis(pathsConfig *models.PathsConfig, title, deviceID, slot string) (*whereisInfo, error) {
+	info := &whereisInfo{
+		Title:        title,
+		ProcessNames: process.GetGameProcessNames(title),
+	}
+
+	entry := pathsConfig.Paths[title][deviceID]
+	info.RawPaths = entry.Paths
+	info.Preferred = entry.Preferred
+	info.SetFiles = entry.SetFiles
+
+	if _, err := sync.GetPreferredLocalPath(pathsConfig, title, deviceID); err != nil {
+		info.LocalErr = err
+	} else {
+		info.Expanded = utils.ExpandEnvPath(entry.Paths[entry.Preferred])
+		info.Resolved = utils.ResolvePath(info.Expanded)
+		if info.Local, err = sync.GetFileMetadataNoHash(info.Resolved); err != nil {
+			info.LocalErr = err
+		}
+	}
+
+	vaultPath, err := sync.GetVaultFilePath(title, slot, getVaultFileName(title))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault path: %w", err)
+	}
+	info.VaultPath = vaultPath
+	if info.Vault, err = sync.GetFileMetadataNoHash(vaultPath); err != nil {
+		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
+	}
+
+	historyDir, err := backup.GetHistoryDir(title, slot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history directory: %w", err)
+	}
+	info.HistoryDir = historyDir
+	if backups, err := backup.ListBackupsIn(historyDir); err == nil {
+		info.Backups = len(backups)
+	}
+
+	return info, nil
+}
+
+// printWhereis prints the paths collected by collectWhereis.
+func printWhereis(info *whereisInfo) {
+	fmt.Println("Registered paths:")
+	if len(info.RawPaths) == 0 {
+		fmt.Println("  (none, run 'thlocalsync detect' first)")
+	}
+	for i, p := range info.RawPaths {
+		marker := " "
+		if i == info.Preferred {
+			marker = "*"
+		}
+		fmt.Printf("  %s [%d] %s\n", marker, i, p)
+	}
+
+	switch {
+	case info.Expanded != "":
+		fmt.Printf("Preferred path: %s\n", info.Expanded)
+		if info.Resolved != info.Expanded {
+			fmt.Printf("Resolved path:  %s\n", info.Resolved)
+		}
+		if info.LocalErr != nil {
+			fmt.Printf("Local file:     error: %v\n", info.LocalErr)
+		} else {
+			fmt.Printf("Local file:     %s\n", formatWhereisFile(info.Local))
+		}
+	case info.LocalErr != nil:
+		fmt.Printf("Preferred path: none (%v)\n", info.LocalErr)
+	}
+
+	if len(info.SetFiles) > 0 {
+		fmt.Printf("Set files:      %s\n", strings.Join(info.SetFiles, ", "))
+	}
+
+	fmt.Printf("Vault file:     %s\n", info.VaultPath)
+	fmt.Printf("                %s\n", formatWhereisFile(info.Vault))
+	fmt.Printf("History dir:    %s (%d backups)\n", info.HistoryDir, info.Backups)
+	fmt.Printf("Process names:  %s\n", strings.Join(info.ProcessNames, ", "))
+}
+
+// formatWhereisFile describes whether a file exists and can be read.
+func formatWhereisFile(meta *models.FileMetadata) string {
+	switch {
+	case !meta.Exists:
+		return "not found"
+	case !meta.Readable:
+		return "exists, not readable"
+	}
+	return fmt.Sprintf("exists, readable, %s, modified %s", formatBytes(meta.Size), formatTimeAgo(meta.ModTime.Local(), "2006-01-02 15:04:05"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+func TestCollectWhereis(t *testing.T) {
+	home := t.TempDir()
+	paths.HomeOverride = home
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	saves := filepath.Join(home, "saves")
+	if err := os.MkdirAll(saves, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(saves, "score.dat"), []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("THLOCALSYNC_TEST_SAVES", saves)
+
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {"dev1": {Paths: []string{"/old/score.dat", "${THLOCALSYNC_TEST_SAVES}/score.dat"}, Preferred: 1}},
+	}}
+
+	info, err := collectWhereis(pathsConfig, "th08", "dev1", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Expanded != filepath.Join(saves, "score.dat") || info.LocalErr != nil {
+		t.Errorf("Unexpected preferred path %q (%v)", info.Expanded, info.LocalErr)
+	}
+	if !info.Local.Exists || !info.Local.Readable || info.Local.Size != 4 {
+		t.Errorf("Expected a readable local file, got %+v", info.Local)
+	}
+	if info.VaultPath != filepath.Join(home, "vault", "th08", "main", "score.dat") || info.Vault.Exists {
+		t.Errorf("Unexpected vault file %q (exists: %v)", info.VaultPath, info.Vault.Exists)
+	}
+	if len(info.ProcessNames) == 0 || info.ProcessNames[0] != "th08.exe" {
+		t.Errorf("Unexpected process names: %v", info.ProcessNames)
+	}
+
+	// Other devices' paths are not this device's
+	info, err = collectWhereis(pathsConfig, "th08", "dev2", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LocalErr == nil || info.Expanded != "" || len(info.RawPaths) != 0 {
+		t.Errorf("Expected no local path for another device, got %+v", info)
+	}
+}
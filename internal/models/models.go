@@ -1,14 +1,25 @@
 // Package models defines internal data structures used across the application.
 package models
 
-import "time"
+import (
+	"io/fs"
+	"strings"
+	"time"
+)
 
 // Device represents a PC/device that uses this sync tool.
 type Device struct {
-	ID       string    `json:"id"`        // SHA256(hostname+mac) の先頭12文字
-	Hostname string    `json:"hostname"`  // PC名
-	MACHash  string    `json:"mac_hash"`  // "sha256:..." 形式
-	LastSeen time.Time `json:"last_seen"` // 最終接続時刻
+	ID          string    `json:"id"`                     // SHA256(hostname+mac) の先頭12文字（MACがなければhostname+uuid）
+	Hostname    string    `json:"hostname"`               // PC名
+	MACHash     string    `json:"mac_hash"`               // "sha256:..." 形式
+	LastSeen    time.Time `json:"last_seen"`              // 最終接続時刻
+	OS          string    `json:"os,omitempty"`           // runtime.GOOS
+	Arch        string    `json:"arch,omitempty"`         // runtime.GOARCH
+	ToolVersion string    `json:"tool_version,omitempty"` // 最終接続時のthlocalsyncバージョン
+
+	PreferExistingLocal bool `json:"prefer_existing_local,omitempty"` // 前回push以降に更新されたローカルを上書きしない
+
+	UUID string `json:"uuid,omitempty"` // 安定したMACがない場合のID生成用ランダムUUID
 }
 
 // DeviceConfig represents the devices.json structure.
@@ -18,50 +29,108 @@ type DeviceConfig struct {
 
 // PathEntry represents a single path configuration for a title on a specific device.
 type PathEntry struct {
-	Paths     []string `json:"paths"`     // 複数パス候補（環境変数展開前）
-	Preferred int      `json:"preferred"` // 優先パスのインデックス
+	Paths      []string  `json:"paths"`                 // 複数パス候補（環境変数展開前）
+	Preferred  int       `json:"preferred"`             // 優先パスのインデックス
+	LastPushed time.Time `json:"last_pushed,omitzero"` // このデバイスへの最終push時刻（UTC）
+	LastSynced time.Time `json:"last_synced,omitzero"` // このデバイスで最後にpull/pushして一致した時刻（UTC）
+	ReplayDir  string    `json:"replay_dir,omitempty"`  // リプレイフォルダ（環境変数展開前、空なら同期しない）
+	SyncDir    string    `json:"sync_dir,omitempty"`    // フォルダごと同期するタイトルフォルダ（設定・音楽解放状態など、空なら同期しない）
+	SetFiles   []string  `json:"set_files,omitempty"`   // セーブファイルと一組で同期する同じフォルダ内のファイル名（設定ファイルなど）
 }
 
 // PathsConfig represents the paths.json structure.
 // Map: title -> device_id -> PathEntry
 type PathsConfig struct {
 	Paths map[string]map[string]PathEntry `json:"paths"` // title -> device_id -> PathEntry
+
+	// title -> vault内のファイル名（省略時はタイトル定義のファイル名）。ローカルのファイル名に関わらず正本の名前を固定する
+	VaultFileNames map[string]string `json:"vault_file_names,omitempty"`
 }
 
 // Rules represents the rules.json structure.
 type Rules struct {
 	Include      []string `json:"include"`       // 同期対象パターン
 	Exclude      []string `json:"exclude"`       // 除外パターン
-	HistoryLimit int      `json:"history_limit"` // 履歴保存上限
+	HistoryLimit int      `json:"history_limit"` // 履歴保存上限（0なら無制限）
+
+	HistoryMaxAgeDays int  `json:"history_max_age_days,omitempty"` // これより古い履歴を削除（日数、0以下なら無制限）
+	CompressBackups   bool `json:"compress_backups,omitempty"`     // 履歴をgzip圧縮して保存（<timestamp>-<name>.gz）
+	EncryptVault      bool `json:"encrypt_vault,omitempty"`        // vaultに書き込むファイルをパスフレーズ由来の鍵でAES-GCM暗号化
+
+	SizeRatioThreshold    float64 `json:"size_ratio_threshold,omitempty"`    // これを超えるサイズ比をCONFLICTとする（0以下なら既定値2.0）
+	DriftToleranceSeconds int     `json:"drift_tolerance_seconds,omitempty"` // mtimeを同一とみなす許容差（秒、0以下なら既定値3）
+
+	LogRetentionDays int `json:"log_retention_days,omitempty"` // ログ保持日数（0以下なら既定値90）
+	LogMaxSizeKB     int `json:"log_max_size_kb,omitempty"`    // ログ1ファイルの上限（KB、0以下なら既定値1024）
+
+	HashAlgo string `json:"hash_algo,omitempty"` // 変更検出のハッシュ（sha256|xxhash|blake3、空ならsha256）
+
+	RetryAttempts  int `json:"retry_attempts,omitempty"`   // 一時的なI/Oエラー時の試行回数（0以下なら既定値3、1で再試行なし）
+	RetryBackoffMS int `json:"retry_backoff_ms,omitempty"` // 最初の再試行までの待ち時間（ミリ秒、再試行ごとに倍増、0以下なら既定値200）
+
+	PreSync  []string `json:"pre_sync,omitempty"`  // 各タイトルのpull/push前に実行するコマンド（引数リスト、{title} {path} {direction} を置換、失敗でそのタイトルを中止）
+	PostSync []string `json:"post_sync,omitempty"` // 各タイトルのpull/push後に実行するコマンド（同上、失敗は警告のみ）
+}
+
+// TitlesConfig represents the titles.json structure.
+type TitlesConfig struct {
+	Titles       []TitleDefinition   `json:"titles"`                  // 組み込みタイトルに追加・上書きするタイトル
+	ProcessNames map[string][]string `json:"process_names,omitempty"` // タイトルコード -> 追加の実行ファイル名（名前を変えたexeの検出用）
+}
+
+// TitleDefinition is a user-defined title (fangame, new release) in titles.json.
+type TitleDefinition struct {
+	Code       string   `json:"code"`                   // タイトルコード（vaultのディレクトリ名にもなる）
+	Name       string   `json:"name"`                   // 表示名
+	FileName   string   `json:"file_name"`              // セーブファイル名（例: "score.dat"）
+	Patterns   []string `json:"patterns,omitempty"`     // 検索するパス（環境変数 ${APPDATA} などを展開）
+	UseAppData bool     `json:"use_appdata,omitempty"`  // %APPDATA% 配下に保存されるタイトル
+	UseGameDir bool     `json:"use_game_dir,omitempty"` // ゲームディレクトリも検索する
+	ReplayDir  string   `json:"replay_dir,omitempty"`   // リプレイのサブディレクトリ名（空なら同期しない）
+
+	ProcessNames []string `json:"process_names,omitempty"` // <code>.exe 以外の実行ファイル名（起動中判定用）
+	SyncWholeDir bool     `json:"sync_whole_dir,omitempty"` // セーブファイルのあるフォルダ内のファイルもすべて同期する
+	SetFiles     []string `json:"set_files,omitempty"`      // セーブファイルと一組で同期するファイル名（設定ファイルなど、すべて揃えてコピー）
 }
 
 // FileMetadata contains file information for comparison.
 type FileMetadata struct {
-	Path     string    // 絶対パス
-	Exists   bool      // ファイル存在
-	Readable bool      // 読み取り可能
-	Size     int64     // サイズ（バイト）
-	ModTime  time.Time // 最終更新時刻（UTC）
-	Hash     string    // SHA256ハッシュ（フル）
+	Path     string    `json:"path"`     // 絶対パス
+	Exists   bool      `json:"exists"`   // ファイル存在
+	Readable bool      `json:"readable"` // 読み取り可能
+	Size     int64     `json:"size"`     // サイズ（バイト）
+	ModTime  time.Time `json:"mtime"`    // 最終更新時刻（UTC）
+	Hash     string    `json:"hash"`     // ハッシュ（フル、SHA256以外は "xxhash:..." のようにアルゴリズム名付き）
+	Info     fs.FileInfo `json:"-"`      // 開いたハンドルのstat結果（os.SameFileによる同一ファイル判定用）
 }
 
-// HashShort returns the first 12 characters of the hash for display.
+// HashShort returns the first 12 characters of the hash digest for display,
+// without the algorithm prefix.
 func (fm *FileMetadata) HashShort() string {
-	if len(fm.Hash) < 12 {
-		return fm.Hash
+	hash := fm.Hash
+	if i := strings.IndexByte(hash, ':'); i >= 0 {
+		hash = hash[i+1:]
+	}
+	if len(hash) < 12 {
+		return hash
 	}
-	return fm.Hash[:12]
+	return hash[:12]
 }
 
 // ComparisonResult represents the result of comparing two files.
 type ComparisonResult struct {
-	LocalMeta     *FileMetadata
-	RemoteMeta    *FileMetadata
-	HashMatch     bool   // ハッシュ一致
-	SizeDiff      int64  // サイズ差（Local - Remote）
-	TimeDiff      int64  // 時間差（秒、Local - Remote）
-	Recommendation string // "PULL", "PUSH", "SKIP", "CONFLICT"
-	Reason        string // 判定理由
+	LocalMeta     *FileMetadata `json:"local"`
+	RemoteMeta    *FileMetadata `json:"remote"`
+	HashMatch     bool   `json:"hash_match"`     // ハッシュ一致
+	SizeDiff      int64  `json:"size_diff"`      // サイズ差（Local - Remote）
+	TimeDiff      int64  `json:"time_diff"`      // 時間差（秒、Local - Remote）
+	Recommendation string `json:"recommendation"` // "PULL", "PUSH", "SKIP", "CONFLICT"
+	Reason        string `json:"reason"`         // 判定理由
+	Rehashed      bool   `json:"rehashed"`       // 曖昧判定のため両側を再ハッシュした
+	ByteCompared  bool   `json:"byte_compared"`  // ハッシュの代わりに内容をバイト比較した
+	Suspicious    bool   `json:"suspicious"`     // サイズ比のヒューリスティックによるCONFLICT
+	EmptyLocal    bool   `json:"empty_local"`    // 0バイトのローカルファイルを存在しないものとして扱った
+	EmptyRemote   bool   `json:"empty_remote"`   // 0バイトのvault側ファイルを存在しないものとして扱った
 }
 
 // SyncOperation represents a single sync operation for logging.
@@ -80,7 +149,10 @@ type SyncOperation struct {
 
 // DetectCandidate represents a detected save file candidate.
 type DetectCandidate struct {
-	Title    string        // タイトルコード（th06等）
-	Path     string        // 絶対パス
-	Metadata *FileMetadata // ファイル情報
+	Title     string        // タイトルコード（th06等）
+	Path      string        // 絶対パス
+	Metadata  *FileMetadata // ファイル情報
+	ReplayDir string        // リプレイフォルダの絶対パス（見つからなければ空）
+	SyncDir   string        // フォルダごと同期するタイトルフォルダの絶対パス（対象外なら空）
+	SetFiles  []string      // セーブファイルと一組で同期するファイル名（なければ空）
 }
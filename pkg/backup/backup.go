@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
@@ -17,44 +20,106 @@ const (
 	HistoryDir = "_history"
 	// ReplayArchiveDir is the subdirectory name for replay archives
 	ReplayArchiveDir = "replay_archive"
+	// ReplaySyncDir is the subdirectory name for synced replay files
+	ReplaySyncDir = "replay"
+	// FolderSyncDir is the subdirectory name for the other files of a title synced as a whole folder
+	FolderSyncDir = "folder"
 	// SnapshotArchiveDir is the subdirectory name for snapshot archives
 	SnapshotArchiveDir = "snapshot_archive"
 	// BestshotArchiveDir is the subdirectory name for bestshot archives
 	BestshotArchiveDir = "bestshot_archive"
+	// QuarantineDir is the subdirectory name for quarantined suspicious files
+	QuarantineDir = "_quarantine"
+	// DefaultSlot is the vault slot used when no --slot is given
+	DefaultSlot = "main"
+	// PinTag is the backup tag that protects a backup from history cleanup
+	PinTag = "pinned"
+	// backupTimeLayout is the UTC timestamp prefix of backup filenames
+	backupTimeLayout = "2006-01-02T15-04-05Z"
 )
 
-// GetVaultDir returns the path to the vault directory.
-// Assumes vault is at <exe_dir>/vault
-func GetVaultDir() (string, error) {
-	exePath, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("failed to get executable path: %w", err)
+// tagPattern restricts backup tags to a single word, so the tag and the original
+// filename can be told apart at the hyphen that follows it.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.]*$`)
+
+// slotPattern restricts slot names to a single safe path component.
+// Names starting with "_" are reserved for vault bookkeeping directories.
+var slotPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateSlot checks that slot can be used as a vault slot name.
+// Slots share the title directory with the replay and archive directories,
+// so those names are rejected.
+func ValidateSlot(slot string) error {
+	if !slotPattern.MatchString(slot) {
+		return fmt.Errorf("invalid slot name %q: use letters, digits, '.', '_' or '-' and start with a letter or digit", slot)
+	}
+	switch slot {
+	case ReplaySyncDir, FolderSyncDir, ReplayArchiveDir, SnapshotArchiveDir, BestshotArchiveDir:
+		return fmt.Errorf("invalid slot name %q: reserved directory name", slot)
 	}
+	return nil
+}
 
-	exeDir := filepath.Dir(exePath)
-	return filepath.Join(exeDir, "vault"), nil
+// GetVaultDir returns the path to the vault directory: vault/ under the base directory
+// unless moved by --vault-dir or THLOCALSYNC_VAULT_DIR (see paths.ResolveVaultDir).
+func GetVaultDir() (string, error) {
+	return paths.GetVaultDir()
 }
 
-// GetTitleVaultPath returns the path to a title's vault directory.
+// GetTitleVaultPath returns the path to a slot of a title's vault directory.
 // Example: <vault>/th08/main
-func GetTitleVaultPath(title string) (string, error) {
+func GetTitleVaultPath(title string, slot string) (string, error) {
 	vaultDir, err := GetVaultDir()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(vaultDir, title, "main"), nil
+	return GetTitleVaultPathIn(vaultDir, title, slot), nil
+}
+
+// GetTitleVaultPathIn returns the path to a slot of a title's vault directory under an arbitrary vault root.
+// Example: <vaultDir>/th08/main
+func GetTitleVaultPathIn(vaultDir string, title string, slot string) string {
+	return filepath.Join(vaultDir, title, slot)
+}
+
+// ListVaultTitles returns the title codes that have a directory under the given vault root.
+// Returns an empty list if the vault root does not exist.
+func ListVaultTitles(vaultDir string) ([]string, error) {
+	if _, err := os.Stat(vaultDir); os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	entries, err := os.ReadDir(vaultDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault directory: %w", err)
+	}
+
+	var titles []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		titles = append(titles, entry.Name())
+	}
+
+	return titles, nil
 }
 
-// GetHistoryDir returns the path to a title's history directory.
-// Example: <vault>/th08/_history
-func GetHistoryDir(title string) (string, error) {
+// GetHistoryDir returns the path to the history directory of a title's vault slot.
+// The default slot keeps the original location; other slots get a subdirectory,
+// which ListBackups of the default slot ignores.
+// Example: <vault>/th08/_history (main), <vault>/th08/_history/scoring
+func GetHistoryDir(title string, slot string) (string, error) {
 	vaultDir, err := GetVaultDir()
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Join(vaultDir, title, HistoryDir), nil
+	if slot == DefaultSlot {
+		return filepath.Join(vaultDir, title, HistoryDir), nil
+	}
+	return filepath.Join(vaultDir, title, HistoryDir, slot), nil
 }
 
 // GetReplayArchiveDir returns the path to a title's replay archive directory.
@@ -111,13 +176,99 @@ func GetSnapshotArchiveDir(title string) (string, error) {
 	return archiveDir, nil
 }
 
-// CreateBackup creates a backup of the specified file in the history directory.
-// Returns the path to the created backup file.
-func CreateBackup(title string, sourceFile string) (string, error) {
-	historyDir, err := GetHistoryDir(title)
+// CreateBackup creates a backup of the specified file in the slot's history directory,
+// unless the newest backup of that file already has the same contents (see CreateBackupIn).
+// Returns the path to the created or existing backup file.
+func CreateBackup(title string, slot string, sourceFile string) (string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return CreateBackupIn(historyDir, sourceFile)
+}
+
+// CreateTaggedBackup is CreateBackup with a tag embedded in the filename after the timestamp.
+// Backups tagged with PinTag are skipped by history cleanup. A new backup is always
+// written, even if it is identical to the newest one; an empty tag gives a plain backup.
+func CreateTaggedBackup(title string, slot string, sourceFile string, tag string) (string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return CreateTaggedBackupIn(historyDir, sourceFile, tag)
+}
+
+// CreateBackupIn creates a backup of the specified file in an explicit history directory.
+// When Compress is set the backup is gzip-compressed and named with CompressedExt.
+// If the newest backup of a file with the same name holds identical contents, nothing
+// is written and that backup is returned instead, so the history only records changes.
+// Returns the path to the created or existing backup file.
+func CreateBackupIn(historyDir string, sourceFile string) (string, error) {
+	existing, err := IdenticalNewestBackupIn(historyDir, sourceFile)
 	if err != nil {
 		return "", err
 	}
+	if existing != "" {
+		return existing, nil
+	}
+
+	return CreateTaggedBackupIn(historyDir, sourceFile, "")
+}
+
+// IdenticalNewestBackup returns the path of the newest backup of sourceFile in a slot's
+// history if its contents equal sourceFile's, or "" if they differ or there is no backup yet.
+func IdenticalNewestBackup(title string, slot string, sourceFile string) (string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return IdenticalNewestBackupIn(historyDir, sourceFile)
+}
+
+// IdenticalNewestBackupIn is IdenticalNewestBackup for an explicit history directory.
+func IdenticalNewestBackupIn(historyDir string, sourceFile string) (string, error) {
+	backups, err := ListBackupsIn(historyDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Names sort newest first; tagged and compressed backups of the file count too
+	suffix := "-" + filepath.Base(sourceFile)
+	for _, name := range backups {
+		if _, ok := parseBackupTime(name); !ok || !strings.HasSuffix(strings.TrimSuffix(name, CompressedExt), suffix) {
+			continue
+		}
+
+		backupPath := filepath.Join(historyDir, name)
+		existingHash, err := backupHash(backupPath)
+		if err != nil {
+			// An unreadable backup does not prevent taking a new one
+			return "", nil
+		}
+		sourceHash, err := utils.CalculateFileHash(sourceFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash source file: %w", err)
+		}
+		if existingHash == sourceHash {
+			return backupPath, nil
+		}
+		return "", nil
+	}
+
+	return "", nil
+}
+
+// CreateTaggedBackupIn is CreateBackupIn with an optional tag (empty for none),
+// named <timestamp>-<tag>-<original name>.
+func CreateTaggedBackupIn(historyDir string, sourceFile string, tag string) (string, error) {
+	defer timing.Start("backup")()
+
+	if tag != "" && !tagPattern.MatchString(tag) {
+		return "", fmt.Errorf("invalid backup tag %q: use letters, digits, '.' or '_' and start with a letter or digit", tag)
+	}
 
 	// Ensure history directory exists
 	if err := utils.EnsureDir(historyDir); err != nil {
@@ -134,13 +285,24 @@ func CreateBackup(title string, sourceFile string) (string, error) {
 	}
 
 	// Generate backup filename with ISO8601 timestamp
-	// Format: 2025-11-11T06-20-30Z-score.dat
-	timestamp := time.Now().UTC().Format("2006-01-02T15-04-05Z")
+	// Format: 2025-11-11T06-20-30Z-score.dat (tagged: 2025-11-11T06-20-30Z-pinned-score.dat)
+	timestamp := time.Now().UTC().Format(backupTimeLayout)
 	sourceBaseName := filepath.Base(sourceFile)
 	backupName := fmt.Sprintf("%s-%s", timestamp, sourceBaseName)
-	backupPath := filepath.Join(historyDir, backupName)
+	if tag != "" {
+		backupName = fmt.Sprintf("%s-%s-%s", timestamp, tag, sourceBaseName)
+	}
 
 	// Copy file to history
+	if Compress {
+		backupPath := filepath.Join(historyDir, backupName+CompressedExt)
+		if err := compressFile(sourceFile, backupPath); err != nil {
+			return "", fmt.Errorf("failed to create backup: %w", err)
+		}
+		return backupPath, nil
+	}
+
+	backupPath := filepath.Join(historyDir, backupName)
 	if err := utils.AtomicCopy(sourceFile, backupPath); err != nil {
 		return "", fmt.Errorf("failed to create backup: %w", err)
 	}
@@ -148,13 +310,18 @@ func CreateBackup(title string, sourceFile string) (string, error) {
 	return backupPath, nil
 }
 
-// ListBackups returns a list of backup files for a title, sorted by timestamp (newest first).
-func ListBackups(title string) ([]string, error) {
-	historyDir, err := GetHistoryDir(title)
+// ListBackups returns a list of backup files for a title's slot, sorted by timestamp (newest first).
+func ListBackups(title string, slot string) ([]string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
 	if err != nil {
 		return nil, err
 	}
 
+	return ListBackupsIn(historyDir)
+}
+
+// ListBackupsIn returns the backup files in an explicit history directory, newest first.
+func ListBackupsIn(historyDir string) ([]string, error) {
 	// Check if history directory exists
 	if _, err := os.Stat(historyDir); os.IsNotExist(err) {
 		return []string{}, nil
@@ -183,65 +350,296 @@ func ListBackups(title string) ([]string, error) {
 	return backups, nil
 }
 
-// RestoreBackup restores a backup file to the vault main directory.
+// RestoreBackup restores a backup from a slot's history to targetFile.
 // backupName should be the filename only (e.g., "2025-11-11T06-20-30Z-score.dat")
-func RestoreBackup(title string, backupName string, targetFile string) error {
-	historyDir, err := GetHistoryDir(title)
+// Returns false without touching anything when the target already matches the backup,
+// unless force is set.
+func RestoreBackup(title string, slot string, backupName string, targetFile string, force bool) (bool, error) {
+	historyDir, err := GetHistoryDir(title, slot)
 	if err != nil {
-		return err
+		return false, err
 	}
 
+	return RestoreBackupIn(historyDir, backupName, targetFile, force)
+}
+
+// RestoreBackupIn restores a backup from an explicit history directory.
+// The current target is backed up into the same history directory first.
+// When the target's hash already equals the backup's hash the restore is a no-op
+// and returns false, so redundant restores do not consume a history slot.
+// Compressed backups are decompressed; hashes always refer to the original contents.
+func RestoreBackupIn(historyDir string, backupName string, targetFile string, force bool) (bool, error) {
 	backupPath := filepath.Join(historyDir, backupName)
 
 	// Check if backup exists
 	exists, readable := utils.FileExists(backupPath)
 	if !exists {
-		return fmt.Errorf("backup file does not exist: %s", backupName)
+		return false, fmt.Errorf("backup file does not exist: %s", backupName)
 	}
 	if !readable {
-		return fmt.Errorf("backup file is not readable: %s", backupName)
+		return false, fmt.Errorf("backup file is not readable: %s", backupName)
+	}
+
+	targetExists, targetReadable := utils.FileExists(targetFile)
+
+	// Skip restores that would not change anything
+	if targetExists && targetReadable && !force {
+		sourceHash, err := backupHash(backupPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash backup: %w", err)
+		}
+		targetHash, err := utils.CalculateFileHash(targetFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to hash current file: %w", err)
+		}
+		if sourceHash == targetHash {
+			return false, nil
+		}
 	}
 
 	// Before restoring, create a backup of the current target file if it exists
-	if targetExists, _ := utils.FileExists(targetFile); targetExists {
-		if _, err := CreateBackup(title, targetFile); err != nil {
-			return fmt.Errorf("failed to backup current file before restore: %w", err)
+	if targetExists {
+		if _, err := CreateBackupIn(historyDir, targetFile); err != nil {
+			return false, fmt.Errorf("failed to backup current file before restore: %w", err)
 		}
 	}
 
 	// Copy backup to target
-	if err := utils.AtomicCopy(backupPath, targetFile); err != nil {
-		return fmt.Errorf("failed to restore backup: %w", err)
+	if err := restoreFile(backupPath, targetFile); err != nil {
+		return false, fmt.Errorf("failed to restore backup: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
 
-// CleanupOldBackups removes old backups beyond the history limit.
-func CleanupOldBackups(title string, limit int) error {
-	backups, err := ListBackups(title)
+// MergeHistoryDir copies backups from another history directory into historyDir.
+// Backups are skipped when a file with the same name or the same hash already exists.
+// Returns the number of merged and skipped backups.
+func MergeHistoryDir(historyDir string, otherHistoryDir string) (merged int, skipped int, err error) {
+	if !utils.DirExists(otherHistoryDir) {
+		return 0, 0, nil
+	}
+
+	otherEntries, err := os.ReadDir(otherHistoryDir)
 	if err != nil {
-		return err
+		return 0, 0, fmt.Errorf("failed to read other history directory: %w", err)
+	}
+
+	if err := utils.EnsureDir(historyDir); err != nil {
+		return 0, 0, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// Index existing backups by hash
+	existingHashes := make(map[string]bool)
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read history directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if hash, err := backupHash(filepath.Join(historyDir, entry.Name())); err == nil {
+			existingHashes[hash] = true
+		}
 	}
 
-	// If we're under the limit, nothing to do
-	if len(backups) <= limit {
-		return nil
+	for _, entry := range otherEntries {
+		if entry.IsDir() {
+			continue
+		}
+
+		srcPath := filepath.Join(otherHistoryDir, entry.Name())
+		destPath := filepath.Join(historyDir, entry.Name())
+
+		// Same filename already present
+		if exists, _ := utils.FileExists(destPath); exists {
+			skipped++
+			continue
+		}
+
+		// Same content already present under a different name
+		hash, err := backupHash(srcPath)
+		if err != nil {
+			return merged, skipped, fmt.Errorf("failed to hash %s: %w", entry.Name(), err)
+		}
+		if existingHashes[hash] {
+			skipped++
+			continue
+		}
+
+		if err := utils.AtomicCopy(srcPath, destPath); err != nil {
+			return merged, skipped, fmt.Errorf("failed to merge backup %s: %w", entry.Name(), err)
+		}
+		existingHashes[hash] = true
+		merged++
 	}
 
-	historyDir, err := GetHistoryDir(title)
+	return merged, skipped, nil
+}
+
+// PruneResult lists the backups removed by PruneHistory and the space they took.
+type PruneResult struct {
+	Removed    []string
+	FreedBytes int64
+}
+
+// PruneHistory applies a history limit and maximum age to a slot's backups.
+// See PruneHistoryIn.
+func PruneHistory(title string, slot string, limit int, maxAge time.Duration) (PruneResult, error) {
+	defer timing.Start("cleanup")()
+
+	historyDir, err := GetHistoryDir(title, slot)
 	if err != nil {
-		return err
+		return PruneResult{}, err
 	}
 
-	// Remove backups beyond the limit
-	for i := limit; i < len(backups); i++ {
-		backupPath := filepath.Join(historyDir, backups[i])
-		if err := os.Remove(backupPath); err != nil {
-			return fmt.Errorf("failed to remove old backup %s: %w", backups[i], err)
+	return PruneHistoryIn(historyDir, limit, maxAge, time.Now())
+}
+
+// PruneHistoryIn removes the backups in an explicit history directory beyond limit and
+// those whose filename timestamp is more than maxAge before now; zero disables either.
+// Pinned backups are never removed and do not count towards the limit, and the most
+// recent backup is always kept, so pruning never empties a slot's history. Running it
+// again removes nothing more.
+func PruneHistoryIn(historyDir string, limit int, maxAge time.Duration, now time.Time) (PruneResult, error) {
+	var result PruneResult
+
+	backups, err := ListBackupsIn(historyDir)
+	if err != nil {
+		return result, err
+	}
+
+	// Names sort newest first. Files without a timestamp are not backups and are left alone.
+	cutoff := now.UTC().Add(-maxAge)
+	seen, kept := 0, 0
+	for _, name := range backups {
+		t, ok := parseBackupTime(name)
+		if !ok {
+			continue
+		}
+		seen++
+		if IsPinned(name) {
+			continue
+		}
+		kept++
+
+		expired := maxAge > 0 && t.Before(cutoff)
+		overLimit := limit > 0 && kept > limit
+		// The most recent backup is kept even when it is expired
+		if seen == 1 || (!overLimit && !expired) {
+			continue
+		}
+
+		path := filepath.Join(historyDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return result, fmt.Errorf("failed to stat old backup %s: %w", name, err)
 		}
+		if err := os.Remove(path); err != nil {
+			return result, fmt.Errorf("failed to remove old backup %s: %w", name, err)
+		}
+		result.Removed = append(result.Removed, name)
+		result.FreedBytes += info.Size()
 	}
 
+	return result, nil
+}
+
+// parseBackupTime extracts the timestamp from a backup filename
+// (format: 2025-11-11T06-20-30Z-score.dat).
+func parseBackupTime(name string) (time.Time, bool) {
+	if len(name) < len(backupTimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupTimeLayout, name[:len(backupTimeLayout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// pinPrefix follows the timestamp in the name of a pinned backup.
+const pinPrefix = "-" + PinTag + "-"
+
+// IsPinned reports whether a backup filename carries the pin marker.
+func IsPinned(name string) bool {
+	if _, ok := parseBackupTime(name); !ok {
+		return false
+	}
+	return strings.HasPrefix(name[len(backupTimeLayout):], pinPrefix)
+}
+
+// PinBackup renames a backup in a slot's history so that cleanup keeps it.
+// Returns the new backup name.
+func PinBackup(title string, slot string, backupName string) (string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return PinBackupIn(historyDir, backupName)
+}
+
+// UnpinBackup removes the pin marker from a backup in a slot's history.
+// Returns the new backup name.
+func UnpinBackup(title string, slot string, backupName string) (string, error) {
+	historyDir, err := GetHistoryDir(title, slot)
+	if err != nil {
+		return "", err
+	}
+
+	return UnpinBackupIn(historyDir, backupName)
+}
+
+// PinBackupIn inserts the pin marker after the timestamp of a backup in an explicit
+// history directory: 2025-11-11T06-20-30Z-score.dat becomes 2025-11-11T06-20-30Z-pinned-score.dat.
+func PinBackupIn(historyDir string, backupName string) (string, error) {
+	if IsPinned(backupName) {
+		return "", fmt.Errorf("backup is already pinned: %s", backupName)
+	}
+	if _, ok := parseBackupTime(backupName); !ok {
+		return "", fmt.Errorf("not a backup file name: %s", backupName)
+	}
+
+	pinned := backupName[:len(backupTimeLayout)] + "-" + PinTag + backupName[len(backupTimeLayout):]
+	if err := renameBackup(historyDir, backupName, pinned); err != nil {
+		return "", err
+	}
+	return pinned, nil
+}
+
+// UnpinBackupIn removes the pin marker from a backup in an explicit history directory.
+func UnpinBackupIn(historyDir string, backupName string) (string, error) {
+	if !IsPinned(backupName) {
+		return "", fmt.Errorf("backup is not pinned: %s", backupName)
+	}
+
+	unpinned := backupName[:len(backupTimeLayout)] + backupName[len(backupTimeLayout)+len(pinPrefix)-1:]
+	if err := renameBackup(historyDir, backupName, unpinned); err != nil {
+		return "", err
+	}
+	return unpinned, nil
+}
+
+// renameBackup renames a backup within historyDir without replacing an existing file.
+func renameBackup(historyDir, from, to string) error {
+	if filepath.Base(from) != from {
+		return fmt.Errorf("invalid backup name: %s", from)
+	}
+
+	src := filepath.Join(historyDir, from)
+	dest := filepath.Join(historyDir, to)
+	if exists, _ := utils.FileExists(src); !exists {
+		return fmt.Errorf("backup file does not exist: %s", from)
+	}
+	if exists, _ := utils.FileExists(dest); exists {
+		return fmt.Errorf("backup file already exists: %s", to)
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		return fmt.Errorf("failed to rename backup %s: %w", from, err)
+	}
 	return nil
 }
 
@@ -250,18 +648,25 @@ type BackupInfo struct {
 	Name      string
 	Path      string
 	Timestamp time.Time
-	Size      int64
+	Size      int64 // Original size; compressed backups report their uncompressed size
 	Error     error
 }
 
-// GetBackupDetails returns detailed information about backups.
-func GetBackupDetails(title string) ([]BackupInfo, error) {
-	backups, err := ListBackups(title)
+// GetBackupDetails returns detailed information about a slot's backups.
+func GetBackupDetails(title string, slot string) ([]BackupInfo, error) {
+	historyDir, err := GetHistoryDir(title, slot)
 	if err != nil {
 		return nil, err
 	}
 
-	historyDir, err := GetHistoryDir(title)
+	return GetBackupDetailsIn(historyDir)
+}
+
+// GetBackupDetailsIn returns detailed information about the backups in an explicit history directory.
+// The timestamp is read from the fixed-length prefix written by CreateBackupIn, so hyphens
+// in the original filename do not affect it.
+func GetBackupDetailsIn(historyDir string) ([]BackupInfo, error) {
+	backups, err := ListBackupsIn(historyDir)
 	if err != nil {
 		return nil, err
 	}
@@ -275,20 +680,13 @@ func GetBackupDetails(title string) ([]BackupInfo, error) {
 			Path: backupPath,
 		}
 
-		// Parse timestamp from filename (format: 2025-11-11T06-20-30Z-score.dat)
-		parts := strings.Split(backup, "-")
-		if len(parts) >= 6 {
-			// Reconstruct timestamp string
-			timestampStr := strings.Join(parts[:6], "-")
-			timestampStr = strings.Replace(timestampStr, "-", ":", 2) // Fix time colons
-			if t, err := time.Parse("2006-01-02T15:04:05Z", timestampStr); err == nil {
-				info.Timestamp = t
-			}
+		if t, ok := parseBackupTime(backup); ok {
+			info.Timestamp = t
 		}
 
-		// Get file size
-		if stat, err := os.Stat(backupPath); err == nil {
-			info.Size = stat.Size()
+		// Get the original size, decompressing if necessary
+		if size, err := backupSize(backupPath); err == nil {
+			info.Size = size
 		} else {
 			info.Error = err
 		}
@@ -298,3 +696,25 @@ func GetBackupDetails(title string) ([]BackupInfo, error) {
 
 	return details, nil
 }
+
+// Summary totals the backups of one title's vault slot.
+type Summary struct {
+	Title     string
+	Count     int
+	Newest    time.Time // zero when no backup has a readable timestamp
+	DiskBytes int64     // on-disk size, so compressed backups count as stored
+}
+
+// Summarize totals the backups listed by GetBackupDetails.
+func Summarize(title string, details []BackupInfo) Summary {
+	summary := Summary{Title: title, Count: len(details)}
+	for _, detail := range details {
+		if detail.Timestamp.After(summary.Newest) {
+			summary.Newest = detail.Timestamp
+		}
+		if info, err := os.Stat(detail.Path); err == nil {
+			summary.DiskBytes += info.Size()
+		}
+	}
+	return summary
+}
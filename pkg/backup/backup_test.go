@@ -0,0 +1,498 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupHistory creates a history directory containing one backup and a target file.
+func setupHistory(t *testing.T, backupData, targetData string) (historyDir, backupName, targetFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	historyDir = filepath.Join(dir, HistoryDir)
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	backupName = "2025-11-11T06-20-30Z-score.dat"
+	if err := os.WriteFile(filepath.Join(historyDir, backupName), []byte(backupData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The backup keeps the mtime of the file it was taken from
+	backupTime := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(historyDir, backupName), backupTime, backupTime); err != nil {
+		t.Fatal(err)
+	}
+
+	targetFile = filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(targetFile, []byte(targetData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return historyDir, backupName, targetFile
+}
+
+func countEntries(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestRestoreBackupIn(t *testing.T) {
+	tests := []struct {
+		name             string
+		backupData       string
+		targetData       string
+		force            bool
+		expectedRestored bool
+		expectedHistory  int
+	}{
+		{
+			name:             "Identical target - no-op, no new backup",
+			backupData:       "same",
+			targetData:       "same",
+			expectedRestored: false,
+			expectedHistory:  1,
+		},
+		{
+			name:             "Identical target with force - restores, identical backup not repeated",
+			backupData:       "same",
+			targetData:       "same",
+			force:            true,
+			expectedRestored: true,
+			expectedHistory:  1,
+		},
+		{
+			name:             "Different target - restores and backs up",
+			backupData:       "old",
+			targetData:       "new",
+			expectedRestored: true,
+			expectedHistory:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyDir, backupName, targetFile := setupHistory(t, tt.backupData, tt.targetData)
+
+			restored, err := RestoreBackupIn(historyDir, backupName, targetFile, tt.force)
+			if err != nil {
+				t.Fatalf("RestoreBackupIn failed: %v", err)
+			}
+
+			if restored != tt.expectedRestored {
+				t.Errorf("Expected restored=%v, got %v", tt.expectedRestored, restored)
+			}
+			if got := countEntries(t, historyDir); got != tt.expectedHistory {
+				t.Errorf("Expected %d history entries, got %d", tt.expectedHistory, got)
+			}
+
+			data, err := os.ReadFile(targetFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.backupData {
+				t.Errorf("Expected target %q, got %q", tt.backupData, string(data))
+			}
+
+			// A restore is a new write and must not keep the backup's older mtime
+			if tt.expectedRestored {
+				info, err := os.Stat(targetFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if time.Since(info.ModTime()) > time.Minute {
+					t.Errorf("Expected restored file to get the current mtime, got %v", info.ModTime())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSlot(t *testing.T) {
+	valid := []string{"main", "scoring", "1cc-practice", "v2.0"}
+	for _, slot := range valid {
+		if err := ValidateSlot(slot); err != nil {
+			t.Errorf("ValidateSlot(%q) returned error: %v", slot, err)
+		}
+	}
+
+	invalid := []string{"", "_history", "_quarantine", "../main", "a/b", `a\b`, ".hidden", "replay", "replay_archive"}
+	for _, slot := range invalid {
+		if err := ValidateSlot(slot); err == nil {
+			t.Errorf("ValidateSlot(%q) should return error", slot)
+		}
+	}
+}
+
+func TestGetHistoryDir_PerSlot(t *testing.T) {
+	mainDir, err := GetHistoryDir("th08", DefaultSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(mainDir) != HistoryDir {
+		t.Errorf("Expected default slot history at .../%s, got %s", HistoryDir, mainDir)
+	}
+
+	slotDir, err := GetHistoryDir("th08", "scoring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slotDir != filepath.Join(mainDir, "scoring") {
+		t.Errorf("Expected slot history under %s, got %s", mainDir, slotDir)
+	}
+}
+
+func TestCreateBackupIn_SkipsIdentical(t *testing.T) {
+	dir := t.TempDir()
+	historyDir := filepath.Join(dir, HistoryDir)
+	source := filepath.Join(dir, "score.dat")
+	config := filepath.Join(dir, "game.cfg")
+	for path, data := range map[string]string{source: "v1", config: "v1"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := CreateBackupIn(historyDir, source)
+	if err != nil {
+		t.Fatalf("CreateBackupIn failed: %v", err)
+	}
+
+	// Unchanged contents return the newest backup instead of a new one
+	again, err := CreateBackupIn(historyDir, source)
+	if err != nil {
+		t.Fatalf("CreateBackupIn failed: %v", err)
+	}
+	if again != first {
+		t.Errorf("Expected the existing backup %s, got %s", first, again)
+	}
+
+	// A different file with the same contents gets its own backup
+	if _, err := CreateBackupIn(historyDir, config); err != nil {
+		t.Fatalf("CreateBackupIn failed: %v", err)
+	}
+	if got := countEntries(t, historyDir); got != 2 {
+		t.Errorf("Expected 2 backups, got %d", got)
+	}
+
+	// A tagged backup is always written
+	if _, err := CreateTaggedBackupIn(historyDir, source, "manual"); err != nil {
+		t.Fatalf("CreateTaggedBackupIn failed: %v", err)
+	}
+	if got := countEntries(t, historyDir); got != 3 {
+		t.Errorf("Expected 3 backups, got %d", got)
+	}
+
+	// Changed contents are backed up again
+	if err := os.WriteFile(source, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := CreateBackupIn(historyDir, source)
+	if err != nil {
+		t.Fatalf("CreateBackupIn failed: %v", err)
+	}
+	if data, _ := os.ReadFile(changed); string(data) != "v2" {
+		t.Errorf("Expected a backup of the changed contents, got %q", data)
+	}
+}
+
+func TestPruneHistoryIn_MissingDir(t *testing.T) {
+	if _, err := PruneHistoryIn(filepath.Join(t.TempDir(), "missing"), 1, time.Hour, time.Now()); err != nil {
+		t.Errorf("Expected no error for missing history, got %v", err)
+	}
+}
+
+func TestPruneHistoryIn(t *testing.T) {
+	now := time.Date(2025, 4, 11, 0, 0, 0, 0, time.UTC)
+	names := []string{
+		"2025-04-10T00-00-00Z-score.dat",        // newest
+		"2025-04-05T00-00-00Z-score.dat",        // 6 days old
+		"2025-03-01T00-00-00Z-pinned-score.dat", // pinned, 41 days old
+		"2025-02-01T00-00-00Z-score.dat",        // 69 days old
+		"2025-01-01T00-00-00Z-score.dat",        // 100 days old
+		"notes.txt",                             // no timestamp
+	}
+
+	tests := []struct {
+		name        string
+		files       []string
+		limit       int
+		maxAge      time.Duration
+		wantRemoved []string
+	}{
+		{
+			name:        "Limit keeps newest unpinned",
+			files:       names,
+			limit:       2,
+			wantRemoved: []string{names[3], names[4]},
+		},
+		{
+			name:        "Age removes expired unpinned",
+			files:       names,
+			maxAge:      30 * 24 * time.Hour,
+			wantRemoved: []string{names[3], names[4]},
+		},
+		{
+			name:        "Limit and age combined",
+			files:       names,
+			limit:       3,
+			maxAge:      90 * 24 * time.Hour,
+			wantRemoved: []string{names[4]},
+		},
+		{
+			name:        "Newest kept when everything expired",
+			files:       names,
+			maxAge:      time.Hour,
+			wantRemoved: []string{names[1], names[3], names[4]},
+		},
+		{
+			name:        "No limits",
+			files:       names,
+			wantRemoved: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyDir := t.TempDir()
+			for _, name := range tt.files {
+				if err := os.WriteFile(filepath.Join(historyDir, name), []byte(name), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := PruneHistoryIn(historyDir, tt.limit, tt.maxAge, now)
+			if err != nil {
+				t.Fatalf("PruneHistoryIn failed: %v", err)
+			}
+			if !reflect.DeepEqual(result.Removed, tt.wantRemoved) {
+				t.Errorf("Removed = %v, want %v", result.Removed, tt.wantRemoved)
+			}
+			var wantFreed int64
+			for _, name := range tt.wantRemoved {
+				wantFreed += int64(len(name))
+			}
+			if result.FreedBytes != wantFreed {
+				t.Errorf("FreedBytes = %d, want %d", result.FreedBytes, wantFreed)
+			}
+
+			// Pruning again removes nothing more
+			again, err := PruneHistoryIn(historyDir, tt.limit, tt.maxAge, now)
+			if err != nil {
+				t.Fatalf("second PruneHistoryIn failed: %v", err)
+			}
+			if len(again.Removed) != 0 {
+				t.Errorf("Expected second run to remove nothing, removed %v", again.Removed)
+			}
+
+			remaining, err := ListBackupsIn(historyDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := len(tt.files) - len(tt.wantRemoved); len(remaining) != want {
+				t.Errorf("Expected %d files left, got %v", want, remaining)
+			}
+		})
+	}
+}
+
+func TestPruneHistoryIn_OnlyBackupExpired(t *testing.T) {
+	historyDir := t.TempDir()
+	name := "2020-01-01T00-00-00Z-score.dat"
+	if err := os.WriteFile(filepath.Join(historyDir, name), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PruneHistoryIn(historyDir, 1, time.Hour, time.Now())
+	if err != nil {
+		t.Fatalf("PruneHistoryIn failed: %v", err)
+	}
+	if len(result.Removed) != 0 {
+		t.Errorf("Expected the only backup to be kept, removed %v", result.Removed)
+	}
+}
+
+func TestParseBackupTime(t *testing.T) {
+	got, ok := parseBackupTime("2025-11-11T06-20-30Z-score.dat")
+	if !ok {
+		t.Fatal("Expected timestamp to parse")
+	}
+	if want := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, ok := parseBackupTime("score.dat"); ok {
+		t.Error("Expected filename without timestamp to be rejected")
+	}
+}
+
+func TestGetBackupDetailsIn_HyphenatedNames(t *testing.T) {
+	historyDir := t.TempDir()
+	names := map[string]time.Time{
+		"2025-11-11T06-20-30Z-my-score-backup.dat": time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC),
+		"2025-11-12T23-59-59Z-score-th08.dat":      time.Date(2025, 11, 12, 23, 59, 59, 0, time.UTC),
+		"2025-11-13T00-00-00Z-score.dat":           time.Date(2025, 11, 13, 0, 0, 0, 0, time.UTC),
+	}
+	for name := range names {
+		if err := os.WriteFile(filepath.Join(historyDir, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	details, err := GetBackupDetailsIn(historyDir)
+	if err != nil {
+		t.Fatalf("GetBackupDetailsIn failed: %v", err)
+	}
+	if len(details) != len(names) {
+		t.Fatalf("Expected %d backups, got %d", len(names), len(details))
+	}
+
+	for _, detail := range details {
+		want := names[detail.Name]
+		if !detail.Timestamp.Equal(want) {
+			t.Errorf("%s: expected timestamp %v, got %v", detail.Name, want, detail.Timestamp)
+		}
+		if detail.Size != 4 {
+			t.Errorf("%s: expected size 4, got %d", detail.Name, detail.Size)
+		}
+	}
+}
+
+func TestParseBackupTime_HyphenatedSource(t *testing.T) {
+	got, ok := parseBackupTime("2025-01-02T03-04-05Z-my-score-backup.dat")
+	if !ok {
+		t.Fatal("Expected timestamp to parse")
+	}
+	if want := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// A name that only looks like a timestamp is rejected
+	if _, ok := parseBackupTime("my-score-backup-2025-01-02.dat"); ok {
+		t.Error("Expected non-prefixed name to be rejected")
+	}
+}
+
+func TestPinBackupIn_SurvivesCleanup(t *testing.T) {
+	historyDir := t.TempDir()
+	names := []string{
+		"2025-11-13T06-20-30Z-score.dat",
+		"2025-11-12T06-20-30Z-score.dat",
+		"2025-11-11T06-20-30Z-score.dat",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(historyDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pinned, err := PinBackupIn(historyDir, names[2])
+	if err != nil {
+		t.Fatalf("PinBackupIn failed: %v", err)
+	}
+	if pinned != "2025-11-11T06-20-30Z-pinned-score.dat" || !IsPinned(pinned) {
+		t.Fatalf("Unexpected pinned name: %s", pinned)
+	}
+	if _, err := PinBackupIn(historyDir, pinned); err == nil {
+		t.Error("Expected error when pinning twice")
+	}
+
+	// The pinned oldest backup neither counts towards the limit nor gets removed
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := PruneHistoryIn(historyDir, 1, 0, now); err != nil {
+		t.Fatalf("PruneHistoryIn failed: %v", err)
+	}
+	backups, err := ListBackupsIn(historyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0] != names[0] || backups[1] != pinned {
+		t.Errorf("Expected newest and pinned backups to remain, got %v", backups)
+	}
+
+	// Nor is it removed by age
+	if _, err := PruneHistoryIn(historyDir, 0, 24*time.Hour, now); err != nil {
+		t.Fatalf("PruneHistoryIn failed: %v", err)
+	}
+	if backups, _ := ListBackupsIn(historyDir); len(backups) != 2 || backups[1] != pinned {
+		t.Errorf("Expected the pinned backup to remain, got %v", backups)
+	}
+
+	unpinned, err := UnpinBackupIn(historyDir, pinned)
+	if err != nil {
+		t.Fatalf("UnpinBackupIn failed: %v", err)
+	}
+	if unpinned != names[2] || IsPinned(unpinned) {
+		t.Errorf("Unexpected unpinned name: %s", unpinned)
+	}
+	if info, err := GetBackupDetailsIn(historyDir); err != nil || len(info) != 2 || info[1].Timestamp.IsZero() {
+		t.Errorf("Expected unpinned backup with timestamp, got %+v (%v)", info, err)
+	}
+}
+
+func TestCreateTaggedBackupIn(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(source, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	historyDir := filepath.Join(dir, HistoryDir)
+
+	path, err := CreateTaggedBackupIn(historyDir, source, PinTag)
+	if err != nil {
+		t.Fatalf("CreateTaggedBackupIn failed: %v", err)
+	}
+	name := filepath.Base(path)
+	if !IsPinned(name) || !strings.HasSuffix(name, "-pinned-score.dat") {
+		t.Errorf("Expected pinned backup name, got %s", name)
+	}
+	if _, ok := parseBackupTime(name); !ok {
+		t.Errorf("Expected timestamp in %s", name)
+	}
+
+	if _, err := CreateTaggedBackupIn(historyDir, source, "bad-tag"); err == nil {
+		t.Error("Expected error for a tag containing '-'")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	dir := t.TempDir()
+	older := time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	details := []BackupInfo{
+		{Name: "a", Path: write("a", 100), Timestamp: newer, Size: 100},
+		{Name: "b.gz", Path: write("b.gz", 30), Timestamp: older, Size: 100}, // compressed: disk size counts
+		{Name: "c", Path: filepath.Join(dir, "missing")},                     // no timestamp, unreadable
+	}
+
+	summary := Summarize("th08", details)
+	if summary.Title != "th08" || summary.Count != 3 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if !summary.Newest.Equal(newer) {
+		t.Errorf("Expected newest %v, got %v", newer, summary.Newest)
+	}
+	if summary.DiskBytes != 130 {
+		t.Errorf("Expected 130 bytes on disk, got %d", summary.DiskBytes)
+	}
+
+	if empty := Summarize("th06", nil); empty.Count != 0 || !empty.Newest.IsZero() || empty.DiskBytes != 0 {
+		t.Errorf("Expected empty summary, got %+v", empty)
+	}
+}
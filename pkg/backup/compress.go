@@ -0,0 +1,218 @@
+package backup
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
+)
+
+// CompressedExt is appended to the name of gzip-compressed backups.
+const CompressedExt = ".gz"
+
+// Compress makes CreateBackup write gzip-compressed backups (<timestamp>-<name>.gz).
+// Set from rules.json compress_backups. Existing backups are read either way.
+var Compress bool
+
+// isCompressed reports whether a backup file name refers to a gzip-compressed backup.
+func isCompressed(name string) bool {
+	return strings.HasSuffix(name, CompressedExt)
+}
+
+// backupReader reads a backup's original contents and closes the underlying file.
+type backupReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (b *backupReader) Close() error {
+	return b.file.Close()
+}
+
+// openBackup opens a backup for reading its original contents, decrypting encrypted
+// backups (see vaultcrypt) and decompressing gzip-compressed ones on the fly.
+func openBackup(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	contents, _, err := vaultcrypt.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if !isCompressed(path) {
+		return &backupReader{Reader: contents, file: file}, nil
+	}
+
+	zr, err := gzip.NewReader(contents)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read compressed backup: %w", err)
+	}
+	return &backupReader{Reader: zr, file: file}, nil
+}
+
+// HashBackup returns the SHA256 of a backup's original (uncompressed) contents.
+func HashBackup(path string) (string, error) {
+	return backupHash(path)
+}
+
+// backupHash returns the SHA256 of a backup's original (uncompressed) contents.
+func backupHash(path string) (string, error) {
+	r, err := openBackup(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	return utils.CalculateReaderHash(r)
+}
+
+// backupSize returns the original (uncompressed, decrypted) size of a backup.
+func backupSize(path string) (int64, error) {
+	if !isCompressed(path) {
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := vaultcrypt.Sniff(file)
+		if err != nil {
+			return 0, err
+		}
+		return vaultcrypt.ContentSize(info.Size(), encrypted), nil
+	}
+
+	r, err := openBackup(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read compressed backup: %w", err)
+	}
+	return n, nil
+}
+
+// compressFile writes a gzip-compressed copy of src to dest atomically.
+func compressFile(src, dest string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer srcFile.Close()
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat source file: %w", err)
+	}
+	contents, _, err := vaultcrypt.NewReader(srcFile, srcInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	return atomicWrite(dest, srcInfo.Mode(), func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		zw.Name = filepath.Base(src)
+		if _, err := io.Copy(zw, contents); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// restoreFile copies a backup's original contents to dest atomically,
+// decompressing gzip-compressed backups. dest gets the current time as its mtime.
+func restoreFile(backupPath, dest string) error {
+	if !isCompressed(backupPath) {
+		if err := utils.AtomicCopy(backupPath, dest); err != nil {
+			return err
+		}
+		return touchNow(dest)
+	}
+
+	r, err := openBackup(backupPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return atomicWrite(dest, 0644, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// touchNow sets a file's modification time to now. A restore is a deliberate new write:
+// keeping the old version's mtime would make it look older than the copies it is meant
+// to replace, and the next pull would undo it.
+func touchNow(path string) error {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
+	}
+	return nil
+}
+
+// atomicWrite writes dest through a temporary file in the same directory,
+// renaming it into place with permissions perm only after write succeeds.
+// What is written to a dest in an encrypted vault is encrypted (see vaultcrypt).
+func atomicWrite(dest string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(dest), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+
+	// Clean up temp file on error
+	defer func() {
+		if err != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if vaultcrypt.Covers(dest) {
+		encrypter := vaultcrypt.NewWriter(tmpFile)
+		if err = write(encrypter); err == nil {
+			err = encrypter.Close()
+		}
+	} else {
+		err = write(tmpFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write data: %w", err)
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err = os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err = utils.SafeRename(tmpPath, dest); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressedBackupRoundTrip(t *testing.T) {
+	Compress = true
+	defer func() { Compress = false }()
+
+	dir := t.TempDir()
+	historyDir := filepath.Join(dir, HistoryDir)
+	original := strings.Repeat("score data ", 100)
+	sourceFile := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(sourceFile, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, err := CreateBackupIn(historyDir, sourceFile)
+	if err != nil {
+		t.Fatalf("CreateBackupIn failed: %v", err)
+	}
+	if !strings.HasSuffix(backupPath, "-score.dat"+CompressedExt) {
+		t.Errorf("Expected compressed backup name, got %s", filepath.Base(backupPath))
+	}
+	if stat, _ := os.Stat(backupPath); stat.Size() >= int64(len(original)) {
+		t.Errorf("Expected compressed size below %d, got %d", len(original), stat.Size())
+	}
+
+	// Details report the uncompressed size
+	details, err := GetBackupDetailsIn(historyDir)
+	if err != nil {
+		t.Fatalf("GetBackupDetailsIn failed: %v", err)
+	}
+	if len(details) != 1 || details[0].Size != int64(len(original)) {
+		t.Fatalf("Expected one backup of size %d, got %+v", len(original), details)
+	}
+	if details[0].Timestamp.IsZero() {
+		t.Error("Expected timestamp to be parsed from compressed backup name")
+	}
+
+	// Restoring over identical contents is a no-op
+	restored, err := RestoreBackupIn(historyDir, filepath.Base(backupPath), sourceFile, false)
+	if err != nil {
+		t.Fatalf("RestoreBackupIn failed: %v", err)
+	}
+	if restored {
+		t.Error("Expected no restore when contents already match")
+	}
+
+	// Restoring over changed contents decompresses the original
+	// (backdated so the pre-restore backup taken in the same second gets a different name)
+	oldName := "2025-01-01T00-00-00Z-score.dat" + CompressedExt
+	if err := os.Rename(backupPath, filepath.Join(historyDir, oldName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sourceFile, []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = RestoreBackupIn(historyDir, oldName, sourceFile, false)
+	if err != nil {
+		t.Fatalf("RestoreBackupIn failed: %v", err)
+	}
+	if !restored {
+		t.Fatal("Expected restore to happen")
+	}
+	data, err := os.ReadFile(sourceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Error("Expected restored file to match the original contents")
+	}
+}
+
+func TestMixedBackups(t *testing.T) {
+	dir := t.TempDir()
+	historyDir := filepath.Join(dir, HistoryDir)
+	sourceFile := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(sourceFile, []byte("plain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateBackupIn(historyDir, sourceFile); err != nil {
+		t.Fatal(err)
+	}
+
+	Compress = true
+	defer func() { Compress = false }()
+	compressedPath := filepath.Join(historyDir, "2099-01-01T00-00-00Z-score.dat"+CompressedExt)
+	if err := compressFile(sourceFile, compressedPath); err != nil {
+		t.Fatal(err)
+	}
+
+	details, err := GetBackupDetailsIn(historyDir)
+	if err != nil {
+		t.Fatalf("GetBackupDetailsIn failed: %v", err)
+	}
+	if len(details) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(details))
+	}
+	for _, detail := range details {
+		if detail.Size != 5 {
+			t.Errorf("%s: expected size 5, got %d", detail.Name, detail.Size)
+		}
+	}
+
+	// Both forms hash to the same original contents
+	plainHash, err := backupHash(details[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressedHash, err := backupHash(details[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plainHash != compressedHash {
+		t.Error("Expected compressed and plain backups of the same file to hash equally")
+	}
+}
@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// GetQuarantineDir returns the path to a title's quarantine directory.
+// Example: <vault>/th08/_quarantine
+func GetQuarantineDir(title string) (string, error) {
+	vaultDir, err := GetVaultDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(vaultDir, title, QuarantineDir), nil
+}
+
+// QuarantineFile copies a suspicious file into the title's quarantine directory.
+// Returns the path to the quarantined copy.
+func QuarantineFile(title string, sourceFile string) (string, error) {
+	quarantineDir, err := GetQuarantineDir(title)
+	if err != nil {
+		return "", err
+	}
+
+	return QuarantineFileIn(quarantineDir, sourceFile)
+}
+
+// QuarantineFileIn copies a suspicious file into an explicit quarantine directory.
+// The copy is named like history backups: 2025-11-11T06-20-30Z-score.dat
+func QuarantineFileIn(quarantineDir string, sourceFile string) (string, error) {
+	if err := utils.EnsureDir(quarantineDir); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	exists, readable := utils.FileExists(sourceFile)
+	if !exists {
+		return "", fmt.Errorf("source file does not exist: %s", sourceFile)
+	}
+	if !readable {
+		return "", fmt.Errorf("source file is not readable: %s", sourceFile)
+	}
+
+	timestamp := time.Now().UTC().Format(backupTimeLayout)
+	quarantineName := fmt.Sprintf("%s-%s", timestamp, filepath.Base(sourceFile))
+	quarantinePath := filepath.Join(quarantineDir, quarantineName)
+
+	if err := utils.AtomicCopy(sourceFile, quarantinePath); err != nil {
+		return "", fmt.Errorf("failed to quarantine file: %w", err)
+	}
+
+	return quarantinePath, nil
+}
+
+// ListQuarantine returns the quarantined files for a title with their details.
+func ListQuarantine(title string) ([]BackupInfo, error) {
+	quarantineDir, err := GetQuarantineDir(title)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.DirExists(quarantineDir) {
+		return []BackupInfo{}, nil
+	}
+
+	entries, err := os.ReadDir(quarantineDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantine directory: %w", err)
+	}
+
+	var infos []BackupInfo
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		info := BackupInfo{
+			Name: entry.Name(),
+			Path: filepath.Join(quarantineDir, entry.Name()),
+		}
+		if stat, err := entry.Info(); err == nil {
+			info.Size = stat.Size()
+			info.Timestamp = stat.ModTime()
+		} else {
+			info.Error = err
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// PromoteQuarantine replaces targetFile with a quarantined file and removes it from quarantine.
+// The current target is backed up into the history of the given vault slot first.
+func PromoteQuarantine(title string, slot string, name string, targetFile string) error {
+	quarantineDir, err := GetQuarantineDir(title)
+	if err != nil {
+		return err
+	}
+
+	quarantinePath := filepath.Join(quarantineDir, name)
+	if exists, _ := utils.FileExists(quarantinePath); !exists {
+		return fmt.Errorf("quarantined file does not exist: %s", name)
+	}
+
+	if targetExists, _ := utils.FileExists(targetFile); targetExists {
+		if _, err := CreateBackup(title, slot, targetFile); err != nil {
+			return fmt.Errorf("failed to backup current file before promote: %w", err)
+		}
+	}
+
+	if err := utils.EnsureDir(filepath.Dir(targetFile)); err != nil {
+		return fmt.Errorf("failed to create target directory: %w", err)
+	}
+
+	if err := utils.AtomicCopy(quarantinePath, targetFile); err != nil {
+		return fmt.Errorf("failed to promote quarantined file: %w", err)
+	}
+	if err := touchNow(targetFile); err != nil {
+		return err
+	}
+
+	if err := os.Remove(quarantinePath); err != nil {
+		return fmt.Errorf("failed to remove promoted file from quarantine: %w", err)
+	}
+
+	return nil
+}
+
+// DiscardQuarantine deletes a quarantined file.
+func DiscardQuarantine(title string, name string) error {
+	quarantineDir, err := GetQuarantineDir(title)
+	if err != nil {
+		return err
+	}
+
+	quarantinePath := filepath.Join(quarantineDir, name)
+	if exists, _ := utils.FileExists(quarantinePath); !exists {
+		return fmt.Errorf("quarantined file does not exist: %s", name)
+	}
+
+	if err := os.Remove(quarantinePath); err != nil {
+		return fmt.Errorf("failed to discard quarantined file: %w", err)
+	}
+
+	return nil
+}
@@ -6,15 +6,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
 const (
-	// ConfigDir is the relative path to the config directory from the executable
-	ConfigDir = "data"
+	// ConfigDir is the relative path to the config directory from the home directory
+	ConfigDir = paths.ConfigDirName
 
 	// DevicesFile is the filename for device configuration
 	DevicesFile = "devices.json"
@@ -24,29 +29,43 @@ const (
 
 	// RulesFile is the filename for sync rules
 	RulesFile = "rules.json"
-)
 
-// GetConfigDir returns the absolute path to the config directory.
-// It assumes the config directory is relative to the executable location.
-func GetConfigDir() (string, error) {
-	// Get executable path
-	exePath, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("failed to get executable path: %w", err)
-	}
+	// TitlesFile is the filename for user-defined titles (optional)
+	TitlesFile = "titles.json"
+
+	// HashCacheFile is the filename for cached file hashes
+	HashCacheFile = "hashcache.json"
+
+	// DefaultSizeRatioThreshold is the size ratio (larger/smaller) above which a change is flagged as suspicious
+	DefaultSizeRatioThreshold = 2.0
 
-	// Get directory containing executable
-	exeDir := filepath.Dir(exePath)
+	// DefaultDriftToleranceSeconds is the mtime difference (in seconds) within which two files count as equally new
+	DefaultDriftToleranceSeconds = utils.DefaultTimeDriftTolerance
 
-	// Config directory is <exe_dir>/data
-	configDir := filepath.Join(exeDir, ConfigDir)
+	// DefaultLogRetentionDays is how many days of log files are kept
+	DefaultLogRetentionDays = logger.DefaultRetentionDays
 
-	return configDir, nil
+	// DefaultLogMaxSizeKB is the size (in KB) after which a day's log continues in a new file
+	DefaultLogMaxSizeKB = logger.DefaultMaxFileSize / 1024
+
+	// DefaultRetryAttempts is how many times pull/push try a file operation that fails with a transient error
+	DefaultRetryAttempts = utils.DefaultRetryAttempts
+
+	// DefaultRetryBackoffMS is the delay (in milliseconds) before the first retry
+	DefaultRetryBackoffMS = int(utils.DefaultRetryBackoff / time.Millisecond)
+)
+
+// GetConfigDir returns the absolute path to the config directory: data/ under the base
+// directory unless moved by --config-dir or THLOCALSYNC_CONFIG_DIR (see paths.ResolveConfigDir).
+func GetConfigDir() (string, error) {
+	return paths.GetConfigDir()
 }
 
 // LoadDevices loads the devices.json configuration.
 // If the file doesn't exist, returns an empty config.
 func LoadDevices() (*models.DeviceConfig, error) {
+	defer timing.Start("config_load")()
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return nil, err
@@ -103,7 +122,7 @@ func SaveDevices(config *models.DeviceConfig) error {
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, filePath); err != nil {
+	if err := utils.SafeRename(tmpPath, filePath); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
@@ -114,6 +133,8 @@ func SaveDevices(config *models.DeviceConfig) error {
 // LoadPaths loads the paths.json configuration.
 // If the file doesn't exist, returns an empty config.
 func LoadPaths() (*models.PathsConfig, error) {
+	defer timing.Start("config_load")()
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return nil, err
@@ -147,9 +168,41 @@ func LoadPaths() (*models.PathsConfig, error) {
 		config.Paths = make(map[string]map[string]models.PathEntry)
 	}
 
+	if err := ValidatePaths(&config); err != nil {
+		return nil, fmt.Errorf("invalid paths.json: %w", err)
+	}
+
 	return &config, nil
 }
 
+// ValidatePaths reports vault file names that cannot be used. They name the file inside
+// vault/<title>/<slot>/, so they must be plain file names.
+func ValidatePaths(config *models.PathsConfig) error {
+	for title, name := range config.VaultFileNames {
+		if !titleCodePattern.MatchString(title) {
+			return fmt.Errorf("vault_file_names: invalid title code %q", title)
+		}
+		if !isBareFileName(name) {
+			return fmt.Errorf("vault_file_names[%s]: must be a file name without directories, got %q", title, name)
+		}
+	}
+	for title, devices := range config.Paths {
+		for deviceID, entry := range devices {
+			for _, name := range entry.SetFiles {
+				if !isBareFileName(name) {
+					return fmt.Errorf("paths[%s][%s]: set_files must be file names without directories, got %q", title, deviceID, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isBareFileName reports whether name is a file name without any directory part.
+func isBareFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\:`)
+}
+
 // SavePaths saves the paths.json configuration atomically.
 func SavePaths(config *models.PathsConfig) error {
 	configDir, err := GetConfigDir()
@@ -177,7 +230,7 @@ func SavePaths(config *models.PathsConfig) error {
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, filePath); err != nil {
+	if err := utils.SafeRename(tmpPath, filePath); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
@@ -188,6 +241,8 @@ func SavePaths(config *models.PathsConfig) error {
 // LoadRules loads the rules.json configuration.
 // If the file doesn't exist, returns default rules.
 func LoadRules() (*models.Rules, error) {
+	defer timing.Start("config_load")()
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return nil, err
@@ -198,11 +253,7 @@ func LoadRules() (*models.Rules, error) {
 	// If file doesn't exist, return default config
 	exists, _ := utils.FileExists(filePath)
 	if !exists {
-		return &models.Rules{
-			Include:      []string{"score.dat", "scoreth*.dat"},
-			Exclude:      []string{"*.tmp", "_history/*"},
-			HistoryLimit: 20,
-		}, nil
+		return DefaultRules(), nil
 	}
 
 	data, err := os.ReadFile(filePath)
@@ -218,11 +269,74 @@ func LoadRules() (*models.Rules, error) {
 		return nil, fmt.Errorf("failed to parse rules.json (backed up to %s): %w", backupPath, err)
 	}
 
+	applyRuleDefaults(&config)
+
 	return &config, nil
 }
 
+// DefaultRules returns the rules used when rules.json does not exist.
+func DefaultRules() *models.Rules {
+	return &models.Rules{
+		Include:               []string{"score.dat", "scoreth*.dat"},
+		Exclude:               []string{"*.tmp", "_history/*"},
+		HistoryLimit:          20,
+		SizeRatioThreshold:    DefaultSizeRatioThreshold,
+		DriftToleranceSeconds: DefaultDriftToleranceSeconds,
+		LogRetentionDays:      DefaultLogRetentionDays,
+		LogMaxSizeKB:          DefaultLogMaxSizeKB,
+		HashAlgo:              utils.HashSHA256,
+		RetryAttempts:         DefaultRetryAttempts,
+		RetryBackoffMS:        DefaultRetryBackoffMS,
+	}
+}
+
+// applyRuleDefaults fills unset or invalid thresholds and log limits in rules with their defaults.
+// A zero or negative size ratio would flag every change as suspicious, so it falls back too.
+func applyRuleDefaults(rules *models.Rules) {
+	if rules.SizeRatioThreshold <= 0 {
+		rules.SizeRatioThreshold = DefaultSizeRatioThreshold
+	}
+	if rules.DriftToleranceSeconds <= 0 {
+		rules.DriftToleranceSeconds = DefaultDriftToleranceSeconds
+	}
+	if rules.LogRetentionDays <= 0 {
+		rules.LogRetentionDays = DefaultLogRetentionDays
+	}
+	if rules.LogMaxSizeKB <= 0 {
+		rules.LogMaxSizeKB = DefaultLogMaxSizeKB
+	}
+	if rules.HashAlgo == "" {
+		rules.HashAlgo = utils.HashSHA256
+	}
+	if rules.RetryAttempts <= 0 {
+		rules.RetryAttempts = DefaultRetryAttempts
+	}
+	if rules.RetryBackoffMS <= 0 {
+		rules.RetryBackoffMS = DefaultRetryBackoffMS
+	}
+}
+
+// ValidateRules reports values in rules that must not be saved.
+func ValidateRules(rules *models.Rules) error {
+	if rules.HistoryLimit < 0 {
+		return fmt.Errorf("history_limit must be non-negative, got %d", rules.HistoryLimit)
+	}
+	if rules.HistoryMaxAgeDays < 0 {
+		return fmt.Errorf("history_max_age_days must be non-negative, got %d", rules.HistoryMaxAgeDays)
+	}
+	if err := utils.ValidateHashAlgo(rules.HashAlgo); err != nil {
+		return fmt.Errorf("hash_algo: %w", err)
+	}
+	return nil
+}
+
 // SaveRules saves the rules.json configuration atomically.
+// Rules that fail ValidateRules are rejected.
 func SaveRules(config *models.Rules) error {
+	if err := ValidateRules(config); err != nil {
+		return err
+	}
+
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return err
@@ -248,10 +362,97 @@ func SaveRules(config *models.Rules) error {
 	}
 
 	// Atomic rename
-	if err := os.Rename(tmpPath, filePath); err != nil {
+	if err := utils.SafeRename(tmpPath, filePath); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
 	return nil
 }
+
+// titleCodePattern restricts user title codes to names that are safe as vault directory names.
+var titleCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadTitles loads the titles.json user title definitions.
+// If the file doesn't exist, returns an empty config.
+func LoadTitles() (*models.TitlesConfig, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	filePath := filepath.Join(configDir, TitlesFile)
+
+	// If file doesn't exist, there are no user titles
+	exists, _ := utils.FileExists(filePath)
+	if !exists {
+		return &models.TitlesConfig{}, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read titles.json: %w", err)
+	}
+
+	var config models.TitlesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		// Backup corrupted file
+		backupPath := filePath + ".backup-" + time.Now().Format("20060102-150405")
+		_ = utils.AtomicCopy(filePath, backupPath)
+		return nil, fmt.Errorf("failed to parse titles.json (backed up to %s): %w", backupPath, err)
+	}
+
+	if err := ValidateTitles(&config); err != nil {
+		return nil, fmt.Errorf("invalid titles.json: %w", err)
+	}
+
+	return &config, nil
+}
+
+// ValidateTitles reports title definitions that cannot be used.
+// Codes become vault directory names, so they are limited to lowercase letters,
+// digits, '-' and '_', and each code may be defined only once.
+func ValidateTitles(config *models.TitlesConfig) error {
+	seen := make(map[string]bool)
+	for i, title := range config.Titles {
+		if !titleCodePattern.MatchString(title.Code) {
+			return fmt.Errorf("titles[%d]: invalid code %q (use lowercase letters, digits, '-' and '_')", i, title.Code)
+		}
+		if seen[title.Code] {
+			return fmt.Errorf("titles[%d]: duplicate code %q", i, title.Code)
+		}
+		seen[title.Code] = true
+
+		if !isBareFileName(title.FileName) {
+			return fmt.Errorf("titles[%d] (%s): file_name must be a file name without directories, got %q", i, title.Code, title.FileName)
+		}
+		for _, name := range title.SetFiles {
+			if !isBareFileName(name) || strings.EqualFold(name, title.FileName) {
+				return fmt.Errorf("titles[%d] (%s): set_files must be file names without directories, other than file_name, got %q", i, title.Code, name)
+			}
+		}
+		if err := validateProcessNames(title.ProcessNames); err != nil {
+			return fmt.Errorf("titles[%d] (%s): %w", i, title.Code, err)
+		}
+	}
+
+	for code, names := range config.ProcessNames {
+		if !titleCodePattern.MatchString(code) {
+			return fmt.Errorf("process_names: invalid title code %q", code)
+		}
+		if err := validateProcessNames(names); err != nil {
+			return fmt.Errorf("process_names[%s]: %w", code, err)
+		}
+	}
+	return nil
+}
+
+// validateProcessNames checks that each executable name is a bare file name.
+func validateProcessNames(names []string) error {
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) {
+			return fmt.Errorf("process name must be an executable name without directories, got %q", name)
+		}
+	}
+	return nil
+}
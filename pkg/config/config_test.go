@@ -0,0 +1,209 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestApplyRuleDefaults_SizeRatioThreshold(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{"Unset falls back to default", 0, DefaultSizeRatioThreshold},
+		{"Negative falls back to default", -1.5, DefaultSizeRatioThreshold},
+		{"Custom value is kept", 4.0, 4.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &models.Rules{SizeRatioThreshold: tt.value}
+			applyRuleDefaults(rules)
+			if rules.SizeRatioThreshold != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, rules.SizeRatioThreshold)
+			}
+		})
+	}
+}
+
+func TestApplyRuleDefaults_DriftToleranceSeconds(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    int
+		expected int
+	}{
+		{"Unset falls back to default", 0, DefaultDriftToleranceSeconds},
+		{"Negative falls back to default", -2, DefaultDriftToleranceSeconds},
+		{"Custom value is kept", 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &models.Rules{DriftToleranceSeconds: tt.value}
+			applyRuleDefaults(rules)
+			if rules.DriftToleranceSeconds != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, rules.DriftToleranceSeconds)
+			}
+		})
+	}
+}
+
+func TestValidateRules_HistoryLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		wantErr bool
+	}{
+		{"Zero is allowed", 0, false},
+		{"Positive is allowed", 30, false},
+		{"Negative is rejected", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules(&models.Rules{HistoryLimit: tt.limit})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRules_HistoryMaxAgeDays(t *testing.T) {
+	if err := ValidateRules(&models.Rules{HistoryMaxAgeDays: 90}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := ValidateRules(&models.Rules{HistoryMaxAgeDays: -1}); err == nil {
+		t.Error("Expected error for negative history_max_age_days")
+	}
+}
+
+func TestValidateTitles(t *testing.T) {
+	title := func(code, fileName string) models.TitleDefinition {
+		return models.TitleDefinition{Code: code, Name: code, FileName: fileName}
+	}
+
+	tests := []struct {
+		name    string
+		titles  []models.TitleDefinition
+		wantErr bool
+	}{
+		{"Fangame code", []models.TitleDefinition{title("hrtp", "score.dat")}, false},
+		{"Overrides built-in code", []models.TitleDefinition{title("th20", "scoreth20.dat")}, false},
+		{"Uppercase code", []models.TitleDefinition{title("HRTP", "score.dat")}, true},
+		{"Path in code", []models.TitleDefinition{title("../th08", "score.dat")}, true},
+		{"Reserved prefix", []models.TitleDefinition{title("_history", "score.dat")}, true},
+		{"Duplicate code", []models.TitleDefinition{title("hrtp", "score.dat"), title("hrtp", "score.dat")}, true},
+		{"Missing file name", []models.TitleDefinition{title("hrtp", "")}, true},
+		{"Directory in file name", []models.TitleDefinition{title("hrtp", `save\score.dat`)}, true},
+		{"Set files", []models.TitleDefinition{{Code: "hrtp", Name: "hrtp", FileName: "score.dat", SetFiles: []string{"hrtp.cfg"}}}, false},
+		{"Directory in set file", []models.TitleDefinition{{Code: "hrtp", Name: "hrtp", FileName: "score.dat", SetFiles: []string{"../hrtp.cfg"}}}, true},
+		{"Save file in set files", []models.TitleDefinition{{Code: "hrtp", Name: "hrtp", FileName: "score.dat", SetFiles: []string{"SCORE.DAT"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTitles(&models.TitlesConfig{Titles: tt.titles})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateTitles_ProcessNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  models.TitlesConfig
+		wantErr bool
+	}{
+		{"Renamed built-in exe", models.TitlesConfig{ProcessNames: map[string][]string{"th08": {"th08e.exe", "東方永夜抄.exe"}}}, false},
+		{"Invalid title code", models.TitlesConfig{ProcessNames: map[string][]string{"TH08": {"th08e.exe"}}}, true},
+		{"Path instead of name", models.TitlesConfig{ProcessNames: map[string][]string{"th08": {`C:\th08\th08.exe`}}}, true},
+		{"Empty name in definition", models.TitlesConfig{Titles: []models.TitleDefinition{
+			{Code: "hrtp", Name: "hrtp", FileName: "score.dat", ProcessNames: []string{""}},
+		}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTitles(&tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePaths_VaultFileNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   map[string]string
+		wantErr bool
+	}{
+		{"None set", nil, false},
+		{"Canonical name", map[string]string{"th16": "scoreth16.dat"}, false},
+		{"Invalid title code", map[string]string{"TH16": "scoreth16.dat"}, true},
+		{"Empty name", map[string]string{"th16": ""}, true},
+		{"Directory in name", map[string]string{"th16": "../scoreth16.dat"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePaths(&models.PathsConfig{VaultFileNames: tt.names})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePaths_SetFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		setFiles []string
+		wantErr  bool
+	}{
+		{"None set", nil, false},
+		{"Config next to the save file", []string{"th128.cfg"}, false},
+		{"Directory in name", []string{`..\th128.cfg`}, true},
+		{"Empty name", []string{""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+				"th128": {"abcdefabcdef": {Paths: []string{`C:\th128\scoreth128.dat`}, SetFiles: tt.setFiles}},
+			}}
+			if err := ValidatePaths(config); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPathEntry_OmitsZeroTimes(t *testing.T) {
+	data, err := json.Marshal(models.PathEntry{Paths: []string{`C:\th08\score.dat`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "last_pushed") || strings.Contains(string(data), "last_synced") {
+		t.Errorf("Expected no times for a device never synced: %s", data)
+	}
+
+	at := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	data, err = json.Marshal(models.PathEntry{LastPushed: at, LastSynced: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"last_pushed":"2025-12-01T12:00:00Z"`, `"last_synced":"2025-12-01T12:00:00Z"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s to be written: %s", want, data)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// schemaFS holds the JSON Schemas for the config files, maintained alongside the model structs.
+//
+//go:embed schemas/*.schema.json
+var schemaFS embed.FS
+
+// SchemaFiles maps each config file name to its embedded schema file name.
+var SchemaFiles = map[string]string{
+	DevicesFile: "devices.schema.json",
+	PathsFile:   "paths.schema.json",
+	RulesFile:   "rules.schema.json",
+	TitlesFile:  "titles.schema.json",
+}
+
+// Schema is the subset of JSON Schema (draft-07) used by the config schemas.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"` // allowed string values
+}
+
+// SchemaError describes a single schema violation in a config file.
+type SchemaError struct {
+	Path    string // JSON path (e.g. "$.paths.th08.abc123.preferred")
+	Line    int    // 1-based line number
+	Column  int    // 1-based column number
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("line %d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// LoadSchema returns the embedded schema for a config file (e.g. "paths.json").
+func LoadSchema(configFile string) (*Schema, error) {
+	data, err := SchemaBytes(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema for %s: %w", configFile, err)
+	}
+
+	return &schema, nil
+}
+
+// SchemaBytes returns the raw embedded schema for a config file.
+func SchemaBytes(configFile string) ([]byte, error) {
+	schemaFile, ok := SchemaFiles[configFile]
+	if !ok {
+		return nil, fmt.Errorf("no schema for %s", configFile)
+	}
+
+	return schemaFS.ReadFile("schemas/" + schemaFile)
+}
+
+// WriteSchemas writes the embedded schemas into dir for editor tooling.
+// Existing schema files are overwritten so they track the tool version.
+func WriteSchemas(dir string) error {
+	if err := utils.EnsureDir(dir); err != nil {
+		return fmt.Errorf("failed to create schema directory: %w", err)
+	}
+
+	for configFile, schemaFile := range SchemaFiles {
+		data, err := SchemaBytes(configFile)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, schemaFile), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", schemaFile, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateConfigFile validates on-disk config data against the schema for configFile.
+// A JSON syntax error is reported as a single SchemaError with its position.
+func ValidateConfigFile(configFile string, data []byte) ([]SchemaError, error) {
+	schema, err := LoadSchema(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseNode(data)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		var pErr *parseError
+		offset := int64(len(data))
+		if errors.As(err, &syntaxErr) {
+			offset = syntaxErr.Offset
+		} else if errors.As(err, &pErr) {
+			offset = pErr.Offset
+		}
+		line, col := lineColumn(data, offset)
+		return []SchemaError{{Path: "$", Line: line, Column: col, Message: err.Error()}}, nil
+	}
+
+	v := &validator{data: data}
+	v.validate(schema, root, "$")
+
+	return v.errors, nil
+}
+
+// ValidateConfigDir validates every config file present in the config directory.
+// Missing files are skipped. Returns errors keyed by config file name.
+func ValidateConfigDir() (map[string][]SchemaError, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]SchemaError)
+	for configFile := range SchemaFiles {
+		filePath := filepath.Join(configDir, configFile)
+		if exists, _ := utils.FileExists(filePath); !exists {
+			continue
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
+		}
+
+		schemaErrors, err := ValidateConfigFile(configFile, data)
+		if err != nil {
+			return nil, err
+		}
+		results[configFile] = schemaErrors
+	}
+
+	return results, nil
+}
+
+// node is a parsed JSON value with its byte offset in the source.
+type node struct {
+	kind    string // "object", "array", "string", "number", "boolean", "null"
+	offset  int64
+	str     string
+	num     json.Number
+	members []member
+	items   []*node
+}
+
+// parseError is a structural JSON error with its byte offset.
+type parseError struct {
+	Offset int64
+	Msg    string
+}
+
+func (e *parseError) Error() string {
+	return e.Msg
+}
+
+// member is a key/value pair of a JSON object, in source order.
+type member struct {
+	key   string
+	value *node
+}
+
+// parseNode parses data into a node tree, recording offsets for error reporting.
+func parseNode(data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := parseValue(dec, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject trailing data
+	offset := skipSeparators(data, dec.InputOffset())
+	if _, err := dec.Token(); err != io.EOF {
+		if err != nil {
+			return nil, err
+		}
+		return nil, &parseError{Offset: offset, Msg: "unexpected data after top-level value"}
+	}
+
+	return root, nil
+}
+
+func parseValue(dec *json.Decoder, data []byte) (*node, error) {
+	offset := skipSeparators(data, dec.InputOffset())
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, &parseError{Offset: offset, Msg: "unexpected end of JSON input"}
+		}
+		return nil, err
+	}
+
+	n := &node{offset: offset}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n.kind = "object"
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, ok := keyTok.(string)
+				if !ok {
+					return nil, &parseError{Offset: offset, Msg: "expected object key"}
+				}
+				value, err := parseValue(dec, data)
+				if err != nil {
+					return nil, err
+				}
+				n.members = append(n.members, member{key: key, value: value})
+			}
+		case '[':
+			n.kind = "array"
+			for dec.More() {
+				item, err := parseValue(dec, data)
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, item)
+			}
+		}
+		// Consume closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n.kind = "string"
+		n.str = t
+	case json.Number:
+		n.kind = "number"
+		n.num = t
+	case bool:
+		n.kind = "boolean"
+	case nil:
+		n.kind = "null"
+	}
+
+	return n, nil
+}
+
+// skipSeparators advances offset past whitespace, commas, and colons.
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineColumn converts a byte offset into a 1-based line and column.
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return line, int(offset) - lineStart + 1
+}
+
+// validator accumulates schema errors for a document.
+type validator struct {
+	data   []byte
+	errors []SchemaError
+}
+
+func (v *validator) fail(n *node, path string, format string, args ...interface{}) {
+	line, col := lineColumn(v.data, n.offset)
+	v.errors = append(v.errors, SchemaError{
+		Path:    path,
+		Line:    line,
+		Column:  col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(schema *Schema, n *node, path string) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Type != "" && !typeMatches(schema.Type, n) {
+		v.fail(n, path, "expected %s, got %s", schema.Type, n.kind)
+		return
+	}
+
+	switch n.kind {
+	case "object":
+		v.validateObject(schema, n, path)
+	case "array":
+		for i, item := range n.items {
+			v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "number":
+		if schema.Minimum != nil {
+			if f, err := n.num.Float64(); err == nil && f < *schema.Minimum {
+				v.fail(n, path, "must be >= %v, got %s", *schema.Minimum, n.num)
+			}
+		}
+	case "string":
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, n.str); err != nil {
+				v.fail(n, path, "invalid date-time %q", n.str)
+			}
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, n.str) {
+			v.fail(n, path, "must be one of %s, got %q", strings.Join(schema.Enum, ", "), n.str)
+		}
+	}
+}
+
+func (v *validator) validateObject(schema *Schema, n *node, path string) {
+	present := make(map[string]bool)
+	for _, m := range n.members {
+		present[m.key] = true
+	}
+
+	for _, key := range schema.Required {
+		if !present[key] {
+			v.fail(n, path, "missing required key %q", key)
+		}
+	}
+
+	additional, additionalAllowed := parseAdditional(schema.AdditionalProperties)
+
+	for _, m := range n.members {
+		childPath := path + "." + m.key
+		if propSchema, ok := schema.Properties[m.key]; ok {
+			v.validate(propSchema, m.value, childPath)
+			continue
+		}
+		if !additionalAllowed {
+			v.fail(m.value, childPath, "unknown key %q (allowed: %s)", m.key, strings.Join(sortedKeys(schema.Properties), ", "))
+			continue
+		}
+		v.validate(additional, m.value, childPath)
+	}
+}
+
+// parseAdditional interprets additionalProperties, which is either a boolean or a schema.
+func parseAdditional(raw json.RawMessage) (*Schema, bool) {
+	if len(raw) == 0 {
+		return nil, true
+	}
+
+	var allowed bool
+	if err := json.Unmarshal(raw, &allowed); err == nil {
+		return nil, allowed
+	}
+
+	var schema Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, true
+	}
+	return &schema, true
+}
+
+// typeMatches reports whether a parsed node satisfies a JSON Schema type.
+func typeMatches(schemaType string, n *node) bool {
+	switch schemaType {
+	case "integer":
+		if n.kind != "number" {
+			return false
+		}
+		_, err := n.num.Int64()
+		return err == nil
+	default:
+		return schemaType == n.kind
+	}
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestValidateConfigFile_Good(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+	}{
+		{
+			file: DevicesFile,
+			data: `{
+  "devices": [
+    {
+      "id": "abc123def456",
+      "hostname": "PC1",
+      "mac_hash": "sha256:0000",
+      "last_seen": "2025-11-11T06:20:30.123456Z",
+      "os": "windows"
+    }
+  ]
+}`,
+		},
+		{
+			file: PathsFile,
+			data: `{
+  "paths": {
+    "th08": {
+      "abc123def456": {
+        "paths": ["C:\\Games\\th08\\score.dat"],
+        "preferred": 0
+      }
+    }
+  }
+}`,
+		},
+		{
+			file: RulesFile,
+			data: `{"include": ["score.dat"], "exclude": ["*.tmp"], "history_limit": 20}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			errs, err := ValidateConfigFile(tt.file, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("ValidateConfigFile failed: %v", err)
+			}
+			if len(errs) != 0 {
+				t.Errorf("Expected no schema errors, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestValidateConfigFile_Malformed(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         string
+		data         string
+		expectedPath string
+		expectedLine int
+		expectedMsg  string
+	}{
+		{
+			name:         "Wrong type for preferred",
+			file:         PathsFile,
+			data:         "{\n  \"paths\": {\n    \"th08\": {\n      \"dev\": {\"paths\": [], \"preferred\": \"0\"}\n    }\n  }\n}",
+			expectedPath: "$.paths.th08.dev.preferred",
+			expectedLine: 4,
+			expectedMsg:  "expected integer",
+		},
+		{
+			name:         "Negative preferred",
+			file:         PathsFile,
+			data:         `{"paths": {"th08": {"dev": {"paths": [], "preferred": -1}}}}`,
+			expectedPath: "$.paths.th08.dev.preferred",
+			expectedLine: 1,
+			expectedMsg:  "must be >= 0",
+		},
+		{
+			name:         "Missing required key",
+			file:         DevicesFile,
+			data:         "{\n  \"devices\": [\n    {\"id\": \"abc\", \"hostname\": \"PC1\", \"last_seen\": \"2025-11-11T06:20:30Z\"}\n  ]\n}",
+			expectedPath: "$.devices[0]",
+			expectedLine: 3,
+			expectedMsg:  `missing required key "mac_hash"`,
+		},
+		{
+			name:         "Unknown key",
+			file:         RulesFile,
+			data:         "{\n  \"history_limit\": 20,\n  \"histroy_limit\": 5\n}",
+			expectedPath: "$.histroy_limit",
+			expectedLine: 3,
+			expectedMsg:  `unknown key "histroy_limit"`,
+		},
+		{
+			name:         "Invalid date-time",
+			file:         DevicesFile,
+			data:         `{"devices": [{"id": "a", "hostname": "b", "mac_hash": "c", "last_seen": "yesterday"}]}`,
+			expectedPath: "$.devices[0].last_seen",
+			expectedLine: 1,
+			expectedMsg:  "invalid date-time",
+		},
+		{
+			name:         "Unknown enum value",
+			file:         RulesFile,
+			data:         `{"hash_algo": "md5"}`,
+			expectedPath: "$.hash_algo",
+			expectedLine: 1,
+			expectedMsg:  "must be one of sha256, xxhash, blake3",
+		},
+		{
+			name:         "Syntax error",
+			file:         RulesFile,
+			data:         "{\n  \"history_limit\": 20,\n}",
+			expectedPath: "$",
+			expectedLine: 2,
+			expectedMsg:  "invalid character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := ValidateConfigFile(tt.file, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("ValidateConfigFile failed: %v", err)
+			}
+			if len(errs) != 1 {
+				t.Fatalf("Expected 1 schema error, got %d: %v", len(errs), errs)
+			}
+
+			got := errs[0]
+			if got.Path != tt.expectedPath {
+				t.Errorf("Expected path %s, got %s", tt.expectedPath, got.Path)
+			}
+			if got.Line != tt.expectedLine {
+				t.Errorf("Expected line %d, got %d (%s)", tt.expectedLine, got.Line, got)
+			}
+			if !strings.Contains(got.Message, tt.expectedMsg) {
+				t.Errorf("Expected message containing %q, got %q", tt.expectedMsg, got.Message)
+			}
+		})
+	}
+}
+
+// jsonKeys returns the JSON field names of a struct type.
+func jsonKeys(typ reflect.Type) []string {
+	var keys []string
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+// TestSchemas_MatchModels guards against the schemas drifting from the model structs.
+func TestSchemas_MatchModels(t *testing.T) {
+	devices, err := LoadSchema(DevicesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := LoadSchema(PathsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadSchema(RulesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles, err := LoadSchema(TitlesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pathEntry, _ := parseAdditional(paths.Properties["paths"].AdditionalProperties)
+	pathEntry, _ = parseAdditional(pathEntry.AdditionalProperties)
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		model  reflect.Type
+	}{
+		{"Device", devices.Properties["devices"].Items, reflect.TypeOf(models.Device{})},
+		{"PathEntry", pathEntry, reflect.TypeOf(models.PathEntry{})},
+		{"Rules", rules, reflect.TypeOf(models.Rules{})},
+		{"TitlesConfig", titles, reflect.TypeOf(models.TitlesConfig{})},
+		{"TitleDefinition", titles.Properties["titles"].Items, reflect.TypeOf(models.TitleDefinition{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := jsonKeys(tt.model)
+			for _, key := range keys {
+				if _, ok := tt.schema.Properties[key]; !ok {
+					t.Errorf("Schema is missing property %q", key)
+				}
+			}
+			if len(tt.schema.Properties) != len(keys) {
+				t.Errorf("Schema has %d properties, model has %d", len(tt.schema.Properties), len(keys))
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package console controls how much the CLI prints to stdout (--quiet / --verbose).
+//
+// Routine progress (headers, ✓/- result lines, summaries) goes through Printf and
+// is dropped in quiet mode. Details such as full paths and hashes go through
+// Verbosef and only appear in verbose mode. Errors, warnings and prompts are
+// printed directly by their callers and are never suppressed.
+package console
+
+import "fmt"
+
+// Verbosity is how much routine output is printed.
+type Verbosity int
+
+const (
+	// Quiet prints errors only.
+	Quiet Verbosity = iota - 1
+	// Normal prints progress and summaries.
+	Normal
+	// Verbose also prints per-file details.
+	Verbose
+)
+
+// verbosity is set from the root command's --quiet / --verbose flags.
+var verbosity = Normal
+
+// SetVerbosity sets how much routine output is printed.
+func SetVerbosity(v Verbosity) {
+	verbosity = v
+}
+
+// IsQuiet reports whether routine output is suppressed.
+func IsQuiet() bool {
+	return verbosity <= Quiet
+}
+
+// IsVerbose reports whether per-file details are printed.
+func IsVerbose() bool {
+	return verbosity >= Verbose
+}
+
+// Printf prints routine output unless quiet.
+func Printf(format string, a ...interface{}) {
+	if IsQuiet() {
+		return
+	}
+	fmt.Printf(format, a...)
+}
+
+// Println prints routine output unless quiet.
+func Println(a ...interface{}) {
+	if IsQuiet() {
+		return
+	}
+	fmt.Println(a...)
+}
+
+// Verbosef prints details only when verbose.
+func Verbosef(format string, a ...interface{}) {
+	if !IsVerbose() {
+		return
+	}
+	fmt.Printf(format, a...)
+}
@@ -0,0 +1,46 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetVerbosity(t *testing.T) {
+	defer SetVerbosity(Normal)
+
+	tests := []struct {
+		verbosity   Verbosity
+		wantQuiet   bool
+		wantVerbose bool
+	}{
+		{Quiet, true, false},
+		{Normal, false, false},
+		{Verbose, false, true},
+	}
+
+	for _, tt := range tests {
+		SetVerbosity(tt.verbosity)
+		if IsQuiet() != tt.wantQuiet || IsVerbose() != tt.wantVerbose {
+			t.Errorf("Verbosity %d: IsQuiet=%v IsVerbose=%v, want %v %v",
+				tt.verbosity, IsQuiet(), IsVerbose(), tt.wantQuiet, tt.wantVerbose)
+		}
+	}
+}
+
+func TestStartSpinner_ClearsLineOnStop(t *testing.T) {
+	var buf bytes.Buffer
+	stop := startSpinner(&buf, "searching", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	stop()
+	stop() // stopping twice is harmless
+
+	out := buf.String()
+	if !strings.Contains(out, "| searching") {
+		t.Errorf("output %q has no spinner frame", out)
+	}
+	if want := "\r" + strings.Repeat(" ", len("searching")+2) + "\r"; !strings.HasSuffix(out, want) {
+		t.Errorf("output %q does not end by clearing the line", out)
+	}
+}
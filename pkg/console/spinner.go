@@ -0,0 +1,65 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// spinnerFrames are drawn in turn in front of the spinner label.
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// spinnerInterval is how often the spinner is redrawn.
+const spinnerInterval = 100 * time.Millisecond
+
+// StartSpinner draws label with a spinning mark on one line until the returned
+// function is called, which clears the line. Nothing is drawn in quiet or verbose
+// mode (verbose output would be interleaved with it) or when stdout is not a terminal.
+func StartSpinner(label string) func() {
+	if IsQuiet() || IsVerbose() || !stdoutIsTerminal() {
+		return func() {}
+	}
+	return startSpinner(os.Stdout, label, spinnerInterval)
+}
+
+func startSpinner(w io.Writer, label string, interval time.Duration) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			fmt.Fprintf(w, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], label)
+			select {
+			case <-stop:
+				// Blank out the frame and label so the next line starts clean
+				fmt.Fprintf(w, "\r%*s\r", utf8.RuneCountInString(label)+2, "")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal rather than a pipe or file.
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -2,15 +2,40 @@
 package device
 
 import (
+	"crypto/rand"
 	"fmt"
 	"net"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
-// GetDeviceID generates a unique device ID based on hostname and primary MAC address.
+// EnvDeviceID is the environment variable that overrides the device ID.
+const EnvDeviceID = "THLOCALSYNC_DEVICE_ID"
+
+// OverrideID, when set (by --device-id), is used as the device ID instead of
+// deriving one from the MAC address. It takes precedence over EnvDeviceID.
+var OverrideID string
+
+// deviceIDPattern is the form of every device ID: 12 lowercase hex characters.
+var deviceIDPattern = regexp.MustCompile(`^[0-9a-f]{12}$`)
+
+// virtualInterfaceMarkers are lower-case substrings of interface names that
+// belong to virtual, VPN or Bluetooth adapters, which come and go with software.
+var virtualInterfaceMarkers = []string{
+	"vethernet", "virtual", "vmware", "vmnet", "vbox", "hyper-v", "wsl",
+	"docker", "veth", "br-", "virbr", "tun", "tap", "utun", "wg",
+	"vpn", "zerotier", "tailscale", "bluetooth", "npcap", "awdl", "llw",
+}
+
+// GetDeviceID generates a unique device ID based on hostname and a stable MAC address.
+// If no stable MAC is available, a random UUID persisted in devices.json is used instead.
+// An override from --device-id or THLOCALSYNC_DEVICE_ID bypasses both.
 // Returns: device_id (first 12 chars of SHA256(hostname+mac)), full hash, hostname, error
 func GetDeviceID() (id string, hash string, hostname string, err error) {
 	// Get hostname
@@ -19,29 +44,127 @@ func GetDeviceID() (id string, hash string, hostname string, err error) {
 		return "", "", "", fmt.Errorf("failed to get hostname: %w", err)
 	}
 
-	// Get primary MAC address
+	// Use the override as-is; the hostname is still recorded
+	if override, source := overrideID(); override != "" {
+		override = strings.ToLower(override)
+		if !deviceIDPattern.MatchString(override) {
+			return "", "", "", fmt.Errorf("invalid device ID from %s: %q (must be 12 hex characters)", source, override)
+		}
+		return override, "sha256:" + utils.CalculateStringHash(hostname+override), hostname, nil
+	}
+
+	// Get primary MAC address, falling back to a persisted UUID
 	mac, err := getPrimaryMAC()
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to get MAC address: %w", err)
+		return getFallbackDeviceID(hostname)
+	}
+
+	deviceID, hashWithPrefix, err := hashDeviceID(hostname, mac)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return deviceID, hashWithPrefix, hostname, nil
+}
+
+// overrideID returns the device ID override and where it came from, or empty strings if none is set.
+func overrideID() (string, string) {
+	if OverrideID != "" {
+		return OverrideID, "--device-id"
+	}
+	if env := os.Getenv(EnvDeviceID); env != "" {
+		return env, EnvDeviceID
 	}
+	return "", ""
+}
+
+// IsOverridden reports whether the device ID comes from --device-id or THLOCALSYNC_DEVICE_ID.
+func IsOverridden() bool {
+	id, _ := overrideID()
+	return id != ""
+}
 
-	// Calculate hash: SHA256(hostname + mac)
-	combined := hostname + mac
-	fullHash := utils.CalculateStringHash(combined)
+// hashDeviceID derives the device ID and the stored "sha256:" hash from hostname and a machine key.
+func hashDeviceID(hostname string, key string) (string, string, error) {
+	// Calculate hash: SHA256(hostname + key)
+	fullHash := utils.CalculateStringHash(hostname + key)
 
 	// Device ID is first 12 characters of hash
 	if len(fullHash) < 12 {
-		return "", "", "", fmt.Errorf("hash too short: %s", fullHash)
+		return "", "", fmt.Errorf("hash too short: %s", fullHash)
 	}
-	deviceID := fullHash[:12]
 
 	// Return full hash with "sha256:" prefix for storage
-	hashWithPrefix := "sha256:" + fullHash
+	return fullHash[:12], "sha256:" + fullHash, nil
+}
 
-	return deviceID, hashWithPrefix, hostname, nil
+// getFallbackDeviceID derives the device ID from a random UUID stored for this
+// hostname in devices.json, generating and saving one on first use.
+func getFallbackDeviceID(hostname string) (string, string, string, error) {
+	devicesConfig, err := config.LoadDevices()
+	if err != nil {
+		return "", "", "", fmt.Errorf("no stable MAC address and failed to load devices config: %w", err)
+	}
+
+	for _, d := range devicesConfig.Devices {
+		if d.Hostname == hostname && d.UUID != "" {
+			deviceID, hash, err := hashDeviceID(hostname, d.UUID)
+			return deviceID, hash, hostname, err
+		}
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to generate device UUID: %w", err)
+	}
+
+	deviceID, hash, err := hashDeviceID(hostname, uuid)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// Attach the UUID to this machine's existing entry so MigrateDeviceID can
+	// move it (and its paths) to the new ID; otherwise register a new entry.
+	existing := -1
+	for i, d := range devicesConfig.Devices {
+		if d.Hostname == hostname {
+			if existing >= 0 {
+				existing = -1
+				break
+			}
+			existing = i
+		}
+	}
+	if existing >= 0 {
+		devicesConfig.Devices[existing].UUID = uuid
+	} else {
+		devicesConfig.Devices = append(devicesConfig.Devices, models.Device{
+			ID:       deviceID,
+			Hostname: hostname,
+			MACHash:  hash,
+			UUID:     uuid,
+		})
+	}
+	if err := config.SaveDevices(devicesConfig); err != nil {
+		return "", "", "", fmt.Errorf("failed to save device UUID: %w", err)
+	}
+
+	return deviceID, hash, hostname, nil
+}
+
+// newUUID returns a random (version 4) UUID string.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
 }
 
-// getPrimaryMAC returns the MAC address of the first non-loopback network interface.
+// getPrimaryMAC returns the MAC address of the first stable network interface.
 // Returns the MAC address as a string (e.g., "00:11:22:33:44:55").
 func getPrimaryMAC() (string, error) {
 	interfaces, err := net.Interfaces()
@@ -49,7 +172,27 @@ func getPrimaryMAC() (string, error) {
 		return "", fmt.Errorf("failed to get network interfaces: %w", err)
 	}
 
-	for _, iface := range interfaces {
+	mac := selectStableMAC(interfaces)
+	if mac == "" {
+		return "", fmt.Errorf("no valid network interface found")
+	}
+
+	return mac, nil
+}
+
+// selectStableMAC picks the same physical adapter on every run, regardless of
+// which adapters are currently connected. Interfaces are sorted by name and
+// loopback, virtual/VPN/Bluetooth adapters and locally administered (randomized)
+// addresses are skipped. Up/down state is ignored on purpose, since it flips
+// between Wi-Fi and Ethernet. Returns empty string if none qualifies.
+func selectStableMAC(interfaces []net.Interface) string {
+	sorted := make([]net.Interface, len(interfaces))
+	copy(sorted, interfaces)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, iface := range sorted {
 		// Skip loopback and interfaces without MAC address
 		if iface.Flags&net.FlagLoopback != 0 {
 			continue
@@ -58,17 +201,74 @@ func getPrimaryMAC() (string, error) {
 			continue
 		}
 
-		// Skip interfaces that are down
-		if iface.Flags&net.FlagUp == 0 {
+		// Skip randomized and software-assigned addresses
+		if iface.HardwareAddr[0]&0x02 != 0 {
+			continue
+		}
+
+		if isVirtualInterface(iface.Name) {
 			continue
 		}
 
-		// Found a valid interface
-		mac := iface.HardwareAddr.String()
-		if mac != "" {
-			return strings.ToLower(mac), nil
+		return strings.ToLower(iface.HardwareAddr.String())
+	}
+
+	return ""
+}
+
+// isVirtualInterface reports whether an interface name looks like a virtual, VPN or Bluetooth adapter.
+func isVirtualInterface(name string) bool {
+	lower := strings.ToLower(name)
+	for _, marker := range virtualInterfaceMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateDeviceID moves the entry for this machine over to deviceID when the ID
+// has changed (e.g. after an update to MAC selection). The old entry is found by
+// hostname and must be unique; its paths.json entries are re-keyed to deviceID.
+// An overridden device ID is never migrated, so forcing an identity for testing
+// leaves the machine's real entry alone.
+// Returns the old ID, or empty string if nothing was migrated.
+func MigrateDeviceID(devicesConfig *models.DeviceConfig, pathsConfig *models.PathsConfig, deviceID string, hostname string) string {
+	if IsOverridden() {
+		return ""
+	}
+
+	oldIndex := -1
+	for i, d := range devicesConfig.Devices {
+		if d.ID == deviceID {
+			// Already registered under the current ID
+			return ""
+		}
+		if d.Hostname == hostname {
+			if oldIndex >= 0 {
+				// Ambiguous: several machines share the hostname
+				return ""
+			}
+			oldIndex = i
+		}
+	}
+	if oldIndex < 0 {
+		return ""
+	}
+
+	oldID := devicesConfig.Devices[oldIndex].ID
+	devicesConfig.Devices[oldIndex].ID = deviceID
+
+	if pathsConfig != nil {
+		for _, titlePaths := range pathsConfig.Paths {
+			if entry, ok := titlePaths[oldID]; ok {
+				if _, exists := titlePaths[deviceID]; !exists {
+					titlePaths[deviceID] = entry
+				}
+				delete(titlePaths, oldID)
+			}
 		}
 	}
 
-	return "", fmt.Errorf("no valid network interface found")
+	return oldID
 }
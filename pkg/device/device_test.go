@@ -0,0 +1,167 @@
+package device
+
+import (
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func mustMAC(t *testing.T, s string) net.HardwareAddr {
+	t.Helper()
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mac
+}
+
+func TestSelectStableMAC_IgnoresOrderAndUpState(t *testing.T) {
+	ethernet := net.Interface{Name: "Ethernet", HardwareAddr: mustMAC(t, "00:11:22:33:44:55")}
+	wifi := net.Interface{Name: "Wi-Fi", HardwareAddr: mustMAC(t, "00:aa:bb:cc:dd:ee"), Flags: net.FlagUp}
+
+	first := selectStableMAC([]net.Interface{wifi, ethernet})
+	second := selectStableMAC([]net.Interface{ethernet, wifi})
+
+	if first != "00:11:22:33:44:55" || second != first {
+		t.Errorf("Expected Ethernet MAC both times, got %q and %q", first, second)
+	}
+}
+
+func TestSelectStableMAC_SkipsVirtualAndRandomized(t *testing.T) {
+	interfaces := []net.Interface{
+		{Name: "lo", HardwareAddr: mustMAC(t, "00:00:00:00:00:01"), Flags: net.FlagLoopback},
+		{Name: "Bluetooth Network Connection", HardwareAddr: mustMAC(t, "00:10:00:00:00:01")},
+		{Name: "vEthernet (WSL)", HardwareAddr: mustMAC(t, "00:15:5d:00:00:01")},
+		{Name: "docker0", HardwareAddr: mustMAC(t, "00:42:ac:00:00:01")},
+		// Locally administered (randomized Wi-Fi address)
+		{Name: "Wi-Fi 2", HardwareAddr: mustMAC(t, "02:11:22:33:44:55")},
+		{Name: "Wi-Fi", HardwareAddr: mustMAC(t, "00:aa:bb:cc:dd:ee")},
+	}
+
+	if got := selectStableMAC(interfaces); got != "00:aa:bb:cc:dd:ee" {
+		t.Errorf("Expected physical Wi-Fi MAC, got %q", got)
+	}
+}
+
+func TestSelectStableMAC_NoneQualifies(t *testing.T) {
+	interfaces := []net.Interface{
+		{Name: "tun0"},
+		{Name: "vmnet1", HardwareAddr: mustMAC(t, "00:50:56:c0:00:01")},
+	}
+
+	if got := selectStableMAC(interfaces); got != "" {
+		t.Errorf("Expected no MAC, got %q", got)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	uuid, err := newUUID()
+	if err != nil {
+		t.Fatalf("newUUID failed: %v", err)
+	}
+
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !pattern.MatchString(uuid) {
+		t.Errorf("Unexpected UUID format: %s", uuid)
+	}
+}
+
+func TestMigrateDeviceID(t *testing.T) {
+	devicesConfig := &models.DeviceConfig{Devices: []models.Device{
+		{ID: "oldid0000000", Hostname: "laptop"},
+		{ID: "other0000000", Hostname: "desktop"},
+	}}
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {
+			"oldid0000000": {Paths: []string{"C:\\th08\\score.dat"}},
+			"other0000000": {Paths: []string{"D:\\th08\\score.dat"}},
+		},
+	}}
+
+	oldID := MigrateDeviceID(devicesConfig, pathsConfig, "newid0000000", "laptop")
+	if oldID != "oldid0000000" {
+		t.Fatalf("Expected migration from oldid0000000, got %q", oldID)
+	}
+
+	if devicesConfig.Devices[0].ID != "newid0000000" {
+		t.Errorf("Expected device entry to be re-keyed, got %s", devicesConfig.Devices[0].ID)
+	}
+	if _, ok := pathsConfig.Paths["th08"]["newid0000000"]; !ok {
+		t.Error("Expected paths to move to the new ID")
+	}
+	if _, ok := pathsConfig.Paths["th08"]["oldid0000000"]; ok {
+		t.Error("Expected old ID to be removed from paths")
+	}
+	if _, ok := pathsConfig.Paths["th08"]["other0000000"]; !ok {
+		t.Error("Expected other devices to be untouched")
+	}
+}
+
+func TestMigrateDeviceID_NoOp(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []models.Device
+	}{
+		{"Already registered", []models.Device{{ID: "newid0000000", Hostname: "laptop"}, {ID: "oldid0000000", Hostname: "laptop"}}},
+		{"Ambiguous hostname", []models.Device{{ID: "a00000000000", Hostname: "laptop"}, {ID: "b00000000000", Hostname: "laptop"}}},
+		{"Unknown hostname", []models.Device{{ID: "oldid0000000", Hostname: "desktop"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devicesConfig := &models.DeviceConfig{Devices: tt.devices}
+			if oldID := MigrateDeviceID(devicesConfig, nil, "newid0000000", "laptop"); oldID != "" {
+				t.Errorf("Expected no migration, got %q", oldID)
+			}
+		})
+	}
+}
+
+func TestGetDeviceID_Override(t *testing.T) {
+	t.Setenv(EnvDeviceID, "ABCDEF012345")
+
+	id, _, hostname, err := GetDeviceID()
+	if err != nil {
+		t.Fatalf("GetDeviceID failed: %v", err)
+	}
+	if id != "abcdef012345" {
+		t.Errorf("Expected env override, got %s", id)
+	}
+	if hostname == "" {
+		t.Error("Expected hostname to still be returned")
+	}
+
+	// The flag takes precedence over the environment variable
+	OverrideID = "0123456789ab"
+	defer func() { OverrideID = "" }()
+
+	id, _, _, err = GetDeviceID()
+	if err != nil {
+		t.Fatalf("GetDeviceID failed: %v", err)
+	}
+	if id != "0123456789ab" {
+		t.Errorf("Expected flag override, got %s", id)
+	}
+}
+
+func TestGetDeviceID_InvalidOverride(t *testing.T) {
+	for _, value := range []string{"abc", "0123456789abc", "zzzzzzzzzzzz"} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv(EnvDeviceID, value)
+			if _, _, _, err := GetDeviceID(); err == nil {
+				t.Errorf("Expected error for override %q", value)
+			}
+		})
+	}
+}
+
+func TestMigrateDeviceID_SkippedWhenOverridden(t *testing.T) {
+	t.Setenv(EnvDeviceID, "0123456789ab")
+
+	devicesConfig := &models.DeviceConfig{Devices: []models.Device{{ID: "oldid0000000", Hostname: "laptop"}}}
+	if oldID := MigrateDeviceID(devicesConfig, nil, "0123456789ab", "laptop"); oldID != "" {
+		t.Errorf("Expected no migration with an override, got %q", oldID)
+	}
+}
@@ -0,0 +1,99 @@
+// Package hook runs the user commands configured as pre_sync / post_sync in rules.json
+// around each title of a pull or push, such as closing a game launcher before a push
+// or starting a cloud upload after a pull.
+//
+// A hook is an argument list, not a shell command line: the first element is the
+// program and the rest are its arguments, so paths with spaces need no quoting. The
+// placeholders {title}, {path} and {direction} are replaced in every element.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// PlaceholderTitle is replaced with the title code (e.g. th08)
+	PlaceholderTitle = "{title}"
+	// PlaceholderPath is replaced with the local save file path
+	PlaceholderPath = "{path}"
+	// PlaceholderDirection is replaced with "pull" or "push"
+	PlaceholderDirection = "{direction}"
+)
+
+// Timeout is how long a hook may run before it is killed.
+var Timeout = 5 * time.Minute
+
+// Vars are the values substituted for the placeholders.
+type Vars struct {
+	Title     string
+	Path      string
+	Direction string
+}
+
+// Result is the outcome of a hook that was started.
+type Result struct {
+	Args     []string // the command after placeholder substitution
+	Output   string   // combined stdout and stderr
+	ExitCode int      // -1 if the hook was killed on timeout
+	Duration time.Duration
+}
+
+// Expand returns args with the placeholders replaced by vars.
+func Expand(args []string, vars Vars) []string {
+	r := strings.NewReplacer(
+		PlaceholderTitle, vars.Title,
+		PlaceholderPath, vars.Path,
+		PlaceholderDirection, vars.Direction,
+	)
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = r.Replace(arg)
+	}
+	return expanded
+}
+
+// Run runs the hook args with the placeholders replaced by vars and waits for it.
+// A hook that exits non-zero or times out returns its Result together with an error;
+// one that cannot be started returns a nil Result.
+func Run(args []string, vars Vars) (*Result, error) {
+	if len(args) == 0 || args[0] == "" {
+		return nil, errors.New("hook has no command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	expanded := Expand(args, vars)
+	cmd := exec.CommandContext(ctx, expanded[0], expanded[1:]...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err := cmd.Run()
+	result := &Result{
+		Args:     expanded,
+		Output:   output.String(),
+		Duration: time.Since(start),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return result, nil
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		return result, fmt.Errorf("%s timed out after %s", expanded[0], Timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		return result, fmt.Errorf("%s exited with code %d", expanded[0], result.ExitCode)
+	default:
+		return nil, fmt.Errorf("failed to run %s: %w", expanded[0], err)
+	}
+}
@@ -0,0 +1,82 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as a hook: with HOOK_TEST_EXIT set it prints its
+// arguments and exits with that code instead of running the tests.
+func TestMain(m *testing.M) {
+	if code := os.Getenv("HOOK_TEST_EXIT"); code != "" {
+		if os.Getenv("HOOK_TEST_SLEEP") != "" {
+			time.Sleep(time.Minute)
+		}
+		fmt.Println(strings.Join(os.Args[1:], "|"))
+		n, _ := strconv.Atoi(code)
+		os.Exit(n)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExpand(t *testing.T) {
+	args := []string{"upload", "--name={title}-{direction}", "{path}", "{unknown}"}
+	got := Expand(args, Vars{Title: "th08", Path: `C:\Games\th08\score.dat`, Direction: "pull"})
+
+	want := []string{"upload", "--name=th08-pull", `C:\Games\th08\score.dat`, "{unknown}"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+	if args[1] != "--name={title}-{direction}" {
+		t.Error("Expected the configured args to be left unchanged")
+	}
+}
+
+func TestRun(t *testing.T) {
+	vars := Vars{Title: "th08", Path: "/saves/my score.dat", Direction: "push"}
+
+	t.Setenv("HOOK_TEST_EXIT", "0")
+	result, err := Run([]string{os.Args[0], "{title}", "{path}"}, vars)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if strings.TrimSpace(result.Output) != "th08|/saves/my score.dat" || result.ExitCode != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	t.Setenv("HOOK_TEST_EXIT", "3")
+	result, err = Run([]string{os.Args[0], "{direction}"}, vars)
+	if err == nil || result == nil || result.ExitCode != 3 {
+		t.Fatalf("Expected exit code 3 with an error, got %+v, %v", result, err)
+	}
+	if strings.TrimSpace(result.Output) != "push" {
+		t.Errorf("Expected the output of the failed hook, got %q", result.Output)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	old := Timeout
+	Timeout = 100 * time.Millisecond
+	t.Cleanup(func() { Timeout = old })
+
+	t.Setenv("HOOK_TEST_EXIT", "0")
+	t.Setenv("HOOK_TEST_SLEEP", "1")
+	result, err := Run([]string{os.Args[0]}, Vars{})
+	if err == nil || result == nil || result.ExitCode != -1 {
+		t.Errorf("Expected a timeout, got %+v, %v", result, err)
+	}
+}
+
+func TestRun_NotStarted(t *testing.T) {
+	if _, err := Run(nil, Vars{}); err == nil {
+		t.Error("Expected an error for an empty hook")
+	}
+	result, err := Run([]string{"thlocalsync-no-such-hook"}, Vars{})
+	if err == nil || result != nil {
+		t.Errorf("Expected a missing program to fail without a result, got %+v, %v", result, err)
+	}
+}
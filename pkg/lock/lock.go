@@ -0,0 +1,234 @@
+// Package lock implements the advisory lock file that keeps two thlocalsync runs
+// from writing to data/ and the vault at the same time.
+//
+// The lock is a file created exclusively in the data directory, holding the PID,
+// command and start time of its owner. The owner refreshes the file's mtime while
+// it runs, so a lock whose mtime has not moved for StaleAfter was left behind by a
+// run that was killed, and the next run takes it over.
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+const (
+	// FileName is the lock file's name inside the data directory
+	FileName = ".lock"
+
+	// StaleAfter is how long a lock may go without a refresh before it is taken over
+	StaleAfter = 2 * time.Minute
+
+	// refreshInterval is how often the owner refreshes the lock's mtime
+	refreshInterval = StaleAfter / 4
+)
+
+// ErrLocked is returned by Acquire when another run holds a fresh lock.
+var ErrLocked = errors.New("another thlocalsync run is in progress")
+
+// Info is the content of a lock file.
+type Info struct {
+	PID       int       `json:"pid"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Lock is a held lock file.
+type Lock struct {
+	path string
+	info Info
+
+	// Stale is the previous owner whose abandoned lock was taken over, or nil
+	Stale *Info
+
+	stop        chan struct{}
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+// Acquire creates the lock file in dir for command, taking over a stale one.
+// It returns an error wrapping ErrLocked, naming the owner, if another run holds it.
+// The lock is refreshed in the background until Release is called.
+func Acquire(dir, command string) (*Lock, error) {
+	if err := utils.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+
+	l := &Lock{
+		path: filepath.Join(dir, FileName),
+		info: Info{PID: os.Getpid(), Command: command, StartedAt: time.Now().UTC()},
+		stop: make(chan struct{}),
+	}
+	stale, err := acquire(l.path, l.info, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	l.Stale = stale
+
+	go l.refresh()
+	return l, nil
+}
+
+// acquire creates the lock file at path holding info. A lock file not refreshed within
+// StaleAfter before now is removed and returned as the stale owner; a fresh one fails.
+func acquire(path string, info Info, now time.Time) (*Info, error) {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock: %w", err)
+	}
+
+	var stale *Info
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", err)
+			}
+			return stale, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		fileInfo, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			// Released in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock file: %w", err)
+		}
+		if now.Sub(fileInfo.ModTime()) < StaleAfter {
+			return nil, lockedError(path)
+		}
+
+		staleFound()
+		owner, err := takeOver(path, now)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil {
+			stale = owner
+		}
+	}
+
+	// Another run took the lock between our attempts
+	return nil, lockedError(path)
+}
+
+// staleFound is called between finding a lock stale and taking it over; tests use it
+// to let another run take the lock over first.
+var staleFound = func() {}
+
+// takeOver removes the stale lock file at path and returns its owner, or nil if it was
+// released in the meantime. Another run may have taken the stale lock over since it was
+// found stale, so the file is first moved aside under a unique name, which only one run
+// can do, and checked there: a lock that turns out to be fresh is put back and the
+// takeover refused.
+func takeOver(path string, now time.Time) (*Info, error) {
+	aside := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), rand.Uint64())
+	if err := os.Rename(path, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+	}
+
+	fileInfo, err := os.Stat(aside)
+	if err == nil && now.Sub(fileInfo.ModTime()) < StaleAfter {
+		// The lock of a run that took over first: give it back. A hard link never
+		// replaces a lock created since; storage without hard links falls back to rename.
+		if err := os.Link(aside, path); err == nil {
+			os.Remove(aside)
+		} else if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+			os.Rename(aside, path)
+		} else {
+			os.Remove(aside)
+		}
+		return nil, lockedError(path)
+	}
+
+	owner, readErr := Read(aside)
+	if readErr != nil {
+		owner = &Info{}
+	}
+	if err := os.Remove(aside); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+	}
+	return owner, nil
+}
+
+// lockedError describes the owner of the lock at path.
+func lockedError(path string) error {
+	owner, err := Read(path)
+	if err != nil {
+		return fmt.Errorf("%w (lock file %s)", ErrLocked, path)
+	}
+	return fmt.Errorf("%w: '%s' (PID %d) since %s (lock file %s)",
+		ErrLocked, owner.Command, owner.PID, owner.StartedAt.Local().Format("2006-01-02 15:04:05"), path)
+}
+
+// Read returns the content of the lock file at path.
+func Read(path string) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+	return &info, nil
+}
+
+// refresh bumps the lock file's mtime until the lock is released, so it never looks stale.
+func (l *Lock) refresh() {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Release stops refreshing the lock and removes the lock file, unless another run
+// has taken it over in the meantime. It is safe to call more than once.
+func (l *Lock) Release() error {
+	l.releaseOnce.Do(func() {
+		close(l.stop)
+
+		owner, err := Read(l.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				l.releaseErr = fmt.Errorf("failed to read lock file: %w", err)
+			}
+			return
+		}
+		if owner.PID != l.info.PID || !owner.StartedAt.Equal(l.info.StartedAt) {
+			return
+		}
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			l.releaseErr = fmt.Errorf("failed to remove lock file: %w", err)
+		}
+	})
+	return l.releaseErr
+}
@@ -0,0 +1,177 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire_RefusesFreshLock(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := Acquire(dir, "thlocalsync push")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if first.Stale != nil {
+		t.Errorf("Expected no stale owner, got %+v", first.Stale)
+	}
+
+	info, err := Read(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if info.PID != os.Getpid() || info.Command != "thlocalsync push" || info.StartedAt.IsZero() {
+		t.Errorf("Unexpected lock content: %+v", info)
+	}
+
+	if _, err := Acquire(dir, "thlocalsync pull"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked while the lock is held, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := first.Release(); err != nil {
+		t.Errorf("Expected a second Release to be a no-op, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+
+	second, err := Acquire(dir, "thlocalsync pull")
+	if err != nil {
+		t.Fatalf("Expected Acquire to succeed after Release, got %v", err)
+	}
+	second.Release()
+}
+
+func TestAcquire_TakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	now := time.Date(2025, 4, 11, 12, 0, 0, 0, time.UTC)
+
+	dead := Info{PID: 4242, Command: "thlocalsync sync", StartedAt: now.Add(-time.Hour)}
+	if _, err := acquire(path, dead, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not refreshed for less than StaleAfter: still held
+	recent := now.Add(-StaleAfter / 2)
+	if err := os.Chtimes(path, recent, recent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acquire(path, Info{PID: 1}, now); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked for a fresh lock, got %v", err)
+	}
+
+	old := now.Add(-2 * StaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := acquire(path, Info{PID: 1, Command: "thlocalsync pull"}, now)
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+	if stale == nil || stale.PID != dead.PID || stale.Command != dead.Command {
+		t.Errorf("Expected the stale owner %+v, got %+v", dead, stale)
+	}
+
+	info, err := Read(path)
+	if err != nil || info.PID != 1 {
+		t.Errorf("Expected the lock to be rewritten for the new owner, got %+v, %v", info, err)
+	}
+}
+
+func TestRelease_KeepsTakenOverLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+
+	l, err := Acquire(dir, "thlocalsync watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another run took the lock over while this one was stalled
+	os.Remove(path)
+	other := Info{PID: l.info.PID + 1, Command: "thlocalsync pull", StartedAt: time.Now().UTC()}
+	if _, err := acquire(path, other, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	info, err := Read(path)
+	if err != nil || info.PID != other.PID {
+		t.Errorf("Expected the other run's lock to be kept, got %+v, %v", info, err)
+	}
+}
+
+func TestAcquire_ConcurrentTakeOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	now := time.Now()
+
+	if _, err := acquire(path, Info{PID: 4242}, now); err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-2 * StaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run A takes the stale lock over after run B found it stale, before B acts on it
+	var errA error
+	staleFound = func() {
+		staleFound = func() {}
+		_, errA = acquire(path, Info{PID: 1}, now)
+	}
+	t.Cleanup(func() { staleFound = func() {} })
+
+	if _, err := acquire(path, Info{PID: 2}, now); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected run B to be refused, got %v", err)
+	}
+	if errA != nil {
+		t.Fatalf("Expected run A to take the stale lock over, got %v", errA)
+	}
+	if info, err := Read(path); err != nil || info.PID != 1 {
+		t.Errorf("Expected run A to keep the lock, got %+v, %v", info, err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected only the lock file to be left, got %v", entries)
+	}
+
+	// Concurrent runs on a stale lock: exactly one may hold it
+	for i := 0; i < 20; i++ {
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		const runs = 4
+		results := make(chan error, runs)
+		start := make(chan struct{})
+		for pid := 10; pid < 10+runs; pid++ {
+			go func() {
+				<-start
+				_, err := acquire(path, Info{PID: pid}, now)
+				results <- err
+			}()
+		}
+		close(start)
+
+		held := 0
+		for range runs {
+			err := <-results
+			switch {
+			case err == nil:
+				held++
+			case !errors.Is(err, ErrLocked):
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if held != 1 {
+			t.Fatalf("Expected exactly one run to hold the lock, got %d", held)
+		}
+	}
+}
@@ -6,16 +6,32 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
+	"github.com/otagao/touhou-local-sync/pkg/paths"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
 const (
-	// LogDir is the relative path to the log directory from the executable
-	LogDir = "logs"
+	// LogDir is the relative path to the log directory from the home directory
+	LogDir = paths.LogDirName
+
+	// DefaultRetentionDays is how many days of log files CleanupOldLogs keeps by default
+	DefaultRetentionDays = 90
+
+	// DefaultMaxFileSize is the default size (in bytes) after which a day's log continues in a new file
+	DefaultMaxFileSize = 1024 * 1024
 )
 
+// MaxFileSize caps the size of a single log file in bytes. When today's file would
+// exceed it, entries go to YYYY-MM-DD.1.log, YYYY-MM-DD.2.log, and so on.
+// Zero or negative disables the cap.
+var MaxFileSize int64 = DefaultMaxFileSize
+
+// logFilePattern matches log file names: YYYY-MM-DD.log or YYYY-MM-DD.N.log
+var logFilePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(\.\d+)?\.log$`)
+
 // Level represents the log level.
 type Level string
 
@@ -29,11 +45,71 @@ const (
 )
 
 // Entry represents a single log entry.
+// It is encoded as one flat JSON object; see MarshalJSON.
 type Entry struct {
 	Level   Level                  `json:"level"`
 	Time    time.Time              `json:"time"`
 	Message string                 `json:"msg"`
-	Fields  map[string]interface{} `json:",inline"`
+	Fields  map[string]interface{} `json:"-"`
+}
+
+// legacyFieldsKey is where older versions nested Fields in the JSON output.
+const legacyFieldsKey = "Fields"
+
+// MarshalJSON encodes the entry as a single flat object holding level, time, msg
+// and every key of Fields. On a key collision the base field wins and the
+// colliding Fields value is dropped, so level, time and msg are always reliable.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	flat := make(map[string]interface{}, len(e.Fields)+3)
+	for key, value := range e.Fields {
+		flat[key] = value
+	}
+	flat["level"] = e.Level
+	flat["time"] = e.Time
+	flat["msg"] = e.Message
+
+	return json.Marshal(flat)
+}
+
+// UnmarshalJSON decodes a flat object written by MarshalJSON: level, time and msg
+// fill the base fields and every other key goes to Fields. Lines from older
+// versions, which nested the fields under "Fields", are flattened the same way.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var base struct {
+		Level   Level     `json:"level"`
+		Time    time.Time `json:"time"`
+		Message string    `json:"msg"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return err
+	}
+
+	var flat map[string]interface{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		return err
+	}
+	delete(flat, "level")
+	delete(flat, "time")
+	delete(flat, "msg")
+
+	if legacy, ok := flat[legacyFieldsKey].(map[string]interface{}); ok {
+		delete(flat, legacyFieldsKey)
+		for key, value := range legacy {
+			if _, exists := flat[key]; !exists {
+				flat[key] = value
+			}
+		}
+	}
+
+	e.Level = base.Level
+	e.Time = base.Time
+	e.Message = base.Message
+	e.Fields = nil
+	if len(flat) > 0 {
+		e.Fields = flat
+	}
+
+	return nil
 }
 
 // Logger handles logging operations.
@@ -43,18 +119,12 @@ type Logger struct {
 
 // New creates a new logger instance.
 func New() (*Logger, error) {
-	// Get executable path
-	exePath, err := os.Executable()
+	// Log directory is <home>/logs unless moved by --log-dir or THLOCALSYNC_LOG_DIR
+	logDir, err := paths.GetLogDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get executable path: %w", err)
+		return nil, err
 	}
 
-	// Get directory containing executable
-	exeDir := filepath.Dir(exePath)
-
-	// Log directory is <exe_dir>/logs
-	logDir := filepath.Join(exeDir, LogDir)
-
 	// Ensure log directory exists
 	if err := utils.EnsureDir(logDir); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
@@ -63,10 +133,59 @@ func New() (*Logger, error) {
 	return &Logger{logDir: logDir}, nil
 }
 
-// getLogFilePath returns the path to the log file for the current date.
-func (l *Logger) getLogFilePath() string {
+// getLogFilePath returns the path to the log file for the current date
+// that can take another n bytes without exceeding MaxFileSize.
+//
+// Full files are never renamed; writing simply moves on to the next numbered
+// file. Two processes appending at the same time may both pick the same file,
+// which can overshoot the cap slightly but never loses or interleaves entries.
+func (l *Logger) getLogFilePath(n int) string {
 	today := time.Now().Format("2006-01-02")
-	return filepath.Join(l.logDir, today+".log")
+	path := filepath.Join(l.logDir, today+".log")
+	if MaxFileSize <= 0 {
+		return path
+	}
+
+	for i := 1; ; i++ {
+		info, err := os.Stat(path)
+		// An empty file takes any entry, so an oversized entry cannot loop forever
+		if err != nil || info.Size() == 0 || info.Size()+int64(n) <= MaxFileSize {
+			return path
+		}
+		path = filepath.Join(l.logDir, fmt.Sprintf("%s.%d.log", today, i))
+	}
+}
+
+// CleanupOldLogs removes log files dated more than retentionDays days ago.
+// Zero or negative retentionDays keeps every file. Files already removed by
+// another process are ignored.
+func (l *Logger) CleanupOldLogs(retentionDays int) error {
+	if retentionDays <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -retentionDays).Format("2006-01-02")
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		match := logFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil || match[1] >= cutoff {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(l.logDir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old log: %w", err)
+		}
+	}
+
+	return nil
 }
 
 // log writes a log entry to the appropriate log file.
@@ -88,7 +207,7 @@ func (l *Logger) log(level Level, message string, fields map[string]interface{})
 	data = append(data, '\n')
 
 	// Open log file in append mode
-	logFile := l.getLogFilePath()
+	logFile := l.getLogFilePath(len(data))
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLog_RotatesWhenMaxFileSizeExceeded(t *testing.T) {
+	oldMax := MaxFileSize
+	MaxFileSize = 200
+	defer func() { MaxFileSize = oldMax }()
+
+	l := &Logger{logDir: t.TempDir()}
+	for i := 0; i < 5; i++ {
+		if err := l.Info("rotation_test", map[string]interface{}{"i": i}); err != nil {
+			t.Fatalf("Info failed: %v", err)
+		}
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, name := range []string{today + ".log", today + ".1.log"} {
+		info, err := os.Stat(filepath.Join(l.logDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() > MaxFileSize {
+			t.Errorf("%s is %d bytes, exceeds cap %d", name, info.Size(), MaxFileSize)
+		}
+	}
+}
+
+func TestLog_OversizedEntryStillWritten(t *testing.T) {
+	oldMax := MaxFileSize
+	MaxFileSize = 10
+	defer func() { MaxFileSize = oldMax }()
+
+	l := &Logger{logDir: t.TempDir()}
+	if err := l.Info("an entry larger than the cap", nil); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if err := l.Info("another one", nil); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, name := range []string{today + ".log", today + ".1.log"} {
+		if _, err := os.Stat(filepath.Join(l.logDir, name)); err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+	}
+}
+
+func TestCleanupOldLogs(t *testing.T) {
+	dir := t.TempDir()
+	l := &Logger{logDir: dir}
+
+	old := time.Now().AddDate(0, 0, -40).Format("2006-01-02")
+	recent := time.Now().AddDate(0, 0, -5).Format("2006-01-02")
+
+	files := map[string]bool{ // name -> expected to remain
+		old + ".log":    false,
+		old + ".1.log":  false,
+		recent + ".log": true,
+		"notes.txt":     true,
+		"old.log":       true,
+	}
+	for name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.CleanupOldLogs(30); err != nil {
+		t.Fatalf("CleanupOldLogs failed: %v", err)
+	}
+
+	for name, remain := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if remain && err != nil {
+			t.Errorf("Expected %s to remain: %v", name, err)
+		}
+		if !remain && err == nil {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+}
+
+func TestCleanupOldLogs_ZeroKeepsEverything(t *testing.T) {
+	dir := t.TempDir()
+	l := &Logger{logDir: dir}
+
+	name := time.Now().AddDate(-1, 0, 0).Format("2006-01-02") + ".log"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.CleanupOldLogs(0); err != nil {
+		t.Fatalf("CleanupOldLogs failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+		t.Errorf("Expected %s to remain: %v", name, err)
+	}
+}
+
+func TestReadEntries_FiltersAndSkipsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	l := &Logger{logDir: dir}
+
+	lines := `{"level":"INFO","time":"2025-01-01T10:00:00Z","msg":"pull","Fields":{"title":"th08"}}
+not json
+{"level":"ERROR","time":"2025-01-01T11:00:00Z","msg":"push_error","Fields":{"title":"th08"}}
+{"level":"ERROR","time":"2025-01-01T12:00:00Z","msg":"push_error","Fields":{"title":"th10"}}
+`
+	if err := os.WriteFile(filepath.Join(dir, "2025-01-01.log"), []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Not a log file
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, parseErrors, err := l.ReadEntries(Filter{Title: "th08", Level: LevelError})
+	if err != nil {
+		t.Fatalf("ReadEntries failed: %v", err)
+	}
+	if len(parseErrors) != 1 || parseErrors[0].Line != 2 {
+		t.Errorf("Expected one parse error on line 2, got %v", parseErrors)
+	}
+	if len(records) != 1 || records[0].Message != "push_error" {
+		t.Fatalf("Expected the th08 push_error entry, got %+v", records)
+	}
+}
+
+func TestReadEntries_OrderSinceAndLimit(t *testing.T) {
+	dir := t.TempDir()
+	l := &Logger{logDir: dir}
+
+	files := map[string]string{
+		"2024-12-31.log":   `{"level":"INFO","time":"2024-12-31T10:00:00Z","msg":"old"}` + "\n",
+		"2025-01-02.log":   `{"level":"INFO","time":"2025-01-02T10:00:00Z","msg":"first"}` + "\n",
+		"2025-01-02.1.log": `{"level":"INFO","time":"2025-01-02T11:00:00Z","msg":"second"}` + "\n",
+		"2025-01-03.log":   `{"level":"INFO","time":"2025-01-03T10:00:00Z","msg":"third"}` + "\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	since := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	records, _, err := l.ReadEntries(Filter{Since: since, Limit: 2})
+	if err != nil {
+		t.Fatalf("ReadEntries failed: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].Message != "second" || records[1].Message != "third" {
+		t.Errorf("Expected [second third], got [%s %s]", records[0].Message, records[1].Message)
+	}
+}
+
+func TestEntry_MarshalJSONIsFlat(t *testing.T) {
+	entry := Entry{
+		Level:   LevelInfo,
+		Time:    time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		Message: "pull",
+		Fields:  map[string]interface{}{"title": "th08", "msg": "collides"},
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var flat map[string]interface{}
+	if err := json.Unmarshal(data, &flat); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if flat["title"] != "th08" {
+		t.Errorf("Expected title at top level, got %s", data)
+	}
+	if _, nested := flat["Fields"]; nested {
+		t.Errorf("Expected no nested Fields key, got %s", data)
+	}
+	// Base field wins on collision
+	if flat["msg"] != "pull" {
+		t.Errorf("Expected msg %q, got %v", "pull", flat["msg"])
+	}
+}
+
+func TestEntry_RoundTrip(t *testing.T) {
+	entry := Entry{
+		Level:   LevelError,
+		Time:    time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC),
+		Message: "push_error",
+		Fields:  map[string]interface{}{"title": "th08", "size": float64(1024)},
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded Entry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(entry, decoded) {
+		t.Errorf("Round trip mismatch:\n  got  %+v\n  want %+v", decoded, entry)
+	}
+}
+
+func TestEntry_UnmarshalLegacyNestedFields(t *testing.T) {
+	line := `{"level":"INFO","time":"2025-01-01T10:00:00Z","msg":"pull","Fields":{"title":"th08"}}`
+
+	var entry Entry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if entry.Fields["title"] != "th08" {
+		t.Errorf("Expected legacy fields to be flattened, got %+v", entry.Fields)
+	}
+	if _, nested := entry.Fields["Fields"]; nested {
+		t.Errorf("Expected no nested Fields key, got %+v", entry.Fields)
+	}
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Filter selects entries returned by ReadEntries. Zero values match everything.
+type Filter struct {
+	Title string    // Fields["title"] must equal this
+	Level Level     // Entry level must equal this
+	Since time.Time // Entry time must not be before this
+	Limit int       // Keep only the most recent Limit entries
+}
+
+// Record is a parsed log line together with its original JSON.
+type Record struct {
+	Entry
+	Raw string
+}
+
+// ParseError describes a log line that could not be parsed.
+type ParseError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// ReadEntries reads every log file in the log directory and returns the entries
+// matching filter, oldest first. Malformed lines are skipped and reported as ParseErrors.
+func (l *Logger) ReadEntries(filter Filter) ([]Record, []*ParseError, error) {
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	var sinceDate string
+	if !filter.Since.IsZero() {
+		sinceDate = filter.Since.Local().Format("2006-01-02")
+	}
+
+	var records []Record
+	var parseErrors []*ParseError
+	for _, entry := range entries {
+		match := logFilePattern.FindStringSubmatch(entry.Name())
+		if !entry.Type().IsRegular() || match == nil {
+			continue
+		}
+		// File names carry the local date, so older files cannot hold matching entries
+		if sinceDate != "" && match[1] < sinceDate {
+			continue
+		}
+
+		fileRecords, fileErrors, err := readLogFile(filepath.Join(l.logDir, entry.Name()), filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, fileRecords...)
+		parseErrors = append(parseErrors, fileErrors...)
+	}
+
+	// Rotated files (YYYY-MM-DD.N.log) do not sort by name, so order by entry time
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+
+	return records, parseErrors, nil
+}
+
+// readLogFile parses one JSON Lines log file and returns the entries matching filter.
+func readLogFile(path string, filter Filter) ([]Record, []*ParseError, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	var parseErrors []*ParseError
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			parseErrors = append(parseErrors, &ParseError{File: filepath.Base(path), Line: lineNum, Err: err})
+			continue
+		}
+
+		if !filter.matches(&entry) {
+			continue
+		}
+		records = append(records, Record{Entry: entry, Raw: string(line)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read log file %s: %w", filepath.Base(path), err)
+	}
+
+	return records, parseErrors, nil
+}
+
+// matches reports whether entry passes the title, level and since conditions.
+func (f Filter) matches(entry *Entry) bool {
+	if f.Level != "" && entry.Level != f.Level {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if f.Title != "" {
+		title, _ := entry.Fields["title"].(string)
+		if title != f.Title {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Package manifest records the known-good state of each vault main file.
+//
+// After every write by thlocalsync the file's size, mtime and hash are stored in
+// <vault>/manifest.json, so later checks can tell silent corruption (same size and
+// mtime, different contents) from legitimate changes.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// FileName is the manifest file name under the vault root.
+const FileName = "manifest.json"
+
+// Entry is the recorded state of one title/slot main file.
+type Entry struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mtime"`
+	Hash      string    `json:"hash"`
+	UpdatedAt time.Time `json:"updated_at"` // when the entry was recorded
+}
+
+// Manifest maps title -> slot -> Entry.
+type Manifest struct {
+	Titles map[string]map[string]Entry `json:"titles"`
+}
+
+// GetPath returns the path to the vault manifest.
+// Example: <vault>/manifest.json
+func GetPath() (string, error) {
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vaultDir, FileName), nil
+}
+
+// Load reads the manifest at path. A missing file yields an empty manifest.
+// A corrupted file is backed up and reported, like the config files.
+func Load(path string) (*Manifest, error) {
+	m := &Manifest{Titles: make(map[string]map[string]Entry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		backupPath := path + ".backup-" + time.Now().Format("20060102-150405")
+		_ = utils.AtomicCopy(path, backupPath)
+		return nil, fmt.Errorf("failed to parse manifest (backed up to %s): %w", backupPath, err)
+	}
+	if m.Titles == nil {
+		m.Titles = make(map[string]map[string]Entry)
+	}
+
+	return m, nil
+}
+
+// Save writes the manifest to path atomically.
+func (m *Manifest) Save(path string) error {
+	if err := utils.EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create vault directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	// Write to temp file first
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// Atomic rename
+	if err := utils.SafeRename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// Set records meta as the known-good state of a title's slot.
+func (m *Manifest) Set(title, slot string, meta *models.FileMetadata) {
+	slots, ok := m.Titles[title]
+	if !ok {
+		slots = make(map[string]Entry)
+		m.Titles[title] = slots
+	}
+	slots[slot] = Entry{
+		Size:      meta.Size,
+		ModTime:   meta.ModTime.UTC(),
+		Hash:      meta.Hash,
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// Lookup returns the recorded state of a title's slot.
+func (m *Manifest) Lookup(title, slot string) (Entry, bool) {
+	entry, ok := m.Titles[title][slot]
+	return entry, ok
+}
+
+// Update records meta for a title's slot in the vault manifest.
+func Update(title, slot string, meta *models.FileMetadata) error {
+	path, err := GetPath()
+	if err != nil {
+		return err
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	m.Set(title, slot, meta)
+	return m.Save(path)
+}
+
+// Check compares meta against a recorded entry and describes any mismatch.
+// Equal size and mtime with a different hash means the contents changed silently
+// (bit-rot); other differences mean the file was replaced outside thlocalsync.
+// An entry recorded with another hash algorithm is compared by rehashing meta.Path with it.
+// Returns "" when meta matches the entry.
+func Check(entry Entry, meta *models.FileMetadata) string {
+	if utils.RehashLike(meta.Path, meta.Hash, entry.Hash) == entry.Hash {
+		return ""
+	}
+	if meta.Size == entry.Size && meta.ModTime.Equal(entry.ModTime) {
+		return "contents changed without a size or mtime change (possible corruption)"
+	}
+	return "file changed outside thlocalsync since the last recorded write"
+}
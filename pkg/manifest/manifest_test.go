@@ -0,0 +1,100 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault", FileName)
+	mtime := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of missing manifest failed: %v", err)
+	}
+	m.Set("th08", "main", &models.FileMetadata{Size: 10, ModTime: mtime, Hash: "aaa"})
+	m.Set("th08", "scoring", &models.FileMetadata{Size: 20, ModTime: mtime, Hash: "bbb"})
+	if err := m.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	entry, ok := loaded.Lookup("th08", "scoring")
+	if !ok {
+		t.Fatal("Expected th08/scoring entry")
+	}
+	if entry.Size != 20 || entry.Hash != "bbb" || !entry.ModTime.Equal(mtime) {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if _, ok := loaded.Lookup("th10", "main"); ok {
+		t.Error("Expected no entry for unrecorded title")
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Expected temp file to be renamed away")
+	}
+}
+
+func TestLoad_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for corrupted manifest")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	mtime := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC)
+	entry := Entry{Size: 10, ModTime: mtime, Hash: "aaa"}
+
+	tests := []struct {
+		name    string
+		meta    *models.FileMetadata
+		wantMsg bool
+	}{
+		{"Matches", &models.FileMetadata{Size: 10, ModTime: mtime, Hash: "aaa"}, false},
+		{"Silent change", &models.FileMetadata{Size: 10, ModTime: mtime, Hash: "bbb"}, true},
+		{"Replaced", &models.FileMetadata{Size: 12, ModTime: mtime.Add(time.Hour), Hash: "bbb"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(entry, tt.meta); (got != "") != tt.wantMsg {
+				t.Errorf("Check() = %q, want message=%v", got, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestCheck_OtherHashAlgorithm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "score.dat")
+	if err := os.WriteFile(path, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := utils.CalculateFileHashWith(path, utils.HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := utils.CalculateFileHashWith(path, utils.HashBLAKE3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry written before hash_algo changed must not be reported as corrupted
+	entry := Entry{Size: 4, Hash: recorded}
+	if got := Check(entry, &models.FileMetadata{Path: path, Size: 4, Hash: current}); got != "" {
+		t.Errorf("Expected match across hash algorithms, got %q", got)
+	}
+}
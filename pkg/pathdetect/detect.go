@@ -5,34 +5,43 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/console"
 	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
 // DetectResult represents the result of detecting save files.
 type DetectResult struct {
 	Candidates []models.DetectCandidate // Found candidates
-	NotFound   []KnownTitle              // Titles not found
+	NotFound   []KnownTitle             // Titles not found
+
+	// GameDirTruncated reports that the game directory search stopped after
+	// gameDirSearchLimit entries
+	GameDirTruncated bool
 }
 
+// DetectJobs is the maximum number of titles searched at once by DetectSaveFiles.
+// Set via detect --jobs; <= 0 means runtime.NumCPU().
+var DetectJobs = 0
+
 // DetectSaveFiles searches for save files using known patterns.
+// Without gameDirOverride the user is asked for a game directory first, unless
+// promptGameDir is false; the titles are then searched in parallel and the results
+// are returned in release order.
 // Returns candidates found and titles not found.
-func DetectSaveFiles(gameDirOverride string) (*DetectResult, error) {
-	result := &DetectResult{
-		Candidates: []models.DetectCandidate{},
-		NotFound:   []KnownTitle{},
-	}
-
+func DetectSaveFiles(gameDirOverride string, promptGameDir bool) (*DetectResult, error) {
 	titles := GetKnownTitles()
 
 	// Ask user for game directory if any title uses it
 	var gameDir string
 	if gameDirOverride != "" {
 		gameDir = gameDirOverride
-	} else {
+	} else if promptGameDir {
 		// Check if any title needs game directory
 		needGameDir := false
 		for _, title := range titles {
@@ -42,7 +51,9 @@ func DetectSaveFiles(gameDirOverride string) (*DetectResult, error) {
 			}
 		}
 
-		if needGameDir {
+		if needGameDir && !utils.IsInteractive() {
+			fmt.Println("Warning: stdin is not interactive, skipping game directory prompt (use --gamedir)")
+		} else if needGameDir {
 			fmt.Println("Some titles may be installed in a game directory.")
 			fmt.Print("Enter game directory path (or press Enter to skip): ")
 			reader := bufio.NewReader(os.Stdin)
@@ -53,63 +64,213 @@ func DetectSaveFiles(gameDirOverride string) (*DetectResult, error) {
 		}
 	}
 
+	stopSpinner := console.StartSpinner("searching known locations...")
+	result := Detect(gameDir)
+	stopSpinner()
+
+	if result.GameDirTruncated {
+		fmt.Printf("Warning: stopped searching %s after %d entries; pass a narrower --gamedir or a smaller --depth\n", gameDir, gameDirSearchLimit)
+	}
+
+	return result, nil
+}
+
+// Detect searches every known title for save files, including gameDir if it is not
+// empty, without prompting or printing anything but the --verbose traces. The titles
+// are searched in parallel and the results are returned in release order.
+func Detect(gameDir string) *DetectResult {
+	result := &DetectResult{
+		Candidates: []models.DetectCandidate{},
+		NotFound:   []KnownTitle{},
+	}
+
+	titles := GetKnownTitles()
+
 	// Search for each title
-	for _, title := range titles {
-		foundPaths := []string{}
+	defer timing.Start("detect")()
+
+	// Walk the game directory once for executables in nested folders
+	var gameDirHits map[string]string
+	if gameDir != "" {
+		gameDirHits, result.GameDirTruncated = SearchGameDirectoryForScoreDat(strings.Trim(gameDir, "\""), GameDirSearchDepth)
+	}
 
-		// Search in known patterns
-		foundPaths = append(foundPaths, SearchForTitle(title)...)
+	// Search the titles in parallel; verbose traces are per title, so they run one at a time
+	jobs := DetectJobs
+	if console.IsVerbose() {
+		jobs = 1
+	}
+	perTitle := detectTitlesParallel(titles, jobs, func(title KnownTitle) []models.DetectCandidate {
+		return detectTitle(title, gameDir, gameDirHits)
+	})
+
+	// Assemble in release order regardless of which worker finished first
+	for i, title := range titles {
+		if len(perTitle[i]) > 0 {
+			result.Candidates = append(result.Candidates, perTitle[i]...)
+		} else {
+			result.NotFound = append(result.NotFound, title)
+		}
+	}
 
-		// Search in game directory if provided
-		if gameDir != "" && title.UseGameDir {
-			// Clean the game directory path (remove quotes if present)
-			cleanGameDir := strings.Trim(gameDir, "\"")
+	return result
+}
 
-			// Look for score file in game directory directly
-			scorePath := filepath.Join(cleanGameDir, title.FileName)
-			if FileExists(scorePath) {
-				foundPaths = append(foundPaths, scorePath)
-			}
+// detectTitle searches the known locations, the game directory and the Steam paths
+// for one title and returns its candidates. gameDirHits holds the score files found
+// next to executables deeper in the game directory.
+func detectTitle(title KnownTitle, gameDir string, gameDirHits map[string]string) []models.DetectCandidate {
+	foundPaths := []string{}
+	console.Verbosef("  %s:\n", FormatTitleDisplay(title.Code, title.Name))
+
+	// Search in known patterns
+	foundPaths = append(foundPaths, SearchForTitle(title)...)
+
+	// Search in game directory if provided
+	if gameDir != "" && title.UseGameDir {
+		// Clean the game directory path (remove quotes if present)
+		cleanGameDir := strings.Trim(gameDir, "\"")
+
+		// Look for score file in game directory directly
+		scorePath := filepath.Join(cleanGameDir, title.SaveFileName())
+		if probePath(scorePath) {
+			foundPaths = append(foundPaths, scorePath)
+		}
 
-			// Check for title-specific subdirectory (e.g., gameDir/th06/)
-			titleDir := filepath.Join(cleanGameDir, title.Code)
-			scorePathInTitle := filepath.Join(titleDir, title.FileName)
-			if FileExists(scorePathInTitle) {
-				foundPaths = append(foundPaths, scorePathInTitle)
-			}
+		// Check for title-specific subdirectory (e.g., gameDir/th06/)
+		titleDir := filepath.Join(cleanGameDir, title.Code)
+		scorePathInTitle := filepath.Join(titleDir, title.SaveFileName())
+		if probePath(scorePathInTitle) {
+			foundPaths = append(foundPaths, scorePathInTitle)
+		}
 
-			// Also check for game name subdirectory (e.g., gameDir/東方紅魔郷/)
-			if title.Name != "" {
-				nameDir := filepath.Join(cleanGameDir, title.Name)
-				scorePathInName := filepath.Join(nameDir, title.FileName)
-				if FileExists(scorePathInName) {
-					foundPaths = append(foundPaths, scorePathInName)
-				}
+		// Also check for game name subdirectory (e.g., gameDir/東方紅魔郷/)
+		if title.Name != "" {
+			nameDir := filepath.Join(cleanGameDir, title.Name)
+			scorePathInName := filepath.Join(nameDir, title.SaveFileName())
+			if probePath(scorePathInName) {
+				foundPaths = append(foundPaths, scorePathInName)
 			}
 		}
 
-		// Create candidates for each found path
-		if len(foundPaths) > 0 {
-			for _, path := range foundPaths {
-				// Get metadata
-				meta, err := sync.GetFileMetadata(path)
-				if err != nil {
-					continue
-				}
-
-				candidate := models.DetectCandidate{
-					Title:    title.Code,
-					Path:     path,
-					Metadata: meta,
-				}
-				result.Candidates = append(result.Candidates, candidate)
+		// Score file next to an executable found deeper in the game directory
+		if hit, ok := gameDirHits[title.Code]; ok && !containsPath(foundPaths, hit) {
+			console.Verbosef("    search %s: found %s\n", cleanGameDir, hit)
+			foundPaths = append(foundPaths, hit)
+		}
+	}
+
+	// Create candidates for each found path
+	var titleCandidates []models.DetectCandidate
+	for _, path := range foundPaths {
+		// Honor rules.json include/exclude
+		if !sync.AllowsFile(filepath.Base(path)) {
+			continue
+		}
+
+		// Get metadata
+		meta, err := sync.GetFileMetadata(path)
+		if err != nil {
+			continue
+		}
+
+		titleCandidates = append(titleCandidates, models.DetectCandidate{
+			Title:     title.Code,
+			Path:      path,
+			Metadata:  meta,
+			ReplayDir: DetectReplayDir(title.Code, path),
+			SyncDir:   DetectSyncDir(title.Code, path),
+			SetFiles:  DetectSetFiles(title.Code),
+		})
+	}
+
+	// Search Steam release paths (nothing is found if Steam is not installed)
+	for _, path := range SearchSteamForTitle(title) {
+		if !sync.AllowsFile(filepath.Base(path)) {
+			continue
+		}
+
+		meta, err := sync.GetFileMetadata(path)
+		if err != nil {
+			continue
+		}
+
+		// The Steam copy often resolves to the same file as the Roaming path
+		if isDuplicateCandidate(titleCandidates, path, meta) {
+			continue
+		}
+
+		titleCandidates = append(titleCandidates, models.DetectCandidate{
+			Title:     title.Code,
+			Path:      path,
+			Metadata:  meta,
+			ReplayDir: DetectReplayDir(title.Code, path),
+			SyncDir:   DetectSyncDir(title.Code, path),
+			SetFiles:  DetectSetFiles(title.Code),
+		})
+	}
+
+	return titleCandidates
+}
+
+// detectTitlesParallel runs detect for each title using at most jobs concurrent workers.
+// Results are returned in the same order as titles. jobs <= 0 means runtime.NumCPU().
+func detectTitlesParallel(titles []KnownTitle, jobs int, detect func(KnownTitle) []models.DetectCandidate) [][]models.DetectCandidate {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(titles) {
+		jobs = len(titles)
+	}
+
+	results := make([][]models.DetectCandidate, len(titles))
+	indexes := make(chan int)
+	done := make(chan struct{})
+
+	// Each worker writes only to the result slots of the indexes it receives
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range indexes {
+				results[i] = detect(titles[i])
 			}
-		} else {
-			result.NotFound = append(result.NotFound, title)
+			done <- struct{}{}
+		}()
+	}
+
+	for i := range titles {
+		indexes <- i
+	}
+	close(indexes)
+
+	for w := 0; w < jobs; w++ {
+		<-done
+	}
+
+	return results
+}
+
+// containsPath reports whether paths already holds path.
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if utils.NormalizePath(p) == utils.NormalizePath(path) {
+			return true
 		}
 	}
+	return false
+}
 
-	return result, nil
+// isDuplicateCandidate reports whether path is already among candidates,
+// either as the same path or as a file with the same content hash.
+func isDuplicateCandidate(candidates []models.DetectCandidate, path string, meta *models.FileMetadata) bool {
+	for _, c := range candidates {
+		if utils.NormalizePath(c.Path) == utils.NormalizePath(path) {
+			return true
+		}
+		if meta.Hash != "" && c.Metadata != nil && c.Metadata.Hash == meta.Hash {
+			return true
+		}
+	}
+	return false
 }
 
 // DisplayCandidates prints detected candidates in a user-friendly format.
@@ -135,13 +296,28 @@ func DisplayCandidates(candidates []models.DetectCandidate) {
 			fmt.Printf("ModTime: %s  ", candidate.Metadata.ModTime.Format("2006-01-02 15:04"))
 			fmt.Printf("Hash: %s\n", candidate.Metadata.HashShort())
 		}
+
+		if candidate.ReplayDir != "" {
+			fmt.Printf("      Replay: %s\n", candidate.ReplayDir)
+		}
+		if candidate.SyncDir != "" {
+			fmt.Printf("      Folder: %s\n", candidate.SyncDir)
+		}
+		if len(candidate.SetFiles) > 0 {
+			fmt.Printf("      Set: %s\n", strings.Join(candidate.SetFiles, ", "))
+		}
 	}
 	fmt.Println()
 }
 
 // PromptCandidateSelection asks user to select which candidates to register.
-// Returns indices of selected candidates.
+// Returns indices of selected candidates. Selects none when stdin is not interactive.
 func PromptCandidateSelection(count int) ([]int, error) {
+	if !utils.IsInteractive() {
+		fmt.Println("Warning: stdin is not interactive, skipping registration")
+		return []int{}, nil
+	}
+
 	fmt.Printf("Select to register: 1-%d (comma-separated), 'a' for all, 's' to skip: ", count)
 
 	reader := bufio.NewReader(os.Stdin)
@@ -218,10 +394,11 @@ func AddCandidateToConfig(candidate models.DetectCandidate, deviceID string, pat
 		}
 	}
 
-	// Check if path already exists
+	// Check if path already exists, however it is spelled
 	pathExists := false
+	candidatePath := utils.NormalizePath(candidate.Path)
 	for _, p := range pathEntry.Paths {
-		if utils.ExpandEnvPath(p) == candidate.Path {
+		if utils.NormalizePath(p) == candidatePath {
 			pathExists = true
 			break
 		}
@@ -235,26 +412,53 @@ func AddCandidateToConfig(candidate models.DetectCandidate, deviceID string, pat
 		}
 	}
 
+	// Register the replay folder as an additional syncable unit
+	if pathEntry.ReplayDir == "" && candidate.ReplayDir != "" {
+		pathEntry.ReplayDir = candidate.ReplayDir
+	}
+
+	// Register the title folder for titles whose options live next to the save file
+	if pathEntry.SyncDir == "" && candidate.SyncDir != "" {
+		pathEntry.SyncDir = candidate.SyncDir
+	}
+
+	// Register the files copied together with the save file
+	if len(pathEntry.SetFiles) == 0 && len(candidate.SetFiles) > 0 {
+		pathEntry.SetFiles = candidate.SetFiles
+	}
+
 	pathsConfig.Paths[title][deviceID] = pathEntry
 }
 
 // PromptManualPath asks user to manually enter a path for a title.
-// Returns the path or empty string if user skips.
-func PromptManualPath(title KnownTitle) (string, error) {
-	fmt.Printf("\nNo entry for %s (%s). Add manually? [y/N]: ", title.Code, title.Name)
+// With assumeYes the y/N confirmations are answered yes, but the path itself is still read;
+// an empty path skips the title.
+// Returns the path or empty string if user skips or stdin is not interactive.
+func PromptManualPath(title KnownTitle, assumeYes bool) (string, error) {
+	if !utils.IsInteractive() {
+		fmt.Printf("Warning: no entry for %s (%s), skipping manual entry (stdin is not interactive)\n", title.Code, title.Name)
+		return "", nil
+	}
 
 	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
-	if err != nil {
-		return "", fmt.Errorf("failed to read input: %w", err)
-	}
 
-	input = strings.TrimSpace(strings.ToLower(input))
-	if input != "y" && input != "yes" {
-		return "", nil
+	if assumeYes {
+		fmt.Printf("\nNo entry for %s (%s).\n", title.Code, title.Name)
+	} else {
+		fmt.Printf("\nNo entry for %s (%s). Add manually? [y/N]: ", title.Code, title.Name)
+
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" {
+			return "", nil
+		}
 	}
 
-	fmt.Printf("Enter absolute path for %s %s: ", title.Code, title.FileName)
+	fmt.Printf("Enter absolute path for %s %s: ", title.Code, title.SaveFileName())
 	pathInput, err := reader.ReadString('\n')
 	if err != nil {
 		return "", fmt.Errorf("failed to read path: %w", err)
@@ -275,6 +479,10 @@ func PromptManualPath(title KnownTitle) (string, error) {
 	exists, readable := utils.FileExists(path)
 	if !exists {
 		fmt.Printf("Warning: File does not exist: %s\n", path)
+		if assumeYes {
+			fmt.Println("Registering anyway (--yes)")
+			return path, nil
+		}
 		fmt.Print("Register anyway? [y/N]: ")
 		confirm, _ := reader.ReadString('\n')
 		confirm = strings.TrimSpace(strings.ToLower(confirm))
@@ -288,6 +496,10 @@ func PromptManualPath(title KnownTitle) (string, error) {
 		fmt.Println("Validated: OK")
 	}
 
+	if assumeYes {
+		return path, nil
+	}
+
 	fmt.Print("Register this path? [Y/n]: ")
 	confirm, _ := reader.ReadString('\n')
 	confirm = strings.TrimSpace(strings.ToLower(confirm))
@@ -318,10 +530,15 @@ func DetectBestshotDir(titleCode, scorePath string) string {
 }
 
 // DetectReplayDir returns the replay directory path if it exists.
-// Returns empty string if not found.
-func DetectReplayDir(scorePath string) string {
+// Returns empty string if the title has no replay directory or it is not found.
+func DetectReplayDir(titleCode, scorePath string) string {
+	title := GetTitleByCode(titleCode)
+	if title == nil || title.ReplayDir == "" {
+		return ""
+	}
+
 	scoreDir := filepath.Dir(scorePath)
-	replayDir := filepath.Join(scoreDir, "replay")
+	replayDir := filepath.Join(scoreDir, title.ReplayDir)
 
 	if utils.DirExists(replayDir) {
 		return replayDir
@@ -330,6 +547,33 @@ func DetectReplayDir(scorePath string) string {
 	return ""
 }
 
+// DetectSyncDir returns the folder holding the save file if the title syncs its whole folder.
+// Returns empty string if the title does not, or the folder is not found.
+func DetectSyncDir(titleCode, scorePath string) string {
+	title := GetTitleByCode(titleCode)
+	if title == nil || !title.SyncWholeDir {
+		return ""
+	}
+
+	scoreDir := filepath.Dir(scorePath)
+	if utils.DirExists(scoreDir) {
+		return scoreDir
+	}
+
+	return ""
+}
+
+// DetectSetFiles returns the names of the files that pull/push copy together with the
+// title's save file, such as its config. Returns nil if the title has none.
+func DetectSetFiles(titleCode string) []string {
+	title := GetTitleByCode(titleCode)
+	if title == nil {
+		return nil
+	}
+
+	return title.SetFiles()
+}
+
 // DetectSnapshotDir returns the snapshot directory path if it exists.
 // Returns empty string if not found.
 func DetectSnapshotDir(scorePath string) string {
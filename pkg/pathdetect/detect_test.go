@@ -0,0 +1,56 @@
+package pathdetect
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestDetectTitlesParallel_OrderAndBound(t *testing.T) {
+	titles := GetKnownTitles()
+
+	var inFlight, peak int32
+	detect := func(title KnownTitle) []models.DetectCandidate {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+
+		// Every other title is not found
+		if len(title.Code)%2 == 0 {
+			return nil
+		}
+		return []models.DetectCandidate{{Title: title.Code}}
+	}
+
+	results := detectTitlesParallel(titles, 3, detect)
+
+	if len(results) != len(titles) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(titles))
+	}
+	for i, title := range titles {
+		if len(results[i]) > 0 && results[i][0].Title != title.Code {
+			t.Errorf("results[%d] = %s, want %s", i, results[i][0].Title, title.Code)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want <= 3", peak)
+	}
+}
+
+func TestDetectTitlesParallel_Empty(t *testing.T) {
+	results := detectTitlesParallel(nil, 4, func(KnownTitle) []models.DetectCandidate {
+		t.Error("detect called for no titles")
+		return nil
+	})
+	if len(results) != 0 {
+		t.Errorf("len(results) = %d, want 0", len(results))
+	}
+}
@@ -0,0 +1,92 @@
+package pathdetect
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// ImportResult is the outcome of ImportPaths.
+type ImportResult struct {
+	Registered []models.DetectCandidate
+	Skipped    []string // why each skipped line was not registered
+}
+
+// ImportPaths registers the "title=path" lines read from r for deviceID, as detect --import
+// does. Malformed lines and save files that are missing or unreadable are skipped with a
+// reason; the error is for read failures only.
+func ImportPaths(r io.Reader, deviceID string, pathsConfig *models.PathsConfig) (*ImportResult, error) {
+	candidates, problems, err := parsePathsFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Skipped: problems}
+	for _, candidate := range candidates {
+		exists, readable := utils.FileExists(candidate.Path)
+		if !exists {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: file not found: %s", candidate.Title, candidate.Path))
+			continue
+		}
+		if !readable {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: file is not readable: %s", candidate.Title, candidate.Path))
+			continue
+		}
+		candidate.ReplayDir = DetectReplayDir(candidate.Title, candidate.Path)
+		candidate.SyncDir = DetectSyncDir(candidate.Title, candidate.Path)
+		candidate.SetFiles = DetectSetFiles(candidate.Title)
+		AddCandidateToConfig(candidate, deviceID, pathsConfig)
+		result.Registered = append(result.Registered, candidate)
+	}
+
+	return result, nil
+}
+
+// parsePathsFile reads "title=path" lines. Blank lines and lines starting with # are skipped;
+// paths may be quoted and have environment variables expanded. Malformed lines are returned
+// as problems ("line N: ...") without stopping the parse; the error is for read failures only.
+func parsePathsFile(r io.Reader) ([]models.DetectCandidate, []string, error) {
+	var candidates []models.DetectCandidate
+	var problems []string
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		title, path, ok := strings.Cut(line, "=")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("line %d: expected title=path, got %q", lineNum, line))
+			continue
+		}
+		title = strings.TrimSpace(title)
+		path = strings.Trim(strings.TrimSpace(path), "\"")
+
+		if !IsValidTitleCode(title) {
+			problems = append(problems, fmt.Sprintf("line %d: invalid title code: %s", lineNum, title))
+			continue
+		}
+		if path == "" {
+			problems = append(problems, fmt.Sprintf("line %d: empty path for %s", lineNum, title))
+			continue
+		}
+
+		candidates = append(candidates, models.DetectCandidate{
+			Title: title,
+			Path:  utils.ExpandEnvPath(path),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read: %w", err)
+	}
+
+	return candidates, problems, nil
+}
@@ -0,0 +1,83 @@
+package pathdetect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestParsePathsFile(t *testing.T) {
+	t.Setenv("THLOCALSYNC_TEST_SAVES", "/saves")
+
+	input := `# first-time setup
+th08=/games/th08/score.dat
+
+th10 = "${THLOCALSYNC_TEST_SAVES}/th10/scoreth10.dat"
+`
+	candidates, problems, err := parsePathsFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parsePathsFile failed: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("Expected 2 candidates, got %d", len(candidates))
+	}
+	if candidates[0].Title != "th08" || candidates[0].Path != "/games/th08/score.dat" {
+		t.Errorf("Unexpected first candidate: %+v", candidates[0])
+	}
+	if candidates[1].Title != "th10" || candidates[1].Path != "/saves/th10/scoreth10.dat" {
+		t.Errorf("Expected quotes stripped and env expanded, got %+v", candidates[1])
+	}
+
+	// Malformed lines are reported without dropping the valid ones around them
+	invalid := []string{
+		"th08 /games/th08/score.dat",
+		"TH08=/games/th08/score.dat",
+		"th08=",
+	}
+	for _, line := range invalid {
+		candidates, problems, err := parsePathsFile(strings.NewReader("th10=/ok\n" + line + "\nth11=/ok"))
+		if err != nil {
+			t.Fatalf("parsePathsFile failed: %v", err)
+		}
+		if len(problems) != 1 || !strings.HasPrefix(problems[0], "line 2:") {
+			t.Errorf("Expected a line 2 problem for %q, got %v", line, problems)
+		}
+		if len(candidates) != 2 {
+			t.Errorf("Expected both valid lines to be kept for %q, got %+v", line, candidates)
+		}
+	}
+}
+
+func TestImportPaths(t *testing.T) {
+	dir := t.TempDir()
+	savePath := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(savePath, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := "th08=" + savePath + "\nth10=" + filepath.Join(dir, "missing.dat") + "\nbroken\n"
+	pathsConfig := &models.PathsConfig{Paths: make(map[string]map[string]models.PathEntry)}
+
+	result, err := ImportPaths(strings.NewReader(input), "abcdefabcdef", pathsConfig)
+	if err != nil {
+		t.Fatalf("ImportPaths failed: %v", err)
+	}
+	if len(result.Registered) != 1 || result.Registered[0].Title != "th08" {
+		t.Errorf("Expected only th08 to be registered, got %+v", result.Registered)
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("Expected the missing file and the malformed line to be skipped, got %v", result.Skipped)
+	}
+	if entry := pathsConfig.Paths["th08"]["abcdefabcdef"]; len(entry.Paths) != 1 || entry.Paths[0] != savePath {
+		t.Errorf("Expected th08 to be added to the paths config, got %+v", entry)
+	}
+	if _, ok := pathsConfig.Paths["th10"]; ok {
+		t.Errorf("Expected the missing th10 file not to be registered")
+	}
+}
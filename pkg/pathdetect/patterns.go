@@ -6,23 +6,100 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/console"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
 // KnownTitle represents a known Touhou title with its detection patterns.
 type KnownTitle struct {
-	Code            string   // Title code (e.g., "th06", "th08")
-	Name            string   // Display name
-	Patterns        []string // Path patterns to search
-	UseAppData      bool     // If true, search in %APPDATA%
-	UseGameDir      bool     // If true, ask user for game directory
-	FileName        string   // Expected filename (e.g., "score.dat")
-	BestshotSubDir  string   // Subdirectory name containing bestshot files (empty if none)
+	Code           string   // Title code (e.g., "th06", "th08", or a user-defined code)
+	Name           string   // Display name
+	Patterns       []string // Path patterns to search
+	UseAppData     bool     // If true, search in %APPDATA%
+	UseGameDir     bool     // If true, ask user for game directory
+	FileNames      []string // Save file name first (e.g., "score.dat"), then the files restored together with it (e.g., the config)
+	BestshotSubDir string   // Subdirectory name containing bestshot files (empty if none)
+	SteamPatterns  []string // Glob patterns for the Steam release (empty if not on Steam)
+	ReplayDir      string   // Subdirectory name containing replay files (empty if not synced)
+	ProcessNames   []string // Executable names besides <code>.exe that count as the game running
+	SyncWholeDir   bool     // If true, the other files next to the save file (options, music unlocks) are synced too
+}
+
+// SaveFileName returns the name of the title's save file, the first of FileNames.
+func (t KnownTitle) SaveFileName() string {
+	if len(t.FileNames) == 0 {
+		return ""
+	}
+	return t.FileNames[0]
+}
+
+// SetFiles returns the names of the files restored together with the save file.
+func (t KnownTitle) SetFiles() []string {
+	if len(t.FileNames) < 2 {
+		return nil
+	}
+	return t.FileNames[1:]
+}
+
+// userTitles are the titles.json definitions merged over the built-in titles. Set via SetUserTitles.
+var userTitles []KnownTitle
+
+// SetUserTitles registers user-defined titles from titles.json.
+// Environment variables in their patterns (e.g. ${APPDATA}) are expanded here.
+func SetUserTitles(defs []models.TitleDefinition) {
+	userTitles = make([]KnownTitle, len(defs))
+	for i, def := range defs {
+		userTitles[i] = KnownTitle{
+			Code:       def.Code,
+			Name:       def.Name,
+			Patterns:   ExpandPathPatterns(def.Patterns),
+			UseAppData: def.UseAppData,
+			UseGameDir: def.UseGameDir,
+			FileNames:  append([]string{def.FileName}, def.SetFiles...),
+			ReplayDir:  def.ReplayDir,
+
+			ProcessNames: def.ProcessNames,
+			SyncWholeDir: def.SyncWholeDir,
+		}
+	}
 }
 
 // GetKnownTitles returns a list of known Touhou titles with their detection patterns.
+// User-defined titles replace built-in titles with the same code and otherwise follow them.
 func GetKnownTitles() []KnownTitle {
+	return mergeTitles(builtinTitles(), userTitles)
+}
+
+// mergeTitles overlays user titles on the built-in list, keeping the built-in release order.
+func mergeTitles(builtin, user []KnownTitle) []KnownTitle {
+	merged := make([]KnownTitle, len(builtin), len(builtin)+len(user))
+	copy(merged, builtin)
+
+	index := make(map[string]int, len(merged))
+	for i, title := range merged {
+		index[title.Code] = i
+	}
+
+	for _, title := range user {
+		if i, ok := index[title.Code]; ok {
+			merged[i] = title
+			continue
+		}
+		index[title.Code] = len(merged)
+		merged = append(merged, title)
+	}
+
+	return merged
+}
+
+// builtinTitles returns the Touhou titles supported out of the box.
+func builtinTitles() []KnownTitle {
 	appData := os.Getenv("APPDATA")
 	localAppData := os.Getenv("LOCALAPPDATA")
+	steamRoot := getSteamRoot()
 
 	return []KnownTitle{
 		// th06-th09: score.dat in game directory, may also be in VirtualStore
@@ -30,7 +107,10 @@ func GetKnownTitles() []KnownTitle {
 			Code:       "th06",
 			Name:       "東方紅魔郷",
 			UseGameDir: true,
-			FileName:   "score.dat",
+			FileNames:  []string{"score.dat"},
+			ReplayDir:  "replay",
+			// The original release ships with a Japanese executable name
+			ProcessNames: []string{"東方紅魔郷.exe"},
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方紅魔郷\score.dat`),
 				filepath.Join(localAppData, `VirtualStore\Program Files (x86)\上海アリス幻樂団\東方紅魔郷\score.dat`),
@@ -40,7 +120,8 @@ func GetKnownTitles() []KnownTitle {
 			Code:       "th07",
 			Name:       "東方妖々夢",
 			UseGameDir: true,
-			FileName:   "score.dat",
+			FileNames:  []string{"score.dat"},
+			ReplayDir:  "replay",
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方妖々夢\score.dat`),
 				filepath.Join(localAppData, `VirtualStore\Program Files (x86)\上海アリス幻樂団\東方妖々夢\score.dat`),
@@ -50,7 +131,8 @@ func GetKnownTitles() []KnownTitle {
 			Code:       "th08",
 			Name:       "東方永夜抄",
 			UseGameDir: true,
-			FileName:   "score.dat",
+			FileNames:  []string{"score.dat"},
+			ReplayDir:  "replay",
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方永夜抄\score.dat`),
 				filepath.Join(localAppData, `VirtualStore\Program Files (x86)\上海アリス幻樂団\東方永夜抄\score.dat`),
@@ -60,7 +142,8 @@ func GetKnownTitles() []KnownTitle {
 			Code:       "th09",
 			Name:       "東方花映塚",
 			UseGameDir: true,
-			FileName:   "score.dat",
+			FileNames:  []string{"score.dat"},
+			ReplayDir:  "replay",
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方花映塚\score.dat`),
 				filepath.Join(localAppData, `VirtualStore\Program Files (x86)\上海アリス幻樂団\東方花映塚\score.dat`),
@@ -71,7 +154,8 @@ func GetKnownTitles() []KnownTitle {
 			Code:           "th095",
 			Name:           "東方文花帖",
 			UseGameDir:     true,
-			FileName:       "scoreth095.dat",
+			FileNames:      []string{"scoreth095.dat"},
+			ReplayDir:      "replay",
 			BestshotSubDir: "bestshot",
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方文花帖\scoreth095.dat`),
@@ -79,10 +163,12 @@ func GetKnownTitles() []KnownTitle {
 			},
 		},
 		{
-			Code:       "th10",
-			Name:       "東方風神録",
-			UseGameDir: true,
-			FileName:   "scoreth10.dat",
+			Code:          "th10",
+			Name:          "東方風神録",
+			UseGameDir:    true,
+			FileNames:     []string{"scoreth10.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th10", "scoreth10.dat"),
 			Patterns: []string{
 				filepath.Join(localAppData, `VirtualStore\Program Files\上海アリス幻樂団\東方風神録\scoreth10.dat`),
 				filepath.Join(localAppData, `VirtualStore\Program Files (x86)\上海アリス幻樂団\東方風神録\scoreth10.dat`),
@@ -90,80 +176,98 @@ func GetKnownTitles() []KnownTitle {
 		},
 		// th11, th12: scorethXX.dat in game directory (no VirtualStore needed)
 		{
-			Code:       "th11",
-			Name:       "東方地霊殿",
-			UseGameDir: true,
-			FileName:   "scoreth11.dat",
-			Patterns:   []string{},
+			Code:          "th11",
+			Name:          "東方地霊殿",
+			UseGameDir:    true,
+			FileNames:     []string{"scoreth11.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th11", "scoreth11.dat"),
+			Patterns:      []string{},
 		},
 		{
-			Code:       "th12",
-			Name:       "東方星蓮船",
-			UseGameDir: true,
-			FileName:   "scoreth12.dat",
-			Patterns:   []string{},
+			Code:          "th12",
+			Name:          "東方星蓮船",
+			UseGameDir:    true,
+			FileNames:     []string{"scoreth12.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th12", "scoreth12.dat"),
+			Patterns:      []string{},
 		},
 		// th125+: scorethXX.dat in AppData/Roaming/ShanghaiAlice
 		{
 			Code:           "th125",
 			Name:           "ダブルスポイラー",
 			UseAppData:     true,
-			FileName:       "scoreth125.dat",
+			FileNames:      []string{"scoreth125.dat"},
+			ReplayDir:      "replay",
 			BestshotSubDir: "bestshot",
+			SteamPatterns:  steamPatterns(steamRoot, "th125", "scoreth125.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th125\scoreth125.dat`),
 			},
 		},
 		{
-			Code:       "th128",
-			Name:       "妖精大戦争",
-			UseAppData: true,
-			FileName:   "scoreth128.dat",
+			Code:          "th128",
+			Name:          "妖精大戦争",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth128.dat", "th128.cfg"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th128", "scoreth128.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th128\scoreth128.dat`),
 			},
 		},
 		{
-			Code:       "th13",
-			Name:       "東方神霊廟",
-			UseAppData: true,
-			FileName:   "scoreth13.dat",
+			Code:          "th13",
+			Name:          "東方神霊廟",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth13.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th13", "scoreth13.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th13\scoreth13.dat`),
 			},
 		},
 		{
-			Code:       "th14",
-			Name:       "東方輝針城",
-			UseAppData: true,
-			FileName:   "scoreth14.dat",
+			Code:          "th14",
+			Name:          "東方輝針城",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth14.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th14", "scoreth14.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th14\scoreth14.dat`),
 			},
 		},
 		{
-			Code:       "th143",
-			Name:       "弾幕アマノジャク",
-			UseAppData: true,
-			FileName:   "scoreth143.dat",
+			Code:          "th143",
+			Name:          "弾幕アマノジャク",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth143.dat", "th143.cfg"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th143", "scoreth143.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th143\scoreth143.dat`),
 			},
 		},
 		{
-			Code:       "th15",
-			Name:       "東方紺珠伝",
-			UseAppData: true,
-			FileName:   "scoreth15.dat",
+			Code:          "th15",
+			Name:          "東方紺珠伝",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth15.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th15", "scoreth15.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th15\scoreth15.dat`),
 			},
 		},
 		{
-			Code:       "th16",
-			Name:       "東方天空璋",
-			UseAppData: true,
-			FileName:   "scoreth16.dat",
+			Code:          "th16",
+			Name:          "東方天空璋",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth16.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th16", "scoreth16.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th16\scoreth16.dat`),
 			},
@@ -172,53 +276,69 @@ func GetKnownTitles() []KnownTitle {
 			Code:           "th165",
 			Name:           "秘封ナイトメアダイアリー",
 			UseAppData:     true,
-			FileName:       "scoreth165.dat",
+			FileNames:      []string{"scoreth165.dat"},
+			ReplayDir:      "replay",
 			BestshotSubDir: "savedata",
+			SteamPatterns:  steamPatterns(steamRoot, "th165", "scoreth165.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th165\scoreth165.dat`),
 			},
 		},
 		{
-			Code:       "th17",
-			Name:       "東方鬼形獣",
-			UseAppData: true,
-			FileName:   "scoreth17.dat",
+			Code:          "th17",
+			Name:          "東方鬼形獣",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth17.dat"},
+			ReplayDir:     "replay",
+			SteamPatterns: steamPatterns(steamRoot, "th17", "scoreth17.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th17\scoreth17.dat`),
 			},
 		},
 		{
-			Code:       "th18",
-			Name:       "東方虹龍洞",
-			UseAppData: true,
-			FileName:   "scoreth18.dat",
+			Code:          "th18",
+			Name:          "東方虹龍洞",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth18.dat"},
+			ReplayDir:     "replay",
+			SyncWholeDir:  true,
+			SteamPatterns: steamPatterns(steamRoot, "th18", "scoreth18.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th18\scoreth18.dat`),
 			},
 		},
 		{
-			Code:       "th185",
-			Name:       "バレットフィリア達の闇市場",
-			UseAppData: true,
-			FileName:   "scoreth185.dat",
+			Code:          "th185",
+			Name:          "バレットフィリア達の闇市場",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth185.dat"},
+			ReplayDir:     "replay",
+			SyncWholeDir:  true,
+			SteamPatterns: steamPatterns(steamRoot, "th185", "scoreth185.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th185\scoreth185.dat`),
 			},
 		},
 		{
-			Code:       "th19",
-			Name:       "東方獣王園",
-			UseAppData: true,
-			FileName:   "scoreth19.dat",
+			Code:          "th19",
+			Name:          "東方獣王園",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth19.dat"},
+			ReplayDir:     "replay",
+			SyncWholeDir:  true,
+			SteamPatterns: steamPatterns(steamRoot, "th19", "scoreth19.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th19\scoreth19.dat`),
 			},
 		},
 		{
-			Code:       "th20",
-			Name:       "東方錦上京",
-			UseAppData: true,
-			FileName:   "scoreth20.dat",
+			Code:          "th20",
+			Name:          "東方錦上京",
+			UseAppData:    true,
+			FileNames:     []string{"scoreth20.dat"},
+			ReplayDir:     "replay",
+			SyncWholeDir:  true,
+			SteamPatterns: steamPatterns(steamRoot, "th20", "scoreth20.dat"),
 			Patterns: []string{
 				filepath.Join(appData, `ShanghaiAlice\th20\scoreth20.dat`),
 			},
@@ -226,12 +346,46 @@ func GetKnownTitles() []KnownTitle {
 	}
 }
 
-// IsValidTitleCode checks if a string matches the pattern for a Touhou title code.
+// getSteamRoot returns the default Steam installation directory.
+// Returns empty string if Program Files (x86) is not defined.
+func getSteamRoot() string {
+	programFiles := os.Getenv("ProgramFiles(x86)")
+	if programFiles == "" {
+		return ""
+	}
+	return filepath.Join(programFiles, "Steam")
+}
+
+// steamPatterns returns the glob patterns for a title's save file under a Steam installation:
+// the game folder in steamapps\common, and the per-app prefix in steamapps\compatdata.
+// Returns nil if steamRoot is empty.
+func steamPatterns(steamRoot, code, fileName string) []string {
+	if steamRoot == "" {
+		return nil
+	}
+
+	steamApps := filepath.Join(steamRoot, "steamapps")
+	return []string{
+		filepath.Join(steamApps, "common", "*", fileName),
+		filepath.Join(steamApps, "compatdata", "*", "pfx", "drive_c", "users", "steamuser",
+			"AppData", "Roaming", "ShanghaiAlice", code, fileName),
+	}
+}
+
+// IsValidTitleCode checks if a string is a Touhou title code or a user-defined title code.
 // Valid formats: th06, th07, ..., th20, th095, th125, th128, th143, th165, th185
 func IsValidTitleCode(code string) bool {
 	// Match thXX or thXXX format
-	matched, _ := regexp.MatchString(`^th\d+$`, code)
-	return matched
+	if matched, _ := regexp.MatchString(`^th\d+$`, code); matched {
+		return true
+	}
+
+	for _, title := range userTitles {
+		if title.Code == code {
+			return true
+		}
+	}
+	return false
 }
 
 // GetTitleByCode returns the KnownTitle for a given code.
@@ -245,55 +399,116 @@ func GetTitleByCode(code string) *KnownTitle {
 	return nil
 }
 
-// SearchGameDirectoryForScoreDat searches for score.dat files in a game directory.
-// Returns a map of title code -> absolute path.
-func SearchGameDirectoryForScoreDat(gameDir string) map[string]string {
-	results := make(map[string]string)
-
-	// Search for executable files that match th\d+.exe pattern
-	entries, err := os.ReadDir(gameDir)
-	if err != nil {
-		return results
+// ExpectedFileName returns the save file name for a title code and whether the title is known.
+// Unknown codes follow the naming of later titles: score<code>.dat (e.g. th21 -> scoreth21.dat).
+func ExpectedFileName(code string) (string, bool) {
+	if title := GetTitleByCode(code); title != nil {
+		return title.SaveFileName(), true
 	}
+	return "score" + code + ".dat", false
+}
 
-	exePattern := regexp.MustCompile(`^(th\d+)\.exe$`)
+// DefaultGameDirSearchDepth is how many directory levels below the game directory
+// are searched for game executables unless --depth says otherwise.
+const DefaultGameDirSearchDepth = 3
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
+// GameDirSearchDepth is the depth used by SearchGameDirectoryForScoreDat. Set via detect --depth.
+var GameDirSearchDepth = DefaultGameDirSearchDepth
+
+// gameDirSearchLimit caps the directory entries visited by one game directory search,
+// so pointing --gamedir at a whole drive stays fast.
+var gameDirSearchLimit = 20000
+
+// skippedSearchDirs are directories that never hold a game installation.
+var skippedSearchDirs = map[string]bool{
+	"_history":     true,
+	".git":         true,
+	"node_modules": true,
+}
+
+// gameExePattern matches a game executable such as th08.exe.
+var gameExePattern = regexp.MustCompile(`^(?i)(th\d+)\.exe$`)
+
+// SearchGameDirectoryForScoreDat walks gameDir up to maxDepth levels deep for th\d+.exe
+// and looks for the matching score file next to each executable or in a subdirectory
+// named after the title code, which covers layouts like Touhou\th08\東方永夜抄\th08.exe.
+// Returns a map of title code -> absolute path; when a title is found more than once,
+// the shallowest match wins. truncated reports that the search stopped early at the
+// entry limit, so titles may be missing.
+func SearchGameDirectoryForScoreDat(gameDir string, maxDepth int) (results map[string]string, truncated bool) {
+	results = make(map[string]string)
+	depths := make(map[string]int)
+	visited := 0
+	root := filepath.Clean(gameDir)
 
-		matches := exePattern.FindStringSubmatch(entry.Name())
-		if matches != nil {
-			titleCode := matches[1]
-			title := GetTitleByCode(titleCode)
-			if title == nil {
-				continue
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped, the rest of the tree is still searched
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			// Check if score file exists in the same directory
-			scorePath := filepath.Join(gameDir, title.FileName)
-			if _, err := os.Stat(scorePath); err == nil {
-				results[titleCode] = scorePath
+		visited++
+		if visited > gameDirSearchLimit {
+			truncated = true
+			return filepath.SkipAll
+		}
+
+		depth := searchDepth(root, path)
+		if d.IsDir() {
+			// The entries of d lie one level below d itself
+			if path != root && (skippedSearchDirs[strings.ToLower(d.Name())] || depth+1 > maxDepth) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+
+		matches := gameExePattern.FindStringSubmatch(d.Name())
+		if matches == nil {
+			return nil
+		}
+		titleCode := strings.ToLower(matches[1])
+		title := GetTitleByCode(titleCode)
+		if title == nil || title.SaveFileName() == "" {
+			return nil
+		}
+		if prev, ok := depths[titleCode]; ok && prev <= depth {
+			return nil
+		}
 
-			// Also check in subdirectories with title name
-			titleSubDir := filepath.Join(gameDir, titleCode)
-			scorePathInSub := filepath.Join(titleSubDir, title.FileName)
-			if _, err := os.Stat(scorePathInSub); err == nil {
-				results[titleCode] = scorePathInSub
+		exeDir := filepath.Dir(path)
+		for _, scorePath := range []string{
+			filepath.Join(exeDir, title.SaveFileName()),
+			filepath.Join(exeDir, titleCode, title.SaveFileName()),
+		} {
+			if FileExists(scorePath) {
+				results[titleCode] = scorePath
+				depths[titleCode] = depth
+				break
 			}
 		}
-	}
+		return nil
+	})
 
-	return results
+	return results, truncated
+}
+
+// searchDepth returns how many directory levels path lies below root (0 for root's entries).
+func searchDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator))
 }
 
 // ExpandPathPatterns expands environment variables in path patterns.
 func ExpandPathPatterns(patterns []string) []string {
 	expanded := make([]string, len(patterns))
 	for i, pattern := range patterns {
-		expanded[i] = os.ExpandEnv(pattern)
+		expanded[i] = utils.ExpandEnvPath(pattern)
 	}
 	return expanded
 }
@@ -311,7 +526,7 @@ func SearchForTitle(title KnownTitle) []string {
 
 	// Search in known patterns
 	for _, pattern := range title.Patterns {
-		if FileExists(pattern) {
+		if probePath(pattern) {
 			found = append(found, pattern)
 		}
 	}
@@ -319,6 +534,38 @@ func SearchForTitle(title KnownTitle) []string {
 	return found
 }
 
+// probePath reports whether a candidate save file exists, printing the check with --verbose.
+func probePath(path string) bool {
+	exists := FileExists(path)
+	if exists {
+		console.Verbosef("    check %s: found\n", path)
+	} else {
+		console.Verbosef("    check %s: not found\n", path)
+	}
+	return exists
+}
+
+// SearchSteamForTitle searches for save files of a title's Steam release.
+// Returns nothing if Steam is not installed.
+func SearchSteamForTitle(title KnownTitle) []string {
+	var found []string
+
+	for _, pattern := range title.SteamPatterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		console.Verbosef("    glob  %s: %d match(es)\n", pattern, len(matches))
+		for _, match := range matches {
+			if FileExists(match) {
+				found = append(found, match)
+			}
+		}
+	}
+
+	return found
+}
+
 // GetAllTitleCodes returns a list of all known title codes.
 func GetAllTitleCodes() []string {
 	titles := GetKnownTitles()
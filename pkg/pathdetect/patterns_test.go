@@ -0,0 +1,240 @@
+package pathdetect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestSearchSteamForTitle(t *testing.T) {
+	steamRoot := t.TempDir()
+
+	commonPath := filepath.Join(steamRoot, "steamapps", "common", "th16", "scoreth16.dat")
+	compatPath := filepath.Join(steamRoot, "steamapps", "compatdata", "745880", "pfx", "drive_c",
+		"users", "steamuser", "AppData", "Roaming", "ShanghaiAlice", "th16", "scoreth16.dat")
+	for _, path := range []string{commonPath, compatPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("score"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	title := KnownTitle{
+		Code:          "th16",
+		FileNames:     []string{"scoreth16.dat"},
+		SteamPatterns: steamPatterns(steamRoot, "th16", "scoreth16.dat"),
+	}
+
+	found := SearchSteamForTitle(title)
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 Steam paths, got %d: %v", len(found), found)
+	}
+	if found[0] != commonPath || found[1] != compatPath {
+		t.Errorf("Unexpected Steam paths: %v", found)
+	}
+}
+
+func TestSearchSteamForTitle_NoSteam(t *testing.T) {
+	if patterns := steamPatterns("", "th16", "scoreth16.dat"); patterns != nil {
+		t.Errorf("Expected no patterns without a Steam root, got %v", patterns)
+	}
+
+	title := KnownTitle{
+		Code:          "th16",
+		FileNames:     []string{"scoreth16.dat"},
+		SteamPatterns: steamPatterns(filepath.Join(t.TempDir(), "missing"), "th16", "scoreth16.dat"),
+	}
+	if found := SearchSteamForTitle(title); len(found) != 0 {
+		t.Errorf("Expected no Steam paths, got %v", found)
+	}
+}
+
+func TestIsDuplicateCandidate(t *testing.T) {
+	candidates := []models.DetectCandidate{
+		{
+			Title:    "th16",
+			Path:     "/roaming/th16/scoreth16.dat",
+			Metadata: &models.FileMetadata{Hash: "sha256:aaaa"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		hash     string
+		expected bool
+	}{
+		{"Same path", "/roaming/th16/scoreth16.dat", "", true},
+		{"Same path spelled differently", "/roaming//th16/./scoreth16.dat", "", true},
+		{"Same hash", "/steam/th16/scoreth16.dat", "sha256:aaaa", true},
+		{"Different file", "/steam/th16/scoreth16.dat", "sha256:bbbb", false},
+		{"Unreadable file", "/steam/th16/scoreth16.dat", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &models.FileMetadata{Hash: tt.hash}
+			if got := isDuplicateCandidate(candidates, tt.path, meta); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetUserTitles(t *testing.T) {
+	defer SetUserTitles(nil)
+
+	SetUserTitles([]models.TitleDefinition{
+		{Code: "th08", Name: "東方永夜抄 (custom)", FileName: "score.dat"},
+		{Code: "hrtp", Name: "東方幻想郷", FileName: "score.dat", ReplayDir: "replay", SetFiles: []string{"hrtp.cfg"}},
+	})
+
+	titles := GetKnownTitles()
+	if len(titles) != len(builtinTitles())+1 {
+		t.Fatalf("Expected one title to be added, got %d titles", len(titles))
+	}
+	if titles[len(titles)-1].Code != "hrtp" {
+		t.Errorf("Expected the new title to follow the built-ins, got %s last", titles[len(titles)-1].Code)
+	}
+
+	th08 := GetTitleByCode("th08")
+	if th08 == nil || th08.Name != "東方永夜抄 (custom)" {
+		t.Errorf("Expected th08 to be overridden, got %+v", th08)
+	}
+
+	if !IsValidTitleCode("hrtp") {
+		t.Error("Expected user title code to be valid")
+	}
+	if IsValidTitleCode("unknown") {
+		t.Error("Expected undefined non-th code to be invalid")
+	}
+
+	if got := DetectSetFiles("hrtp"); len(got) != 1 || got[0] != "hrtp.cfg" {
+		t.Errorf("Expected hrtp.cfg as the set file of hrtp, got %v", got)
+	}
+	if name, _ := ExpectedFileName("hrtp"); name != "score.dat" {
+		t.Errorf("Expected score.dat as the save file of hrtp, got %q", name)
+	}
+
+	sorted := SortTitlesByRelease([]string{"hrtp", "th08", "th06"})
+	if sorted[0] != "th06" || sorted[1] != "th08" || sorted[2] != "hrtp" {
+		t.Errorf("Unexpected release order: %v", sorted)
+	}
+}
+
+func TestExpectedFileName(t *testing.T) {
+	tests := []struct {
+		code      string
+		wantName  string
+		wantKnown bool
+	}{
+		{"th08", "score.dat", true},
+		{"th17", "scoreth17.dat", true},
+		{"th075", "scoreth075.dat", false},
+		{"th21", "scoreth21.dat", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			name, known := ExpectedFileName(tt.code)
+			if name != tt.wantName || known != tt.wantKnown {
+				t.Errorf("ExpectedFileName(%q) = %q, %v; want %q, %v", tt.code, name, known, tt.wantName, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestDetectSetFiles(t *testing.T) {
+	if got := DetectSetFiles("th128"); len(got) != 1 || got[0] != "th128.cfg" {
+		t.Errorf("Expected th128.cfg to be restored with the th128 score, got %v", got)
+	}
+	if got := DetectSetFiles("th08"); got != nil {
+		t.Errorf("Expected no set files for th08, got %v", got)
+	}
+	if got := DetectSetFiles("th21"); got != nil {
+		t.Errorf("Expected no set files for an unknown title, got %v", got)
+	}
+}
+
+func TestDetectSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	scorePath := filepath.Join(dir, "scoreth18.dat")
+
+	if got := DetectSyncDir("th18", scorePath); got != dir {
+		t.Errorf("Expected th18 to sync its whole folder %s, got %q", dir, got)
+	}
+	if got := DetectSyncDir("th08", filepath.Join(dir, "score.dat")); got != "" {
+		t.Errorf("Expected no folder sync for th08, got %q", got)
+	}
+	if got := DetectSyncDir("th18", filepath.Join(dir, "missing", "scoreth18.dat")); got != "" {
+		t.Errorf("Expected no folder for a missing directory, got %q", got)
+	}
+}
+
+func TestSearchGameDirectoryForScoreDat(t *testing.T) {
+	root := t.TempDir()
+	write := func(parts ...string) string {
+		path := filepath.Join(append([]string{root}, parts...)...)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Touhou\th08\東方永夜抄 is three levels below the game directory
+	write("Touhou", "th08", "東方永夜抄", "th08.exe")
+	th08Score := write("Touhou", "th08", "東方永夜抄", "score.dat")
+	// The score in the title subdirectory next to the executable
+	write("th07", "TH07.EXE")
+	th07Score := write("th07", "th07", "score.dat")
+	// The shallower th06 wins over the copy in a backup folder
+	write("th06", "th06.exe")
+	th06Score := write("th06", "score.dat")
+	write("old", "copy", "th06.exe")
+	write("old", "copy", "score.dat")
+	// Too deep, in a skipped directory, or without a score file
+	write("a", "b", "c", "th09", "th09.exe")
+	write("a", "b", "c", "th09", "score.dat")
+	write("_history", "th06", "th06.exe")
+	write("th10", "th10.exe")
+
+	results, truncated := SearchGameDirectoryForScoreDat(root, DefaultGameDirSearchDepth)
+	if truncated {
+		t.Error("Expected the search not to be truncated")
+	}
+	want := map[string]string{"th06": th06Score, "th07": th07Score, "th08": th08Score}
+	if len(results) != len(want) {
+		t.Errorf("Expected %d titles, got %v", len(want), results)
+	}
+	for code, path := range want {
+		if results[code] != path {
+			t.Errorf("Expected %s at %s, got %q", code, path, results[code])
+		}
+	}
+
+	// th09 is found one level deeper
+	results, _ = SearchGameDirectoryForScoreDat(root, DefaultGameDirSearchDepth+1)
+	if _, ok := results["th09"]; !ok {
+		t.Errorf("Expected th09 with a deeper search, got %v", results)
+	}
+
+	// Only the game directory itself
+	results, _ = SearchGameDirectoryForScoreDat(root, 0)
+	if len(results) != 0 {
+		t.Errorf("Expected nothing at depth 0, got %v", results)
+	}
+
+	// The entry limit stops the walk
+	defer func(limit int) { gameDirSearchLimit = limit }(gameDirSearchLimit)
+	gameDirSearchLimit = 3
+	if _, truncated := SearchGameDirectoryForScoreDat(root, DefaultGameDirSearchDepth); !truncated {
+		t.Error("Expected the search to be truncated at the entry limit")
+	}
+}
@@ -0,0 +1,188 @@
+// Package paths resolves the base directory that holds data/, vault/ and logs/, and
+// each of those directories, which can also be moved out of the base directory on
+// their own. Every consumer (config, backup, logger and the commands) goes through
+// GetConfigDir, GetVaultDir and GetLogDir, so they always agree on where the data lives.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// EnvHome is the environment variable that overrides the base directory holding data/, vault/ and logs/.
+const EnvHome = "THLOCALSYNC_HOME"
+
+// HomeOverride, when set (by --home), is used as the base directory instead of
+// the executable's directory. It takes precedence over EnvHome.
+var HomeOverride string
+
+// Environment variables that move data/, vault/ and logs/ out of the base directory.
+const (
+	EnvConfigDir = "THLOCALSYNC_CONFIG_DIR"
+	EnvVaultDir  = "THLOCALSYNC_VAULT_DIR"
+	EnvLogDir    = "THLOCALSYNC_LOG_DIR"
+)
+
+// Names of the directories under the base directory.
+const (
+	ConfigDirName = "data"
+	VaultDirName  = "vault"
+	LogDirName    = "logs"
+)
+
+// ConfigDirOverride, VaultDirOverride and LogDirOverride, when set (by --config-dir,
+// --vault-dir and --log-dir), are used instead of data/, vault/ and logs/ under the
+// base directory. Each takes precedence over its environment variable.
+var (
+	ConfigDirOverride string
+	VaultDirOverride  string
+	LogDirOverride    string
+)
+
+// Source tells which rule chose a directory.
+type Source string
+
+const (
+	SourceFlag       Source = "--home"
+	SourceEnv        Source = EnvHome
+	SourceExecutable Source = "executable directory"
+	SourceWorkingDir Source = "current directory"
+
+	SourceConfigDirFlag Source = "--config-dir"
+	SourceConfigDirEnv  Source = EnvConfigDir
+	SourceVaultDirFlag  Source = "--vault-dir"
+	SourceVaultDirEnv   Source = EnvVaultDir
+	SourceLogDirFlag    Source = "--log-dir"
+	SourceLogDirEnv     Source = EnvLogDir
+)
+
+// GetBaseDir returns the base directory that holds data/, vault/ and logs/.
+// See ResolveBaseDir for the precedence.
+func GetBaseDir() (string, error) {
+	dir, _, err := ResolveBaseDir()
+	return dir, err
+}
+
+// ResolveBaseDir returns the base directory and the rule that chose it, in order:
+//  1. --home (HomeOverride)
+//  2. the THLOCALSYNC_HOME environment variable
+//  3. the directory containing the executable
+//  4. the current directory, when the executable lives in the temp directory
+//     (go run, or a launcher that extracts the binary there)
+//
+// Relative overrides are resolved against the current directory.
+func ResolveBaseDir() (string, Source, error) {
+	if HomeOverride != "" {
+		dir, err := absDir(HomeOverride)
+		return dir, SourceFlag, err
+	}
+	if home := os.Getenv(EnvHome); home != "" {
+		dir, err := absDir(home)
+		return dir, SourceEnv, err
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if !isTempExecutable(exePath, os.TempDir()) {
+		return filepath.Dir(exePath), SourceExecutable, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return cwd, SourceWorkingDir, nil
+}
+
+// GetConfigDir returns the directory holding the configuration (data/).
+// See ResolveConfigDir for the precedence.
+func GetConfigDir() (string, error) {
+	dir, _, err := ResolveConfigDir()
+	return dir, err
+}
+
+// GetVaultDir returns the vault directory. See ResolveVaultDir for the precedence.
+func GetVaultDir() (string, error) {
+	dir, _, err := ResolveVaultDir()
+	return dir, err
+}
+
+// GetLogDir returns the log directory. See ResolveLogDir for the precedence.
+func GetLogDir() (string, error) {
+	dir, _, err := ResolveLogDir()
+	return dir, err
+}
+
+// ResolveConfigDir returns the configuration directory and the rule that chose it:
+// --config-dir (ConfigDirOverride), then THLOCALSYNC_CONFIG_DIR, then data/ under the
+// base directory, in which case the source is that of the base directory.
+func ResolveConfigDir() (string, Source, error) {
+	return resolveDir(ConfigDirOverride, SourceConfigDirFlag, EnvConfigDir, SourceConfigDirEnv, ConfigDirName)
+}
+
+// ResolveVaultDir returns the vault directory and the rule that chose it: --vault-dir
+// (VaultDirOverride), then THLOCALSYNC_VAULT_DIR, then vault/ under the base directory.
+func ResolveVaultDir() (string, Source, error) {
+	return resolveDir(VaultDirOverride, SourceVaultDirFlag, EnvVaultDir, SourceVaultDirEnv, VaultDirName)
+}
+
+// ResolveLogDir returns the log directory and the rule that chose it: --log-dir
+// (LogDirOverride), then THLOCALSYNC_LOG_DIR, then logs/ under the base directory.
+func ResolveLogDir() (string, Source, error) {
+	return resolveDir(LogDirOverride, SourceLogDirFlag, EnvLogDir, SourceLogDirEnv, LogDirName)
+}
+
+// resolveDir returns override, else the env environment variable, else name under the
+// base directory. Relative overrides are resolved against the current directory.
+func resolveDir(override string, flagSource Source, env string, envSource Source, name string) (string, Source, error) {
+	if override != "" {
+		dir, err := absDir(override)
+		return dir, flagSource, err
+	}
+	if value := os.Getenv(env); value != "" {
+		dir, err := absDir(value)
+		return dir, envSource, err
+	}
+
+	base, source, err := ResolveBaseDir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(base, name), source, nil
+}
+
+// absDir expands environment variables in an override and makes it absolute.
+func absDir(home string) (string, error) {
+	absHome, err := filepath.Abs(utils.ExpandEnvPath(home))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %q: %w", home, err)
+	}
+	return absHome, nil
+}
+
+// isTempExecutable reports whether exePath lies under tempDir, where a data/ or vault/
+// next to it would vanish with the temp files. Test binaries built by go test also live
+// there but are exempt, so tests keep their data out of the source tree.
+func isTempExecutable(exePath, tempDir string) bool {
+	name := strings.TrimSuffix(filepath.Base(exePath), ".exe")
+	if strings.HasSuffix(name, ".test") {
+		return false
+	}
+
+	// The temp directory may itself be a symlink (/tmp -> /private/tmp on macOS)
+	if resolved, err := filepath.EvalSymlinks(tempDir); err == nil {
+		tempDir = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	rel, err := filepath.Rel(tempDir, exePath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
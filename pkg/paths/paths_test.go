@@ -0,0 +1,121 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetBaseDir_Override(t *testing.T) {
+	exePath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(EnvHome, "")
+	home, source, err := ResolveBaseDir()
+	if err != nil {
+		t.Fatalf("ResolveBaseDir failed: %v", err)
+	}
+	// Test binaries are exempt from the temp directory fallback
+	if home != filepath.Dir(exePath) || source != SourceExecutable {
+		t.Errorf("Expected executable directory, got %s (%s)", home, source)
+	}
+
+	envHome := t.TempDir()
+	t.Setenv(EnvHome, envHome)
+	home, source, err = ResolveBaseDir()
+	if err != nil {
+		t.Fatalf("ResolveBaseDir failed: %v", err)
+	}
+	if home != envHome || source != SourceEnv {
+		t.Errorf("Expected env override %s, got %s (%s)", envHome, home, source)
+	}
+
+	// The flag takes precedence over the environment variable
+	HomeOverride = "relative-home"
+	defer func() { HomeOverride = "" }()
+
+	home, err = GetBaseDir()
+	if err != nil {
+		t.Fatalf("GetBaseDir failed: %v", err)
+	}
+	want, _ := filepath.Abs("relative-home")
+	if home != want {
+		t.Errorf("Expected flag override %s, got %s", want, home)
+	}
+}
+
+func TestIsTempExecutable(t *testing.T) {
+	tempDir := t.TempDir()
+	other := t.TempDir()
+
+	tests := []struct {
+		name    string
+		exePath string
+		want    bool
+	}{
+		{"go run build", filepath.Join(tempDir, "go-build123", "b001", "exe", "thlocalsync"), true},
+		{"extracted by a launcher", filepath.Join(tempDir, "thlocalsync.exe"), true},
+		{"go test binary", filepath.Join(tempDir, "go-build123", "b001", "sync.test"), false},
+		{"go test binary on Windows", filepath.Join(tempDir, "go-build123", "b001", "sync.test.exe"), false},
+		{"outside the temp directory", filepath.Join(other, "thlocalsync"), false},
+		{"sibling with a common prefix", tempDir + "-usb" + string(filepath.Separator) + "thlocalsync", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTempExecutable(tt.exePath, tempDir); got != tt.want {
+			t.Errorf("%s: isTempExecutable(%s) = %v, want %v", tt.name, tt.exePath, got, tt.want)
+		}
+	}
+}
+
+func TestResolveVaultDir(t *testing.T) {
+	home := t.TempDir()
+	HomeOverride = home
+	t.Cleanup(func() {
+		HomeOverride = ""
+		VaultDirOverride = ""
+	})
+
+	// Unset, the vault follows the base directory
+	t.Setenv(EnvVaultDir, "")
+	dir, source, err := ResolveVaultDir()
+	if err != nil {
+		t.Fatalf("ResolveVaultDir failed: %v", err)
+	}
+	if dir != filepath.Join(home, VaultDirName) || source != SourceFlag {
+		t.Errorf("Expected vault/ under the base directory, got %s (%s)", dir, source)
+	}
+
+	envVault := filepath.Join(t.TempDir(), "usb-vault")
+	t.Setenv(EnvVaultDir, envVault)
+	dir, source, err = ResolveVaultDir()
+	if err != nil {
+		t.Fatalf("ResolveVaultDir failed: %v", err)
+	}
+	if dir != envVault || source != SourceVaultDirEnv {
+		t.Errorf("Expected env override %s, got %s (%s)", envVault, dir, source)
+	}
+
+	// The flag takes precedence and is resolved against the current directory
+	VaultDirOverride = "relative-vault"
+	dir, err = GetVaultDir()
+	if err != nil {
+		t.Fatalf("GetVaultDir failed: %v", err)
+	}
+	want, _ := filepath.Abs("relative-vault")
+	if dir != want {
+		t.Errorf("Expected flag override %s, got %s", want, dir)
+	}
+
+	// The other directories are not moved along
+	t.Setenv(EnvConfigDir, "")
+	t.Setenv(EnvLogDir, "")
+	if dir, _ := GetConfigDir(); dir != filepath.Join(home, ConfigDirName) {
+		t.Errorf("Expected data/ under the base directory, got %s", dir)
+	}
+	if dir, _ := GetLogDir(); dir != filepath.Join(home, LogDirName) {
+		t.Errorf("Expected logs/ under the base directory, got %s", dir)
+	}
+}
@@ -1,140 +1,121 @@
 // Package process handles game process detection and file lock checking.
-// Windows-specific implementation.
+// Platform-specific implementations of IsProcessRunning and IsFileLocked live in
+// process_windows.go, process_unix.go (/proc scan, for Wine/Proton), and process_other.go.
 package process
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"runtime"
 	"strings"
-	"syscall"
-	"unsafe"
-)
+	"time"
 
-var (
-	kernel32           = syscall.NewLazyDLL("kernel32.dll")
-	procCreateToolhelp = kernel32.NewProc("CreateToolhelp32Snapshot")
-	procProcess32First = kernel32.NewProc("Process32FirstW")
-	procProcess32Next  = kernel32.NewProc("Process32NextW")
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 )
 
 const (
-	TH32CS_SNAPPROCESS         = 0x00000002
-	MAX_PATH                   = 260
-	ERROR_SHARING_VIOLATION    = syscall.Errno(32)
-)
+	// NetworkLockProbeTimeout is the lock probe timeout used for network-mounted vaults,
+	// where a transient sharing violation is more likely than on local disks.
+	NetworkLockProbeTimeout = 5 * time.Second
 
-// PROCESSENTRY32 represents a process entry in Windows.
-type PROCESSENTRY32 struct {
-	dwSize              uint32
-	cntUsage            uint32
-	th32ProcessID       uint32
-	th32DefaultHeapID   uintptr
-	th32ModuleID        uint32
-	cntThreads          uint32
-	th32ParentProcessID uint32
-	pcPriClassBase      int32
-	dwFlags             uint32
-	szExeFile           [MAX_PATH]uint16
-}
+	// lockProbeInterval is the delay between lock probe retries.
+	lockProbeInterval = 500 * time.Millisecond
 
-// IsProcessRunning checks if a process with the given name is currently running.
-// processName should include the .exe extension (e.g., "th08.exe").
-func IsProcessRunning(processName string) (bool, error) {
-	processName = strings.ToLower(processName)
+	// safeWriteWaitInterval is the delay between CanSafelyWrite polls while waiting.
+	safeWriteWaitInterval = 500 * time.Millisecond
+)
 
-	// Create snapshot of all processes
-	handle, _, err := procCreateToolhelp.Call(TH32CS_SNAPPROCESS, 0)
-	if handle == 0 || handle == uintptr(syscall.InvalidHandle) {
-		return false, fmt.Errorf("failed to create process snapshot: %w", err)
-	}
-	defer syscall.CloseHandle(syscall.Handle(handle))
+// LockProbeTimeout is how long CanSafelyWrite keeps retrying the file lock check
+// before reporting the file as locked. Zero means a single probe.
+var LockProbeTimeout time.Duration
 
-	// Iterate through processes
-	var entry PROCESSENTRY32
-	entry.dwSize = uint32(unsafe.Sizeof(entry))
+// SafeWriteWait is how long CanSafelyWrite keeps polling while the game is still
+// running or the file is still locked, e.g. while the game is closing. Zero means
+// no waiting. Set by push --wait.
+var SafeWriteWait time.Duration
 
-	// Get first process
-	ret, _, err := procProcess32First.Call(handle, uintptr(unsafe.Pointer(&entry)))
-	if ret == 0 {
-		return false, fmt.Errorf("failed to get first process: %w", err)
-	}
+// ErrNotSupported is returned when process detection is not available on this platform.
+var ErrNotSupported = errors.New("process detection not available")
 
-	// Check first process
-	exeName := strings.ToLower(syscall.UTF16ToString(entry.szExeFile[:]))
-	if exeName == processName {
-		return true, nil
-	}
+// extraProcessNames are executable names per title besides <title>.exe. Set via SetGameProcessNames.
+var extraProcessNames map[string][]string
 
-	// Iterate through remaining processes
-	for {
-		ret, _, _ := procProcess32Next.Call(handle, uintptr(unsafe.Pointer(&entry)))
-		if ret == 0 {
-			break
-		}
-
-		exeName := strings.ToLower(syscall.UTF16ToString(entry.szExeFile[:]))
-		if exeName == processName {
-			return true, nil
-		}
-	}
+// GetGameProcessName returns the expected process name for a given title.
+// For example, "th08" -> "th08.exe"
+func GetGameProcessName(title string) string {
+	return title + ".exe"
+}
 
-	return false, nil
+// SetGameProcessNames registers additional executable names per title, for renamed
+// or localized executables. They are checked together with GetGameProcessName.
+func SetGameProcessNames(names map[string][]string) {
+	extraProcessNames = names
 }
 
-// IsFileLocked checks if a file is currently locked by another process.
-// This attempts to open the file with exclusive access to detect locks.
-func IsFileLocked(filePath string) (bool, error) {
-	// Check if file exists first
-	if _, err := os.Stat(filePath); err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
+// GetGameProcessNames returns every executable name that counts as the title's game,
+// starting with GetGameProcessName. Names are compared case-insensitively, as on Windows.
+func GetGameProcessNames(title string) []string {
+	names := []string{GetGameProcessName(title)}
+	seen := map[string]bool{strings.ToLower(names[0]): true}
+	for _, name := range extraProcessNames[title] {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
 		}
-		return false, fmt.Errorf("failed to stat file: %w", err)
+		seen[key] = true
+		names = append(names, name)
 	}
+	return names
+}
 
-	// Try to open with exclusive access
-	// Windows: Use CreateFile with no sharing flags
-	pathPtr, err := syscall.UTF16PtrFromString(filePath)
-	if err != nil {
-		return false, fmt.Errorf("failed to convert path: %w", err)
-	}
-
-	handle, err := syscall.CreateFile(
-		pathPtr,
-		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
-		0, // dwShareMode = 0 means exclusive access
-		nil,
-		syscall.OPEN_EXISTING,
-		syscall.FILE_ATTRIBUTE_NORMAL,
-		0,
-	)
-
-	if err != nil {
-		// If we get a sharing violation, the file is locked
-		if err == ERROR_SHARING_VIOLATION {
-			return true, nil
+// IsGameRunning checks whether any of the title's executables is running.
+// Returns the name of the running executable when one is found.
+func IsGameRunning(title string) (bool, string, error) {
+	for _, name := range GetGameProcessNames(title) {
+		running, err := IsProcessRunning(name)
+		if err != nil {
+			return false, "", err
+		}
+		if running {
+			return true, name, nil
 		}
-		// Other errors might indicate permission issues
-		return false, fmt.Errorf("failed to open file for lock check: %w", err)
 	}
-
-	// Successfully opened, file is not locked
-	syscall.CloseHandle(handle)
-	return false, nil
-}
-
-// GetGameProcessName returns the expected process name for a given title.
-// For example, "th08" -> "th08.exe"
-func GetGameProcessName(title string) string {
-	return title + ".exe"
+	return false, "", nil
 }
 
 // CanSafelyWrite checks if it's safe to write to a file.
 // Returns true if the file is not locked and the game is not running.
+// While either check fails it polls again until SafeWriteWait elapses; the reason
+// then names the check that kept failing.
+// On platforms without process detection it reports the file as unsafe
+// (reason "process detection not available") so that only --force proceeds.
 func CanSafelyWrite(filePath string, title string) (safe bool, reason string, err error) {
-	// Check if game process is running
-	processName := GetGameProcessName(title)
-	running, err := IsProcessRunning(processName)
+	defer timing.Start("process_check")()
+
+	deadline := time.Now().Add(SafeWriteWait)
+	for {
+		safe, reason, err = checkSafeWrite(filePath, title)
+		if errors.Is(err, ErrNotSupported) {
+			return false, fmt.Sprintf("%s on %s", ErrNotSupported, runtime.GOOS), nil
+		}
+		if safe || err != nil || SafeWriteWait <= 0 {
+			return safe, reason, err
+		}
+		if !time.Now().Before(deadline) {
+			return false, fmt.Sprintf("%s (still failing after waiting %s)", reason, SafeWriteWait), nil
+		}
+		time.Sleep(safeWriteWaitInterval)
+	}
+}
+
+// checkSafeWrite runs the process and lock checks once for CanSafelyWrite.
+// Returns ErrNotSupported when either check is unavailable on this platform.
+func checkSafeWrite(filePath string, title string) (safe bool, reason string, err error) {
+	// Check if any of the game's executables is running
+	running, processName, err := IsGameRunning(title)
+	if errors.Is(err, ErrNotSupported) {
+		return false, "", err
+	}
 	if err != nil {
 		return false, "", fmt.Errorf("failed to check process: %w", err)
 	}
@@ -142,8 +123,16 @@ func CanSafelyWrite(filePath string, title string) (safe bool, reason string, er
 		return false, fmt.Sprintf("process_running: %s", processName), nil
 	}
 
-	// Check if file is locked
+	// Check if file is locked, retrying until LockProbeTimeout elapses
+	deadline := time.Now().Add(LockProbeTimeout)
 	locked, err := IsFileLocked(filePath)
+	for err == nil && locked && time.Now().Before(deadline) {
+		time.Sleep(lockProbeInterval)
+		locked, err = IsFileLocked(filePath)
+	}
+	if errors.Is(err, ErrNotSupported) {
+		return false, "", err
+	}
 	if err != nil {
 		return false, "", fmt.Errorf("failed to check file lock: %w", err)
 	}
@@ -0,0 +1,13 @@
+//go:build !windows && !unix
+
+package process
+
+// IsProcessRunning is not available on this platform.
+func IsProcessRunning(processName string) (bool, error) {
+	return false, ErrNotSupported
+}
+
+// IsFileLocked is not available on this platform.
+func IsFileLocked(filePath string) (bool, error) {
+	return false, ErrNotSupported
+}
@@ -0,0 +1,129 @@
+//go:build unix
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// procRoot is the procfs mount point. Overridden in tests.
+var procRoot = "/proc"
+
+// IsProcessRunning checks if a process with the given name is currently running.
+// processName should include the .exe extension (e.g., "th08.exe").
+// Games running under Wine/Proton keep their Windows executable name, which is
+// found in /proc/<pid>/comm or as the first argument in /proc/<pid>/cmdline.
+// Returns ErrNotSupported if procfs is not available (e.g. macOS).
+func IsProcessRunning(processName string) (bool, error) {
+	processName = strings.ToLower(processName)
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, ErrNotSupported
+		}
+		return false, fmt.Errorf("failed to read %s: %w", procRoot, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !isPID(entry.Name()) {
+			continue
+		}
+
+		pidDir := filepath.Join(procRoot, entry.Name())
+
+		// comm is truncated to 15 characters, which still fits "thXXX.exe"
+		if comm, err := os.ReadFile(filepath.Join(pidDir, "comm")); err == nil {
+			if strings.ToLower(strings.TrimSpace(string(comm))) == processName {
+				return true, nil
+			}
+		}
+
+		// Wine may report the loader in comm; the Windows path is in argv[0]
+		if cmdline, err := os.ReadFile(filepath.Join(pidDir, "cmdline")); err == nil {
+			if exeBaseName(cmdline) == processName {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// IsFileLocked checks if a file is currently held open by another process.
+// Unix has no mandatory locks, so this scans /proc/<pid>/fd for the file.
+// Returns ErrNotSupported if procfs is not available (e.g. macOS).
+func IsFileLocked(filePath string) (bool, error) {
+	// Check if file exists first
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, ErrNotSupported
+		}
+		return false, fmt.Errorf("failed to read %s: %w", procRoot, err)
+	}
+
+	self := fmt.Sprint(os.Getpid())
+	for _, entry := range entries {
+		if !entry.IsDir() || !isPID(entry.Name()) || entry.Name() == self {
+			continue
+		}
+
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// Other users' processes are not readable; skip them
+			continue
+		}
+
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err == nil && target == absPath {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// isPID reports whether a /proc entry name is a process ID.
+func isPID(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// exeBaseName returns the lowercased base name of argv[0] from a NUL-separated cmdline,
+// accepting both Windows (Wine) and Unix path separators.
+func exeBaseName(cmdline []byte) string {
+	argv0 := string(cmdline)
+	if i := strings.IndexByte(argv0, 0); i >= 0 {
+		argv0 = argv0[:i]
+	}
+	if i := strings.LastIndexAny(argv0, `/\`); i >= 0 {
+		argv0 = argv0[i+1:]
+	}
+	return strings.ToLower(argv0)
+}
@@ -0,0 +1,175 @@
+//go:build unix
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeProc creates a fake /proc/<pid> entry with the given comm and cmdline.
+func writeProc(t *testing.T, root, pid, comm, cmdline string) {
+	t.Helper()
+	dir := filepath.Join(root, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsProcessRunning_Proc(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "100", "bash", "/bin/bash\x00")
+	writeProc(t, root, "200", "th08.exe", "th08.exe\x00")
+	writeProc(t, root, "300", "wine64-preloader", `C:\Games\th16\th16.exe`+"\x00--arg\x00")
+	// Non-PID entries are ignored
+	writeProc(t, root, "self", "th18.exe", "th18.exe\x00")
+
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	tests := []struct {
+		name     string
+		process  string
+		expected bool
+	}{
+		{"Found by comm", "th08.exe", true},
+		{"Found by Wine cmdline", "TH16.EXE", true},
+		{"Not running", "th10.exe", false},
+		{"Non-PID entry ignored", "th18.exe", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, err := IsProcessRunning(tt.process)
+			if err != nil {
+				t.Fatalf("IsProcessRunning failed: %v", err)
+			}
+			if running != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, running)
+			}
+		})
+	}
+}
+
+func TestIsProcessRunning_NoProc(t *testing.T) {
+	orig := procRoot
+	procRoot = filepath.Join(t.TempDir(), "missing")
+	defer func() { procRoot = orig }()
+
+	if _, err := IsProcessRunning("th08.exe"); err != ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+
+	// CanSafelyWrite reports unsafe (not an error) so --force can still proceed
+	safe, reason, err := CanSafelyWrite(filepath.Join(t.TempDir(), "score.dat"), "th08")
+	if err != nil {
+		t.Fatalf("CanSafelyWrite failed: %v", err)
+	}
+	if safe || reason == "" {
+		t.Errorf("Expected unsafe with reason, got safe=%v reason=%q", safe, reason)
+	}
+}
+
+func TestIsGameRunning_ExtraNames(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "300", "wine64-preloader", `C:\Games\th08\東方永夜抄.exe`+"\x00")
+
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	running, _, err := IsGameRunning("th08")
+	if err != nil {
+		t.Fatalf("IsGameRunning failed: %v", err)
+	}
+	if running {
+		t.Error("Expected a renamed executable to go unnoticed without extra names")
+	}
+
+	SetGameProcessNames(map[string][]string{"th08": {"TH08.EXE", "th08e.exe", "東方永夜抄.exe"}})
+	defer SetGameProcessNames(nil)
+
+	if names := GetGameProcessNames("th08"); len(names) != 3 || names[0] != "th08.exe" {
+		t.Errorf("Expected th08.exe first and the duplicate dropped, got %v", names)
+	}
+
+	running, name, err := IsGameRunning("th08")
+	if err != nil {
+		t.Fatalf("IsGameRunning failed: %v", err)
+	}
+	if !running || name != "東方永夜抄.exe" {
+		t.Errorf("Expected the renamed executable to be found, got running=%v name=%q", running, name)
+	}
+
+	safe, reason, err := CanSafelyWrite(filepath.Join(t.TempDir(), "score.dat"), "th08")
+	if err != nil {
+		t.Fatalf("CanSafelyWrite failed: %v", err)
+	}
+	if safe || reason != "process_running: 東方永夜抄.exe" {
+		t.Errorf("Expected unsafe because of the renamed executable, got safe=%v reason=%q", safe, reason)
+	}
+}
+
+func TestCanSafelyWrite_Wait(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "200", "th08.exe", "th08.exe\x00")
+
+	orig := procRoot
+	procRoot = root
+	defer func() { procRoot = orig }()
+
+	SafeWriteWait = time.Second
+	defer func() { SafeWriteWait = 0 }()
+
+	localPath := filepath.Join(t.TempDir(), "score.dat")
+
+	// The game keeps running: the reason names the process check
+	safe, reason, err := CanSafelyWrite(localPath, "th08")
+	if err != nil {
+		t.Fatalf("CanSafelyWrite failed: %v", err)
+	}
+	if safe || !strings.Contains(reason, "process_running: th08.exe") || !strings.Contains(reason, "after waiting") {
+		t.Errorf("Expected timeout on the running process, got safe=%v reason=%q", safe, reason)
+	}
+
+	// The game exits while waiting
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.RemoveAll(filepath.Join(root, "200"))
+	}()
+	safe, reason, err = CanSafelyWrite(localPath, "th08")
+	if err != nil {
+		t.Fatalf("CanSafelyWrite failed: %v", err)
+	}
+	if !safe {
+		t.Errorf("Expected safe once the game exited, got reason=%q", reason)
+	}
+}
+
+func TestExeBaseName(t *testing.T) {
+	tests := []struct {
+		cmdline  string
+		expected string
+	}{
+		{"th08.exe\x00", "th08.exe"},
+		{`Z:\home\user\Games\th17\th17.exe` + "\x00", "th17.exe"},
+		{"/usr/bin/wine\x00th18.exe\x00", "wine"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := exeBaseName([]byte(tt.cmdline)); got != tt.expected {
+			t.Errorf("exeBaseName(%q) = %q, want %q", tt.cmdline, got, tt.expected)
+		}
+	}
+}
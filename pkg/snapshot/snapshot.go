@@ -0,0 +1,263 @@
+// Package snapshot exports and imports the whole tool state as a single zip archive.
+//
+// A snapshot holds vault/ (main files, histories, quarantine, manifest) and the data/
+// config files, under the same directory names inside the archive. File mtimes are
+// stored in the archive and restored on import, so sync comparisons stay meaningful.
+// The hash cache is left out, since its entries describe files on the exporting PC,
+// and so is the run lock of a command in progress.
+package snapshot
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/lock"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// Top-level directories inside a snapshot archive.
+const (
+	vaultRoot = "vault"
+	dataRoot  = "data"
+)
+
+// ErrVaultNotEmpty is returned by Import when the vault already has files and force is not set.
+var ErrVaultNotEmpty = errors.New("vault is not empty")
+
+// Export writes vault/ and data/ into a zip archive at archivePath, replacing it atomically.
+// Returns the number of files written.
+func Export(archivePath string) (int, error) {
+	roots, err := snapshotRoots()
+	if err != nil {
+		return 0, err
+	}
+
+	// An archive inside a snapshotted directory would end up containing itself
+	absArchive, err := filepath.Abs(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve archive path: %w", err)
+	}
+	for _, dir := range roots {
+		if rel, err := filepath.Rel(dir, absArchive); err == nil && !strings.HasPrefix(rel, "..") {
+			return 0, fmt.Errorf("archive must not be inside %s", dir)
+		}
+	}
+
+	if err := utils.EnsureDir(filepath.Dir(absArchive)); err != nil {
+		return 0, err
+	}
+	tmpPath := absArchive + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	count, err := writeArchive(f, roots)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	if err := utils.SafeRename(tmpPath, absArchive); err != nil {
+		os.Remove(tmpPath)
+		return 0, fmt.Errorf("failed to move archive into place: %w", err)
+	}
+	return count, nil
+}
+
+// Import extracts a snapshot archive into vault/ and data/, restoring file mtimes.
+// Files in the archive replace existing ones; other existing files are left alone.
+// It refuses to write into a vault that already has files unless force is set,
+// and rejects the whole archive if any entry would land outside vault/ or data/.
+// Returns the number of files written.
+func Import(archivePath string, force bool) (int, error) {
+	roots, err := snapshotRoots()
+	if err != nil {
+		return 0, err
+	}
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	// Validate every entry before writing anything
+	var files []*zip.File
+	for _, file := range zr.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		if _, _, err := entryPath(file.Name); err != nil {
+			return 0, err
+		}
+		files = append(files, file)
+	}
+
+	if !force {
+		entries, err := os.ReadDir(roots[vaultRoot])
+		if err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to read vault directory: %w", err)
+		}
+		if len(entries) > 0 {
+			return 0, ErrVaultNotEmpty
+		}
+	}
+
+	for i, file := range files {
+		root, rel, _ := entryPath(file.Name)
+		dest := filepath.Join(roots[root], filepath.FromSlash(rel))
+		if err := extractFile(file, dest); err != nil {
+			return i, err
+		}
+	}
+	return len(files), nil
+}
+
+// snapshotRoots returns the directories included in a snapshot, keyed by their archive name.
+func snapshotRoots() (map[string]string, error) {
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	dataDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return map[string]string{vaultRoot: vaultDir, dataRoot: dataDir}, nil
+}
+
+// writeArchive writes the files under each root into a zip stream, vault/ first.
+func writeArchive(w io.Writer, roots map[string]string) (int, error) {
+	zw := zip.NewWriter(w)
+	count := 0
+
+	for _, root := range []string{vaultRoot, dataRoot} {
+		dir := roots[root]
+		if !utils.DirExists(dir) {
+			continue
+		}
+
+		err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			if root == dataRoot && (rel == config.HashCacheFile || rel == lock.FileName) {
+				return nil
+			}
+			if err := addFile(zw, p, root+"/"+filepath.ToSlash(rel)); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to archive %s: %w", dir, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return count, nil
+}
+
+// addFile stores one file in the archive under name, keeping its mtime.
+func addFile(zw *zip.Writer, src, name string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// entryPath splits an archive entry name into its root (vault or data) and the
+// slash-separated path below it, rejecting names that would escape the root.
+func entryPath(name string) (root, rel string, err error) {
+	clean := path.Clean(name)
+	if strings.Contains(name, `\`) || path.IsAbs(clean) {
+		return "", "", fmt.Errorf("invalid archive entry: %s", name)
+	}
+
+	root, rel, _ = strings.Cut(clean, "/")
+	if (root != vaultRoot && root != dataRoot) || rel == "" {
+		return "", "", fmt.Errorf("unexpected archive entry (not under vault/ or data/): %s", name)
+	}
+	return root, rel, nil
+}
+
+// extractFile writes one archive entry to dest via a temporary file and restores its mtime.
+func extractFile(file *zip.File, dest string) error {
+	if err := utils.EnsureDir(filepath.Dir(dest)); err != nil {
+		return err
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s from archive: %w", file.Name, err)
+	}
+	defer r.Close()
+
+	tmpPath := dest + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+
+	if err := utils.SafeRename(tmpPath, dest); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", dest, err)
+	}
+
+	modTime := file.Modified
+	if err := os.Chtimes(dest, modTime, modTime); err != nil {
+		return fmt.Errorf("failed to restore mtime of %s: %w", dest, err)
+	}
+	return nil
+}
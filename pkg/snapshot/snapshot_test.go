@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+// writeFile creates a file under dir with the given contents and mtime.
+func writeFile(t *testing.T, path, contents string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC)
+
+	source := t.TempDir()
+	t.Setenv(paths.EnvHome, source)
+	writeFile(t, filepath.Join(source, "vault", "th08", "score.dat"), "save", modTime)
+	writeFile(t, filepath.Join(source, "vault", "th08", "_history", "20240501-123045-score.dat"), "old", modTime)
+	writeFile(t, filepath.Join(source, "data", config.PathsFile), `{"paths":{}}`, modTime)
+	writeFile(t, filepath.Join(source, "data", config.HashCacheFile), `{}`, modTime)
+
+	archivePath := filepath.Join(t.TempDir(), "backup.zip")
+	count, err := Export(archivePath)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 files exported (hash cache excluded), got %d", count)
+	}
+
+	target := t.TempDir()
+	t.Setenv(paths.EnvHome, target)
+	count, err = Import(archivePath, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 files imported, got %d", count)
+	}
+
+	restored := filepath.Join(target, "vault", "th08", "score.dat")
+	data, err := os.ReadFile(restored)
+	if err != nil || string(data) != "save" {
+		t.Fatalf("Expected vault file to be restored, got %q (%v)", data, err)
+	}
+	info, err := os.Stat(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected mtime %s to be preserved, got %s", modTime, info.ModTime().UTC())
+	}
+	if _, err := os.Stat(filepath.Join(target, "vault", "th08", "_history", "20240501-123045-score.dat")); err != nil {
+		t.Errorf("Expected history to be restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", config.PathsFile)); err != nil {
+		t.Errorf("Expected config to be restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "data", config.HashCacheFile)); !os.IsNotExist(err) {
+		t.Error("Expected the hash cache not to be restored")
+	}
+
+	// The vault now has files
+	if _, err := Import(archivePath, false); !errors.Is(err, ErrVaultNotEmpty) {
+		t.Errorf("Expected ErrVaultNotEmpty, got %v", err)
+	}
+	if _, err := Import(archivePath, true); err != nil {
+		t.Errorf("Expected --force import to succeed, got %v", err)
+	}
+}
+
+func TestExport_RejectsArchiveInsideVault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.EnvHome, home)
+	writeFile(t, filepath.Join(home, "vault", "th08", "score.dat"), "save", time.Now())
+
+	if _, err := Export(filepath.Join(home, "vault", "backup.zip")); err == nil {
+		t.Error("Expected an error for an archive inside the vault")
+	}
+}
+
+func TestImport_RejectsEscapingEntries(t *testing.T) {
+	for _, name := range []string{"../evil.dat", "vault/../../evil.dat", "logs/2024-05-01.log", `vault\th08\score.dat`, "/vault/th08/score.dat"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "bad.zip")
+			f, err := os.Create(archivePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			for _, entry := range []string{"vault/th08/score.dat", name} {
+				w, err := zw.Create(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Write([]byte("x"))
+			}
+			zw.Close()
+			f.Close()
+
+			home := t.TempDir()
+			t.Setenv(paths.EnvHome, home)
+			if _, err := Import(archivePath, false); err == nil {
+				t.Fatal("Expected the archive to be rejected")
+			}
+			if _, err := os.Stat(filepath.Join(home, "vault", "th08", "score.dat")); !os.IsNotExist(err) {
+				t.Error("Expected nothing to be written from a rejected archive")
+			}
+		})
+	}
+}
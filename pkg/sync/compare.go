@@ -2,26 +2,81 @@ package sync
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
 )
 
-const (
-	// MaxSizeRatio is the maximum acceptable size ratio (new/old) before flagging as suspicious
-	MaxSizeRatio = 2.0
-)
+// CompareOptions holds the thresholds used when comparing two files.
+type CompareOptions struct {
+	SizeRatioThreshold float64 // Maximum acceptable size ratio (larger/smaller) before flagging as suspicious
+	DriftTolerance     int     // Maximum mtime difference (seconds) to consider two files equally new
+	ByteCompare        bool    // Decide equality with utils.FilesEqual when a hash is missing
+}
+
+// compareOptions is used by CompareFiles. Set from rules.json via SetRules.
+var compareOptions = CompareOptionsFromRules(nil)
+
+// CompareOptionsFromRules returns the comparison thresholds configured in rules.
+// Zero or negative values (or nil rules) fall back to the defaults.
+func CompareOptionsFromRules(rules *models.Rules) CompareOptions {
+	opts := CompareOptions{
+		SizeRatioThreshold: config.DefaultSizeRatioThreshold,
+		DriftTolerance:     config.DefaultDriftToleranceSeconds,
+	}
+	if rules == nil {
+		return opts
+	}
 
-// CompareFiles performs a three-point comparison (hash, size, mtime) between two files.
+	if rules.SizeRatioThreshold > 0 {
+		opts.SizeRatioThreshold = rules.SizeRatioThreshold
+	}
+	if rules.DriftToleranceSeconds > 0 {
+		opts.DriftTolerance = rules.DriftToleranceSeconds
+	}
+	return opts
+}
+
+// SetRules applies the comparison thresholds, include/exclude lists, history
+// retention and retry policy from rules.json.
+func SetRules(rules *models.Rules) {
+	compareOptions = CompareOptionsFromRules(rules)
+	fileFilter = FileFilterFromRules(rules)
+	historyPolicy = HistoryPolicyFromRules(rules)
+	retryPolicy = RetryPolicyFromRules(rules)
+}
+
+// CompareFiles compares two files using the thresholds set by SetRules.
+// See CompareFilesWithOptions.
+func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
+	return CompareFilesWithOptions(local, remote, compareOptions)
+}
+
+// CompareFilesQuick compares metadata gathered without hashes (GetFileMetadataNoHash).
+// Equality is decided by streaming both files with utils.FilesEqual, which stops at the
+// first differing byte, instead of by full hashes. Otherwise it is CompareFiles.
+func CompareFilesQuick(local, remote *models.FileMetadata) *models.ComparisonResult {
+	opts := compareOptions
+	opts.ByteCompare = true
+	return CompareFilesWithOptions(local, remote, opts)
+}
+
+// CompareFilesWithOptions performs a three-point comparison (hash, size, mtime) between two files.
 // Returns a ComparisonResult with recommendation and reason.
 //
 // Comparison logic (as per spec §9.2):
+// 0. A 0-byte local file is treated as missing → PUSH, unless the remote is empty too → SKIP;
+//    a 0-byte remote file next to a non-empty local one is treated as missing → PULL
 // 1. If hash matches → files are identical, SKIP
+//    (with opts.ByteCompare and a missing hash, the contents are compared byte by byte instead)
 // 2. If hash differs:
 //    a. If size differs → larger file is preferred (with suspicious check)
 //    b. If size same but mtime differs → newer mtime is preferred (with drift tolerance)
 // 3. Final decision can be overridden by user interaction
-func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
+func CompareFilesWithOptions(local, remote *models.FileMetadata, opts CompareOptions) *models.ComparisonResult {
 	result := &models.ComparisonResult{
 		LocalMeta:  local,
 		RemoteMeta: remote,
@@ -59,12 +114,58 @@ func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
 		return result
 	}
 
+	// Two paths reaching the same file (symlink, junction, hard link) are always in sync
+	if local.Info != nil && remote.Info != nil && os.SameFile(local.Info, remote.Info) {
+		result.HashMatch = true
+		result.Recommendation = "SKIP"
+		result.Reason = "both paths refer to the same file"
+		return result
+	}
+
 	// Calculate differences
 	result.SizeDiff = local.Size - remote.Size
 	result.TimeDiff = utils.TimeDiffSeconds(local.ModTime, remote.ModTime)
 
-	// 1. Check hash match
-	if local.Hash == remote.Hash {
+	// A game that crashes mid-write can leave a 0-byte save file; treat it as missing
+	if local.Size == 0 {
+		if remote.Size == 0 {
+			result.HashMatch = true
+			result.Recommendation = "SKIP"
+			result.Reason = "both files are empty (0 bytes)"
+			return result
+		}
+		result.EmptyLocal = true
+		result.Recommendation = "PUSH"
+		result.Reason = fmt.Sprintf("local file is empty (0 bytes), treated as missing (remote=%d)", remote.Size)
+		return result
+	}
+	if remote.Size == 0 {
+		result.EmptyRemote = true
+		result.Recommendation = "PULL"
+		result.Reason = fmt.Sprintf("remote file is empty (0 bytes), treated as missing (local=%d)", local.Size)
+		return result
+	}
+
+	// 1. Check hash match, or compare the contents directly when hashing was skipped
+	if opts.ByteCompare && (local.Hash == "" || remote.Hash == "") {
+		stop := timing.Start("compare")
+		equal, err := utils.FilesEqual(local.Path, remote.Path)
+		stop()
+		// On a read error fall through to the size/mtime analysis; equal size and mtime
+		// are then resolved by RehashIfAmbiguous
+		if err == nil {
+			result.ByteCompared = true
+			if equal {
+				result.HashMatch = true
+				result.Recommendation = "SKIP"
+				result.Reason = "files are identical (byte comparison)"
+				return result
+			}
+		}
+	}
+
+	// Missing hashes are not a match
+	if !result.ByteCompared && local.Hash != "" && local.Hash == remote.Hash {
 		result.HashMatch = true
 		result.Recommendation = "SKIP"
 		result.Reason = "files are identical (hash match)"
@@ -82,28 +183,22 @@ func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
 	if result.SizeDiff > 0 {
 		// Local is larger
 		sizePreference = "local"
-		if remote.Size > 0 {
-			sizeRatio = float64(local.Size) / float64(remote.Size)
-		} else {
-			sizeRatio = 999.0 // Remote is empty
-		}
+		sizeRatio = float64(local.Size) / float64(remote.Size) // remote is not empty here
 
-		if sizeRatio > MaxSizeRatio {
+		if sizeRatio > opts.SizeRatioThreshold {
 			result.Recommendation = "CONFLICT"
+			result.Suspicious = true
 			result.Reason = fmt.Sprintf("local file suspiciously large (%.1fx larger, local=%d remote=%d)", sizeRatio, local.Size, remote.Size)
 			return result
 		}
 	} else if result.SizeDiff < 0 {
 		// Remote is larger
 		sizePreference = "remote"
-		if local.Size > 0 {
-			sizeRatio = float64(remote.Size) / float64(local.Size)
-		} else {
-			sizeRatio = 999.0 // Local is empty
-		}
+		sizeRatio = float64(remote.Size) / float64(local.Size) // local is not empty here
 
-		if sizeRatio > MaxSizeRatio {
+		if sizeRatio > opts.SizeRatioThreshold {
 			result.Recommendation = "CONFLICT"
+			result.Suspicious = true
 			result.Reason = fmt.Sprintf("remote file suspiciously large (%.1fx larger, remote=%d local=%d)", sizeRatio, remote.Size, local.Size)
 			return result
 		}
@@ -115,9 +210,9 @@ func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
 	// Determine time preference
 	var timePreference string // "local", "remote", or "equal"
 
-	if utils.TimeWithinDrift(local.ModTime, remote.ModTime) {
+	if utils.TimeWithinDrift(local.ModTime, remote.ModTime, opts.DriftTolerance) {
 		timePreference = "equal"
-	} else if utils.IsNewerThan(local.ModTime, remote.ModTime) {
+	} else if utils.IsNewerThan(local.ModTime, remote.ModTime, opts.DriftTolerance) {
 		timePreference = "local"
 	} else {
 		timePreference = "remote"
@@ -128,9 +223,15 @@ func CompareFiles(local, remote *models.FileMetadata) *models.ComparisonResult {
 	// If they conflict, flag as CONFLICT for user confirmation
 
 	if sizePreference == "equal" && timePreference == "equal" {
+		if result.ByteCompared {
+			// The contents were read and differ, so equal size and mtime prove nothing
+			result.Recommendation = "CONFLICT"
+			result.Reason = fmt.Sprintf("contents differ despite equal size (%d) and mtime", local.Size)
+			return result
+		}
 		// Both equal - files are essentially the same
 		result.Recommendation = "SKIP"
-		result.Reason = fmt.Sprintf("files appear identical (size=%d, mtime within %ds drift)", local.Size, utils.TimeDriftTolerance)
+		result.Reason = fmt.Sprintf("files appear identical (size=%d, mtime within %ds drift)", local.Size, opts.DriftTolerance)
 		return result
 	}
 
@@ -1,6 +1,8 @@
 package sync
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -216,3 +218,266 @@ func TestCompareFiles_SuspiciouslySizeRatio(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareFiles_ConfigurableSizeRatio(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	defer SetRules(nil)
+
+	local := &models.FileMetadata{
+		Path:     "/local/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     3000,
+		ModTime:  baseTime,
+		Hash:     "local_hash",
+	}
+	remote := &models.FileMetadata{
+		Path:     "/remote/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     1000,
+		ModTime:  baseTime,
+		Hash:     "remote_hash",
+	}
+
+	tests := []struct {
+		name        string
+		threshold   float64
+		expectedRec string
+	}{
+		{"Raised threshold allows 3x growth", 4.0, "PULL"},
+		{"Zero falls back to default 2.0", 0, "CONFLICT"},
+		{"Negative falls back to default 2.0", -1, "CONFLICT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetRules(&models.Rules{SizeRatioThreshold: tt.threshold})
+
+			result := CompareFiles(local, remote)
+			if result.Recommendation != tt.expectedRec {
+				t.Errorf("Expected %s, got %s. Reason: %s",
+					tt.expectedRec, result.Recommendation, result.Reason)
+			}
+		})
+	}
+}
+
+func TestCompareFilesWithOptions_DriftTolerance(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	local := &models.FileMetadata{
+		Path:     "/local/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     1000,
+		ModTime:  baseTime.Add(5 * time.Second),
+		Hash:     "local_hash",
+	}
+	remote := &models.FileMetadata{
+		Path:     "/remote/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     1000,
+		ModTime:  baseTime,
+		Hash:     "remote_hash",
+	}
+
+	tests := []struct {
+		name        string
+		tolerance   int
+		expectedRec string
+	}{
+		{"Default 3s - 5s difference is newer", 3, "PULL"},
+		{"Wide 10s - 5s difference is within drift", 10, "SKIP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := CompareOptionsFromRules(&models.Rules{DriftToleranceSeconds: tt.tolerance})
+
+			result := CompareFilesWithOptions(local, remote, opts)
+			if result.Recommendation != tt.expectedRec {
+				t.Errorf("Expected %s, got %s. Reason: %s",
+					tt.expectedRec, result.Recommendation, result.Reason)
+			}
+		})
+	}
+}
+
+func TestCompareFilesQuick(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	write := func(name, content string) *models.FileMetadata {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		meta, err := GetFileMetadataNoHash(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Hash != "" {
+			t.Fatalf("Expected no hash, got %s", meta.Hash)
+		}
+		return meta
+	}
+
+	local := write("local.dat", "score data")
+	same := write("same.dat", "score data")
+	differs := write("differs.dat", "score datb")
+
+	result := CompareFilesQuick(local, same)
+	if result.Recommendation != "SKIP" || !result.HashMatch || !result.ByteCompared {
+		t.Errorf("Expected byte-compared SKIP, got %+v", result)
+	}
+
+	// Equal size and mtime but different contents must not look identical
+	result = CompareFilesQuick(local, differs)
+	if result.Recommendation != "CONFLICT" || result.HashMatch {
+		t.Errorf("Expected CONFLICT, got %s (%s)", result.Recommendation, result.Reason)
+	}
+
+	// The byte comparison is final, so no rehash happens
+	rehashed, err := RehashIfAmbiguous(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehashed.Rehashed || local.Hash != "" {
+		t.Error("Expected no rehash after a byte comparison")
+	}
+
+	// A byte comparison that cannot read a file does not make differing files identical
+	longer := write("longer.dat", "score data, longer")
+	os.Remove(longer.Path)
+	result = CompareFilesQuick(local, longer)
+	if result.Recommendation == "SKIP" || result.HashMatch {
+		t.Errorf("Expected the size difference to decide after a read error, got %s (%s)", result.Recommendation, result.Reason)
+	}
+}
+
+func TestCompareFiles_SameFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(path, []byte("score data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.dat")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	local, err := GetFileMetadata(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The game writing between the two reads must not turn one file into a conflict
+	remote.Hash = "sha256:changed"
+	remote.Size += 100
+
+	result := CompareFiles(local, remote)
+	if result.Recommendation != "SKIP" || !result.HashMatch {
+		t.Errorf("Expected SKIP for the same file, got %s (%s)", result.Recommendation, result.Reason)
+	}
+
+	// Copies with the same contents are still compared normally
+	other := filepath.Join(dir, "other.dat")
+	if err := os.WriteFile(other, []byte("score data, more"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if remote, err = GetFileMetadata(other); err != nil {
+		t.Fatal(err)
+	}
+	if result := CompareFiles(local, remote); result.HashMatch {
+		t.Errorf("Expected different files to differ, got %s (%s)", result.Recommendation, result.Reason)
+	}
+}
+
+func TestCompareFiles_EmptyFiles(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	// SHA-256 of no data, as both sides of an empty file would hash to
+	const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	tests := []struct {
+		name            string
+		localSize       int64
+		localHash       string
+		localTime       time.Time
+		remoteSize      int64
+		remoteHash      string
+		expectedRec     string
+		wantEmptyLocal  bool
+		wantEmptyRemote bool
+	}{
+		{
+			name:        "Both empty - SKIP",
+			localSize:   0,
+			localHash:   emptyHash,
+			localTime:   baseTime.Add(10 * time.Minute),
+			remoteSize:  0,
+			remoteHash:  emptyHash,
+			expectedRec: "SKIP",
+		},
+		{
+			name:        "Both empty without hashes - SKIP",
+			localSize:   0,
+			localTime:   baseTime,
+			remoteSize:  0,
+			expectedRec: "SKIP",
+		},
+		{
+			name:           "Local empty and newer - PUSH, treated as missing",
+			localSize:      0,
+			localHash:      emptyHash,
+			localTime:      baseTime.Add(10 * time.Minute),
+			remoteSize:     1000,
+			remoteHash:     "remote_hash",
+			expectedRec:    "PUSH",
+			wantEmptyLocal: true,
+		},
+		{
+			name:            "Remote empty - PULL, treated as missing",
+			localSize:       1000,
+			localHash:       "local_hash",
+			localTime:       baseTime,
+			remoteSize:      0,
+			remoteHash:      emptyHash,
+			expectedRec:     "PULL",
+			wantEmptyRemote: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &models.FileMetadata{
+				Path: "/local/test.dat", Exists: true, Readable: true,
+				Size: tt.localSize, ModTime: tt.localTime, Hash: tt.localHash,
+			}
+			remote := &models.FileMetadata{
+				Path: "/remote/test.dat", Exists: true, Readable: true,
+				Size: tt.remoteSize, ModTime: baseTime, Hash: tt.remoteHash,
+			}
+
+			result := CompareFiles(local, remote)
+
+			if result.Recommendation != tt.expectedRec {
+				t.Errorf("Expected %s, got %s. Reason: %s", tt.expectedRec, result.Recommendation, result.Reason)
+			}
+			if result.EmptyLocal != tt.wantEmptyLocal {
+				t.Errorf("EmptyLocal = %v, want %v", result.EmptyLocal, tt.wantEmptyLocal)
+			}
+			if result.EmptyRemote != tt.wantEmptyRemote {
+				t.Errorf("EmptyRemote = %v, want %v", result.EmptyRemote, tt.wantEmptyRemote)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// DirFileResult is the outcome of syncing a single file within a directory.
+type DirFileResult struct {
+	Name       string                   // ファイル名（ディレクトリからの相対）
+	Comparison *models.ComparisonResult // 比較結果（メタデータ取得失敗時はnil）
+	Err        error                    // 失敗時のエラー
+}
+
+// DirSyncResult summarizes a directory-level sync, one entry per file.
+type DirSyncResult struct {
+	Files []DirFileResult
+}
+
+// Count returns how many files ended with the given recommendation without error.
+func (r *DirSyncResult) Count(recommendation string) int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Err == nil && f.Comparison != nil && f.Comparison.Recommendation == recommendation {
+			count++
+		}
+	}
+	return count
+}
+
+// Errors returns the number of files that failed to sync.
+func (r *DirSyncResult) Errors() int {
+	count := 0
+	for _, f := range r.Files {
+		if f.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// PullDir pulls every file in localDir into vaultDir, applying PullFile to each.
+// Files that exist only in the vault are left untouched. Only the rules.json exclude
+// list applies here; the include list names save files, not directory contents.
+// A missing local directory yields an empty result.
+func PullDir(title string, localDir string, vaultDir string, createBackup bool) (*DirSyncResult, error) {
+	return pullDir(title, localDir, vaultDir, "", createBackup)
+}
+
+// PullTitleDir pulls the files of a title folder synced as a whole (options, music
+// unlocks) into vaultDir, like PullDir. saveName, the save file itself, is left out
+// since it is synced on its own. Subfolders such as replay/ are not included.
+func PullTitleDir(title string, localDir string, vaultDir string, saveName string, createBackup bool) (*DirSyncResult, error) {
+	return pullDir(title, localDir, vaultDir, saveName, createBackup)
+}
+
+// pullDir implements PullDir, leaving out the file named skip.
+func pullDir(title, localDir, vaultDir, skip string, createBackup bool) (*DirSyncResult, error) {
+	names, err := listDirFiles(localDir, skip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local directory: %w", err)
+	}
+
+	result := &DirSyncResult{}
+	for _, name := range names {
+		comparison, err := pullFile(title, backup.DefaultSlot, filepath.Join(localDir, name), filepath.Join(vaultDir, name), nil, createBackup)
+		result.Files = append(result.Files, DirFileResult{Name: name, Comparison: comparison, Err: err})
+	}
+
+	return result, nil
+}
+
+// PushDir pushes every file in vaultDir into localDir, applying PushFile to each.
+// Files that exist only locally are left untouched. As with PullDir, only the
+// exclude list applies.
+// A missing vault directory yields an empty result.
+func PushDir(title string, vaultDir string, localDir string, force bool, createBackup bool) (*DirSyncResult, error) {
+	return pushDir(title, vaultDir, localDir, force, createBackup, "")
+}
+
+// PushTitleDir pushes the vault copy of a title folder synced as a whole back into
+// localDir, like PushDir, leaving out the save file saveName.
+func PushTitleDir(title string, vaultDir string, localDir string, saveName string, force bool, createBackup bool) (*DirSyncResult, error) {
+	return pushDir(title, vaultDir, localDir, force, createBackup, saveName)
+}
+
+// pushDir implements PushDir, leaving out the file named skip.
+func pushDir(title, vaultDir, localDir string, force, createBackup bool, skip string) (*DirSyncResult, error) {
+	names, err := listDirFiles(vaultDir, skip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vault directory: %w", err)
+	}
+
+	result := &DirSyncResult{}
+	for _, name := range names {
+		comparison, err := pushFile(title, backup.DefaultSlot, filepath.Join(vaultDir, name), filepath.Join(localDir, name), nil, force, false, createBackup)
+		result.Files = append(result.Files, DirFileResult{Name: name, Comparison: comparison, Err: err})
+	}
+
+	return result, nil
+}
+
+// listDirFiles returns the sorted names of regular files directly under dir,
+// leaving out skip (if not empty) and those matched by the rules.json exclude list.
+// Returns nil if the directory does not exist.
+func listDirFiles(dir string, skip string) ([]string, error) {
+	if !utils.DirExists(dir) {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if skip != "" && strings.EqualFold(entry.Name(), skip) {
+			continue
+		}
+		if entry.Type().IsRegular() && !fileFilter.Excluded(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// GetVaultReplayDir returns the vault directory holding a title's replay files.
+// Example: <vault>/th08/replay
+func GetVaultReplayDir(title string) (string, error) {
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(vaultDir, title, backup.ReplaySyncDir), nil
+}
+
+// GetLocalReplayDir returns the expanded replay directory configured for a title and device.
+// Returns empty string if no replay directory is registered.
+func GetLocalReplayDir(pathsConfig *models.PathsConfig, title string, deviceID string) string {
+	pathEntry, ok := pathsConfig.Paths[title][deviceID]
+	if !ok || pathEntry.ReplayDir == "" {
+		return ""
+	}
+
+	return utils.ExpandEnvPath(pathEntry.ReplayDir)
+}
+
+// GetVaultTitleDir returns the vault directory holding the files of a title folder synced as a whole.
+// Example: <vault>/th18/folder
+func GetVaultTitleDir(title string) (string, error) {
+	vaultDir, err := backup.GetVaultDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(vaultDir, title, backup.FolderSyncDir), nil
+}
+
+// GetLocalTitleDir returns the expanded title folder registered for whole-folder sync.
+// Returns empty string if none is registered.
+func GetLocalTitleDir(pathsConfig *models.PathsConfig, title string, deviceID string) string {
+	pathEntry, ok := pathsConfig.Paths[title][deviceID]
+	if !ok || pathEntry.SyncDir == "" {
+		return ""
+	}
+
+	return utils.ExpandEnvPath(pathEntry.SyncDir)
+}
@@ -0,0 +1,118 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPullDir(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	localDir := filepath.Join(dir, "local", "replay")
+	vaultDir := filepath.Join(dir, "vault", "th16", "replay")
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(vaultDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// New replay: only on local
+	writeFileWithTime(t, filepath.Join(localDir, "th16_01.rpy"), []byte("new replay"), baseTime)
+	// Identical replay on both sides
+	writeFileWithTime(t, filepath.Join(localDir, "th16_02.rpy"), []byte("same"), baseTime)
+	writeFileWithTime(t, filepath.Join(vaultDir, "th16_02.rpy"), []byte("same"), baseTime)
+	// Vault-only replay must be left untouched
+	writeFileWithTime(t, filepath.Join(vaultDir, "th16_03.rpy"), []byte("vault only"), baseTime)
+	// Subdirectories are not synced
+	if err := os.MkdirAll(filepath.Join(localDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PullDir("th16", localDir, vaultDir, true)
+	if err != nil {
+		t.Fatalf("PullDir failed: %v", err)
+	}
+
+	if len(result.Files) != 2 {
+		t.Fatalf("Expected 2 file results, got %d", len(result.Files))
+	}
+	if got := result.Count("PULL"); got != 1 {
+		t.Errorf("Expected 1 PULL, got %d", got)
+	}
+	if got := result.Count("SKIP"); got != 1 {
+		t.Errorf("Expected 1 SKIP, got %d", got)
+	}
+	if got := result.Errors(); got != 0 {
+		t.Errorf("Expected no errors, got %d", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(vaultDir, "th16_01.rpy"))
+	if err != nil {
+		t.Fatalf("Expected new replay in vault: %v", err)
+	}
+	if string(data) != "new replay" {
+		t.Errorf("Expected %q, got %q", "new replay", string(data))
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "th16_03.rpy")); err != nil {
+		t.Errorf("Expected vault-only replay to remain: %v", err)
+	}
+}
+
+func TestPullDir_MissingLocalDir(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := PullDir("th16", filepath.Join(dir, "missing"), filepath.Join(dir, "vault"), true)
+	if err != nil {
+		t.Fatalf("PullDir failed: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected empty result, got %d files", len(result.Files))
+	}
+}
+
+func TestPullTitleDir_SkipsSaveAndExcluded(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	localDir := filepath.Join(dir, "ShanghaiAlice", "th18")
+	vaultDir := filepath.Join(dir, "vault", "th18", "folder")
+	if err := os.MkdirAll(filepath.Join(localDir, "replay"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFileWithTime(t, filepath.Join(localDir, "scoreth18.dat"), []byte("score"), baseTime)
+	writeFileWithTime(t, filepath.Join(localDir, "th18.cfg"), []byte("options"), baseTime)
+	writeFileWithTime(t, filepath.Join(localDir, "th18.tmp"), []byte("temp"), baseTime)
+	writeFileWithTime(t, filepath.Join(localDir, "replay", "th18_01.rpy"), []byte("replay"), baseTime)
+
+	result, err := PullTitleDir("th18", localDir, vaultDir, "SCORETH18.DAT", true)
+	if err != nil {
+		t.Fatalf("PullTitleDir failed: %v", err)
+	}
+	if len(result.Files) != 1 || result.Files[0].Name != "th18.cfg" {
+		t.Fatalf("Expected only th18.cfg to be synced, got %+v", result.Files)
+	}
+	if got := result.Count("PULL"); got != 1 {
+		t.Errorf("Expected 1 PULL, got %d", got)
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "scoreth18.dat")); !os.IsNotExist(err) {
+		t.Error("Expected the save file to be left to the main sync")
+	}
+
+	// Push back to an empty folder: the options file is restored
+	restoreDir := filepath.Join(dir, "restore")
+	result, err = PushTitleDir("th18", vaultDir, restoreDir, "scoreth18.dat", false, true)
+	if err != nil {
+		t.Fatalf("PushTitleDir failed: %v", err)
+	}
+	if got := result.Count("PUSH"); got != 1 {
+		t.Errorf("Expected 1 PUSH, got %d (%+v)", got, result.Files)
+	}
+	if data, err := os.ReadFile(filepath.Join(restoreDir, "th18.cfg")); err != nil || string(data) != "options" {
+		t.Errorf("Expected options file to be restored, got %q (%v)", data, err)
+	}
+}
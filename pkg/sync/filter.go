@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"path"
+	"path/filepath"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+)
+
+// FileFilter selects files for sync using the include/exclude glob lists from rules.json.
+//
+// Patterns use filepath.Match syntax with "/" as the separator on every OS, and are tried
+// against both the file's base name and its relative path, so "*.tmp" and "_history/*" both work.
+// Exclude wins over include: a file matching any exclude pattern is never synced.
+// An empty include list admits every file that is not excluded.
+type FileFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// fileFilter is used by sync and detection. Set from rules.json via SetRules.
+var fileFilter = FileFilterFromRules(nil)
+
+// FileFilterFromRules returns the filter configured in rules.
+// Nil rules fall back to the default include/exclude lists.
+func FileFilterFromRules(rules *models.Rules) FileFilter {
+	if rules == nil {
+		rules = config.DefaultRules()
+	}
+	return FileFilter{Include: rules.Include, Exclude: rules.Exclude}
+}
+
+// Allows reports whether name is included and not excluded.
+func (f FileFilter) Allows(name string) bool {
+	return !f.Excluded(name) && f.Included(name)
+}
+
+// Included reports whether name matches an include pattern, or the include list is empty.
+func (f FileFilter) Included(name string) bool {
+	if len(f.Include) == 0 {
+		return true
+	}
+	return matchAny(f.Include, name)
+}
+
+// Excluded reports whether name matches any exclude pattern.
+func (f FileFilter) Excluded(name string) bool {
+	return matchAny(f.Exclude, name)
+}
+
+// AllowsFile reports whether a save file passes the include/exclude rules set by SetRules.
+func AllowsFile(name string) bool {
+	return fileFilter.Allows(name)
+}
+
+// matchAny reports whether name, or its base name, matches one of patterns.
+// Malformed patterns never match.
+func matchAny(patterns []string, name string) bool {
+	name = filepath.ToSlash(name)
+	base := path.Base(name)
+
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// excludedComparison returns a SKIP result if the local file is rejected by the rules, otherwise nil.
+// The metadata only carries the paths, since the files are not inspected.
+func excludedComparison(localPath string, vaultPath string) *models.ComparisonResult {
+	if AllowsFile(filepath.Base(localPath)) {
+		return nil
+	}
+
+	return &models.ComparisonResult{
+		LocalMeta:      &models.FileMetadata{Path: localPath},
+		RemoteMeta:     &models.FileMetadata{Path: vaultPath},
+		Recommendation: "SKIP",
+		Reason:         "excluded by rules.json include/exclude",
+	}
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+)
+
+func TestFileFilter_Allows(t *testing.T) {
+	filter := FileFilter{
+		Include: []string{"score*.dat", "*.rpy"},
+		Exclude: []string{"*.tmp", "_history/*", "scoreth1?.dat"},
+	}
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"score.dat", true},
+		{"scoreth08.dat", true},
+		{"th16_01.rpy", true},
+		// Included and excluded: exclude wins
+		{"scoreth16.dat", false},
+		{"score.dat.tmp", false},
+		// Matched by relative path
+		{"_history/score.dat", false},
+		// Not included
+		{"config.ini", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Allows(tt.name); got != tt.expected {
+				t.Errorf("Allows(%q) = %v, expected %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileFilter_EmptyIncludeAdmitsAll(t *testing.T) {
+	filter := FileFilter{Exclude: []string{"*.tmp"}}
+
+	if !filter.Allows("anything.dat") {
+		t.Error("Expected empty include list to admit every file")
+	}
+	if filter.Allows("anything.tmp") {
+		t.Error("Expected exclude to apply with empty include list")
+	}
+}
+
+func TestFileFilter_MalformedPatternNeverMatches(t *testing.T) {
+	filter := FileFilter{Include: []string{"[", "score.dat"}, Exclude: []string{"["}}
+
+	if !filter.Allows("score.dat") {
+		t.Error("Expected malformed patterns to be ignored")
+	}
+	if filter.Allows("[") {
+		t.Error("Expected malformed include pattern not to match")
+	}
+}
+
+func TestPullFile_ExcludedByRules(t *testing.T) {
+	SetRules(&models.Rules{Include: []string{"score*.dat"}, Exclude: []string{"*.tmp"}})
+	defer SetRules(nil)
+
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "local", "score.dat.tmp")
+	vaultPath := filepath.Join(dir, "vault", "score.dat.tmp")
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFileWithTime(t, localPath, []byte("data"), time.Now())
+
+	comparison, err := PullFile("th08", backup.DefaultSlot, localPath, vaultPath, true)
+	if err != nil {
+		t.Fatalf("PullFile failed: %v", err)
+	}
+	if comparison.Recommendation != "SKIP" {
+		t.Errorf("Expected SKIP, got %s", comparison.Recommendation)
+	}
+	if _, err := os.Stat(vaultPath); !os.IsNotExist(err) {
+		t.Error("Expected excluded file not to be copied to the vault")
+	}
+}
+
+func TestPullDir_ExcludeOnly(t *testing.T) {
+	// Default include names save files only; replays must still sync
+	SetRules(&models.Rules{Include: []string{"score.dat"}, Exclude: []string{"*.tmp"}})
+	defer SetRules(nil)
+
+	dir := t.TempDir()
+	localDir := filepath.Join(dir, "local", "replay")
+	vaultDir := filepath.Join(dir, "vault", "th16", "replay")
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	writeFileWithTime(t, filepath.Join(localDir, "th16_01.rpy"), []byte("replay"), baseTime)
+	writeFileWithTime(t, filepath.Join(localDir, "th16_02.rpy.tmp"), []byte("partial"), baseTime)
+
+	result, err := PullDir("th16", localDir, vaultDir, true)
+	if err != nil {
+		t.Fatalf("PullDir failed: %v", err)
+	}
+
+	if len(result.Files) != 1 || result.Files[0].Name != "th16_01.rpy" {
+		t.Fatalf("Expected only th16_01.rpy, got %+v", result.Files)
+	}
+	if got := result.Count("PULL"); got != 1 {
+		t.Errorf("Expected 1 PULL, got %d", got)
+	}
+}
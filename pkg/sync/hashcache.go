@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	stdsync "sync"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// hashCacheEntry records the hash of a file as it was at a given size and mtime.
+type hashCacheEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	Hash    string    `json:"hash"`
+}
+
+// HashCache maps file paths to their last computed hash, so unchanged files
+// are not rehashed on every run. An entry is only used while the file's size
+// and mtime both still match; any mismatch replaces it.
+// Safe for concurrent use by GetFileMetadataParallel.
+type HashCache struct {
+	mu      stdsync.Mutex
+	path    string
+	entries map[string]hashCacheEntry
+	dirty   bool
+}
+
+// hashCache is consulted by GetFileMetadata. Nil disables caching.
+var hashCache *HashCache
+
+// LoadHashCache reads the cache file at path. A missing or unreadable file
+// yields an empty cache, since the cache can always be rebuilt.
+func LoadHashCache(path string) *HashCache {
+	cache := &HashCache{path: path, entries: make(map[string]hashCacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil || cache.entries == nil {
+		cache.entries = make(map[string]hashCacheEntry)
+	}
+
+	return cache
+}
+
+// EnableHashCache makes GetFileMetadata use cache. Nil disables caching.
+func EnableHashCache(cache *HashCache) {
+	hashCache = cache
+}
+
+// SaveHashCache writes the enabled cache back to disk if it changed.
+func SaveHashCache() error {
+	if hashCache == nil {
+		return nil
+	}
+	return hashCache.Save()
+}
+
+// ClearHashCache deletes the cache file at path. A missing file is not an error.
+func ClearHashCache(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove hash cache: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the cached hash for path if its size and mtime are unchanged
+// and it was made with the current hash algorithm.
+func (c *HashCache) lookup(path string, size int64, modTime time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[filepath.Clean(path)]
+	if !ok || entry.Size != size || !entry.ModTime.Equal(modTime) || utils.HashAlgoOf(entry.Hash) != utils.HashAlgo {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+// store records the hash computed for path at the given size and mtime.
+func (c *HashCache) store(path string, size int64, modTime time.Time, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[filepath.Clean(path)] = hashCacheEntry{Size: size, ModTime: modTime, Hash: hash}
+	c.dirty = true
+}
+
+// Save writes the cache atomically if it changed since it was loaded.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	if err := utils.EnsureDir(filepath.Dir(c.path)); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash cache: %w", err)
+	}
+
+	// Write to temp file first
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	// Atomic rename
+	if err := utils.SafeRename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,113 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+func TestGetFileMetadata_UsesHashCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "score.dat")
+	mtime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	writeFileWithTime(t, path, []byte("score data"), mtime)
+
+	cachePath := filepath.Join(dir, "hashcache.json")
+	EnableHashCache(LoadHashCache(cachePath))
+	defer EnableHashCache(nil)
+
+	first, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatalf("GetFileMetadata failed: %v", err)
+	}
+
+	// Plant a fake hash: if it comes back, hashing was skipped
+	hashCache.store(path, first.Size, first.ModTime, "cached")
+	second, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatalf("GetFileMetadata failed: %v", err)
+	}
+	if second.Hash != "cached" {
+		t.Errorf("Expected cached hash, got %s", second.Hash)
+	}
+
+	// Same size, new mtime: the entry is invalidated
+	writeFileWithTime(t, path, []byte("score DATA"), mtime.Add(time.Minute))
+	third, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatalf("GetFileMetadata failed: %v", err)
+	}
+	if third.Hash == "cached" || third.Hash == first.Hash {
+		t.Errorf("Expected fresh hash after mtime change, got %s", third.Hash)
+	}
+}
+
+func TestGetFileMetadata_HashCacheAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "score.dat")
+	writeFileWithTime(t, path, []byte("score data"), time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC))
+
+	EnableHashCache(LoadHashCache(filepath.Join(dir, "hashcache.json")))
+	defer EnableHashCache(nil)
+	defer func() { utils.HashAlgo = utils.HashSHA256 }()
+
+	first, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatalf("GetFileMetadata failed: %v", err)
+	}
+
+	// A cached SHA256 hash must not be reused once another algorithm is selected
+	utils.HashAlgo = utils.HashXXHash
+	second, err := GetFileMetadata(path)
+	if err != nil {
+		t.Fatalf("GetFileMetadata failed: %v", err)
+	}
+	if second.Hash == first.Hash || utils.HashAlgoOf(second.Hash) != utils.HashXXHash {
+		t.Errorf("Expected a fresh xxhash, got %s", second.Hash)
+	}
+}
+
+func TestHashCache_SaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "data", "hashcache.json")
+	mtime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	cache := LoadHashCache(cachePath)
+	cache.store("/games/th08/score.dat", 1024, mtime, "abc")
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded := LoadHashCache(cachePath)
+	if hash, ok := loaded.lookup("/games/th08/score.dat", 1024, mtime); !ok || hash != "abc" {
+		t.Errorf("Expected cached hash abc, got %q (hit=%v)", hash, ok)
+	}
+	if _, ok := loaded.lookup("/games/th08/score.dat", 2048, mtime); ok {
+		t.Error("Expected size mismatch to miss")
+	}
+
+	if err := ClearHashCache(cachePath); err != nil {
+		t.Fatalf("ClearHashCache failed: %v", err)
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Error("Expected cache file to be removed")
+	}
+	if err := ClearHashCache(cachePath); err != nil {
+		t.Errorf("Expected clearing a missing cache to succeed, got %v", err)
+	}
+}
+
+func TestLoadHashCache_CorruptedStartsEmpty(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "hashcache.json")
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := LoadHashCache(cachePath)
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected empty cache, got %d entries", len(cache.entries))
+	}
+}
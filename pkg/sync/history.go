@@ -0,0 +1,70 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+)
+
+// HistoryPolicy limits how many backups a vault slot keeps and for how long.
+// Zero values disable the corresponding limit.
+type HistoryPolicy struct {
+	Limit  int           // Maximum number of backups kept per slot
+	MaxAge time.Duration // Backups older than this are removed
+}
+
+// historyPolicy is applied after pull/push create a backup. Set from rules.json via SetRules.
+var historyPolicy = HistoryPolicyFromRules(nil)
+
+// HistoryPolicyFromRules returns the history retention configured in rules.
+// Nil rules disable pruning entirely.
+func HistoryPolicyFromRules(rules *models.Rules) HistoryPolicy {
+	if rules == nil {
+		return HistoryPolicy{}
+	}
+
+	policy := HistoryPolicy{}
+	if rules.HistoryLimit > 0 {
+		policy.Limit = rules.HistoryLimit
+	}
+	if rules.HistoryMaxAgeDays > 0 {
+		policy.MaxAge = time.Duration(rules.HistoryMaxAgeDays) * 24 * time.Hour
+	}
+	return policy
+}
+
+// CurrentHistoryPolicy returns the history policy set by SetRules.
+func CurrentHistoryPolicy() HistoryPolicy {
+	return historyPolicy
+}
+
+// PruneHistory applies the count and age limits of the history policy set by SetRules
+// to a slot's history, always keeping its most recent backup. Without limits nothing
+// is removed.
+func PruneHistory(title string, slot string) (backup.PruneResult, error) {
+	if historyPolicy.Limit <= 0 && historyPolicy.MaxAge <= 0 {
+		return backup.PruneResult{}, nil
+	}
+	return backup.PruneHistory(title, slot, historyPolicy.Limit, historyPolicy.MaxAge)
+}
+
+// pruneReporter is told about each history trim after a backup. Set via SetPruneReporter.
+var pruneReporter func(title string, slot string, result backup.PruneResult, err error)
+
+// SetPruneReporter makes pull/push report the backups removed from a slot's history
+// after they create a backup, or the error that stopped the removal. nil turns
+// reporting off.
+func SetPruneReporter(report func(title string, slot string, result backup.PruneResult, err error)) {
+	pruneReporter = report
+}
+
+// pruneAfterBackup trims a slot's history right after pull/push backed up the file
+// they overwrite. A failure does not fail the pull/push: the backup was taken, and
+// pruning runs again after the next one.
+func pruneAfterBackup(title string, slot string) {
+	result, err := PruneHistory(title, slot)
+	if pruneReporter != nil && (err != nil || len(result.Removed) > 0) {
+		pruneReporter(title, slot, result, err)
+	}
+}
@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+func TestHistoryPolicyFromRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules *models.Rules
+		want  HistoryPolicy
+	}{
+		{"Nil rules disable pruning", nil, HistoryPolicy{}},
+		{"Zero values are unlimited", &models.Rules{}, HistoryPolicy{}},
+		{"Count and age", &models.Rules{HistoryLimit: 20, HistoryMaxAgeDays: 90}, HistoryPolicy{Limit: 20, MaxAge: 90 * 24 * time.Hour}},
+		{"Negative age is unlimited", &models.Rules{HistoryLimit: 5, HistoryMaxAgeDays: -1}, HistoryPolicy{Limit: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HistoryPolicyFromRules(tt.rules); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPullFile_PrunesHistory(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	paths.HomeOverride = dir
+	t.Cleanup(func() { paths.HomeOverride = "" })
+	SetRules(&models.Rules{HistoryLimit: 2})
+	t.Cleanup(func() { SetRules(nil) })
+
+	var reported []string
+	SetPruneReporter(func(title, slot string, result backup.PruneResult, err error) {
+		if err != nil {
+			t.Errorf("Unexpected prune error: %v", err)
+		}
+		reported = append(reported, result.Removed...)
+	})
+	t.Cleanup(func() { SetPruneReporter(nil) })
+
+	historyDir, err := backup.GetHistoryDir("th08", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := []string{
+		"2025-01-03T00-00-00Z-score.dat",
+		"2025-01-02T00-00-00Z-score.dat",
+		"2025-01-01T00-00-00Z-score.dat",
+	}
+	for _, name := range old {
+		if err := os.WriteFile(filepath.Join(historyDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localPath := filepath.Join(dir, "local", "score.dat")
+	vaultPath := filepath.Join(dir, "vault", "th08", "main", "score.dat")
+	for _, p := range []string{localPath, vaultPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFileWithTime(t, localPath, []byte("local progress"), baseTime)
+	writeFileWithTime(t, vaultPath, []byte("vault progress"), baseTime.Add(-time.Hour))
+
+	if _, err := PullFile("th08", "main", localPath, vaultPath, true); err != nil {
+		t.Fatalf("PullFile failed: %v", err)
+	}
+
+	// The new backup and the newest old one are kept
+	backups, err := backup.ListBackupsIn(historyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[1] != old[0] {
+		t.Errorf("Expected the new backup and %s to be kept, got %v", old[0], backups)
+	}
+	if len(reported) != 2 {
+		t.Errorf("Expected 2 trimmed backups to be reported, got %v", reported)
+	}
+}
@@ -0,0 +1,65 @@
+package sync
+
+import (
+	stdsync "sync"
+	"time"
+)
+
+// IOStats is the time spent hashing and copying save files, and how much was read or
+// written, for reporting throughput.
+type IOStats struct {
+	HashTime  time.Duration
+	HashBytes int64
+	CopyTime  time.Duration // including verifying the written file and any retries
+	CopyBytes int64
+}
+
+// Add returns the sum of s and other.
+func (s IOStats) Add(other IOStats) IOStats {
+	return IOStats{
+		HashTime:  s.HashTime + other.HashTime,
+		HashBytes: s.HashBytes + other.HashBytes,
+		CopyTime:  s.CopyTime + other.CopyTime,
+		CopyBytes: s.CopyBytes + other.CopyBytes,
+	}
+}
+
+// CopyRate returns the copy throughput in bytes per second, or 0 if nothing was copied.
+func (s IOStats) CopyRate() float64 {
+	if s.CopyTime <= 0 {
+		return 0
+	}
+	return float64(s.CopyBytes) / s.CopyTime.Seconds()
+}
+
+// HashRate returns the hashing throughput in bytes per second, or 0 if nothing was hashed.
+func (s IOStats) HashRate() float64 {
+	if s.HashTime <= 0 {
+		return 0
+	}
+	return float64(s.HashBytes) / s.HashTime.Seconds()
+}
+
+var (
+	ioStatsMu stdsync.Mutex
+	ioStats   IOStats
+)
+
+// TakeIOStats returns the hashing and copying done since the last call and resets it.
+// Hashes served from the hash cache are not counted.
+func TakeIOStats() IOStats {
+	ioStatsMu.Lock()
+	defer ioStatsMu.Unlock()
+
+	stats := ioStats
+	ioStats = IOStats{}
+	return stats
+}
+
+// recordIO adds one hash or copy to the stats returned by TakeIOStats.
+func recordIO(stats IOStats) {
+	ioStatsMu.Lock()
+	defer ioStatsMu.Unlock()
+
+	ioStats = ioStats.Add(stats)
+}
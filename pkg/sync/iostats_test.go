@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTakeIOStats(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(src, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+	TakeIOStats()
+
+	if _, err := GetFileMetadata(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyVerified(src, filepath.Join(dir, "copy.dat"), 4096); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := TakeIOStats()
+	if stats.HashBytes != 4096 || stats.CopyBytes != 4096 {
+		t.Errorf("Expected 4096 bytes hashed and copied, got %+v", stats)
+	}
+	if stats.CopyTime <= 0 || stats.CopyRate() <= 0 {
+		t.Errorf("Expected the copy to be timed, got %+v", stats)
+	}
+	if again := TakeIOStats(); again != (IOStats{}) {
+		t.Errorf("Expected the stats to be reset, got %+v", again)
+	}
+}
+
+func TestIOStats_Rates(t *testing.T) {
+	stats := IOStats{HashTime: time.Second, HashBytes: 2048}.Add(IOStats{CopyTime: 2 * time.Second, CopyBytes: 1024})
+	if stats.HashRate() != 2048 || stats.CopyRate() != 512 {
+		t.Errorf("Unexpected rates: hash %v, copy %v", stats.HashRate(), stats.CopyRate())
+	}
+	if (IOStats{}).CopyRate() != 0 {
+		t.Error("Expected no rate when nothing was copied")
+	}
+}
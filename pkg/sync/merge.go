@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// MergeResult represents the outcome of merging one title from another vault.
+type MergeResult struct {
+	Title          string
+	Action         string // "identical", "kept_current", "took_other", "conflict", "skip"
+	Comparison     *models.ComparisonResult
+	HistoryMerged  int
+	HistorySkipped int
+}
+
+// MergeVaultTitle merges a single title from otherVault into currentVault.
+//
+// The preferred file is chosen by CompareFiles, with the current vault in the "local"
+// role and the other vault in the "remote" role. The losing side is always preserved
+// in the current vault's history. History directories are merged as well, deduplicated
+// by filename and hash.
+//
+// policy decides CONFLICT results: "current" keeps the current file, "other" takes the
+// other vault's file, and anything else leaves the title untouched (Action "conflict").
+func MergeVaultTitle(title, fileName, currentVault, otherVault, policy string) (*MergeResult, error) {
+	currentPath := filepath.Join(backup.GetTitleVaultPathIn(currentVault, title, backup.DefaultSlot), fileName)
+	otherPath := filepath.Join(backup.GetTitleVaultPathIn(otherVault, title, backup.DefaultSlot), fileName)
+	historyDir := filepath.Join(currentVault, title, backup.HistoryDir)
+
+	currentMeta, err := GetFileMetadata(currentPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current metadata: %w", err)
+	}
+
+	otherMeta, err := GetFileMetadata(otherPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get other metadata: %w", err)
+	}
+
+	comparison, err := CompareWithRehash(currentMeta, otherMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MergeResult{
+		Title:      title,
+		Comparison: comparison,
+	}
+
+	// Merge history first so the losing side's backup is never deduplicated away
+	merged, skipped, err := backup.MergeHistoryDir(historyDir, filepath.Join(otherVault, title, backup.HistoryDir))
+	if err != nil {
+		return result, fmt.Errorf("failed to merge history: %w", err)
+	}
+	result.HistoryMerged = merged
+	result.HistorySkipped = skipped
+
+	// Determine winner
+	var winner string
+	switch comparison.Recommendation {
+	case "PULL":
+		winner = "current"
+	case "PUSH":
+		winner = "other"
+	case "CONFLICT":
+		if policy == "current" || policy == "other" {
+			winner = policy
+		} else {
+			result.Action = "conflict"
+			return result, nil
+		}
+	default:
+		if comparison.HashMatch {
+			result.Action = "identical"
+		} else {
+			result.Action = "skip"
+		}
+		return result, nil
+	}
+
+	switch winner {
+	case "current":
+		// Keep current file, but preserve the other vault's version in history
+		if otherMeta.Exists && otherMeta.Readable {
+			if _, err := backup.CreateBackupIn(historyDir, otherPath); err != nil {
+				return result, fmt.Errorf("failed to backup other vault file: %w", err)
+			}
+		}
+		result.Action = "kept_current"
+
+	case "other":
+		if err := utils.EnsureDir(filepath.Dir(currentPath)); err != nil {
+			return result, fmt.Errorf("failed to create vault directory: %w", err)
+		}
+		if currentMeta.Exists && currentMeta.Readable {
+			if _, err := backup.CreateBackupIn(historyDir, currentPath); err != nil {
+				return result, fmt.Errorf("failed to backup current vault file: %w", err)
+			}
+		}
+		if err := utils.AtomicCopy(otherPath, currentPath); err != nil {
+			return result, fmt.Errorf("failed to copy file: %w", err)
+		}
+		result.Action = "took_other"
+	}
+
+	return result, nil
+}
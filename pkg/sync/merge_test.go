@@ -0,0 +1,164 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeVaultFile writes a vault main file for a title under vaultDir.
+func writeVaultFile(t *testing.T, vaultDir, title, fileName string, data []byte, modTime time.Time) string {
+	t.Helper()
+	dir := filepath.Join(vaultDir, title, "main")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %v", dir, err)
+	}
+	path := filepath.Join(dir, fileName)
+	writeFileWithTime(t, path, data, modTime)
+	return path
+}
+
+// countHistory returns the number of backups in a title's history directory.
+func countHistory(t *testing.T, vaultDir, title string) int {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(vaultDir, title, "_history"))
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	return len(entries)
+}
+
+func TestMergeVaultTitle(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		currentData     string
+		currentTime     time.Time
+		otherData       string
+		otherTime       time.Time
+		policy          string
+		expectedAction  string
+		expectedData    string
+		expectedHistory int
+	}{
+		{
+			name:            "Identical - nothing changes",
+			currentData:     "AAAA",
+			currentTime:     baseTime,
+			otherData:       "AAAA",
+			otherTime:       baseTime,
+			expectedAction:  "identical",
+			expectedData:    "AAAA",
+			expectedHistory: 0,
+		},
+		{
+			name:            "Other newer - take other, back up current",
+			currentData:     "AAAA",
+			currentTime:     baseTime,
+			otherData:       "BBBBB",
+			otherTime:       baseTime.Add(10 * time.Minute),
+			expectedAction:  "took_other",
+			expectedData:    "BBBBB",
+			expectedHistory: 1,
+		},
+		{
+			name:            "Conflict without policy - untouched",
+			currentData:     "AAAAA",
+			currentTime:     baseTime,
+			otherData:       "BBBB",
+			otherTime:       baseTime.Add(10 * time.Minute),
+			expectedAction:  "conflict",
+			expectedData:    "AAAAA",
+			expectedHistory: 0,
+		},
+		{
+			name:            "Conflict with current policy - keep current, back up other",
+			currentData:     "AAAAA",
+			currentTime:     baseTime,
+			otherData:       "BBBB",
+			otherTime:       baseTime.Add(10 * time.Minute),
+			policy:          "current",
+			expectedAction:  "kept_current",
+			expectedData:    "AAAAA",
+			expectedHistory: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currentVault := t.TempDir()
+			otherVault := t.TempDir()
+			currentPath := writeVaultFile(t, currentVault, "th08", "score.dat", []byte(tt.currentData), tt.currentTime)
+			writeVaultFile(t, otherVault, "th08", "score.dat", []byte(tt.otherData), tt.otherTime)
+
+			result, err := MergeVaultTitle("th08", "score.dat", currentVault, otherVault, tt.policy)
+			if err != nil {
+				t.Fatalf("MergeVaultTitle failed: %v", err)
+			}
+
+			if result.Action != tt.expectedAction {
+				t.Errorf("Expected action %s, got %s. Reason: %s",
+					tt.expectedAction, result.Action, result.Comparison.Reason)
+			}
+
+			data, err := os.ReadFile(currentPath)
+			if err != nil {
+				t.Fatalf("failed to read current file: %v", err)
+			}
+			if string(data) != tt.expectedData {
+				t.Errorf("Expected current file %q, got %q", tt.expectedData, string(data))
+			}
+
+			if got := countHistory(t, currentVault, "th08"); got != tt.expectedHistory {
+				t.Errorf("Expected %d history entries, got %d", tt.expectedHistory, got)
+			}
+		})
+	}
+}
+
+func TestMergeVaultTitle_MergesHistory(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	currentVault := t.TempDir()
+	otherVault := t.TempDir()
+	writeVaultFile(t, currentVault, "th08", "score.dat", []byte("AAAA"), baseTime)
+	writeVaultFile(t, otherVault, "th08", "score.dat", []byte("AAAA"), baseTime)
+
+	currentHistory := filepath.Join(currentVault, "th08", "_history")
+	otherHistory := filepath.Join(otherVault, "th08", "_history")
+	for _, dir := range []string{currentHistory, otherHistory} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Same name in both, same content under a different name, and one unique backup
+	files := map[string]string{
+		filepath.Join(currentHistory, "2025-11-01T00-00-00Z-score.dat"): "old1",
+		filepath.Join(otherHistory, "2025-11-01T00-00-00Z-score.dat"):   "old1",
+		filepath.Join(otherHistory, "2025-11-02T00-00-00Z-score.dat"):   "old1",
+		filepath.Join(otherHistory, "2025-11-03T00-00-00Z-score.dat"):   "old3",
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := MergeVaultTitle("th08", "score.dat", currentVault, otherVault, "")
+	if err != nil {
+		t.Fatalf("MergeVaultTitle failed: %v", err)
+	}
+
+	if result.HistoryMerged != 1 || result.HistorySkipped != 2 {
+		t.Errorf("Expected 1 merged and 2 skipped, got %d merged and %d skipped",
+			result.HistoryMerged, result.HistorySkipped)
+	}
+	if got := countHistory(t, currentVault, "th08"); got != 2 {
+		t.Errorf("Expected 2 history entries, got %d", got)
+	}
+}
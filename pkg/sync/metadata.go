@@ -2,46 +2,188 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/timing"
 	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
 )
 
+// metadataFS abstracts the filesystem calls made by GetFileMetadata,
+// so that round-trips can be counted against a simulated slow filesystem in tests.
+type metadataFS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// osFS is the metadataFS backed by the real filesystem.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)     { return os.Open(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
 // GetFileMetadata retrieves metadata for a file.
 // Returns nil if the file doesn't exist or can't be read.
+//
+// The file is opened once; size/mtime come from the open handle and the hash is
+// streamed from the same handle. This keeps round-trips low on network-mounted vaults.
+// When a hash cache is enabled and size/mtime match its entry, hashing is skipped.
+//
+// A vault file encrypted by vaultcrypt reports the size and hash of its contents, so it
+// compares with the unencrypted local file like any other vault file.
 func GetFileMetadata(path string) (*models.FileMetadata, error) {
+	return getFileMetadata(osFS{}, path, true)
+}
+
+// GetFileMetadataNoHash is GetFileMetadata without the hash, for comparisons that only
+// need to know whether two files are equal (see CompareFilesQuick).
+func GetFileMetadataNoHash(path string) (*models.FileMetadata, error) {
+	return getFileMetadata(osFS{}, path, false)
+}
+
+func getFileMetadata(fsys metadataFS, path string, withHash bool) (*models.FileMetadata, error) {
 	meta := &models.FileMetadata{
 		Path: path,
 	}
 
-	// Check existence and readability
-	exists, readable := utils.FileExists(path)
-	meta.Exists = exists
-	meta.Readable = readable
+	file, err := fsys.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return meta, nil
+		}
 
-	if !exists {
+		// File exists but can't be opened (possibly permission issue)
+		meta.Exists = true
+		info, statErr := fsys.Stat(path)
+		if statErr != nil {
+			return meta, fmt.Errorf("failed to stat file: %w", statErr)
+		}
+		meta.Size = info.Size()
+		meta.ModTime = info.ModTime().UTC()
 		return meta, nil
 	}
+	defer file.Close()
+
+	meta.Exists = true
 
-	// Get file info
-	info, err := os.Stat(path)
+	// Get file info from the open handle
+	info, err := file.Stat()
 	if err != nil {
 		return meta, fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	meta.Size = info.Size()
 	meta.ModTime = info.ModTime().UTC()
+	meta.Info = info
 
-	// Calculate hash if readable
-	if readable {
-		hash, err := utils.CalculateFileHash(path)
+	// Only regular files are considered readable
+	if !info.Mode().IsRegular() {
+		return meta, nil
+	}
+	meta.Readable = true
+
+	// Files opened through a test filesystem may not seek; they are never encrypted
+	seeker, canSeek := file.(io.ReadSeeker)
+	if canSeek {
+		encrypted, err := vaultcrypt.Sniff(seeker)
 		if err != nil {
-			return meta, fmt.Errorf("failed to calculate hash: %w", err)
+			return meta, fmt.Errorf("failed to read file: %w", err)
 		}
-		meta.Hash = hash
+		meta.Size = vaultcrypt.ContentSize(meta.Size, encrypted)
+	}
+	if !withHash {
+		return meta, nil
+	}
+
+	// Reuse the cached hash while size and mtime are unchanged
+	if hashCache != nil {
+		if hash, ok := hashCache.lookup(path, meta.Size, meta.ModTime); ok {
+			meta.Hash = hash
+			return meta, nil
+		}
+	}
+
+	// Stream hash from the same handle
+	var contents io.Reader = file
+	if canSeek {
+		if contents, _, err = vaultcrypt.NewReader(seeker, meta.Size); err != nil {
+			return meta, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+	stop := timing.Start("hash")
+	start := time.Now()
+	hash, err := utils.CalculateReaderHash(contents)
+	recordIO(IOStats{HashTime: time.Since(start), HashBytes: meta.Size})
+	stop()
+	if err != nil {
+		return meta, fmt.Errorf("failed to calculate hash: %w", err)
+	}
+	meta.Hash = hash
+
+	if hashCache != nil {
+		hashCache.store(path, meta.Size, meta.ModTime, hash)
 	}
 
 	return meta, nil
 }
+
+// isAmbiguous reports whether a comparison cannot be trusted because the quick pass
+// saw equal size and mtime on both sides but one of the hashes is missing, e.g. because
+// the file was unreadable during the quick pass.
+func isAmbiguous(local, remote *models.FileMetadata) bool {
+	if !local.Exists || !remote.Exists {
+		return false
+	}
+	if local.Size != remote.Size || !utils.TimeWithinDrift(local.ModTime, remote.ModTime, compareOptions.DriftTolerance) {
+		return false
+	}
+	return local.Hash == "" || remote.Hash == ""
+}
+
+// RehashIfAmbiguous re-runs the comparison with freshly computed full hashes when
+// the quick pass could not tell the files apart (equal size/mtime, missing hash).
+// If the rehash reveals that the contents differ, the result is escalated to CONFLICT
+// instead of silently defaulting to SKIP. A side that still cannot be hashed after the
+// retries fails the comparison. Non-ambiguous results are returned unchanged.
+func RehashIfAmbiguous(comparison *models.ComparisonResult) (*models.ComparisonResult, error) {
+	// A byte comparison already read the contents
+	if comparison.ByteCompared || !isAmbiguous(comparison.LocalMeta, comparison.RemoteMeta) {
+		return comparison, nil
+	}
+
+	local := *comparison.LocalMeta
+	remote := *comparison.RemoteMeta
+
+	for _, meta := range []*models.FileMetadata{&local, &remote} {
+		err := withRetry("hash", meta.Path, func() error {
+			hash, err := utils.CalculateFileHash(meta.Path)
+			meta.Hash = hash
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rehash %s: %w", meta.Path, err)
+		}
+		meta.Readable = true
+	}
+
+	result := CompareFiles(&local, &remote)
+	result.Rehashed = true
+
+	if !result.HashMatch && result.Recommendation == "SKIP" {
+		result.Recommendation = "CONFLICT"
+		result.Reason = fmt.Sprintf("rehash revealed different contents despite equal size (%d) and mtime", local.Size)
+	}
+
+	return result, nil
+}
+
+// CompareWithRehash compares two files and resolves quick-pass ambiguity by rehashing.
+func CompareWithRehash(local, remote *models.FileMetadata) (*models.ComparisonResult, error) {
+	return RehashIfAmbiguous(CompareFiles(local, remote))
+}
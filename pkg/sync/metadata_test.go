@@ -0,0 +1,208 @@
+package sync
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+// writeFileWithTime writes data to path and sets its mtime.
+func writeFileWithTime(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime on %s: %v", path, err)
+	}
+}
+
+func TestRehashIfAmbiguous(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		localData     string
+		remoteData    string
+		expectedRec   string
+		expectedMatch bool
+	}{
+		{
+			name:          "Rehash reveals real difference - CONFLICT",
+			localData:     "AAAA",
+			remoteData:    "BBBB",
+			expectedRec:   "CONFLICT",
+			expectedMatch: false,
+		},
+		{
+			name:          "Rehash confirms identical - SKIP",
+			localData:     "AAAA",
+			remoteData:    "AAAA",
+			expectedRec:   "SKIP",
+			expectedMatch: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			localPath := filepath.Join(dir, "local.dat")
+			remotePath := filepath.Join(dir, "remote.dat")
+			writeFileWithTime(t, localPath, []byte(tt.localData), baseTime)
+			writeFileWithTime(t, remotePath, []byte(tt.remoteData), baseTime)
+
+			// Simulate a quick pass where the local hash could not be read
+			local := &models.FileMetadata{
+				Path:     localPath,
+				Exists:   true,
+				Readable: false,
+				Size:     int64(len(tt.localData)),
+				ModTime:  baseTime,
+			}
+			remote, err := GetFileMetadata(remotePath)
+			if err != nil {
+				t.Fatalf("failed to get remote metadata: %v", err)
+			}
+
+			quick := CompareFiles(local, remote)
+			if quick.Recommendation != "SKIP" {
+				t.Fatalf("Expected quick pass to SKIP, got %s", quick.Recommendation)
+			}
+
+			result, err := RehashIfAmbiguous(quick)
+			if err != nil {
+				t.Fatalf("RehashIfAmbiguous failed: %v", err)
+			}
+
+			if !result.Rehashed {
+				t.Error("Expected Rehashed to be true")
+			}
+			if result.HashMatch != tt.expectedMatch {
+				t.Errorf("Expected HashMatch %v, got %v", tt.expectedMatch, result.HashMatch)
+			}
+			if result.Recommendation != tt.expectedRec {
+				t.Errorf("Expected %s, got %s. Reason: %s",
+					tt.expectedRec, result.Recommendation, result.Reason)
+			}
+		})
+	}
+}
+
+func TestRehashIfAmbiguous_RehashFails(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	remotePath := filepath.Join(dir, "remote.dat")
+	writeFileWithTime(t, remotePath, []byte("AAAA"), baseTime)
+
+	// The local save stays unreadable: it exists but cannot be hashed
+	local := &models.FileMetadata{
+		Path:    filepath.Join(dir, "locked.dat"),
+		Exists:  true,
+		Size:    4,
+		ModTime: baseTime,
+	}
+	remote, err := GetFileMetadata(remotePath)
+	if err != nil {
+		t.Fatalf("failed to get remote metadata: %v", err)
+	}
+
+	if result, err := RehashIfAmbiguous(CompareFiles(local, remote)); err == nil {
+		t.Errorf("Expected the failed rehash to be an error, got %s: %s", result.Recommendation, result.Reason)
+	}
+}
+
+func TestRehashIfAmbiguous_NotAmbiguous(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	local := &models.FileMetadata{
+		Path:     "/local/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     1000,
+		ModTime:  baseTime.Add(10 * time.Minute),
+		Hash:     "local_hash",
+	}
+	remote := &models.FileMetadata{
+		Path:     "/remote/test.dat",
+		Exists:   true,
+		Readable: true,
+		Size:     1000,
+		ModTime:  baseTime,
+		Hash:     "remote_hash",
+	}
+
+	result, err := RehashIfAmbiguous(CompareFiles(local, remote))
+	if err != nil {
+		t.Fatalf("RehashIfAmbiguous failed: %v", err)
+	}
+	if result.Rehashed {
+		t.Error("Expected no rehash for a non-ambiguous comparison")
+	}
+	if result.Recommendation != "PULL" {
+		t.Errorf("Expected PULL, got %s", result.Recommendation)
+	}
+}
+
+// slowFS wraps the real filesystem with a fixed latency per call and counts round-trips,
+// simulating a network-mounted vault.
+type slowFS struct {
+	latency time.Duration
+	opens   int
+	stats   int
+}
+
+func (s *slowFS) Open(name string) (fs.File, error) {
+	time.Sleep(s.latency)
+	s.opens++
+	return os.Open(name)
+}
+
+func (s *slowFS) Stat(name string) (fs.FileInfo, error) {
+	time.Sleep(s.latency)
+	s.stats++
+	return os.Stat(name)
+}
+
+func TestGetFileMetadata_MinimizesRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "score.dat")
+	if err := os.WriteFile(path, []byte("score data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := &slowFS{latency: time.Millisecond}
+	meta, err := getFileMetadata(fsys, path, true)
+	if err != nil {
+		t.Fatalf("getFileMetadata failed: %v", err)
+	}
+
+	if !meta.Exists || !meta.Readable || meta.Hash == "" {
+		t.Fatalf("Expected readable file with hash, got %+v", meta)
+	}
+	if meta.Size != int64(len("score data")) {
+		t.Errorf("Expected size %d, got %d", len("score data"), meta.Size)
+	}
+
+	// One open, no path-based stat: everything else uses the open handle
+	if fsys.opens != 1 || fsys.stats != 0 {
+		t.Errorf("Expected 1 open and 0 stats, got %d opens and %d stats", fsys.opens, fsys.stats)
+	}
+}
+
+func TestGetFileMetadata_NotExist(t *testing.T) {
+	fsys := &slowFS{}
+	meta, err := getFileMetadata(fsys, filepath.Join(t.TempDir(), "missing.dat"), true)
+	if err != nil {
+		t.Fatalf("getFileMetadata failed: %v", err)
+	}
+	if meta.Exists {
+		t.Error("Expected Exists to be false")
+	}
+	if fsys.opens != 1 || fsys.stats != 0 {
+		t.Errorf("Expected 1 open and 0 stats, got %d opens and %d stats", fsys.opens, fsys.stats)
+	}
+}
@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"runtime"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+// MetadataResult is the outcome of GetFileMetadata for one path.
+type MetadataResult struct {
+	Meta *models.FileMetadata
+	Err  error
+}
+
+// GetFileMetadataParallel runs GetFileMetadata for each path using at most jobs
+// concurrent workers. Results are returned in the same order as paths.
+// jobs <= 0 means runtime.NumCPU().
+func GetFileMetadataParallel(paths []string, jobs int) []MetadataResult {
+	return getFileMetadataParallel(osFS{}, paths, jobs, true)
+}
+
+// GetFileMetadataParallelNoHash is GetFileMetadataParallel using GetFileMetadataNoHash.
+func GetFileMetadataParallelNoHash(paths []string, jobs int) []MetadataResult {
+	return getFileMetadataParallel(osFS{}, paths, jobs, false)
+}
+
+func getFileMetadataParallel(fsys metadataFS, paths []string, jobs int, withHash bool) []MetadataResult {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(paths) {
+		jobs = len(paths)
+	}
+
+	results := make([]MetadataResult, len(paths))
+	indexes := make(chan int)
+	done := make(chan struct{})
+
+	// Each worker writes only to the result slots of the indexes it receives
+	for w := 0; w < jobs; w++ {
+		go func() {
+			for i := range indexes {
+				meta, err := getFileMetadata(fsys, paths[i], withHash)
+				results[i] = MetadataResult{Meta: meta, Err: err}
+			}
+			done <- struct{}{}
+		}()
+	}
+
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+
+	for w := 0; w < jobs; w++ {
+		<-done
+	}
+
+	return results
+}
@@ -0,0 +1,78 @@
+package sync
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyFS records the highest number of Open calls in flight at once.
+type concurrencyFS struct {
+	latency  time.Duration
+	inFlight int32
+	peak     int32
+}
+
+func (c *concurrencyFS) Open(name string) (fs.File, error) {
+	n := atomic.AddInt32(&c.inFlight, 1)
+	for {
+		peak := atomic.LoadInt32(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&c.peak, peak, n) {
+			break
+		}
+	}
+	time.Sleep(c.latency)
+	atomic.AddInt32(&c.inFlight, -1)
+	return os.Open(name)
+}
+
+func (c *concurrencyFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func TestGetFileMetadataParallel_OrderAndBound(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 8; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("scoreth%02d.dat", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("data %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	// A missing file is reported in place, not as an error
+	paths = append(paths, filepath.Join(dir, "missing.dat"))
+
+	fsys := &concurrencyFS{latency: 5 * time.Millisecond}
+	results := getFileMetadataParallel(fsys, paths, 3, true)
+
+	if len(results) != len(paths) {
+		t.Fatalf("Expected %d results, got %d", len(paths), len(results))
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("%s: unexpected error: %v", paths[i], r.Err)
+			continue
+		}
+		if r.Meta.Path != paths[i] {
+			t.Errorf("Result %d is for %s, expected %s", i, r.Meta.Path, paths[i])
+		}
+	}
+	if results[len(results)-1].Meta.Exists {
+		t.Error("Expected missing file to be reported as not existing")
+	}
+
+	if peak := atomic.LoadInt32(&fsys.peak); peak > 3 {
+		t.Errorf("Expected at most 3 concurrent opens, got %d", peak)
+	}
+}
+
+func TestGetFileMetadataParallel_Empty(t *testing.T) {
+	if results := GetFileMetadataParallel(nil, 4); len(results) != 0 {
+		t.Errorf("Expected no results, got %d", len(results))
+	}
+}
@@ -0,0 +1,29 @@
+package sync
+
+import (
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+)
+
+// QuarantineIfSuspicious sets aside the incoming file of a suspicious CONFLICT
+// (size-ratio heuristic) in the title's quarantine directory.
+// incomingPath is the side that would have overwritten the other: the local file
+// for pull, the vault file for push. The main files are never modified.
+// Returns the quarantined path, or "" if the comparison was not suspicious.
+func QuarantineIfSuspicious(title string, comparison *models.ComparisonResult, incomingPath string) (string, error) {
+	quarantineDir, err := backup.GetQuarantineDir(title)
+	if err != nil {
+		return "", err
+	}
+
+	return QuarantineIfSuspiciousIn(quarantineDir, comparison, incomingPath)
+}
+
+// QuarantineIfSuspiciousIn is QuarantineIfSuspicious with an explicit quarantine directory.
+func QuarantineIfSuspiciousIn(quarantineDir string, comparison *models.ComparisonResult, incomingPath string) (string, error) {
+	if comparison.Recommendation != "CONFLICT" || !comparison.Suspicious {
+		return "", nil
+	}
+
+	return backup.QuarantineFileIn(quarantineDir, incomingPath)
+}
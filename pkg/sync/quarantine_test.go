@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuarantineIfSuspiciousIn(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		localData        string
+		remoteData       string
+		expectQuarantine bool
+	}{
+		{
+			name:             "Local 3x larger - quarantined",
+			localData:        strings.Repeat("A", 3000),
+			remoteData:       strings.Repeat("B", 1000),
+			expectQuarantine: true,
+		},
+		{
+			name:             "Remote zero-byte - treated as missing, not quarantined",
+			localData:        strings.Repeat("A", 1000),
+			remoteData:       "",
+			expectQuarantine: false,
+		},
+		{
+			name:             "Local 1.5x larger - not suspicious",
+			localData:        strings.Repeat("A", 1500),
+			remoteData:       strings.Repeat("B", 1000),
+			expectQuarantine: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			localPath := filepath.Join(dir, "local.dat")
+			remotePath := filepath.Join(dir, "remote.dat")
+			quarantineDir := filepath.Join(dir, "_quarantine")
+			writeFileWithTime(t, localPath, []byte(tt.localData), baseTime)
+			writeFileWithTime(t, remotePath, []byte(tt.remoteData), baseTime)
+
+			localMeta, err := GetFileMetadata(localPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			remoteMeta, err := GetFileMetadata(remotePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			comparison := CompareFiles(localMeta, remoteMeta)
+
+			// Pull direction: the local file is incoming
+			quarantined, err := QuarantineIfSuspiciousIn(quarantineDir, comparison, localPath)
+			if err != nil {
+				t.Fatalf("QuarantineIfSuspiciousIn failed: %v", err)
+			}
+
+			if tt.expectQuarantine {
+				if quarantined == "" {
+					t.Fatalf("Expected file to be quarantined. Recommendation: %s, Reason: %s",
+						comparison.Recommendation, comparison.Reason)
+				}
+				data, err := os.ReadFile(quarantined)
+				if err != nil {
+					t.Fatalf("failed to read quarantined file: %v", err)
+				}
+				if string(data) != tt.localData {
+					t.Error("Quarantined file does not match incoming file")
+				}
+			} else if quarantined != "" {
+				t.Errorf("Expected no quarantine, got %s", quarantined)
+			}
+
+			// The main files must be untouched
+			if data, _ := os.ReadFile(remotePath); string(data) != tt.remoteData {
+				t.Error("Remote file was modified")
+			}
+			if data, _ := os.ReadFile(localPath); string(data) != tt.localData {
+				t.Error("Local file was modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// retryPolicy is applied to the stat and copy operations of pull/push. Set from rules.json via SetRules.
+var retryPolicy = RetryPolicyFromRules(nil)
+
+// RetryPolicyFromRules returns the retry policy configured in rules.
+// Zero or negative values (or nil rules) fall back to the defaults.
+func RetryPolicyFromRules(rules *models.Rules) utils.RetryPolicy {
+	policy := utils.DefaultRetryPolicy()
+	if rules == nil {
+		return policy
+	}
+
+	if rules.RetryAttempts > 0 {
+		policy.Attempts = rules.RetryAttempts
+	}
+	if rules.RetryBackoffMS > 0 {
+		policy.Backoff = time.Duration(rules.RetryBackoffMS) * time.Millisecond
+	}
+	return policy
+}
+
+// retryReporter is told about each retried operation. Set via SetRetryReporter.
+var retryReporter func(op string, path string, attempt int, err error)
+
+// SetRetryReporter makes pull/push report each retry of a stat, hash or copy that failed
+// with a transient error: the operation ("stat", "hash" or "copy"), its path, the failed
+// attempt and its error. nil turns reporting off.
+func SetRetryReporter(report func(op string, path string, attempt int, err error)) {
+	retryReporter = report
+}
+
+// withRetry runs fn under retryPolicy, reporting each retry of op on path.
+func withRetry(op string, path string, fn func() error) error {
+	return utils.Retry(retryPolicy, fn, func(attempt int, err error) {
+		if retryReporter != nil {
+			retryReporter(op, path, attempt, err)
+		}
+	})
+}
+
+// getFileMetadataRetry is GetFileMetadata retried on transient errors.
+func getFileMetadataRetry(path string) (*models.FileMetadata, error) {
+	var meta *models.FileMetadata
+	err := withRetry("stat", path, func() error {
+		var err error
+		meta, err = GetFileMetadata(path)
+		return err
+	})
+	return meta, err
+}
@@ -0,0 +1,32 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+func TestRetryPolicyFromRules(t *testing.T) {
+	defaults := utils.DefaultRetryPolicy()
+
+	tests := []struct {
+		name  string
+		rules *models.Rules
+		want  utils.RetryPolicy
+	}{
+		{"Nil rules use defaults", nil, defaults},
+		{"Zero values use defaults", &models.Rules{}, defaults},
+		{"Configured", &models.Rules{RetryAttempts: 5, RetryBackoffMS: 50}, utils.RetryPolicy{Attempts: 5, Backoff: 50 * time.Millisecond}},
+		{"Single attempt disables retries", &models.Rules{RetryAttempts: 1}, utils.RetryPolicy{Attempts: 1, Backoff: defaults.Backoff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryPolicyFromRules(tt.rules); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
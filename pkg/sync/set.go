@@ -0,0 +1,181 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// fileCopy is one file written by a pull or push.
+type fileCopy struct {
+	src  string
+	dest string
+	size int64
+}
+
+// setFileCopies returns the copies of the set files that travel with the save file
+// copied from src to dest: each name in setFiles, next to src, is copied next to dest.
+// Set files missing on the source side or excluded by rules.json are left out.
+func setFileCopies(src string, dest string, setFiles []string) ([]fileCopy, error) {
+	var copies []fileCopy
+	for _, name := range setFiles {
+		if fileFilter.Excluded(name) {
+			continue
+		}
+
+		path := filepath.Join(filepath.Dir(src), name)
+		meta, err := getFileMetadataRetry(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of %s: %w", name, err)
+		}
+		if !meta.Exists {
+			continue
+		}
+		if !meta.Readable {
+			return nil, fmt.Errorf("set file is not readable: %s", path)
+		}
+
+		copies = append(copies, fileCopy{src: path, dest: filepath.Join(filepath.Dir(dest), name), size: meta.Size})
+	}
+
+	return copies, nil
+}
+
+// backupSetFiles keeps a history copy of each existing set file destination before
+// it is overwritten.
+func backupSetFiles(title string, slot string, copies []fileCopy) error {
+	for _, c := range copies {
+		exists, readable := utils.FileExists(c.dest)
+		if !exists || !readable {
+			continue
+		}
+		if _, err := backup.CreateBackup(title, slot, c.dest); err != nil {
+			return fmt.Errorf("failed to backup %s: %w", filepath.Base(c.dest), err)
+		}
+	}
+	return nil
+}
+
+// asideSuffix names the copy a set copy keeps of each destination it replaces until
+// every file is in place, so a failed rename can put the replaced files back.
+const asideSuffix = ".thls-old"
+
+// commitStaged is StagedCopy.Commit; tests replace it to make a rename fail.
+var commitStaged = (*utils.StagedCopy).Commit
+
+// copySetVerified copies the save file and its set files all or nothing: every file
+// is first written beside its destination and verified, and only once all of them are
+// staged are they renamed into place. If any copy fails, the staged files are removed
+// and no destination is touched; if a rename fails, the files already renamed are
+// rolled back to their previous contents. So the save file and its config never come
+// from different points in time. Transient errors are retried per retryPolicy.
+func copySetVerified(copies []fileCopy) error {
+	if len(copies) == 1 {
+		return copyVerified(copies[0].src, copies[0].dest, copies[0].size)
+	}
+
+	start := time.Now()
+	staged := make([]*utils.StagedCopy, 0, len(copies))
+	discard := func() {
+		for _, s := range staged {
+			s.Discard()
+		}
+	}
+
+	var total int64
+	for _, c := range copies {
+		var cb utils.ProgressFunc
+		if copyProgress != nil && c.size >= ProgressThreshold {
+			cb = copyProgress(c.dest)
+		}
+		err := withRetry("copy", c.dest, func() error {
+			s, err := utils.StageCopyVerified(c.src, c.dest, cb)
+			if err == nil {
+				staged = append(staged, s)
+			}
+			return err
+		})
+		if err != nil {
+			discard()
+			return fmt.Errorf("%s: %w", filepath.Base(c.dest), err)
+		}
+		total += c.size
+	}
+
+	// Renames are quick and only start once every file is staged. Each existing
+	// destination is moved aside first, and only removed once all renames succeeded.
+	var asides []string // per committed file, "" if it had no destination before
+	rollback := func() error {
+		var failed []string
+		for i := len(asides) - 1; i >= 0; i-- {
+			dest := copies[i].dest
+			var err error
+			if asides[i] == "" {
+				err = os.Remove(dest)
+			} else {
+				err = utils.SafeRename(asides[i], dest)
+			}
+			if err != nil {
+				failed = append(failed, filepath.Base(dest))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to roll back %s, restore them from the history", strings.Join(failed, ", "))
+		}
+		return nil
+	}
+
+	for i, s := range staged {
+		dest := copies[i].dest
+		aside, err := moveAside(dest)
+		if err == nil {
+			err = withRetry("copy", dest, func() error { return commitStaged(s) })
+			if err != nil && aside != "" {
+				// The rename left the staged copy in place; put the destination back
+				if restoreErr := utils.SafeRename(aside, dest); restoreErr != nil {
+					err = fmt.Errorf("%w (and failed to restore it: %v)", err, restoreErr)
+				}
+			}
+		}
+		if err != nil {
+			for _, rest := range staged[i:] {
+				rest.Discard()
+			}
+			if rollbackErr := rollback(); rollbackErr != nil {
+				return fmt.Errorf("%s: %w (%v)", filepath.Base(dest), err, rollbackErr)
+			}
+			return fmt.Errorf("%s: %w", filepath.Base(dest), err)
+		}
+		asides = append(asides, aside)
+	}
+
+	for _, aside := range asides {
+		if aside != "" {
+			os.Remove(aside)
+		}
+	}
+
+	recordIO(IOStats{CopyTime: time.Since(start), CopyBytes: total})
+	return nil
+}
+
+// moveAside renames an existing dest to dest+asideSuffix and returns that path, or ""
+// if dest does not exist.
+func moveAside(dest string) (string, error) {
+	aside := dest + asideSuffix
+	err := utils.SafeRename(dest, aside)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to move the previous file aside: %w", err)
+	}
+	return aside, nil
+}
@@ -0,0 +1,173 @@
+package sync
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+func TestPullTitle_SetFiles(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	paths.HomeOverride = dir
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	localDir := filepath.Join(dir, "local")
+	vaultDir := filepath.Join(dir, "vault", "th128", "main")
+	for _, d := range []string{localDir, vaultDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFileWithTime(t, filepath.Join(localDir, "scoreth128.dat"), []byte("local score"), baseTime)
+	writeFileWithTime(t, filepath.Join(localDir, "th128.cfg"), []byte("local config"), baseTime)
+	writeFileWithTime(t, filepath.Join(vaultDir, "scoreth128.dat"), []byte("vault score"), baseTime.Add(-time.Hour))
+	writeFileWithTime(t, filepath.Join(vaultDir, "th128.cfg"), []byte("vault config"), baseTime.Add(-time.Hour))
+
+	target := TitleTarget{
+		Title:     "th128",
+		DeviceID:  "abcdefabcdef",
+		Slot:      "main",
+		LocalPath: filepath.Join(localDir, "scoreth128.dat"),
+		VaultPath: filepath.Join(vaultDir, "scoreth128.dat"),
+		SetFiles:  []string{"th128.cfg", "missing.cfg"},
+	}
+	result, err := PullTitle(&models.PathsConfig{}, target, TitleOptions{CreateBackup: true}, baseTime)
+	if err != nil {
+		t.Fatalf("PullTitle failed: %v", err)
+	}
+	if result.Action != ActionPull {
+		t.Fatalf("Expected pull, got %s (%s)", result.Action, result.Comparison.Reason)
+	}
+
+	// The config travels with the score; a set file missing locally is skipped
+	for name, want := range map[string]string{"scoreth128.dat": "local score", "th128.cfg": "local config"} {
+		if data, _ := os.ReadFile(filepath.Join(vaultDir, name)); string(data) != want {
+			t.Errorf("%s: expected %q in the vault, got %q", name, want, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, "missing.cfg")); !os.IsNotExist(err) {
+		t.Errorf("Expected no missing.cfg in the vault, got err=%v", err)
+	}
+
+	// Both overwritten vault files were backed up
+	details, err := backup.GetBackupDetails("th128", "main")
+	if err != nil {
+		t.Fatalf("GetBackupDetails failed: %v", err)
+	}
+	if len(details) != 2 {
+		t.Errorf("Expected 2 backups, got %d", len(details))
+	}
+}
+
+func TestCopySetVerified_AllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	destDir := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "score.dat"), []byte("new score"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"score.dat", "game.cfg"} {
+		if err := os.WriteFile(filepath.Join(destDir, name), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The config vanished after it was listed, so its copy fails
+	err := copySetVerified([]fileCopy{
+		{src: filepath.Join(dir, "score.dat"), dest: filepath.Join(destDir, "score.dat"), size: 9},
+		{src: filepath.Join(dir, "game.cfg"), dest: filepath.Join(destDir, "game.cfg"), size: 6},
+	})
+	if err == nil {
+		t.Fatal("Expected the set copy to fail")
+	}
+
+	// Neither destination was replaced and no staged copy is left behind
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the two original files, got %d entries", len(entries))
+	}
+	for _, name := range []string{"score.dat", "game.cfg"} {
+		if data, _ := os.ReadFile(filepath.Join(destDir, name)); string(data) != "old" {
+			t.Errorf("%s: expected the old contents, got %q", name, data)
+		}
+	}
+}
+
+func TestCopySetVerified_RollsBackOnFailedRename(t *testing.T) {
+	dir := t.TempDir()
+	destDir := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"score.dat", "game.cfg", "extra.dat"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("new"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"score.dat", "game.cfg"} {
+		if err := os.WriteFile(filepath.Join(destDir, name), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second rename fails after a new file was already renamed into place
+	commits := 0
+	commitStaged = func(s *utils.StagedCopy) error {
+		if commits++; commits == 2 {
+			return fs.ErrPermission
+		}
+		return s.Commit()
+	}
+	t.Cleanup(func() { commitStaged = (*utils.StagedCopy).Commit })
+
+	var copies []fileCopy
+	for _, name := range []string{"extra.dat", "score.dat", "game.cfg"} {
+		copies = append(copies, fileCopy{src: filepath.Join(dir, name), dest: filepath.Join(destDir, name), size: 3})
+	}
+	if err := copySetVerified(copies); err == nil {
+		t.Fatal("Expected the set copy to fail")
+	}
+
+	// Every destination is back to its old state, with nothing left beside it
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the two original files, got %d entries", len(entries))
+	}
+	for _, name := range []string{"score.dat", "game.cfg"} {
+		if data, _ := os.ReadFile(filepath.Join(destDir, name)); string(data) != "old" {
+			t.Errorf("%s: expected the old contents, got %q", name, data)
+		}
+	}
+
+	// Without a failure the whole set is replaced and the old files are removed
+	commitStaged = (*utils.StagedCopy).Commit
+	if err := copySetVerified(copies); err != nil {
+		t.Fatalf("copySetVerified failed: %v", err)
+	}
+	entries, _ = os.ReadDir(destDir)
+	if len(entries) != 3 {
+		t.Errorf("Expected the three copied files, got %d entries", len(entries))
+	}
+	for _, c := range copies {
+		if data, _ := os.ReadFile(c.dest); string(data) != "new" {
+			t.Errorf("%s: expected the new contents, got %q", filepath.Base(c.dest), data)
+		}
+	}
+}
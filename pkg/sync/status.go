@@ -0,0 +1,148 @@
+package sync
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+// StatusOptions controls how Status compares titles.
+type StatusOptions struct {
+	Slot  string // vault slot to compare against
+	Quick bool   // byte-compare same-size files instead of hashing
+	Jobs  int    // maximum parallel hashing
+
+	// VaultFileName returns the vault file name of a title
+	VaultFileName func(title string) string
+}
+
+// TitleStatus is the comparison of one title's local and vault save files, as emitted
+// by status --json. The comparison is omitted for excluded titles and errors; remote
+// is the vault side.
+type TitleStatus struct {
+	Title      string     `json:"title"`
+	Excluded   bool       `json:"excluded,omitempty"` // rejected by rules.json include/exclude
+	Error      string     `json:"error,omitempty"`
+	LastSynced *time.Time `json:"last_synced,omitempty"` // last pull/push from this device
+	*models.ComparisonResult
+}
+
+// statusTarget holds the resolved files for one title compared by Status.
+type statusTarget struct {
+	title      string
+	localPath  string
+	vaultPath  string
+	lastSynced time.Time // zero if never synced from this device
+	excluded   bool      // rejected by rules.json include/exclude
+	err        error     // path resolution failed
+}
+
+// Status compares the local and vault save files of titles for deviceID without writing
+// anything. Every file is hashed up front in parallel; results are in the order of titles.
+// A title that cannot be compared is reported in its TitleStatus, never as an error.
+func Status(pathsConfig *models.PathsConfig, titles []string, deviceID string, opts StatusOptions) []TitleStatus {
+	// Resolve paths, then hash every file up front in parallel
+	targets := make([]statusTarget, 0, len(titles))
+	var paths []string
+	for _, title := range titles {
+		target := resolveStatusTarget(pathsConfig, title, deviceID, opts)
+		if target.err == nil && !target.excluded {
+			paths = append(paths, target.localPath, target.vaultPath)
+		}
+		targets = append(targets, target)
+	}
+
+	var results []MetadataResult
+	if opts.Quick {
+		results = GetFileMetadataParallelNoHash(paths, opts.Jobs)
+	} else {
+		results = GetFileMetadataParallel(paths, opts.Jobs)
+	}
+
+	statuses := make([]TitleStatus, 0, len(targets))
+	next := 0
+	for _, target := range targets {
+		if target.err != nil || target.excluded {
+			statuses = append(statuses, statusTitle(target, MetadataResult{}, MetadataResult{}, opts.Quick))
+			continue
+		}
+		statuses = append(statuses, statusTitle(target, results[next], results[next+1], opts.Quick))
+		next += 2
+	}
+
+	return statuses
+}
+
+// resolveStatusTarget finds the local and vault files compared for a title.
+func resolveStatusTarget(pathsConfig *models.PathsConfig, title, deviceID string, opts StatusOptions) statusTarget {
+	target := statusTarget{title: title}
+	target.lastSynced = pathsConfig.Paths[title][deviceID].LastSynced
+
+	// Get local path
+	localPath, err := GetPreferredLocalPath(pathsConfig, title, deviceID)
+	if err != nil {
+		target.err = fmt.Errorf("no path configured")
+		return target
+	}
+	target.localPath = localPath
+
+	// Files rejected by rules.json are not synced
+	if !AllowsFile(filepath.Base(localPath)) {
+		target.excluded = true
+		return target
+	}
+
+	// Get vault path
+	vaultPath, err := GetVaultFilePath(title, opts.Slot, opts.VaultFileName(title))
+	if err != nil {
+		target.err = fmt.Errorf("failed to get vault path: %w", err)
+		return target
+	}
+	target.vaultPath = vaultPath
+
+	return target
+}
+
+// statusTitle compares one title from pre-gathered metadata. With quick, no hashes
+// were gathered, so equality comes from a byte comparison.
+func statusTitle(target statusTarget, local, vault MetadataResult, quick bool) TitleStatus {
+	status := TitleStatus{Title: target.title}
+	if !target.lastSynced.IsZero() {
+		status.LastSynced = &target.lastSynced
+	}
+
+	if target.err != nil {
+		status.Error = target.err.Error()
+		return status
+	}
+	if target.excluded {
+		status.Excluded = true
+		return status
+	}
+
+	if local.Err != nil {
+		status.Error = fmt.Sprintf("failed to get local metadata: %v", local.Err)
+		return status
+	}
+	if vault.Err != nil {
+		status.Error = fmt.Sprintf("failed to get vault metadata: %v", vault.Err)
+		return status
+	}
+
+	var comparison *models.ComparisonResult
+	var err error
+	if quick {
+		comparison, err = RehashIfAmbiguous(CompareFilesQuick(local.Meta, vault.Meta))
+	} else {
+		comparison, err = CompareWithRehash(local.Meta, vault.Meta)
+	}
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.ComparisonResult = comparison
+
+	return status
+}
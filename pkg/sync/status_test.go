@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+func TestStatusTitle_JSON(t *testing.T) {
+	mtime := time.Date(2025, 11, 11, 6, 20, 30, 0, time.UTC)
+	local := MetadataResult{Meta: &models.FileMetadata{Path: "local/score.dat", Exists: true, Readable: true, Size: 20, ModTime: mtime.Add(time.Hour), Hash: "aaa"}}
+	vault := MetadataResult{Meta: &models.FileMetadata{Path: "vault/score.dat", Exists: true, Readable: true, Size: 10, ModTime: mtime, Hash: "bbb"}}
+
+	results := []TitleStatus{
+		statusTitle(statusTarget{title: "th08", lastSynced: mtime}, local, vault, false),
+		statusTitle(statusTarget{title: "th10", excluded: true}, MetadataResult{}, MetadataResult{}, false),
+		statusTitle(statusTarget{title: "th11", err: errors.New("no path configured")}, MetadataResult{}, MetadataResult{}, false),
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+
+	compared := decoded[0]
+	if compared["title"] != "th08" || compared["recommendation"] != "PULL" {
+		t.Errorf("Unexpected comparison entry: %v", compared)
+	}
+	if compared["size_diff"] != float64(10) || compared["time_diff"] != float64(3600) {
+		t.Errorf("Unexpected diffs: size_diff=%v time_diff=%v", compared["size_diff"], compared["time_diff"])
+	}
+	if compared["last_synced"] != "2025-11-11T06:20:30Z" {
+		t.Errorf("Unexpected last_synced: %v", compared["last_synced"])
+	}
+	if _, ok := decoded[1]["last_synced"]; ok {
+		t.Errorf("Expected no last_synced for a title never synced: %v", decoded[1])
+	}
+	if localMeta, ok := compared["local"].(map[string]interface{}); !ok || localMeta["hash"] != "aaa" {
+		t.Errorf("Unexpected local metadata: %v", compared["local"])
+	}
+
+	if decoded[1]["excluded"] != true || decoded[1]["recommendation"] != nil {
+		t.Errorf("Unexpected excluded entry: %v", decoded[1])
+	}
+	if decoded[2]["error"] != "no path configured" {
+		t.Errorf("Unexpected error entry: %v", decoded[2])
+	}
+}
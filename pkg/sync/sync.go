@@ -1,8 +1,10 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/otagao/touhou-local-sync/internal/models"
 	"github.com/otagao/touhou-local-sync/pkg/backup"
@@ -15,41 +17,85 @@ import (
 //
 // Steps:
 // 1. Compare local and vault files
-// 2. If local is preferred, backup vault file
-// 3. Copy local to vault atomically
-func PullFile(title string, localPath string, vaultPath string) (*models.ComparisonResult, error) {
-	// Get metadata for both files
-	localMeta, err := GetFileMetadata(localPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get local metadata: %w", err)
+// 2. If local is preferred, backup vault file (unless createBackup is false)
+// 3. Copy local to vault atomically, verifying the written hash
+// 4. Trim the slot's history per rules.json (history_limit, history_max_age_days)
+//
+// Reading metadata and copying are retried on transient I/O errors (rules.json
+// retry_attempts, retry_backoff_ms). Files rejected by the rules.json include/exclude
+// lists are skipped.
+func PullFile(title string, slot string, localPath string, vaultPath string, createBackup bool) (*models.ComparisonResult, error) {
+	return pullSet(title, slot, localPath, vaultPath, nil, createBackup)
+}
+
+// pullSet is PullFile that copies the set files named in setFiles along with the save
+// file, all or nothing (see copySetVerified). The save file alone decides the direction.
+func pullSet(title string, slot string, localPath string, vaultPath string, setFiles []string, createBackup bool) (*models.ComparisonResult, error) {
+	if comparison := excludedComparison(localPath, vaultPath); comparison != nil {
+		return comparison, nil
 	}
 
-	vaultMeta, err := GetFileMetadata(vaultPath)
+	return pullFile(title, slot, localPath, vaultPath, setFiles, createBackup)
+}
+
+// pullFile is pullSet without the include/exclude check.
+func pullFile(title string, slot string, localPath string, vaultPath string, setFiles []string, createBackup bool) (*models.ComparisonResult, error) {
+	comparison, err := previewPull(localPath, vaultPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
+		return nil, err
 	}
 
-	// Compare files
-	comparison := CompareFiles(localMeta, vaultMeta)
-
 	// Only proceed if recommendation is PULL
 	if comparison.Recommendation != "PULL" {
 		return comparison, nil
 	}
 
-	return executePull(title, localPath, vaultPath, vaultMeta, comparison)
+	return executePull(title, slot, localPath, vaultPath, setFiles, comparison.RemoteMeta, comparison, createBackup)
+}
+
+// PreviewPull compares local and vault files exactly as PullFile does, without writing anything.
+// Used by --dry-run.
+func PreviewPull(localPath string, vaultPath string) (*models.ComparisonResult, error) {
+	if comparison := excludedComparison(localPath, vaultPath); comparison != nil {
+		return comparison, nil
+	}
+
+	return previewPull(localPath, vaultPath)
+}
+
+// previewPull is PreviewPull without the include/exclude check.
+func previewPull(localPath string, vaultPath string) (*models.ComparisonResult, error) {
+	// Get metadata for both files
+	localMeta, err := getFileMetadataRetry(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get local metadata: %w", err)
+	}
+
+	vaultMeta, err := getFileMetadataRetry(vaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
+	}
+
+	// Compare files (rehashing both sides if the quick pass is ambiguous)
+	return CompareWithRehash(localMeta, vaultMeta)
 }
 
 // ForcePullFile forces a pull operation regardless of comparison result.
 // Used when user explicitly chooses to use local file after conflict resolution.
-func ForcePullFile(title string, localPath string, vaultPath string) (*models.ComparisonResult, error) {
+func ForcePullFile(title string, slot string, localPath string, vaultPath string, createBackup bool) (*models.ComparisonResult, error) {
+	return forcePullSet(title, slot, localPath, vaultPath, nil, createBackup)
+}
+
+// forcePullSet is ForcePullFile that copies the set files named in setFiles along with
+// the save file, as pullSet does.
+func forcePullSet(title string, slot string, localPath string, vaultPath string, setFiles []string, createBackup bool) (*models.ComparisonResult, error) {
 	// Get metadata for both files
-	localMeta, err := GetFileMetadata(localPath)
+	localMeta, err := getFileMetadataRetry(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get local metadata: %w", err)
 	}
 
-	vaultMeta, err := GetFileMetadata(vaultPath)
+	vaultMeta, err := getFileMetadataRetry(vaultPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
 	}
@@ -58,27 +104,41 @@ func ForcePullFile(title string, localPath string, vaultPath string) (*models.Co
 	comparison := CompareFiles(localMeta, vaultMeta)
 	comparison.Recommendation = "PULL" // Force PULL
 
-	return executePull(title, localPath, vaultPath, vaultMeta, comparison)
+	return executePull(title, slot, localPath, vaultPath, setFiles, vaultMeta, comparison, createBackup)
 }
 
-// executePull performs the actual pull operation.
-func executePull(title string, localPath string, vaultPath string, vaultMeta *models.FileMetadata, comparison *models.ComparisonResult) (*models.ComparisonResult, error) {
+// executePull performs the actual pull operation, copying the set files named in
+// setFiles along with the save file. With createBackup false the existing vault
+// files are overwritten without a history copy.
+func executePull(title string, slot string, localPath string, vaultPath string, setFiles []string, vaultMeta *models.FileMetadata, comparison *models.ComparisonResult, createBackup bool) (*models.ComparisonResult, error) {
 	// Ensure vault directory exists
 	vaultDir := filepath.Dir(vaultPath)
 	if err := utils.EnsureDir(vaultDir); err != nil {
 		return comparison, fmt.Errorf("failed to create vault directory: %w", err)
 	}
 
-	// Backup existing vault file if it exists
-	if vaultMeta.Exists && vaultMeta.Readable {
-		_, err := backup.CreateBackup(title, vaultPath)
-		if err != nil {
+	setCopies, err := setFileCopies(localPath, vaultPath, setFiles)
+	if err != nil {
+		return comparison, err
+	}
+
+	// Backup existing vault files if they exist
+	if createBackup {
+		if vaultMeta.Exists && vaultMeta.Readable {
+			_, err := backup.CreateBackup(title, slot, vaultPath)
+			if err != nil {
+				return comparison, fmt.Errorf("failed to backup vault file: %w", err)
+			}
+		}
+		if err := backupSetFiles(title, slot, setCopies); err != nil {
 			return comparison, fmt.Errorf("failed to backup vault file: %w", err)
 		}
+		pruneAfterBackup(title, slot)
 	}
 
-	// Copy local to vault
-	if err := utils.AtomicCopy(localPath, vaultPath); err != nil {
+	// Copy local to vault, together with the set files
+	copies := append([]fileCopy{{src: localPath, dest: vaultPath, size: comparison.LocalMeta.Size}}, setCopies...)
+	if err := copySetVerified(copies); err != nil {
 		return comparison, fmt.Errorf("failed to copy file: %w", err)
 	}
 
@@ -91,9 +151,54 @@ func executePull(title string, localPath string, vaultPath string, vaultMeta *mo
 // Steps:
 // 1. Check if local file is safe to write (no game running, not locked)
 // 2. Compare vault and local files
-// 3. If vault is preferred, backup local file
-// 4. Copy vault to local atomically
-func PushFile(title string, vaultPath string, localPath string, force bool) (*models.ComparisonResult, error) {
+// 3. If vault is preferred, backup local file (unless createBackup is false)
+// 4. Copy vault to local atomically, verifying the written hash
+// 5. Trim the slot's history per rules.json (history_limit, history_max_age_days)
+//
+// Reading metadata and copying are retried on transient I/O errors (rules.json
+// retry_attempts, retry_backoff_ms). Files rejected by the rules.json include/exclude
+// lists are skipped.
+func PushFile(title string, slot string, vaultPath string, localPath string, force bool, createBackup bool) (*models.ComparisonResult, error) {
+	return pushSet(title, slot, vaultPath, localPath, nil, force, false, createBackup)
+}
+
+// pushSet is PushFile that copies the set files named in setFiles along with the save
+// file, all or nothing (see copySetVerified). The save file alone decides the direction.
+// allowEmptyVault lets a forced push write a 0-byte vault file over a non-empty local save.
+func pushSet(title string, slot string, vaultPath string, localPath string, setFiles []string, force, allowEmptyVault bool, createBackup bool) (*models.ComparisonResult, error) {
+	if comparison := excludedComparison(localPath, vaultPath); comparison != nil {
+		return comparison, nil
+	}
+
+	return pushFile(title, slot, vaultPath, localPath, setFiles, force, allowEmptyVault, createBackup)
+}
+
+// pushFile is pushSet without the include/exclude check.
+func pushFile(title string, slot string, vaultPath string, localPath string, setFiles []string, force, allowEmptyVault bool, createBackup bool) (*models.ComparisonResult, error) {
+	comparison, err := previewPush(title, vaultPath, localPath, force, allowEmptyVault)
+	if err != nil {
+		return comparison, err
+	}
+
+	if comparison.Recommendation == "SKIP" {
+		return comparison, nil
+	}
+
+	return executePush(title, slot, vaultPath, localPath, setFiles, comparison.LocalMeta, comparison, createBackup)
+}
+
+// PreviewPush runs the same safety check and comparison as PushFile, without writing anything.
+// It returns the same errors PushFile would for a blocked push. Used by --dry-run.
+func PreviewPush(title string, vaultPath string, localPath string, force bool) (*models.ComparisonResult, error) {
+	if comparison := excludedComparison(localPath, vaultPath); comparison != nil {
+		return comparison, nil
+	}
+
+	return previewPush(title, vaultPath, localPath, force, false)
+}
+
+// previewPush is PreviewPush without the include/exclude check.
+func previewPush(title string, vaultPath string, localPath string, force, allowEmptyVault bool) (*models.ComparisonResult, error) {
 	// Check if it's safe to write to local file
 	safe, reason, err := process.CanSafelyWrite(localPath, title)
 	if err != nil {
@@ -104,39 +209,62 @@ func PushFile(title string, vaultPath string, localPath string, force bool) (*mo
 	}
 
 	// Get metadata for both files
-	vaultMeta, err := GetFileMetadata(vaultPath)
+	vaultMeta, err := getFileMetadataRetry(vaultPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
 	}
 
-	localMeta, err := GetFileMetadata(localPath)
+	localMeta, err := getFileMetadataRetry(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get local metadata: %w", err)
 	}
 
-	// Compare files
-	comparison := CompareFiles(localMeta, vaultMeta)
+	// Compare files (rehashing both sides if the quick pass is ambiguous)
+	comparison, err := CompareWithRehash(localMeta, vaultMeta)
+	if err != nil {
+		return nil, err
+	}
 
-	// Only proceed if recommendation is PUSH
-	if comparison.Recommendation != "PUSH" {
-		// If local is newer, warn and skip unless forced
-		if comparison.Recommendation == "PULL" && !force {
-			return comparison, fmt.Errorf("local file appears newer than vault, skipping push (use --force to override)")
-		}
-		if comparison.Recommendation == "SKIP" {
-			return comparison, nil
-		}
-		if comparison.Recommendation == "CONFLICT" && !force {
-			return comparison, fmt.Errorf("file conflict detected: %s (use --force to override)", comparison.Reason)
-		}
+	// Never replace a good local save with an empty vault file unless forced and confirmed
+	if isEmptyVaultOverLocal(localMeta, vaultMeta) && !(force && allowEmptyVault) {
+		comparison.Recommendation = "SKIP"
+		comparison.Reason = ReasonEmptyVault
+		return comparison, fmt.Errorf("%w (use --force and confirm to override)", ErrEmptyVault)
+	}
+
+	// If local is newer or conflicting, refuse unless forced
+	if comparison.Recommendation == "PULL" && !force {
+		return comparison, fmt.Errorf("local file appears newer than vault, skipping push (use --force to override)")
+	}
+	if comparison.Recommendation == "CONFLICT" && !force {
+		return comparison, fmt.Errorf("file conflict detected: %s (use --force to override)", comparison.Reason)
 	}
 
-	return executePush(title, vaultPath, localPath, localMeta, comparison)
+	return comparison, nil
+}
+
+// ReasonEmptyVault is the comparison reason of a push refused by ErrEmptyVault.
+const ReasonEmptyVault = "vault file is empty (0 bytes), local save is not"
+
+// ErrEmptyVault is returned when a push would overwrite a non-empty, readable local save
+// with a 0-byte vault file, such as one left by a failed earlier pull.
+var ErrEmptyVault = errors.New("refusing to overwrite a non-empty local save with an empty vault file")
+
+// isEmptyVaultOverLocal reports whether the vault file is empty while the local save is
+// readable and not.
+func isEmptyVaultOverLocal(local, vault *models.FileMetadata) bool {
+	return vault.Exists && vault.Size == 0 && local.Exists && local.Readable && local.Size > 0
 }
 
 // ForcePushFile forces a push operation regardless of comparison result.
 // Used when user explicitly chooses to use remote file after conflict resolution.
-func ForcePushFile(title string, vaultPath string, localPath string) (*models.ComparisonResult, error) {
+func ForcePushFile(title string, slot string, vaultPath string, localPath string, createBackup bool) (*models.ComparisonResult, error) {
+	return forcePushSet(title, slot, vaultPath, localPath, nil, createBackup)
+}
+
+// forcePushSet is ForcePushFile that copies the set files named in setFiles along with
+// the save file, as pushSet does.
+func forcePushSet(title string, slot string, vaultPath string, localPath string, setFiles []string, createBackup bool) (*models.ComparisonResult, error) {
 	// Check if it's safe to write to local file
 	safe, reason, err := process.CanSafelyWrite(localPath, title)
 	if err != nil {
@@ -147,49 +275,97 @@ func ForcePushFile(title string, vaultPath string, localPath string) (*models.Co
 	}
 
 	// Get metadata for both files
-	vaultMeta, err := GetFileMetadata(vaultPath)
+	vaultMeta, err := getFileMetadataRetry(vaultPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vault metadata: %w", err)
 	}
 
-	localMeta, err := GetFileMetadata(localPath)
+	localMeta, err := getFileMetadataRetry(localPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get local metadata: %w", err)
 	}
 
 	// Compare files to get metadata, but ignore recommendation
 	comparison := CompareFiles(localMeta, vaultMeta)
+	if isEmptyVaultOverLocal(localMeta, vaultMeta) {
+		comparison.Recommendation = "SKIP"
+		comparison.Reason = ReasonEmptyVault
+		return comparison, ErrEmptyVault
+	}
 	comparison.Recommendation = "PUSH" // Force PUSH
 
-	return executePush(title, vaultPath, localPath, localMeta, comparison)
+	return executePush(title, slot, vaultPath, localPath, setFiles, localMeta, comparison, createBackup)
 }
 
-// executePush performs the actual push operation.
-func executePush(title string, vaultPath string, localPath string, localMeta *models.FileMetadata, comparison *models.ComparisonResult) (*models.ComparisonResult, error) {
+// executePush performs the actual push operation, copying the set files named in
+// setFiles along with the save file. With createBackup false the existing local
+// files are overwritten without a history copy.
+func executePush(title string, slot string, vaultPath string, localPath string, setFiles []string, localMeta *models.FileMetadata, comparison *models.ComparisonResult, createBackup bool) (*models.ComparisonResult, error) {
 	// Ensure local directory exists
 	localDir := filepath.Dir(localPath)
 	if err := utils.EnsureDir(localDir); err != nil {
 		return comparison, fmt.Errorf("failed to create local directory: %w", err)
 	}
 
-	// Backup existing local file if it exists
-	if localMeta.Exists && localMeta.Readable {
-		_, err := backup.CreateBackup(title, localPath)
-		if err != nil {
+	setCopies, err := setFileCopies(vaultPath, localPath, setFiles)
+	if err != nil {
+		return comparison, err
+	}
+
+	// Backup existing local files if they exist
+	if createBackup {
+		if localMeta.Exists && localMeta.Readable {
+			_, err := backup.CreateBackup(title, slot, localPath)
+			if err != nil {
+				return comparison, fmt.Errorf("failed to backup local file: %w", err)
+			}
+		}
+		if err := backupSetFiles(title, slot, setCopies); err != nil {
 			return comparison, fmt.Errorf("failed to backup local file: %w", err)
 		}
+		pruneAfterBackup(title, slot)
 	}
 
-	// Copy vault to local
-	if err := utils.AtomicCopy(vaultPath, localPath); err != nil {
+	// Copy vault to local, together with the set files
+	copies := append([]fileCopy{{src: vaultPath, dest: localPath, size: comparison.RemoteMeta.Size}}, setCopies...)
+	if err := copySetVerified(copies); err != nil {
 		return comparison, fmt.Errorf("failed to copy file: %w", err)
 	}
 
 	return comparison, nil
 }
 
+// ProgressThreshold is the file size (in bytes) from which pull/push copies report progress.
+const ProgressThreshold = 1024 * 1024
+
+// copyProgress creates a progress reporter for a copy to dest. Set via SetCopyProgress.
+var copyProgress func(dest string) utils.ProgressFunc
+
+// SetCopyProgress makes pull/push report progress for files of at least ProgressThreshold bytes.
+// newReporter is called once per copy; nil turns progress reporting off.
+func SetCopyProgress(newReporter func(dest string) utils.ProgressFunc) {
+	copyProgress = newReporter
+}
+
+// copyVerified copies src to dest atomically and verifies the written hash,
+// reporting progress for large files. Transient errors are retried per retryPolicy.
+func copyVerified(src string, dest string, size int64) error {
+	var cb utils.ProgressFunc
+	if copyProgress != nil && size >= ProgressThreshold {
+		cb = copyProgress(dest)
+	}
+	start := time.Now()
+	err := withRetry("copy", dest, func() error {
+		return utils.AtomicCopyVerified(src, dest, cb)
+	})
+	if err == nil {
+		recordIO(IOStats{CopyTime: time.Since(start), CopyBytes: size})
+	}
+	return err
+}
+
 // GetPreferredLocalPath returns the preferred local path for a title and device.
-// Returns the path from the paths.json configuration.
+// Returns the path from the paths.json configuration, resolved with utils.ResolvePath.
 func GetPreferredLocalPath(pathsConfig *models.PathsConfig, title string, deviceID string) (string, error) {
 	// Check if title exists in config
 	titlePaths, ok := pathsConfig.Paths[title]
@@ -213,20 +389,76 @@ func GetPreferredLocalPath(pathsConfig *models.PathsConfig, title string, device
 		return "", fmt.Errorf("invalid preferred index %d for device %s on title %s", pathEntry.Preferred, deviceID, title)
 	}
 
-	// Get preferred path and expand environment variables
+	// Get preferred path, expand environment variables and resolve it to the file
+	// the game actually writes (symlinks, junctions, VirtualStore)
 	path := pathEntry.Paths[pathEntry.Preferred]
 	expandedPath := utils.ExpandEnvPath(path)
 
-	return expandedPath, nil
+	return utils.ResolvePath(expandedPath), nil
 }
 
-// GetVaultFilePath returns the vault file path for a title.
+// GetVaultFilePath returns the vault file path for a title's slot.
 // Example: <vault>/th08/main/score.dat
-func GetVaultFilePath(title string, filename string) (string, error) {
-	vaultPath, err := backup.GetTitleVaultPath(title)
+func GetVaultFilePath(title string, slot string, filename string) (string, error) {
+	vaultPath, err := backup.GetTitleVaultPath(title, slot)
 	if err != nil {
 		return "", err
 	}
 
 	return filepath.Join(vaultPath, filename), nil
 }
+
+// CheckPreferExistingLocal reports whether a push must be refused because the local
+// file was modified after the last push to this device (i.e. it holds progress that
+// was never pulled into the vault). A zero lastPushed means the device has never been
+// pushed to, so any existing local file is treated as unpulled progress.
+func CheckPreferExistingLocal(localMeta *models.FileMetadata, lastPushed time.Time) (blocked bool, reason string) {
+	if !localMeta.Exists {
+		return false, ""
+	}
+
+	if lastPushed.IsZero() {
+		return true, "local file exists but this device has never been pushed to"
+	}
+
+	if utils.IsNewerThan(localMeta.ModTime, lastPushed, compareOptions.DriftTolerance) {
+		return true, fmt.Sprintf("local file modified after last push (local=%s, last push=%s)",
+			localMeta.ModTime.Format("2006-01-02 15:04:05"),
+			lastPushed.Format("2006-01-02 15:04:05"))
+	}
+
+	return false, ""
+}
+
+// RecordPush stores the push time for a title on a device in the paths configuration.
+// A push leaves local and vault equal, so it is recorded as the last sync as well.
+func RecordPush(pathsConfig *models.PathsConfig, title string, deviceID string, pushedAt time.Time) {
+	updatePathEntry(pathsConfig, title, deviceID, func(entry *models.PathEntry) {
+		entry.LastPushed = pushedAt.UTC()
+		entry.LastSynced = pushedAt.UTC()
+	})
+}
+
+// RecordSync stores the time a title's local and vault files were last made (or found)
+// equal on a device, after a successful pull, push or identical comparison.
+func RecordSync(pathsConfig *models.PathsConfig, title string, deviceID string, syncedAt time.Time) {
+	updatePathEntry(pathsConfig, title, deviceID, func(entry *models.PathEntry) {
+		entry.LastSynced = syncedAt.UTC()
+	})
+}
+
+// updatePathEntry applies update to an existing path entry; unknown titles and devices are ignored.
+func updatePathEntry(pathsConfig *models.PathsConfig, title string, deviceID string, update func(entry *models.PathEntry)) {
+	titlePaths, ok := pathsConfig.Paths[title]
+	if !ok {
+		return
+	}
+
+	pathEntry, ok := titlePaths[deviceID]
+	if !ok {
+		return
+	}
+
+	update(&pathEntry)
+	titlePaths[deviceID] = pathEntry
+}
@@ -0,0 +1,271 @@
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+func TestCheckPreferExistingLocal(t *testing.T) {
+	lastPushed := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		localExists   bool
+		localTime     time.Time
+		lastPushed    time.Time
+		expectBlocked bool
+	}{
+		{
+			name:          "Local newer than last push - blocks push",
+			localExists:   true,
+			localTime:     lastPushed.Add(10 * time.Minute),
+			lastPushed:    lastPushed,
+			expectBlocked: true,
+		},
+		{
+			name:          "Local older than last push - allows push",
+			localExists:   true,
+			localTime:     lastPushed.Add(-10 * time.Minute),
+			lastPushed:    lastPushed,
+			expectBlocked: false,
+		},
+		{
+			name:          "Local within drift of last push - allows push",
+			localExists:   true,
+			localTime:     lastPushed.Add(2 * time.Second),
+			lastPushed:    lastPushed,
+			expectBlocked: false,
+		},
+		{
+			name:          "Never pushed to this device - blocks push",
+			localExists:   true,
+			localTime:     lastPushed,
+			expectBlocked: true,
+		},
+		{
+			name:          "No local file - allows push",
+			localExists:   false,
+			lastPushed:    lastPushed,
+			expectBlocked: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &models.FileMetadata{
+				Path:     "/local/test.dat",
+				Exists:   tt.localExists,
+				Readable: tt.localExists,
+				Size:     1000,
+				ModTime:  tt.localTime,
+			}
+
+			blocked, reason := CheckPreferExistingLocal(local, tt.lastPushed)
+			if blocked != tt.expectBlocked {
+				t.Errorf("Expected blocked=%v, got %v. Reason: %s", tt.expectBlocked, blocked, reason)
+			}
+		})
+	}
+}
+
+func TestRecordPush(t *testing.T) {
+	pushedAt := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	pathsConfig := &models.PathsConfig{
+		Paths: map[string]map[string]models.PathEntry{
+			"th08": {
+				"device1": {Paths: []string{"C:\\th08\\score.dat"}},
+			},
+		},
+	}
+
+	RecordPush(pathsConfig, "th08", "device1", pushedAt)
+	RecordPush(pathsConfig, "th08", "unknown-device", pushedAt)
+
+	if got := pathsConfig.Paths["th08"]["device1"].LastPushed; !got.Equal(pushedAt) {
+		t.Errorf("Expected LastPushed %v, got %v", pushedAt, got)
+	}
+	if _, ok := pathsConfig.Paths["th08"]["unknown-device"]; ok {
+		t.Error("RecordPush should not create entries for unknown devices")
+	}
+	if got := pathsConfig.Paths["th08"]["device1"].LastSynced; !got.Equal(pushedAt) {
+		t.Errorf("Expected a push to record LastSynced %v, got %v", pushedAt, got)
+	}
+
+	// A pull updates the last sync only
+	syncedAt := pushedAt.Add(time.Hour)
+	RecordSync(pathsConfig, "th08", "device1", syncedAt)
+	entry := pathsConfig.Paths["th08"]["device1"]
+	if !entry.LastSynced.Equal(syncedAt) || !entry.LastPushed.Equal(pushedAt) {
+		t.Errorf("Expected LastSynced %v and LastPushed %v, got %v and %v", syncedAt, pushedAt, entry.LastSynced, entry.LastPushed)
+	}
+}
+
+func TestPreviewPull_NoWrites(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "local", "score.dat")
+	vaultDir := filepath.Join(dir, "vault", "th08", "main")
+	vaultPath := filepath.Join(vaultDir, "score.dat")
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFileWithTime(t, localPath, []byte("local progress"), baseTime)
+
+	comparison, err := PreviewPull(localPath, vaultPath)
+	if err != nil {
+		t.Fatalf("PreviewPull failed: %v", err)
+	}
+
+	if comparison.Recommendation != "PULL" {
+		t.Errorf("Expected PULL, got %s. Reason: %s", comparison.Recommendation, comparison.Reason)
+	}
+
+	// Nothing may be created on the vault side, not even the directory or a temp file
+	if _, err := os.Stat(vaultDir); !os.IsNotExist(err) {
+		t.Errorf("Expected vault directory to not exist, got err=%v", err)
+	}
+}
+
+func TestPullThenStatus_Skip(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	paths.HomeOverride = dir
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	localPath := filepath.Join(dir, "local", "score.dat")
+	vaultPath := filepath.Join(dir, "vault", "th08", "main", "score.dat")
+	for _, p := range []string{localPath, vaultPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFileWithTime(t, localPath, []byte("local progress"), baseTime)
+	writeFileWithTime(t, vaultPath, []byte("vault progress"), baseTime.Add(-time.Hour))
+
+	comparison, err := PullFile("th08", "main", localPath, vaultPath, true)
+	if err != nil {
+		t.Fatalf("PullFile failed: %v", err)
+	}
+	if comparison.Recommendation != "PULL" {
+		t.Fatalf("Expected PULL, got %s. Reason: %s", comparison.Recommendation, comparison.Reason)
+	}
+
+	// The vault copy keeps the local mtime, so neither direction sees anything to do
+	comparison, err = PreviewPull(localPath, vaultPath)
+	if err != nil {
+		t.Fatalf("PreviewPull failed: %v", err)
+	}
+	if comparison.Recommendation != "SKIP" {
+		t.Errorf("Expected SKIP after pull, got %s. Reason: %s", comparison.Recommendation, comparison.Reason)
+	}
+
+	comparison, err = PreviewPush("th08", vaultPath, localPath, false)
+	if err != nil {
+		t.Fatalf("PreviewPush failed: %v", err)
+	}
+	if comparison.Recommendation != "SKIP" {
+		t.Errorf("Expected SKIP for push after pull, got %s. Reason: %s", comparison.Recommendation, comparison.Reason)
+	}
+}
+
+func TestPullFile_NoBackup(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, createBackup := range []bool{true, false} {
+		dir := t.TempDir()
+		paths.HomeOverride = dir
+		t.Cleanup(func() { paths.HomeOverride = "" })
+
+		localPath := filepath.Join(dir, "local", "score.dat")
+		vaultPath := filepath.Join(dir, "vault", "th08", "main", "score.dat")
+		for _, p := range []string{localPath, vaultPath} {
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeFileWithTime(t, localPath, []byte("local progress"), baseTime)
+		writeFileWithTime(t, vaultPath, []byte("vault progress"), baseTime.Add(-time.Hour))
+
+		comparison, err := PullFile("th08", "main", localPath, vaultPath, createBackup)
+		if err != nil {
+			t.Fatalf("PullFile failed: %v", err)
+		}
+		if comparison.Recommendation != "PULL" {
+			t.Fatalf("Expected PULL, got %s. Reason: %s", comparison.Recommendation, comparison.Reason)
+		}
+
+		// The copy happens either way
+		data, err := os.ReadFile(vaultPath)
+		if err != nil || string(data) != "local progress" {
+			t.Errorf("createBackup=%v: expected the vault to hold the local file, got %q, %v", createBackup, data, err)
+		}
+
+		details, err := backup.GetBackupDetails("th08", "main")
+		if err != nil {
+			t.Fatalf("GetBackupDetails failed: %v", err)
+		}
+		wantBackups := 0
+		if createBackup {
+			wantBackups = 1
+		}
+		if len(details) != wantBackups {
+			t.Errorf("createBackup=%v: expected %d backup(s), got %d", createBackup, wantBackups, len(details))
+		}
+	}
+}
+
+func TestPushFile_RefusesEmptyVault(t *testing.T) {
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := t.TempDir()
+	paths.HomeOverride = dir
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	localPath := filepath.Join(dir, "local", "score.dat")
+	vaultPath := filepath.Join(dir, "vault", "th08", "main", "score.dat")
+	for _, p := range []string{localPath, vaultPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFileWithTime(t, localPath, []byte("local progress"), baseTime)
+	// A failed earlier pull left an empty, newer vault file
+	writeFileWithTime(t, vaultPath, nil, baseTime.Add(time.Hour))
+
+	for _, force := range []bool{false, true} {
+		comparison, err := PushFile("th08", "main", vaultPath, localPath, force, true)
+		if !errors.Is(err, ErrEmptyVault) {
+			t.Fatalf("force=%v: expected ErrEmptyVault, got %v", force, err)
+		}
+		if comparison == nil || comparison.Reason != ReasonEmptyVault {
+			t.Errorf("force=%v: expected reason %q, got %+v", force, ReasonEmptyVault, comparison)
+		}
+	}
+	if _, err := ForcePushFile("th08", "main", vaultPath, localPath, true); !errors.Is(err, ErrEmptyVault) {
+		t.Errorf("ForcePushFile: expected ErrEmptyVault, got %v", err)
+	}
+	if _, err := PreviewPush("th08", vaultPath, localPath, true); !errors.Is(err, ErrEmptyVault) {
+		t.Errorf("PreviewPush: expected ErrEmptyVault, got %v", err)
+	}
+
+	if data, err := os.ReadFile(localPath); err != nil || string(data) != "local progress" {
+		t.Fatalf("Expected the local save to be untouched, got %q, %v", data, err)
+	}
+
+	// Forced and confirmed, the empty file is written
+	if _, err := pushSet("th08", "main", vaultPath, localPath, nil, true, true, true); err != nil {
+		t.Fatalf("Confirmed push failed: %v", err)
+	}
+	if info, err := os.Stat(localPath); err != nil || info.Size() != 0 {
+		t.Errorf("Expected the local save to be replaced by the empty file, got %v, %v", info, err)
+	}
+}
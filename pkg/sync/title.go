@@ -0,0 +1,203 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+)
+
+// Net effect of pulling, pushing or syncing one title
+const (
+	ActionPull     = "pull"
+	ActionPush     = "push"
+	ActionSkip     = "skip"
+	ActionConflict = "conflict" // left for the caller to resolve
+)
+
+// TitleTarget locates the save file of one title on a device and in a vault slot.
+type TitleTarget struct {
+	Title     string
+	DeviceID  string
+	Slot      string
+	LocalPath string
+	VaultPath string
+
+	// SetFiles names the files next to the save file, on both sides, that are copied
+	// together with it all or nothing (e.g. the config of th128 and th143)
+	SetFiles []string
+}
+
+// TitleOptions controls PullTitle and PushTitle.
+type TitleOptions struct {
+	CreateBackup bool // keep a history copy of the overwritten file
+	Quarantine   bool // set a suspicious incoming file aside instead of reporting a conflict
+
+	// Push only
+	Force               bool // ignore the running-game check and push over newer or conflicting files
+	PreferExistingLocal bool // refuse to overwrite local progress made since the last push
+	AllowEmptyVault     bool // with Force, write a 0-byte vault file over a non-empty local save
+}
+
+// TitleSyncResult is the outcome of pulling or pushing one title's save file.
+type TitleSyncResult struct {
+	TitleTarget
+
+	// Action is ActionPull or ActionPush when the file was written, ActionSkip when it
+	// was left alone, or ActionConflict when nothing was written and the caller must
+	// resolve the conflict (e.g. with ForcePullTitle or ForcePushTitle)
+	Action     string
+	Comparison *models.ComparisonResult
+
+	// QuarantinePath is where a suspicious incoming file was set aside, if it was
+	QuarantinePath string
+}
+
+// ResolveTitleTarget finds the preferred local save file of title for deviceID and the
+// vault file named fileName in slot.
+func ResolveTitleTarget(pathsConfig *models.PathsConfig, title, deviceID, slot, fileName string) (TitleTarget, error) {
+	target := TitleTarget{Title: title, DeviceID: deviceID, Slot: slot}
+
+	localPath, err := GetPreferredLocalPath(pathsConfig, title, deviceID)
+	if err != nil {
+		return target, fmt.Errorf("no path configured")
+	}
+	target.LocalPath = localPath
+	target.SetFiles = pathsConfig.Paths[title][deviceID].SetFiles
+
+	vaultPath, err := GetVaultFilePath(title, slot, fileName)
+	if err != nil {
+		return target, fmt.Errorf("failed to get vault path: %w", err)
+	}
+	target.VaultPath = vaultPath
+
+	return target, nil
+}
+
+// PullTitle copies the local save file of target into the vault if it is newer and
+// records the sync in pathsConfig. A conflict is returned as ActionConflict without
+// writing anything, unless opts.Quarantine sets a suspicious local file aside.
+func PullTitle(pathsConfig *models.PathsConfig, target TitleTarget, opts TitleOptions, now time.Time) (*TitleSyncResult, error) {
+	comparison, err := pullSet(target.Title, target.Slot, target.LocalPath, target.VaultPath, target.SetFiles, opts.CreateBackup)
+	if err != nil {
+		return nil, err
+	}
+	result := &TitleSyncResult{TitleTarget: target, Action: ActionSkip, Comparison: comparison}
+
+	switch comparison.Recommendation {
+	case "PULL":
+		result.Action = ActionPull
+		RecordSync(pathsConfig, target.Title, target.DeviceID, now)
+	case "SKIP":
+		if comparison.HashMatch {
+			// Already in sync with the vault
+			RecordSync(pathsConfig, target.Title, target.DeviceID, now)
+		}
+	case "CONFLICT":
+		if opts.Quarantine && comparison.Suspicious {
+			quarantinePath, err := QuarantineIfSuspicious(target.Title, comparison, target.LocalPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to quarantine: %w", err)
+			}
+			result.QuarantinePath = quarantinePath
+			break
+		}
+		result.Action = ActionConflict
+	}
+
+	return result, nil
+}
+
+// ForcePullTitle copies the local save file of target into the vault regardless of
+// the comparison, resolving a conflict in favor of local, and records the sync.
+func ForcePullTitle(pathsConfig *models.PathsConfig, target TitleTarget, createBackup bool, now time.Time) (*TitleSyncResult, error) {
+	comparison, err := forcePullSet(target.Title, target.Slot, target.LocalPath, target.VaultPath, target.SetFiles, createBackup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to force pull: %w", err)
+	}
+	RecordSync(pathsConfig, target.Title, target.DeviceID, now)
+
+	return &TitleSyncResult{TitleTarget: target, Action: ActionPull, Comparison: comparison}, nil
+}
+
+// PushTitle copies the vault save file of target to local if it is newer and records
+// the push in pathsConfig. A conflict is returned as ActionConflict, unless
+// opts.Quarantine sets a suspicious vault file aside; other refused pushes are errors.
+func PushTitle(pathsConfig *models.PathsConfig, target TitleTarget, opts TitleOptions, now time.Time) (*TitleSyncResult, error) {
+	// Refuse to clobber local progress that was never pulled
+	if opts.PreferExistingLocal && !opts.Force {
+		if err := CheckTitlePreferExistingLocal(pathsConfig, target); err != nil {
+			return nil, err
+		}
+	}
+
+	comparison, err := pushSet(target.Title, target.Slot, target.VaultPath, target.LocalPath, target.SetFiles, opts.Force, opts.AllowEmptyVault, opts.CreateBackup)
+	if err != nil {
+		// A conflict is left to the caller instead of failing the title
+		if comparison == nil || comparison.Recommendation != "CONFLICT" {
+			return nil, err
+		}
+	}
+	result := &TitleSyncResult{TitleTarget: target, Action: ActionSkip, Comparison: comparison}
+
+	switch {
+	case comparison.Recommendation == "SKIP":
+		if comparison.HashMatch {
+			// Already in sync with the vault
+			RecordPush(pathsConfig, target.Title, target.DeviceID, now)
+		}
+	case comparison.Recommendation == "PUSH" || opts.Force:
+		// Forced pushes write over newer and conflicting local files too
+		result.Action = ActionPush
+		RecordPush(pathsConfig, target.Title, target.DeviceID, now)
+	case comparison.Recommendation == "CONFLICT":
+		if opts.Quarantine && comparison.Suspicious {
+			quarantinePath, err := QuarantineIfSuspicious(target.Title, comparison, target.VaultPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to quarantine: %w", err)
+			}
+			result.QuarantinePath = quarantinePath
+			break
+		}
+		result.Action = ActionConflict
+	}
+
+	return result, nil
+}
+
+// ForcePushTitle copies the vault save file of target to local regardless of the
+// comparison, resolving a conflict in favor of the vault, and records the push.
+func ForcePushTitle(pathsConfig *models.PathsConfig, target TitleTarget, createBackup bool, now time.Time) (*TitleSyncResult, error) {
+	comparison, err := forcePushSet(target.Title, target.Slot, target.VaultPath, target.LocalPath, target.SetFiles, createBackup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to force push: %w", err)
+	}
+	RecordPush(pathsConfig, target.Title, target.DeviceID, now)
+
+	return &TitleSyncResult{TitleTarget: target, Action: ActionPush, Comparison: comparison}, nil
+}
+
+// CheckTitlePreferExistingLocal refuses a push of target that would overwrite local
+// progress made since the last push to this device. Identical files are never blocked.
+func CheckTitlePreferExistingLocal(pathsConfig *models.PathsConfig, target TitleTarget) error {
+	localMeta, err := GetFileMetadata(target.LocalPath)
+	if err != nil {
+		return fmt.Errorf("failed to get local metadata: %w", err)
+	}
+
+	vaultMeta, err := GetFileMetadata(target.VaultPath)
+	if err != nil {
+		return fmt.Errorf("failed to get vault metadata: %w", err)
+	}
+
+	if localMeta.Hash != "" && localMeta.Hash == vaultMeta.Hash {
+		return nil
+	}
+
+	lastPushed := pathsConfig.Paths[target.Title][target.DeviceID].LastPushed
+	if blocked, reason := CheckPreferExistingLocal(localMeta, lastPushed); blocked {
+		return fmt.Errorf("refusing to overwrite local progress: %s (pull first or use --force)", reason)
+	}
+
+	return nil
+}
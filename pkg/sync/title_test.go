@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+)
+
+func TestPullTitle_ConflictLeftToCaller(t *testing.T) {
+	dir := t.TempDir()
+	paths.HomeOverride = dir
+	t.Cleanup(func() { paths.HomeOverride = "" })
+
+	baseTime := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	target := TitleTarget{
+		Title:     "th08",
+		DeviceID:  "abcdefabcdef",
+		Slot:      "main",
+		LocalPath: filepath.Join(dir, "local", "score.dat"),
+		VaultPath: filepath.Join(dir, "vault", "th08", "main", "score.dat"),
+	}
+	for _, p := range []string{target.LocalPath, target.VaultPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Local is larger but the vault is newer
+	writeFileWithTime(t, target.LocalPath, []byte("local progress!"), baseTime.Add(-time.Hour))
+	writeFileWithTime(t, target.VaultPath, []byte("vault progress"), baseTime)
+
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {"abcdefabcdef": {Paths: []string{target.LocalPath}}},
+	}}
+
+	result, err := PullTitle(pathsConfig, target, TitleOptions{CreateBackup: true}, baseTime)
+	if err != nil {
+		t.Fatalf("PullTitle failed: %v", err)
+	}
+	if result.Action != ActionConflict || result.Comparison.Recommendation != "CONFLICT" {
+		t.Fatalf("Expected a conflict, got %s (%s)", result.Action, result.Comparison.Reason)
+	}
+	if data, _ := os.ReadFile(target.VaultPath); string(data) != "vault progress" {
+		t.Errorf("Expected the vault to be untouched on conflict, got %q", data)
+	}
+	if !pathsConfig.Paths["th08"]["abcdefabcdef"].LastSynced.IsZero() {
+		t.Errorf("Expected no sync to be recorded for a conflict")
+	}
+
+	result, err = ForcePullTitle(pathsConfig, target, true, baseTime)
+	if err != nil {
+		t.Fatalf("ForcePullTitle failed: %v", err)
+	}
+	if result.Action != ActionPull || result.Title != "th08" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if data, _ := os.ReadFile(target.VaultPath); string(data) != "local progress!" {
+		t.Errorf("Expected the vault to hold the local file, got %q", data)
+	}
+	if !pathsConfig.Paths["th08"]["abcdefabcdef"].LastSynced.Equal(baseTime) {
+		t.Errorf("Expected the sync to be recorded, got %v", pathsConfig.Paths["th08"]["abcdefabcdef"].LastSynced)
+	}
+}
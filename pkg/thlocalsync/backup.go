@@ -0,0 +1,109 @@
+package thlocalsync
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// BackupResult is the outcome of backing up one title's vault file.
+type BackupResult struct {
+	Title string `json:"title"`
+
+	// Created is the file name of the new backup in the title's history. In a dry run
+	// nothing is created; a title without Existing or Error would be backed up.
+	Created string `json:"created,omitempty"`
+
+	// Existing is the newest backup that already holds the vault file's contents
+	Existing string `json:"existing,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Backup backs up the current vault file of the configured titles, or of those
+// selected by opts.Titles, like backup --create. A title whose newest backup already
+// holds the same contents is left alone unless opts.Force is set. A failed title is
+// reported in its BackupResult; the error is for the call as a whole.
+func Backup(opts Options) ([]BackupResult, error) {
+	s, release, err := open(opts, "backup", !opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	titles, err := s.titles()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []BackupResult{}
+	for _, title := range titles {
+		result := BackupResult{Title: title}
+		if err := s.backupTitle(&result); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// backupTitle backs up the vault file of result.Title and fills in result.
+func (s *session) backupTitle(result *BackupResult) error {
+	vaultPath, err := sync.GetVaultFilePath(result.Title, s.opts.Slot, s.vaultFileName(result.Title))
+	if err != nil {
+		return fmt.Errorf("failed to get vault path: %w", err)
+	}
+
+	*result, err = BackupVaultFile(result.Title, s.opts.Slot, vaultPath, s.opts.Force, s.opts.DryRun, s.log)
+	return err
+}
+
+// BackupVaultFile backs up the vault file of title at vaultPath into its history in
+// slot and logs the new backup; Backup and backup --create both go through it. Unless
+// force is set, nothing is created when the newest backup already holds the same
+// contents. With dryRun only that check is made.
+func BackupVaultFile(title, slot, vaultPath string, force, dryRun bool, log *logger.Logger) (BackupResult, error) {
+	result := BackupResult{Title: title}
+
+	exists, readable := utils.FileExists(vaultPath)
+	if !exists {
+		return result, fmt.Errorf("no vault file to back up: %s", vaultPath)
+	}
+	if !readable {
+		return result, fmt.Errorf("vault file is not readable: %s", vaultPath)
+	}
+
+	if !force {
+		existing, err := backup.IdenticalNewestBackup(title, slot, vaultPath)
+		if err != nil {
+			return result, fmt.Errorf("failed to compare with the newest backup: %w", err)
+		}
+		if existing != "" {
+			result.Existing = filepath.Base(existing)
+			return result, nil
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	path, err := backup.CreateTaggedBackup(title, slot, vaultPath, "")
+	if err != nil {
+		return result, fmt.Errorf("failed to create backup: %w", err)
+	}
+	result.Created = filepath.Base(path)
+
+	log.Info("backup_create", map[string]interface{}{
+		"title":  title,
+		"slot":   slot,
+		"backup": result.Created,
+	})
+
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package thlocalsync
+
+import (
+	"fmt"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+)
+
+// DetectReport is what Detect found, as emitted by detect --dry-run --json.
+type DetectReport struct {
+	Device     string            `json:"device"`
+	Candidates []DetectCandidate `json:"candidates"`
+	NotFound   []string          `json:"not_found"` // title codes in release order
+
+	// GameDirTruncated reports that the game directory search stopped early
+	GameDirTruncated bool `json:"game_dir_truncated,omitempty"`
+}
+
+// DetectCandidate is one candidate of a DetectReport.
+type DetectCandidate struct {
+	Title      string               `json:"title"`
+	Path       string               `json:"path"`
+	Metadata   *models.FileMetadata `json:"metadata,omitempty"`
+	ReplayDir  string               `json:"replay_dir,omitempty"`
+	SyncDir    string               `json:"sync_dir,omitempty"`
+	SetFiles   []string             `json:"set_files,omitempty"`
+	Registered bool                 `json:"registered"`      // already in paths.json for this device
+	Added      bool                 `json:"added,omitempty"` // registered by this Detect
+}
+
+// Detect searches for the save files of every known title, or of the titles selected
+// by opts.Titles, and registers the new ones for this device in paths.json. With
+// opts.DryRun nothing is registered. Titles that were not found are only reported;
+// registering a path by hand is left to the caller (or detect in the CLI).
+func Detect(opts Options) (*DetectReport, error) {
+	s, release, err := open(opts, "detect", !opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result := pathdetect.Detect(opts.GameDir)
+	if opts.Titles != "" {
+		if result, err = filterDetectResult(result, opts.Titles); err != nil {
+			return nil, err
+		}
+	}
+
+	report := BuildDetectReport(result, s.deviceID, s.pathsConfig)
+	if opts.DryRun {
+		return report, nil
+	}
+
+	added := 0
+	for i, candidate := range report.Candidates {
+		if candidate.Registered {
+			continue
+		}
+		pathdetect.AddCandidateToConfig(result.Candidates[i], s.deviceID, s.pathsConfig)
+		report.Candidates[i].Added = true
+		added++
+	}
+	if added == 0 {
+		return report, nil
+	}
+
+	if err := config.SavePaths(s.pathsConfig); err != nil {
+		return report, fmt.Errorf("failed to save paths config: %w", err)
+	}
+	s.log.Info("detect_registered", map[string]interface{}{
+		"device": s.deviceID,
+		"count":  added,
+	})
+
+	return report, nil
+}
+
+// filterDetectResult keeps the candidates and not-found titles selected by filter,
+// which is matched against every known title.
+func filterDetectResult(result *pathdetect.DetectResult, filter string) (*pathdetect.DetectResult, error) {
+	selected, err := FilterTitles(pathdetect.GetAllTitleCodes(), filter)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(selected))
+	for _, title := range selected {
+		keep[title] = true
+	}
+
+	filtered := &pathdetect.DetectResult{
+		Candidates:       []models.DetectCandidate{},
+		NotFound:         []pathdetect.KnownTitle{},
+		GameDirTruncated: result.GameDirTruncated,
+	}
+	for _, candidate := range result.Candidates {
+		if keep[candidate.Title] {
+			filtered.Candidates = append(filtered.Candidates, candidate)
+		}
+	}
+	for _, title := range result.NotFound {
+		if keep[title.Code] {
+			filtered.NotFound = append(filtered.NotFound, title)
+		}
+	}
+	return filtered, nil
+}
+
+// BuildDetectReport lists the detected candidates, marking the ones already registered
+// for deviceID, and the titles not found.
+func BuildDetectReport(result *pathdetect.DetectResult, deviceID string, pathsConfig *models.PathsConfig) *DetectReport {
+	report := &DetectReport{
+		Device:           deviceID,
+		Candidates:       []DetectCandidate{},
+		NotFound:         []string{},
+		GameDirTruncated: result.GameDirTruncated,
+	}
+
+	for _, candidate := range result.Candidates {
+		report.Candidates = append(report.Candidates, DetectCandidate{
+			Title:      candidate.Title,
+			Path:       candidate.Path,
+			Metadata:   candidate.Metadata,
+			ReplayDir:  candidate.ReplayDir,
+			SyncDir:    candidate.SyncDir,
+			SetFiles:   candidate.SetFiles,
+			Registered: isPathRegistered(pathsConfig, candidate.Title, deviceID, candidate.Path),
+		})
+	}
+	for _, title := range result.NotFound {
+		report.NotFound = append(report.NotFound, title.Code)
+	}
+
+	return report
+}
+
+// isPathRegistered reports whether path is among the paths of title for deviceID,
+// however it is spelled.
+func isPathRegistered(pathsConfig *models.PathsConfig, title, deviceID, path string) bool {
+	entry, ok := pathsConfig.Paths[title][deviceID]
+	if !ok {
+		return false
+	}
+	normalized := utils.NormalizePath(path)
+	for _, p := range entry.Paths {
+		if utils.NormalizePath(p) == normalized {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package thlocalsync
+
+import (
+	"runtime"
+
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+// Status compares the local and vault save files of the configured titles, or of
+// those selected by opts.Titles, without writing anything. Titles that cannot be
+// compared are reported in their TitleStatus; the error is for the call as a whole.
+func Status(opts Options) ([]sync.TitleStatus, error) {
+	s, release, err := open(opts, "status", false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	titles, err := s.titles()
+	if err != nil {
+		return nil, err
+	}
+
+	return sync.Status(s.pathsConfig, titles, s.deviceID, sync.StatusOptions{
+		Slot:          s.opts.Slot,
+		Jobs:          runtime.NumCPU(),
+		VaultFileName: s.vaultFileName,
+	}), nil
+}
@@ -0,0 +1,197 @@
+package thlocalsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+// SyncResult is the outcome of a Pull or Push.
+type SyncResult struct {
+	Device string        `json:"device"`
+	Slot   string        `json:"slot"`
+	DryRun bool          `json:"dry_run,omitempty"`
+	Titles []TitleResult `json:"titles"` // in release order
+}
+
+// TitleResult is the outcome of pulling or pushing one title.
+type TitleResult struct {
+	Title string `json:"title"`
+
+	// Action is sync.ActionPull or sync.ActionPush when the save file was written (or
+	// would be, in a dry run), sync.ActionSkip when it was left alone, and
+	// sync.ActionConflict when nothing was written because of a conflict; rerun with
+	// Force to resolve it in favor of the source. Empty if the title failed.
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Warning is a post_sync hook that failed after the title was synced
+	Warning string `json:"warning,omitempty"`
+
+	LocalPath string `json:"local_path,omitempty"`
+	VaultPath string `json:"vault_path,omitempty"`
+	*models.ComparisonResult
+
+	// Files copied from the registered replay folder and title folder
+	ReplaysCopied int `json:"replays_copied,omitempty"`
+	FolderCopied  int `json:"folder_copied,omitempty"`
+}
+
+// Counts returns how many titles ended with each action, and how many failed.
+func (r *SyncResult) Counts() (written, skipped, conflicts, errors int) {
+	for _, t := range r.Titles {
+		switch {
+		case t.Error != "":
+			errors++
+		case t.Action == sync.ActionConflict:
+			conflicts++
+		case t.Action == sync.ActionSkip:
+			skipped++
+		default:
+			written++
+		}
+	}
+	return written, skipped, conflicts, errors
+}
+
+// Pull copies the local save files that are newer than the vault into the vault, for
+// the configured titles or those selected by opts.Titles, and keeps a history copy of
+// each overwritten vault file. Registered replay and title folders are pulled too.
+// A failed title is reported in its TitleResult; the error is for the call as a whole.
+func Pull(opts Options) (*SyncResult, error) {
+	return runSync(opts, sync.ActionPull)
+}
+
+// Push copies the vault save files that are newer than local to this device, for the
+// configured titles or those selected by opts.Titles, and keeps a history copy of each
+// overwritten local file. A game that is running blocks the push of its title unless
+// opts.Force is set. Otherwise it is Pull in the other direction.
+func Push(opts Options) (*SyncResult, error) {
+	return runSync(opts, sync.ActionPush)
+}
+
+// runSync pulls or pushes (direction) every selected title.
+func runSync(opts Options, direction string) (*SyncResult, error) {
+	s, release, err := open(opts, direction, !opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	titles, err := s.titles()
+	if err != nil {
+		return nil, err
+	}
+
+	syncer := &Syncer{
+		PathsConfig:   s.pathsConfig,
+		DeviceID:      s.deviceID,
+		Slot:          s.opts.Slot,
+		Log:           s.log,
+		Operation:     direction,
+		Force:         s.opts.Force,
+		VaultFileName: s.vaultFileName,
+		PreSync:       s.rules.PreSync,
+		PostSync:      s.rules.PostSync,
+	}
+	result := &SyncResult{
+		Device: s.deviceID,
+		Slot:   s.opts.Slot,
+		DryRun: s.opts.DryRun,
+		Titles: []TitleResult{},
+	}
+	for _, title := range titles {
+		titleResult, err := s.syncTitle(syncer, title, direction)
+		titleResult.Title = title
+		if err != nil {
+			titleResult.Error = err.Error()
+			syncer.LogError(title, err)
+		}
+		result.Titles = append(result.Titles, titleResult)
+	}
+
+	if s.opts.DryRun {
+		return result, nil
+	}
+	if err := config.SavePaths(s.pathsConfig); err != nil {
+		return result, fmt.Errorf("failed to save paths config: %w", err)
+	}
+	return result, nil
+}
+
+// syncTitle pulls or pushes (direction) one title with its folders between the
+// pre_sync and post_sync hooks. A dry run only compares, without running the hooks.
+func (s *session) syncTitle(syncer *Syncer, title, direction string) (TitleResult, error) {
+	target, err := syncer.Target(title)
+	if err != nil {
+		return TitleResult{}, err
+	}
+	titleResult := TitleResult{LocalPath: target.LocalPath, VaultPath: target.VaultPath}
+
+	if s.opts.DryRun {
+		comparison, err := syncer.Preview(target, direction)
+		if err != nil {
+			return titleResult, err
+		}
+		titleResult.ComparisonResult = comparison
+		titleResult.Action = previewAction(comparison, direction)
+		return titleResult, nil
+	}
+
+	postErr, err := syncer.WithHooks(direction, title, func() error {
+		return s.writeTitle(syncer, target, direction, &titleResult)
+	})
+	if postErr != nil {
+		titleResult.Warning = postErr.Error()
+	}
+	return titleResult, err
+}
+
+// writeTitle pulls or pushes (direction) the save file and folders of target and fills
+// in titleResult. Force resolves a conflict in favor of the source.
+func (s *session) writeTitle(syncer *Syncer, target sync.TitleTarget, direction string, titleResult *TitleResult) error {
+	var result *sync.TitleSyncResult
+	var err error
+	if direction == sync.ActionPull {
+		result, err = syncer.Pull(target)
+	} else {
+		// Force already writes over conflicts when pushing
+		result, err = syncer.Push(target)
+	}
+	if err != nil {
+		return err
+	}
+	if result.Action == sync.ActionConflict && s.opts.Force {
+		if result, err = syncer.Resolve(result, ChoiceLocal, result.Comparison.Reason); err != nil {
+			return err
+		}
+	}
+	titleResult.ComparisonResult = result.Comparison
+	titleResult.Action = result.Action
+
+	for _, folder := range syncer.SyncFolders(target, direction) {
+		if folder.Kind == "replay" {
+			titleResult.ReplaysCopied = folder.Transferred
+		} else {
+			titleResult.FolderCopied = folder.Transferred
+		}
+	}
+
+	return nil
+}
+
+// previewAction is the action a dry run would take for comparison: write in direction
+// when the comparison recommends it, conflict or skip otherwise.
+func previewAction(comparison *models.ComparisonResult, direction string) string {
+	switch comparison.Recommendation {
+	case strings.ToUpper(direction):
+		return direction
+	case "CONFLICT":
+		return sync.ActionConflict
+	default:
+		return sync.ActionSkip
+	}
+}
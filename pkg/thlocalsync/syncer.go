@@ -0,0 +1,457 @@
+package thlocalsync
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/hook"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/manifest"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+)
+
+// Operations of a Syncer, as they appear in the log
+const (
+	OperationPull = "pull"
+	OperationPush = "push"
+	OperationSync = "sync"
+)
+
+// Hook stages run by Syncer.RunHook, as logged and shown in messages
+const (
+	HookPreSync  = "pre_sync"
+	HookPostSync = "post_sync"
+)
+
+// Conflict resolutions accepted by Syncer.Resolve
+const (
+	ChoiceLocal  = "local"  // use the local file
+	ChoiceRemote = "remote" // use the vault file
+	ChoiceSkip   = "skip"   // leave both files untouched
+	ChoiceCancel = "cancel" // leave both files untouched, the user cancelled
+)
+
+// Syncer pulls and pushes the titles of one run: the save file, the log entry and
+// manifest record of every write, and the registered replay and title folders. Pull
+// and Push run every title through a Syncer, and so do the CLI commands, which only
+// add the prompts and the output. The caller owns the lock, the loop over the titles
+// and saving PathsConfig afterwards.
+type Syncer struct {
+	PathsConfig *models.PathsConfig
+	DeviceID    string
+	Slot        string
+	Log         *logger.Logger
+
+	// Operation names the run in the log ("pull", "push", "sync", "ui"). A pull or push
+	// run only writes in its own direction, so a conflict resolved the other way keeps
+	// the files as they are. Writes of a sync run are logged as "sync".
+	Operation string
+
+	NoBackup            bool // overwrite without keeping history copies
+	Quarantine          bool // set a suspicious incoming file aside instead of reporting a conflict
+	Force               bool // push: ignore the running-game check and write over newer files
+	PreferExistingLocal bool // push: refuse to overwrite local progress made since the last push
+
+	// VaultFileName returns the vault file name of a title; nil uses the
+	// vault_file_names of PathsConfig and the title's save file name
+	VaultFileName func(title string) string
+
+	// ConfirmEmptyVault is asked before a forced push writes an empty vault file over a
+	// non-empty local save; nil refuses
+	ConfirmEmptyVault func(target sync.TitleTarget) bool
+
+	// PreSync and PostSync are the pre_sync and post_sync commands from rules.json, run
+	// by RunHook around every title
+	PreSync, PostSync []string
+
+	// HookRan is called with the result of every hook that succeeded; nil does nothing
+	HookRan func(stage string, result *hook.Result)
+}
+
+// FolderResult is the outcome of syncing one registered folder of a title.
+type FolderResult struct {
+	Kind        string // "replay" or "folder"
+	Result      *sync.DirSyncResult
+	Err         error
+	Transferred int // files copied
+}
+
+// Target resolves the local and vault save file of title.
+func (s *Syncer) Target(title string) (sync.TitleTarget, error) {
+	var fileName string
+	switch {
+	case s.VaultFileName != nil:
+		fileName = s.VaultFileName(title)
+	case s.PathsConfig.VaultFileNames[title] != "":
+		fileName = s.PathsConfig.VaultFileNames[title]
+	default:
+		fileName, _ = pathdetect.ExpectedFileName(title)
+	}
+	return sync.ResolveTitleTarget(s.PathsConfig, title, s.DeviceID, s.Slot, fileName)
+}
+
+// Pull copies the local save file of target into the vault if it is newer (see
+// sync.PullTitle). A conflict is returned as sync.ActionConflict for Resolve.
+func (s *Syncer) Pull(target sync.TitleTarget) (*sync.TitleSyncResult, error) {
+	result, err := sync.PullTitle(s.PathsConfig, target, sync.TitleOptions{
+		CreateBackup: !s.NoBackup,
+		Quarantine:   s.Quarantine,
+	}, now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.logComparison(result, target.LocalPath)
+	if result.Action == sync.ActionPull {
+		s.logWrite(result, result.Comparison.Reason)
+	}
+	return result, nil
+}
+
+// Push copies the vault save file of target to local if it is newer (see
+// sync.PushTitle). A conflict is returned as sync.ActionConflict for Resolve.
+func (s *Syncer) Push(target sync.TitleTarget) (*sync.TitleSyncResult, error) {
+	opts := sync.TitleOptions{
+		CreateBackup:        !s.NoBackup,
+		Quarantine:          s.Quarantine,
+		Force:               s.Force,
+		PreferExistingLocal: s.PreferExistingLocal,
+	}
+	result, err := sync.PushTitle(s.PathsConfig, target, opts, now())
+	if errors.Is(err, sync.ErrEmptyVault) {
+		s.Log.Warn(s.Operation+"_empty_vault", map[string]interface{}{
+			"title":  target.Title,
+			"device": s.DeviceID,
+			"vault":  target.VaultPath,
+			"local":  target.LocalPath,
+			"reason": sync.ReasonEmptyVault,
+			"force":  s.Force,
+		})
+		// Even Force asks first, so an unattended run never wipes a good save
+		if !s.Force || s.ConfirmEmptyVault == nil || !s.ConfirmEmptyVault(target) {
+			return nil, err
+		}
+		opts.AllowEmptyVault = true
+		result, err = sync.PushTitle(s.PathsConfig, target, opts, now())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logComparison(result, target.VaultPath)
+	if result.Action == sync.ActionPush {
+		s.logWrite(result, result.Comparison.Reason)
+	}
+	return result, nil
+}
+
+// Preview compares target like Pull or Push (direction) would, without writing
+// anything. A push refused by the comparison (local newer, conflict) is returned as a
+// comparison, not an error.
+func (s *Syncer) Preview(target sync.TitleTarget, direction string) (*models.ComparisonResult, error) {
+	if direction == sync.ActionPull {
+		return sync.PreviewPull(target.LocalPath, target.VaultPath)
+	}
+
+	if s.PreferExistingLocal && !s.Force {
+		if err := sync.CheckTitlePreferExistingLocal(s.PathsConfig, target); err != nil {
+			return nil, err
+		}
+	}
+	comparison, err := sync.PreviewPush(target.Title, target.VaultPath, target.LocalPath, s.Force)
+	if comparison != nil {
+		return comparison, nil
+	}
+	return nil, err
+}
+
+// Resolve settles a conflict returned by Pull or Push. ChoiceLocal writes the local
+// file into the vault and ChoiceRemote the vault file to local, except that a pull or
+// push run keeps the files instead of writing against its direction (sync.ActionSkip).
+// ChoiceSkip and ChoiceCancel leave the conflict (sync.ActionConflict). reason is logged.
+func (s *Syncer) Resolve(conflict *sync.TitleSyncResult, choice, reason string) (*sync.TitleSyncResult, error) {
+	target := conflict.TitleTarget
+
+	switch {
+	case choice == ChoiceLocal && s.Operation != OperationPush:
+		result, err := sync.ForcePullTitle(s.PathsConfig, target, !s.NoBackup, now())
+		if err != nil {
+			return nil, err
+		}
+		s.logWrite(result, reason)
+		return result, nil
+	case choice == ChoiceRemote && s.Operation != OperationPull:
+		result, err := sync.ForcePushTitle(s.PathsConfig, target, !s.NoBackup, now())
+		if err != nil {
+			return nil, err
+		}
+		s.logWrite(result, reason)
+		return result, nil
+	}
+
+	action, event := sync.ActionConflict, s.Operation+"_skip"
+	switch choice {
+	case ChoiceLocal, ChoiceRemote:
+		action = sync.ActionSkip
+	case ChoiceCancel:
+		event = s.Operation + "_cancel"
+	}
+	s.Log.Info(event, map[string]interface{}{
+		"title":  target.Title,
+		"device": s.DeviceID,
+		"reason": reason,
+	})
+	return &sync.TitleSyncResult{TitleTarget: target, Action: action, Comparison: conflict.Comparison}, nil
+}
+
+// SyncFolders pulls or pushes (direction) the registered replay folder and title folder
+// of target. The folders are optional: failures are logged and returned, but never
+// fail the title. Folders that are not registered are left out.
+func (s *Syncer) SyncFolders(target sync.TitleTarget, direction string) []FolderResult {
+	title := target.Title
+	saveName := filepath.Base(target.LocalPath)
+	var results []FolderResult
+
+	if localDir := sync.GetLocalReplayDir(s.PathsConfig, title, s.DeviceID); localDir != "" {
+		folder := FolderResult{Kind: "replay"}
+		vaultDir, err := sync.GetVaultReplayDir(title)
+		if err == nil {
+			if direction == sync.ActionPull {
+				folder.Result, err = sync.PullDir(title, localDir, vaultDir, !s.NoBackup)
+			} else {
+				folder.Result, err = sync.PushDir(title, vaultDir, localDir, s.Force, !s.NoBackup)
+			}
+		}
+		folder.Err = err
+		results = append(results, s.logFolder(title, direction, folder))
+	}
+
+	if localDir := sync.GetLocalTitleDir(s.PathsConfig, title, s.DeviceID); localDir != "" {
+		folder := FolderResult{Kind: "folder"}
+		vaultDir, err := sync.GetVaultTitleDir(title)
+		if err == nil {
+			if direction == sync.ActionPull {
+				folder.Result, err = sync.PullTitleDir(title, localDir, vaultDir, saveName, !s.NoBackup)
+			} else {
+				folder.Result, err = sync.PushTitleDir(title, vaultDir, localDir, saveName, s.Force, !s.NoBackup)
+			}
+		}
+		folder.Err = err
+		results = append(results, s.logFolder(title, direction, folder))
+	}
+
+	return results
+}
+
+// RunHook runs the hook of stage (HookPreSync or HookPostSync) for a title that is being
+// pulled or pushed (direction: "pull", "push", or "sync" before a sync run knows which)
+// and logs the command, exit code and output. The result is nil when the hook is not
+// configured. The error is for the caller to decide: a failed pre_sync hook stops the
+// title, a failed post_sync hook only warns.
+func (s *Syncer) RunHook(stage, direction, title string) (*hook.Result, error) {
+	args := s.PreSync
+	if stage == HookPostSync {
+		args = s.PostSync
+	}
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	// A title without a path still runs the hook, with an empty {path}
+	localPath, _ := sync.GetPreferredLocalPath(s.PathsConfig, title, s.DeviceID)
+	vars := hook.Vars{Title: title, Path: localPath, Direction: direction}
+	result, err := hook.Run(args, vars)
+
+	fields := map[string]interface{}{
+		"title":     title,
+		"device":    s.DeviceID,
+		"stage":     stage,
+		"direction": direction,
+		"command":   strings.Join(hook.Expand(args, vars), " "),
+	}
+	if result != nil {
+		fields["exit_code"] = result.ExitCode
+		fields["output"] = strings.TrimSpace(result.Output)
+		fields["duration_ms"] = result.Duration.Milliseconds()
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		s.Log.Error("hook_failed", fields)
+		return result, fmt.Errorf("%s hook failed: %w", stage, err)
+	}
+
+	s.Log.Info("hook", fields)
+	if s.HookRan != nil {
+		s.HookRan(stage, result)
+	}
+	return result, nil
+}
+
+// WithHooks runs the pre_sync hook of a title, then run, then the post_sync hook, so
+// the CLI commands and the API run the hooks the same way. A failed pre_sync hook
+// fails the title without running it, and a failed run skips the post_sync hook. A
+// failed post_sync hook does not fail the title; its error is returned as postErr for
+// the caller to warn about.
+func (s *Syncer) WithHooks(direction, title string, run func() error) (postErr, err error) {
+	if _, err := s.RunHook(HookPreSync, direction, title); err != nil {
+		return nil, err
+	}
+	if err := run(); err != nil {
+		return nil, err
+	}
+	_, postErr = s.RunHook(HookPostSync, direction, title)
+	return postErr, nil
+}
+
+// LogError logs a title that failed.
+func (s *Syncer) LogError(title string, err error) {
+	s.Log.Error(s.Operation+"_error", map[string]interface{}{
+		"title":  title,
+		"device": s.DeviceID,
+		"error":  err.Error(),
+	})
+}
+
+// logComparison logs what the comparison of result noticed: an empty local or vault
+// file, a rehash of an ambiguous quick pass, and a suspicious incoming file (source)
+// that was quarantined.
+func (s *Syncer) logComparison(result *sync.TitleSyncResult, source string) {
+	comparison := result.Comparison
+
+	if comparison.EmptyLocal {
+		s.Log.Warn(s.Operation+"_empty_local", map[string]interface{}{
+			"title":  result.Title,
+			"device": s.DeviceID,
+			"path":   comparison.LocalMeta.Path,
+			"reason": comparison.Reason,
+		})
+	}
+	if comparison.EmptyRemote {
+		s.Log.Warn(s.Operation+"_empty_remote", map[string]interface{}{
+			"title":  result.Title,
+			"device": s.DeviceID,
+			"path":   comparison.RemoteMeta.Path,
+			"reason": comparison.Reason,
+		})
+	}
+
+	if comparison.Rehashed {
+		s.Log.Info(s.Operation+"_rehash", map[string]interface{}{
+			"title":          result.Title,
+			"device":         s.DeviceID,
+			"recommendation": comparison.Recommendation,
+			"reason":         "quick pass ambiguous (equal size/mtime, missing hash)",
+		})
+	}
+
+	if result.QuarantinePath != "" {
+		s.Log.Warn(s.Operation+"_quarantine", map[string]interface{}{
+			"title":      result.Title,
+			"device":     s.DeviceID,
+			"source":     source,
+			"quarantine": result.QuarantinePath,
+			"reason":     comparison.Reason,
+		})
+	}
+}
+
+// logWrite logs a pull or push that wrote the save file, with the vault file's hash
+// afterwards for verify, and records the vault file in the manifest.
+func (s *Syncer) logWrite(result *sync.TitleSyncResult, reason string) {
+	from, to := "local", "usb"
+	vaultHash, overwritten, dest := result.Comparison.LocalMeta.Hash, result.Comparison.RemoteMeta, result.VaultPath
+	if result.Action == sync.ActionPush {
+		from, to = "usb", "local"
+		vaultHash, overwritten, dest = result.Comparison.RemoteMeta.Hash, result.Comparison.LocalMeta, result.LocalPath
+	}
+
+	event, action := result.Action, "update"
+	if s.Operation == OperationSync {
+		event, action = OperationSync, result.Action
+	}
+	s.Log.Info(event, map[string]interface{}{
+		"title":      result.Title,
+		"device":     result.DeviceID,
+		"action":     action,
+		"from":       from,
+		"to":         to,
+		"reason":     reason,
+		"slot":       result.Slot,
+		"vault_hash": vaultHash,
+	})
+
+	// A missing backup is never a silent surprise
+	if s.NoBackup && overwritten.Exists {
+		s.Log.Warn(result.Action+"_backup_skipped", map[string]interface{}{
+			"title":  result.Title,
+			"device": result.DeviceID,
+			"path":   dest,
+			"reason": "--no-backup",
+		})
+	}
+
+	UpdateManifest(result.Title, result.Slot, result.VaultPath, vaultHash, s.Log)
+}
+
+// logFolder logs the sync of a folder of title and counts the files it copied.
+func (s *Syncer) logFolder(title, direction string, folder FolderResult) FolderResult {
+	if folder.Err != nil {
+		s.Log.Error(folder.Kind+"_"+direction+"_error", map[string]interface{}{
+			"title": title,
+			"error": folder.Err.Error(),
+		})
+		return folder
+	}
+	if len(folder.Result.Files) == 0 {
+		return folder
+	}
+
+	folder.Transferred = folder.Result.Count(strings.ToUpper(direction))
+	s.Log.Info(folder.Kind+"_"+direction, map[string]interface{}{
+		"title":       title,
+		"device":      s.DeviceID,
+		"transferred": folder.Transferred,
+		"skipped":     folder.Result.Count("SKIP"),
+		"errors":      folder.Result.Errors(),
+	})
+	return folder
+}
+
+// UpdateManifest records the vault file's state in the vault manifest after a write.
+// hash comes from the caller (copies are verified), size and mtime are read from disk;
+// the size is that of the contents, so an encrypted vault file is recorded like the
+// metadata it is later checked against. Failures are logged but do not fail the write,
+// which itself succeeded.
+func UpdateManifest(title, slot, vaultPath, hash string, log *logger.Logger) {
+	meta, err := sync.GetFileMetadataNoHash(vaultPath)
+	if err == nil && !meta.Exists {
+		err = fmt.Errorf("vault file not found: %s", vaultPath)
+	}
+	if err == nil {
+		err = manifest.Update(title, slot, &models.FileMetadata{
+			Path:     vaultPath,
+			Exists:   true,
+			Readable: true,
+			Size:     meta.Size,
+			ModTime:  meta.ModTime,
+			Hash:     hash,
+		})
+	}
+	if err != nil {
+		log.Warn("manifest_update_failed", map[string]interface{}{
+			"title": title,
+			"slot":  slot,
+			"error": err.Error(),
+		})
+	}
+}
+
+// now is the time recorded for a sync.
+func now() time.Time {
+	return time.Now().UTC()
+}
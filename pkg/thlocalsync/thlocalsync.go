@@ -0,0 +1,251 @@
+// Package thlocalsync is the Go API of thlocalsync, for programs such as game launchers
+// that embed the sync instead of running the CLI.
+//
+// Detect, Status, Pull, Push and Backup work like the commands of the same name run
+// non-interactively: nothing is read from stdin or written to stdout, conflicts are
+// reported in the results instead of prompting, and the outcome of every title is
+// returned as a structured result. The vault, data/ and logs/ are shared with the CLI,
+// including its lock, so the CLI and an embedding program never write at the same time.
+//
+// Every title is pulled or pushed by a Syncer, which the CLI commands use as well, so
+// writes are logged and recorded in the manifest the same way, and the pre_sync and
+// post_sync hooks of rules.json run around every title. Interactive conflict resolution
+// and the replay, snapshot and bestshot archives remain features of the CLI.
+//
+// Some settings are process-wide (the base and data directories, the device ID, the
+// rules from rules.json and the vault key), so calls with different Options must not
+// run concurrently.
+package thlocalsync
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/lock"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/utils"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
+)
+
+// Options controls every API call. The zero value uses the same defaults as the CLI.
+type Options struct {
+	Home      string // directory of data/, vault/ and logs/ (like --home); empty resolves it like the CLI
+	ConfigDir string // data/ directory apart from Home (like --config-dir)
+	VaultDir  string // vault/ directory apart from Home (like --vault-dir)
+	LogDir    string // logs/ directory apart from Home (like --log-dir)
+	DeviceID  string // device ID to use instead of the detected one (like --device-id)
+	Slot      string // vault slot; empty is the default slot
+	Titles    string // comma-separated title codes or globs (like --titles); empty selects every title
+	Force     bool   // Pull/Push: resolve conflicts in favor of the source; Backup: back up identical contents too
+	DryRun    bool   // compare and report without writing anything
+	GameDir   string // Detect: game directory to search in addition to the known locations
+
+	// Passphrase of a vault encrypted with rules.json encrypt_vault; empty falls back to
+	// THLOCALSYNC_PASSPHRASE. It is never prompted for.
+	Passphrase string
+}
+
+// session is the state shared by the API calls: the resolved device and configuration.
+type session struct {
+	opts        Options
+	deviceID    string
+	pathsConfig *models.PathsConfig
+	rules       *models.Rules
+	log         *logger.Logger
+}
+
+// open applies opts and loads the configuration. With write, data/.lock is taken too;
+// the returned function releases it and must always be called.
+func open(opts Options, operation string, write bool) (*session, func(), error) {
+	noop := func() {}
+
+	// Set every override, empty ones included, so none is left over from an earlier call
+	paths.HomeOverride = opts.Home
+	paths.ConfigDirOverride = opts.ConfigDir
+	paths.VaultDirOverride = opts.VaultDir
+	paths.LogDirOverride = opts.LogDir
+	device.OverrideID = opts.DeviceID
+	if opts.Slot == "" {
+		opts.Slot = backup.DefaultSlot
+	}
+	if err := backup.ValidateSlot(opts.Slot); err != nil {
+		return nil, noop, err
+	}
+
+	// A broken titles.json leaves the built-in titles, as in the CLI
+	if titles, err := config.LoadTitles(); err == nil {
+		pathdetect.SetUserTitles(titles.Titles)
+	}
+
+	release := noop
+	if write {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return nil, noop, fmt.Errorf("failed to get config directory: %w", err)
+		}
+		l, err := lock.Acquire(configDir, "thlocalsync "+operation)
+		if err != nil {
+			return nil, noop, err
+		}
+		release = func() { l.Release() }
+	}
+
+	s, err := newSession(opts)
+	if err != nil {
+		release()
+		return nil, noop, err
+	}
+	return s, release, nil
+}
+
+// newSession resolves the device and loads paths.json and rules.json.
+func newSession(opts Options) (*session, error) {
+	deviceID, _, _, err := device.GetDeviceID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device ID: %w", err)
+	}
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load paths config: %w", err)
+	}
+
+	rules, err := ApplyRules()
+	if err != nil {
+		return nil, err
+	}
+	passphrase := opts.Passphrase
+	if passphrase == "" {
+		passphrase = os.Getenv(vaultcrypt.EnvPassphrase)
+	}
+	if err := EnableEncryption(rules, passphrase); err != nil {
+		return nil, err
+	}
+
+	log, err := logger.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	return &session{opts: opts, deviceID: deviceID, pathsConfig: pathsConfig, rules: rules, log: log}, nil
+}
+
+// ApplyRules loads rules.json and applies the comparison thresholds, include/exclude
+// lists, history and retry policy, backup compression and hash algorithm to the sync.
+// The rules are returned for the settings the caller applies itself.
+func ApplyRules() (*models.Rules, error) {
+	rules, err := config.LoadRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules config: %w", err)
+	}
+
+	if err := utils.ValidateHashAlgo(rules.HashAlgo); err != nil {
+		return nil, fmt.Errorf("invalid rules config: hash_algo: %w", err)
+	}
+
+	sync.SetRules(rules)
+	backup.Compress = rules.CompressBackups
+	utils.HashAlgo = rules.HashAlgo
+
+	return rules, nil
+}
+
+// EnableEncryption turns the vault encryption on or off per rules.json encrypt_vault,
+// unlocking the vault's key with passphrase when it is on. A vault without a key file
+// gets one, so the first passphrase used becomes the vault's passphrase.
+func EnableEncryption(rules *models.Rules, passphrase string) error {
+	if !rules.EncryptVault {
+		vaultcrypt.Disable()
+		return nil
+	}
+	if passphrase == "" {
+		return fmt.Errorf("encrypt_vault is set in rules.json but no passphrase was given (set %s)", vaultcrypt.EnvPassphrase)
+	}
+
+	vaultDir, err := paths.GetVaultDir()
+	if err != nil {
+		return fmt.Errorf("failed to get vault directory: %w", err)
+	}
+	key, _, err := vaultcrypt.Unlock(vaultDir, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to unlock the vault: %w", err)
+	}
+	vaultcrypt.Enable(key, vaultDir)
+	return nil
+}
+
+// titles returns the configured titles selected by the Titles option, in release order.
+func (s *session) titles() ([]string, error) {
+	var titles []string
+	for title := range s.pathsConfig.Paths {
+		titles = append(titles, title)
+	}
+	if len(titles) == 0 {
+		return nil, nil
+	}
+	return FilterTitles(titles, s.opts.Titles)
+}
+
+// vaultFileName returns the save file name stored in the vault for a title: the
+// vault_file_names entry in paths.json if set, otherwise the title's save file name.
+func (s *session) vaultFileName(title string) string {
+	if fileName := s.pathsConfig.VaultFileNames[title]; fileName != "" {
+		return fileName
+	}
+	fileName, _ := pathdetect.ExpectedFileName(title)
+	return fileName
+}
+
+// FilterTitles narrows titles to those matching filter, the value of a --titles flag:
+// a comma-separated list of title codes or globs such as "th06,th1*". Matching is
+// case-insensitive and the result is in release order. An empty filter keeps every title.
+// A pattern that is malformed or matches none of titles is an error, so a typo never
+// silently selects nothing.
+func FilterTitles(titles []string, filter string) ([]string, error) {
+	if filter == "" {
+		return pathdetect.SortTitlesByRelease(titles), nil
+	}
+
+	selected := make(map[string]bool)
+	for _, pattern := range strings.Split(filter, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid --titles pattern %q: %w", pattern, err)
+		}
+
+		matched := false
+		for _, title := range titles {
+			if ok, _ := path.Match(pattern, strings.ToLower(title)); ok {
+				selected[title] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("--titles pattern %q matches no configured title (configured: %s)",
+				pattern, strings.Join(pathdetect.SortTitlesByRelease(titles), ", "))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("--titles is empty")
+	}
+
+	var result []string
+	for _, title := range titles {
+		if selected[title] {
+			result = append(result, title)
+		}
+	}
+	return pathdetect.SortTitlesByRelease(result), nil
+}
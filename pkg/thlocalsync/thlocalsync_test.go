@@ -0,0 +1,480 @@
+package thlocalsync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/internal/models"
+	"github.com/otagao/touhou-local-sync/pkg/backup"
+	"github.com/otagao/touhou-local-sync/pkg/config"
+	"github.com/otagao/touhou-local-sync/pkg/device"
+	"github.com/otagao/touhou-local-sync/pkg/logger"
+	"github.com/otagao/touhou-local-sync/pkg/manifest"
+	"github.com/otagao/touhou-local-sync/pkg/pathdetect"
+	"github.com/otagao/touhou-local-sync/pkg/paths"
+	"github.com/otagao/touhou-local-sync/pkg/sync"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
+)
+
+// TestMain lets the test binary act as a hook: with THLOCALSYNC_HOOK_TEST set it exits
+// with the code given as its first argument instead of running the tests.
+func TestMain(m *testing.M) {
+	if os.Getenv("THLOCALSYNC_HOOK_TEST") != "" {
+		code, _ := strconv.Atoi(os.Args[1])
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+func TestFilterTitles(t *testing.T) {
+	configured := []string{"th18", "th06", "th13", "th08", "th128"}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"th06", "th08", "th128", "th13", "th18"}},
+		{"th1*", []string{"th128", "th13", "th18"}},
+		{"th08, TH06", []string{"th06", "th08"}},
+		{"th0?,th06", []string{"th06", "th08"}},
+	}
+	for _, tt := range tests {
+		got, err := FilterTitles(configured, tt.filter)
+		if err != nil {
+			t.Errorf("FilterTitles(%q) failed: %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterTitles(%q) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	for _, filter := range []string{"th2*", "th06,th07", "th[", " , "} {
+		if _, err := FilterTitles(configured, filter); err == nil {
+			t.Errorf("Expected an error for %q", filter)
+		}
+	}
+
+	_, err := FilterTitles(configured, "th9*")
+	if err == nil || !strings.Contains(err.Error(), "th06, th08") {
+		t.Errorf("Expected the error to list the configured titles, got %v", err)
+	}
+}
+
+func TestBuildDetectReport(t *testing.T) {
+	dir := t.TempDir()
+	registered := filepath.Join(dir, "th08", "score.dat")
+	pathsConfig := &models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {"dev1": {Paths: []string{filepath.Join(dir, "th08", ".", "score.dat")}}},
+		"th10": {"dev2": {Paths: []string{filepath.Join(dir, "th10", "scoreth10.dat")}}},
+	}}
+	result := &pathdetect.DetectResult{
+		Candidates: []models.DetectCandidate{
+			{Title: "th08", Path: registered},
+			{Title: "th10", Path: filepath.Join(dir, "th10", "scoreth10.dat")}, // registered for another device only
+		},
+		NotFound: []pathdetect.KnownTitle{{Code: "th06"}, {Code: "th07"}},
+	}
+
+	report := BuildDetectReport(result, "dev1", pathsConfig)
+
+	if len(report.Candidates) != 2 || !report.Candidates[0].Registered || report.Candidates[1].Registered {
+		t.Errorf("Unexpected candidates: %+v", report.Candidates)
+	}
+	if strings.Join(report.NotFound, ",") != "th06,th07" {
+		t.Errorf("Unexpected not found titles: %v", report.NotFound)
+	}
+
+	// Empty results still encode as arrays
+	data, err := json.Marshal(BuildDetectReport(&pathdetect.DetectResult{}, "dev1", pathsConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"device":"dev1","candidates":[],"not_found":[]}`; string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+}
+
+// setupHome creates a base directory with th08 registered at a local save file for
+// the test device, and returns the Options that use it and the local file path.
+func setupHome(t *testing.T) (Options, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Cleanup(func() {
+		paths.HomeOverride = ""
+		device.OverrideID = ""
+	})
+
+	localPath := filepath.Join(home, "game", "score.dat")
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(localPath, []byte("local save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{Home: home, DeviceID: "0123456789ab"}
+	paths.HomeOverride = home
+	if err := config.SavePaths(&models.PathsConfig{Paths: map[string]map[string]models.PathEntry{
+		"th08": {opts.DeviceID: {Paths: []string{localPath}}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return opts, localPath
+}
+
+func TestOpen_OverridesDoNotLeak(t *testing.T) {
+	opts, _ := setupHome(t)
+	t.Cleanup(func() { paths.VaultDirOverride = "" })
+
+	first := opts
+	first.VaultDir = filepath.Join(t.TempDir(), "vault")
+	result, err := Pull(first)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if !strings.HasPrefix(result.Titles[0].VaultPath, first.VaultDir) {
+		t.Fatalf("Expected the first pull to use %s, got %s", first.VaultDir, result.Titles[0].VaultPath)
+	}
+
+	// The second call sets neither the vault directory nor the device ID
+	second := Options{Home: opts.Home}
+	t.Setenv(device.EnvDeviceID, "ba9876543210")
+	statuses, err := Status(second)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Error == "" {
+		t.Errorf("Expected th08 to have no path on another device, got %+v", statuses)
+	}
+
+	second.DeviceID = opts.DeviceID
+	result, err = Pull(second)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if want := filepath.Join(opts.Home, paths.VaultDirName); !strings.HasPrefix(result.Titles[0].VaultPath, want) {
+		t.Errorf("Expected the second pull to use %s, got %s", want, result.Titles[0].VaultPath)
+	}
+}
+
+func TestPullStatusBackup(t *testing.T) {
+	opts, _ := setupHome(t)
+
+	dryRun := opts
+	dryRun.DryRun = true
+	result, err := Pull(dryRun)
+	if err != nil {
+		t.Fatalf("Pull (dry run) failed: %v", err)
+	}
+	if len(result.Titles) != 1 || result.Titles[0].Action != sync.ActionPull {
+		t.Fatalf("Expected th08 to be pulled in the dry run, got %+v", result.Titles)
+	}
+	if _, err := os.Stat(result.Titles[0].VaultPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected the dry run to write nothing, got %v", err)
+	}
+
+	result, err = Pull(opts)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if written, _, _, errs := result.Counts(); written != 1 || errs != 0 {
+		t.Fatalf("Expected th08 to be pulled, got %+v", result.Titles)
+	}
+	data, err := os.ReadFile(result.Titles[0].VaultPath)
+	if err != nil || string(data) != "local save" {
+		t.Fatalf("Expected the local save in the vault, got %q, %v", data, err)
+	}
+
+	statuses, err := Status(opts)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Recommendation != "SKIP" {
+		t.Errorf("Expected th08 to be in sync, got %+v", statuses)
+	}
+
+	backups, err := Backup(opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if len(backups) != 1 || backups[0].Created == "" {
+		t.Fatalf("Expected a backup of th08, got %+v", backups)
+	}
+	backups, err = Backup(opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if backups[0].Created != "" || backups[0].Existing == "" {
+		t.Errorf("Expected the identical backup to be left alone, got %+v", backups)
+	}
+}
+
+func TestPull_Hooks(t *testing.T) {
+	opts, _ := setupHome(t)
+	t.Setenv("THLOCALSYNC_HOOK_TEST", "1")
+
+	// A failed pre_sync hook fails the title without writing it
+	rules := config.DefaultRules()
+	rules.PreSync = []string{os.Args[0], "1"}
+	if err := config.SaveRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Pull(opts)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if len(result.Titles) != 1 || !strings.Contains(result.Titles[0].Error, "pre_sync hook failed") {
+		t.Fatalf("Expected the pre_sync hook to fail th08, got %+v", result.Titles)
+	}
+	if _, err := os.Stat(result.Titles[0].VaultPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing written after the failed hook, got %v", err)
+	}
+
+	// A failed post_sync hook only warns
+	rules.PreSync = []string{os.Args[0], "0"}
+	rules.PostSync = []string{os.Args[0], "2"}
+	if err := config.SaveRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Pull(opts)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if written, _, _, errs := result.Counts(); written != 1 || errs != 0 {
+		t.Fatalf("Expected th08 to be pulled, got %+v", result.Titles)
+	}
+	if !strings.Contains(result.Titles[0].Warning, "post_sync hook failed") {
+		t.Errorf("Expected a post_sync warning, got %+v", result.Titles[0])
+	}
+}
+
+func TestPush(t *testing.T) {
+	opts, localPath := setupHome(t)
+
+	vaultPath, err := sync.GetVaultFilePath("th08", backup.DefaultSlot, "score.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(vaultPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vaultPath, []byte("vault save!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(vaultPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Push(opts)
+	if err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if len(result.Titles) != 1 || result.Titles[0].Action != sync.ActionPush {
+		t.Fatalf("Expected th08 to be pushed, got %+v", result.Titles)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil || string(data) != "vault save!" {
+		t.Errorf("Expected the vault save locally, got %q, %v", data, err)
+	}
+}
+
+func TestSyncer_Resolve(t *testing.T) {
+	opts, localPath := setupHome(t)
+
+	pathsConfig, err := config.LoadPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := logger.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncer := &Syncer{
+		PathsConfig: pathsConfig,
+		DeviceID:    opts.DeviceID,
+		Slot:        backup.DefaultSlot,
+		Log:         log,
+		Operation:   OperationPull,
+	}
+	target, err := syncer.Target("th08")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target.VaultPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target.VaultPath, []byte("vault save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conflict := &sync.TitleSyncResult{TitleTarget: target, Action: sync.ActionConflict, Comparison: &models.ComparisonResult{}}
+
+	// A pull keeps the files rather than pushing
+	result, err := syncer.Resolve(conflict, ChoiceRemote, "chose remote")
+	if err != nil || result.Action != sync.ActionSkip {
+		t.Fatalf("Expected the vault file to be kept, got %+v, %v", result, err)
+	}
+	if data, _ := os.ReadFile(localPath); string(data) != "local save" {
+		t.Errorf("Expected the local save untouched, got %q", data)
+	}
+
+	result, err = syncer.Resolve(conflict, ChoiceLocal, "chose local")
+	if err != nil || result.Action != sync.ActionPull {
+		t.Fatalf("Expected a pull, got %+v, %v", result, err)
+	}
+	if data, _ := os.ReadFile(target.VaultPath); string(data) != "local save" {
+		t.Errorf("Expected the local save in the vault, got %q", data)
+	}
+	manifestPath, err := manifest.GetPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := m.Lookup("th08", backup.DefaultSlot); !ok || entry.Hash != result.Comparison.LocalMeta.Hash {
+		t.Errorf("Expected the pull in the manifest, got %+v", entry)
+	}
+
+	// A sync writes either way
+	syncer.Operation = OperationSync
+	result, err = syncer.Resolve(conflict, ChoiceRemote, "chose remote")
+	if err != nil || result.Action != sync.ActionPush {
+		t.Fatalf("Expected a push, got %+v, %v", result, err)
+	}
+
+	result, err = syncer.Resolve(conflict, ChoiceCancel, "cancelled")
+	if err != nil || result.Action != sync.ActionConflict {
+		t.Errorf("Expected the conflict to be left, got %+v, %v", result, err)
+	}
+}
+
+func TestPull_TitleFilter(t *testing.T) {
+	opts, _ := setupHome(t)
+
+	opts.Titles = "th1*"
+	if _, err := Pull(opts); err == nil {
+		t.Error("Expected an error for a filter matching no configured title")
+	}
+}
+
+func TestDetect_DryRunTitleFilter(t *testing.T) {
+	opts, _ := setupHome(t)
+	opts.DryRun = true
+	opts.GameDir = t.TempDir()
+	opts.Titles = "th06,th07"
+
+	report, err := Detect(opts)
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if report.Device != opts.DeviceID {
+		t.Errorf("Expected device %s, got %s", opts.DeviceID, report.Device)
+	}
+
+	// The machine running the test may have the games installed, so only check the selection
+	seen := make(map[string]bool)
+	for _, candidate := range report.Candidates {
+		seen[candidate.Title] = true
+		if candidate.Added {
+			t.Errorf("Expected nothing to be registered in a dry run, got %+v", candidate)
+		}
+	}
+	for _, title := range report.NotFound {
+		seen[title] = true
+	}
+	if len(seen) != 2 || !seen["th06"] || !seen["th07"] {
+		t.Errorf("Expected only th06 and th07, got %+v", report)
+	}
+}
+
+func TestEncryptedVault(t *testing.T) {
+	opts, localPath := setupHome(t)
+	t.Cleanup(vaultcrypt.Disable)
+
+	rules := config.DefaultRules()
+	rules.EncryptVault = true
+	if err := config.SaveRules(rules); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(vaultcrypt.EnvPassphrase, "")
+	if _, err := Pull(opts); err == nil {
+		t.Fatal("Expected an error without a passphrase")
+	}
+
+	opts.Passphrase = "correct horse"
+	result, err := Pull(opts)
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if written, _, _, errs := result.Counts(); written != 1 || errs != 0 {
+		t.Fatalf("Expected th08 to be pulled, got %+v", result.Titles)
+	}
+	vaultPath := result.Titles[0].VaultPath
+	data, err := os.ReadFile(vaultPath)
+	if err != nil || !vaultcrypt.IsEncrypted(data) || strings.Contains(string(data), "local save") {
+		t.Fatalf("Expected the vault file to be encrypted, got %q, %v", data, err)
+	}
+	manifestPath, err := manifest.GetPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.Load(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := m.Lookup("th08", backup.DefaultSlot); !ok || entry.Size != int64(len("local save")) {
+		t.Errorf("Expected the manifest to record the size of the contents, got %+v", entry)
+	}
+
+	// Comparisons see the contents, not the ciphertext
+	statuses, err := Status(opts)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Recommendation != "SKIP" || !statuses[0].HashMatch {
+		t.Errorf("Expected th08 to be in sync, got %+v", statuses)
+	}
+
+	backups, err := Backup(opts)
+	if err != nil || len(backups) != 1 || backups[0].Created == "" {
+		t.Fatalf("Expected a backup of th08, got %+v, %v", backups, err)
+	}
+	historyDir, err := backup.GetHistoryDir("th08", backup.DefaultSlot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(historyDir, backups[0].Created)); err != nil || !vaultcrypt.IsEncrypted(data) {
+		t.Errorf("Expected the backup to be encrypted, got %q, %v", data, err)
+	}
+	if backups, err = Backup(opts); err != nil || backups[0].Existing == "" {
+		t.Errorf("Expected the identical backup to be recognized, got %+v, %v", backups, err)
+	}
+
+	// A push decrypts into the local save
+	if err := os.Remove(localPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Push(opts); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if data, err := os.ReadFile(localPath); err != nil || string(data) != "local save" {
+		t.Errorf("Expected the decrypted save locally, got %q, %v", data, err)
+	}
+
+	opts.Passphrase = "wrong"
+	if _, err := Status(opts); !errors.Is(err, vaultcrypt.ErrWrongPassphrase) {
+		t.Errorf("Expected a wrong passphrase to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Package timing provides a lightweight phase timer for diagnosing slow runs.
+// Timing is disabled by default; when disabled, Start returns a no-op.
+package timing
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Phase holds the accumulated duration of a named phase.
+type Phase struct {
+	Name  string
+	Count int
+	Total time.Duration
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	phases  = make(map[string]*Phase)
+	order   []string
+)
+
+// Enable turns on timing collection for the rest of the run.
+func Enable() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = true
+}
+
+// Enabled reports whether timing collection is on.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Start begins timing a phase and returns a function that stops it.
+// Usage: defer timing.Start("hash")()
+func Start(name string) func() {
+	if !Enabled() {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		record(name, time.Since(start))
+	}
+}
+
+// record adds a duration to a phase.
+func record(name string, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	phase, ok := phases[name]
+	if !ok {
+		phase = &Phase{Name: name}
+		phases[name] = phase
+		order = append(order, name)
+	}
+	phase.Count++
+	phase.Total += d
+}
+
+// Phases returns the recorded phases in the order they were first seen.
+func Phases() []Phase {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make([]Phase, 0, len(order))
+	for _, name := range order {
+		result = append(result, *phases[name])
+	}
+	return result
+}
+
+// Report writes a human-readable breakdown of recorded phases.
+func Report(w io.Writer) {
+	recorded := Phases()
+
+	fmt.Fprintf(w, "\n=== Timings ===\n")
+	if len(recorded) == 0 {
+		fmt.Fprintln(w, "No phases recorded.")
+		return
+	}
+
+	fmt.Fprintf(w, "%-24s %8s %12s\n", "Phase", "Count", "Total")
+	for _, phase := range recorded {
+		fmt.Fprintf(w, "%-24s %8d %12s\n", phase.Name, phase.Count, phase.Total.Round(time.Microsecond))
+	}
+}
+
+// Fields returns the recorded phases as a map suitable for structured logging.
+// Values are total milliseconds per phase.
+func Fields() map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, phase := range Phases() {
+		fields[phase.Name+"_ms"] = float64(phase.Total.Microseconds()) / 1000
+	}
+	return fields
+}
@@ -0,0 +1,25 @@
+package utils
+
+// DiffOffsets returns up to limit byte offsets at which a and b differ, in ascending order,
+// along with the total number of differing offsets. Bytes past the end of the shorter
+// slice count as differences. A limit of 0 or less returns no offsets, only the total.
+func DiffOffsets(a, b []byte, limit int) ([]int64, int64) {
+	var offsets []int64
+	var total int64
+
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if i < len(a) && i < len(b) && a[i] == b[i] {
+			continue
+		}
+		total++
+		if len(offsets) < limit {
+			offsets = append(offsets, int64(i))
+		}
+	}
+
+	return offsets, total
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffOffsets(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        []byte
+		limit       int
+		wantOffsets []int64
+		wantTotal   int64
+	}{
+		{"Identical", []byte("abcdef"), []byte("abcdef"), 8, nil, 0},
+		{"Single byte", []byte("abcdef"), []byte("abXdef"), 8, []int64{2}, 1},
+		{"Limited", []byte("aaaaaa"), []byte("bbbbbb"), 2, []int64{0, 1}, 6},
+		{"Trailing bytes", []byte("abc"), []byte("abcde"), 8, []int64{3, 4}, 2},
+		{"Empty side", nil, []byte("ab"), 8, []int64{0, 1}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offsets, total := DiffOffsets(tt.a, tt.b, tt.limit)
+			if !reflect.DeepEqual(offsets, tt.wantOffsets) {
+				t.Errorf("offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
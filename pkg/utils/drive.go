@@ -0,0 +1,6 @@
+package utils
+
+import "errors"
+
+// ErrDriveTypeUnknown is returned by IsRemovableDrive when the drive type cannot be determined.
+var ErrDriveTypeUnknown = errors.New("drive type cannot be determined")
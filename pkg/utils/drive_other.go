@@ -0,0 +1,13 @@
+//go:build !windows
+
+package utils
+
+// IsRemovableDrive is not available on this platform.
+func IsRemovableDrive(path string) (bool, error) {
+	return false, ErrDriveTypeUnknown
+}
+
+// IsNetworkDrive is not available on this platform.
+func IsNetworkDrive(path string) (bool, error) {
+	return false, ErrDriveTypeUnknown
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procGetDriveType = kernel32.NewProc("GetDriveTypeW")
+)
+
+// Drive types from GetDriveTypeW
+const (
+	driveRemovable = 2 // DRIVE_REMOVABLE: USB sticks, SD cards
+	driveRemote    = 4 // DRIVE_REMOTE: network shares, UNC paths and mapped drives alike
+)
+
+// IsRemovableDrive reports whether path is on a removable drive.
+// External SSDs/HDDs usually report as fixed drives and are not detected.
+func IsRemovableDrive(path string) (bool, error) {
+	driveType, err := getDriveType(path)
+	return driveType == driveRemovable, err
+}
+
+// IsNetworkDrive reports whether path is on a network share, given as a UNC path
+// (\\server\share\...) or through a mapped drive letter.
+func IsNetworkDrive(path string) (bool, error) {
+	driveType, err := getDriveType(path)
+	return driveType == driveRemote, err
+}
+
+// getDriveType returns the GetDriveTypeW type of the volume holding path.
+func getDriveType(path string) (uintptr, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	// GetDriveTypeW wants the root with a trailing separator, e.g. "E:\" or "\\nas\touhou\"
+	root := filepath.VolumeName(absPath) + `\`
+	rootPtr, err := syscall.UTF16PtrFromString(root)
+	if err != nil {
+		return 0, err
+	}
+
+	driveType, _, _ := procGetDriveType.Call(uintptr(unsafe.Pointer(rootPtr)))
+	if driveType <= 1 {
+		return 0, fmt.Errorf("%w: %s", ErrDriveTypeUnknown, root)
+	}
+	return driveType, nil
+}
@@ -1,12 +1,27 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/otagao/touhou-local-sync/pkg/timing"
+	"github.com/otagao/touhou-local-sync/pkg/vaultcrypt"
 )
 
+// ProgressFunc receives the number of bytes copied so far and the total size.
+type ProgressFunc func(copied, total int64)
+
+// CopyRateLimit caps the write rate of AtomicCopy and its variants in bytes per second,
+// to avoid overwhelming slow or flaky removable media. 0 means unlimited.
+var CopyRateLimit int64
+
 // AtomicCopy performs an atomic file copy operation.
 // It writes to a temporary file first, then atomically renames it to the destination.
 // This prevents partial writes in case of errors.
@@ -14,27 +29,151 @@ import (
 // Steps:
 // 1. Create a .tmp file in the same directory as dest
 // 2. Copy src to .tmp
-// 3. Atomically rename .tmp to dest
-// 4. If any error occurs, clean up the .tmp file
+// 3. Give .tmp the permissions and modification time of src
+// 4. Atomically rename .tmp to dest (see SafeRename)
+// 5. If any error occurs, clean up the .tmp file
+//
+// Keeping the source mtime makes a copied pair compare as equal afterwards,
+// instead of the destination looking newer by the time the copy took.
 func AtomicCopy(src, dest string) error {
+	return atomicCopy(src, dest, nil, false)
+}
+
+// AtomicCopyWithProgress is AtomicCopy that calls cb after each chunk written,
+// and once more when the copy completes. cb runs on the copying goroutine.
+func AtomicCopyWithProgress(src, dest string, cb ProgressFunc) error {
+	return atomicCopy(src, dest, cb, false)
+}
+
+// AtomicCopyVerified is AtomicCopy that re-reads the written temp file and compares
+// its SHA256 with the hash of the source bytes before renaming it into place.
+// On mismatch the bad copy is removed, dest is left untouched, and an error is returned.
+// cb reports progress as in AtomicCopyWithProgress and may be nil.
+func AtomicCopyVerified(src, dest string, cb ProgressFunc) error {
+	return atomicCopy(src, dest, cb, true)
+}
+
+// progressWriter counts bytes written through it and reports them to cb.
+type progressWriter struct {
+	w      io.Writer
+	cb     ProgressFunc
+	copied int64
+	total  int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.copied += int64(n)
+	p.cb(p.copied, p.total)
+	return n, err
+}
+
+// throttledWriter limits writes through it to rate bytes per second on average.
+// Large writes are split into chunks, and after each chunk it sleeps until the
+// bytes written so far are due at that rate.
+type throttledWriter struct {
+	w       io.Writer
+	rate    int64
+	start   time.Time
+	written int64
+	now     func() time.Time    // time.Now, replaced in tests
+	sleep   func(time.Duration) // time.Sleep, replaced in tests
+}
+
+func newThrottledWriter(w io.Writer, rate int64) *throttledWriter {
+	return &throttledWriter{w: w, rate: rate, start: time.Now(), now: time.Now, sleep: time.Sleep}
+}
+
+func (t *throttledWriter) Write(b []byte) (int, error) {
+	// About four chunks per second keeps the writes smooth at low rates
+	chunk := int(t.rate / 4)
+	if chunk < 1 {
+		chunk = 1
+	}
+
+	total := 0
+	for len(b) > 0 {
+		n := len(b)
+		if n > chunk {
+			n = chunk
+		}
+		written, err := t.w.Write(b[:n])
+		total += written
+		t.written += int64(written)
+		if err != nil {
+			return total, err
+		}
+		b = b[n:]
+
+		due := t.start.Add(time.Duration(float64(t.written) / float64(t.rate) * float64(time.Second)))
+		if wait := due.Sub(t.now()); wait > 0 {
+			t.sleep(wait)
+		}
+	}
+	return total, nil
+}
+
+func atomicCopy(src, dest string, cb ProgressFunc, verify bool) (err error) {
+	defer timing.Start("copy")()
+
+	staged, err := stageCopy(src, dest, cb, verify)
+	if err != nil {
+		return err
+	}
+	if err := staged.Commit(); err != nil {
+		staged.Discard()
+		return err
+	}
+	return nil
+}
+
+// StagedCopy is a copy written to a temporary file beside its destination that has
+// not been renamed into place yet. Either Commit or Discard it.
+type StagedCopy struct {
+	tmpPath string
+	dest    string
+	srcInfo os.FileInfo
+	size    int64 // of the contents, which differs from srcInfo for an encrypted file
+	cb      ProgressFunc
+}
+
+// StageCopyVerified runs the first half of AtomicCopyVerified: src is copied to a
+// temporary file in dest's directory and verified, but dest is left untouched until
+// Commit. This lets several files be copied as a set, renaming them only once every
+// copy has succeeded. cb may be nil.
+func StageCopyVerified(src, dest string, cb ProgressFunc) (*StagedCopy, error) {
+	defer timing.Start("copy")()
+
+	return stageCopy(src, dest, cb, true)
+}
+
+// stageCopy writes src to a temporary file beside dest, optionally verifying it.
+func stageCopy(src, dest string, cb ProgressFunc, verify bool) (_ *StagedCopy, err error) {
 	// Open source file
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("failed to open source file: %w", err)
+		return nil, fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer srcFile.Close()
 
 	// Get source file info for permissions
 	srcInfo, err := srcFile.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to stat source file: %w", err)
+		return nil, fmt.Errorf("failed to stat source file: %w", err)
+	}
+
+	// An encrypted vault file is copied as its contents, and re-encrypted if dest is in
+	// the vault too (see vaultcrypt)
+	srcContents, size, err := vaultcrypt.NewReader(srcFile, srcInfo.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
 	}
 
 	// Create temporary file in the same directory as destination
 	destDir := filepath.Dir(dest)
 	tmpFile, err := os.CreateTemp(destDir, ".tmp-*")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
 
@@ -46,34 +185,203 @@ func AtomicCopy(src, dest string) error {
 		}
 	}()
 
-	// Copy data
-	if _, err = io.Copy(tmpFile, srcFile); err != nil {
-		return fmt.Errorf("failed to copy data: %w", err)
+	// Copy data, hashing the source bytes on the way when verifying
+	var dst io.Writer = tmpFile
+	var encrypter io.WriteCloser
+	if vaultcrypt.Covers(dest) {
+		encrypter = vaultcrypt.NewWriter(tmpFile)
+		dst = encrypter
+	}
+	if cb != nil {
+		dst = &progressWriter{w: dst, cb: cb, total: size}
+	}
+	// Throttle outermost, so progress is reported per throttled chunk
+	if CopyRateLimit > 0 {
+		dst = newThrottledWriter(dst, CopyRateLimit)
+	}
+	srcReader := srcContents
+	algo := HashAlgo
+	hasher := newHasher(algo)
+	if verify {
+		srcReader = io.TeeReader(srcContents, hasher)
+	}
+	if _, err = io.Copy(dst, srcReader); err != nil {
+		return nil, fmt.Errorf("failed to copy data: %w", err)
+	}
+	if encrypter != nil {
+		if err = encrypter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt data: %w", err)
+		}
 	}
 
 	// Sync to ensure data is written to disk
 	if err = tmpFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync temp file: %w", err)
+		return nil, fmt.Errorf("failed to sync temp file: %w", err)
 	}
 
 	// Close temp file before rename
 	if err = tmpFile.Close(); err != nil {
-		return fmt.Errorf("failed to close temp file: %w", err)
+		return nil, fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	// Verify what actually reached the disk before it replaces dest
+	if verify {
+		srcHash := formatHash(algo, hasher.Sum(nil))
+		var tmpHash string
+		if tmpHash, err = CalculateFileHashWith(tmpPath, algo); err != nil {
+			return nil, fmt.Errorf("failed to verify copy: %w", err)
+		}
+		if tmpHash != srcHash {
+			err = fmt.Errorf("copy verification failed: hash mismatch (source=%s, written=%s)", srcHash, tmpHash)
+			return nil, err
+		}
 	}
 
 	// Set permissions to match source
 	if err = os.Chmod(tmpPath, srcInfo.Mode()); err != nil {
-		return fmt.Errorf("failed to set permissions: %w", err)
+		return nil, fmt.Errorf("failed to set permissions: %w", err)
 	}
 
-	// Atomic rename
-	if err = os.Rename(tmpPath, dest); err != nil {
+	return &StagedCopy{tmpPath: tmpPath, dest: dest, srcInfo: srcInfo, size: size, cb: cb}, nil
+}
+
+// Commit atomically renames the staged copy over its destination. If the rename
+// fails, the destination is left untouched and the staged copy is kept, so Commit
+// can be retried; Discard it otherwise.
+func (s *StagedCopy) Commit() error {
+	if err := SafeRename(s.tmpPath, s.dest); err != nil {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
+	// Keep the source modification time, so the copy does not look newer than the original.
+	// Set after the rename: a temp file with an old mtime would look stale to CleanupTempFiles.
+	if err := os.Chtimes(s.dest, s.srcInfo.ModTime(), s.srcInfo.ModTime()); err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
+	}
+
+	if s.cb != nil {
+		s.cb(s.size, s.size)
+	}
+
 	return nil
 }
 
+// Discard removes the staged copy, leaving its destination untouched.
+func (s *StagedCopy) Discard() {
+	os.Remove(s.tmpPath)
+}
+
+// rename is os.Rename; tests replace it to simulate a rename across volumes.
+var rename = os.Rename
+
+// SafeRename renames src to dest like os.Rename, replacing dest. A rename that fails
+// because they are on different volumes (EXDEV, or ERROR_NOT_SAME_DEVICE on Windows),
+// as with a redirected TEMP or a DFS link on a network share, falls back to copying src
+// over dest, syncing it to disk and removing src. That fallback is not atomic, so
+// callers still write src beside dest and only get there when the volumes really differ.
+func SafeRename(src, dest string) error {
+	err := rename(src, dest)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+
+	if err := copyFileSynced(src, dest); err != nil {
+		return fmt.Errorf("failed to copy across volumes: %w", err)
+	}
+	return os.Remove(src)
+}
+
+// copyFileSynced copies src over dest with src's permissions and syncs dest to disk.
+func copyFileSynced(src, dest string) (err error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	srcInfo, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, srcInfo.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := destFile.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if _, err = io.Copy(destFile, srcFile); err != nil {
+		return err
+	}
+	return destFile.Sync()
+}
+
+// TempFileMaxAge is how long a temporary file must have gone unmodified before
+// CleanupTempFiles removes it. A write in progress keeps updating the mtime,
+// so temp files that another process is still writing are younger than this.
+const TempFileMaxAge = 10 * time.Minute
+
+// CleanupTempFiles removes the temporary files that interrupted atomic writes left
+// anywhere under dir and that have not been modified within TempFileMaxAge:
+// ".tmp-*" files from AtomicCopy, and "<name>.tmp" files from the config writers.
+// A "<name>.tmp" file is only removed when <name> exists beside it, so an unrelated
+// file that merely ends in .tmp (e.g. in a synced game folder) is left alone.
+// Returns the removed paths; a missing dir has nothing to clean.
+func CleanupTempFiles(dir string) ([]string, error) {
+	return cleanupTempFiles(dir, TempFileMaxAge, time.Now())
+}
+
+func cleanupTempFiles(dir string, maxAge time.Duration, now time.Time) ([]string, error) {
+	if !DirExists(dir) {
+		return nil, nil
+	}
+
+	cutoff := now.Add(-maxAge)
+	var removed []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isTempFile(p) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+		removed = append(removed, p)
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to clean up temp files: %w", err)
+	}
+	return removed, nil
+}
+
+// isTempFile reports whether p looks like the temporary file of an atomic write.
+func isTempFile(p string) bool {
+	name := filepath.Base(p)
+	if strings.HasPrefix(name, ".tmp-") {
+		return true
+	}
+	target, ok := strings.CutSuffix(p, ".tmp")
+	if !ok || name == ".tmp" {
+		return false
+	}
+	exists, _ := FileExists(target)
+	return exists
+}
+
 // EnsureDir creates a directory if it doesn't exist.
 func EnsureDir(path string) error {
 	if err := os.MkdirAll(path, 0755); err != nil {
@@ -108,9 +416,238 @@ func FileExists(path string) (exists bool, readable bool) {
 	return true, true
 }
 
-// ExpandEnvPath expands environment variables in a path (e.g., %APPDATA%)
+// FilesEqual reports whether two files have the same contents. Sizes are compared first,
+// then both files are read side by side and the comparison stops at the first differing
+// chunk, so files that differ early are not read to the end.
+func FilesEqual(a, b string) (bool, error) {
+	fa, sizeA, closeA, err := openContents(a)
+	if err != nil {
+		return false, err
+	}
+	defer closeA()
+
+	fb, sizeB, closeB, err := openContents(b)
+	if err != nil {
+		return false, err
+	}
+	defer closeB()
+
+	if sizeA != sizeB {
+		return false, nil
+	}
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		nA, errA := io.ReadFull(fa, bufA)
+		nB, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+
+		endA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		endB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !endA {
+			return false, fmt.Errorf("failed to read %s: %w", a, errA)
+		}
+		if errB != nil && !endB {
+			return false, fmt.Errorf("failed to read %s: %w", b, errB)
+		}
+		if endA || endB {
+			// Equal only if both ended together (a file may have changed since the stat)
+			return endA && endB, nil
+		}
+	}
+}
+
+// openContents opens path for reading its contents: the file itself, or the decrypted
+// contents of a vault file encrypted by vaultcrypt. size is the size of the contents.
+func openContents(path string) (r io.Reader, size int64, closeFn func(), err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	r, size, err = vaultcrypt.NewReader(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return r, size, func() { file.Close() }, nil
+}
+
+// ReadContents reads the contents of a file like os.ReadFile, decrypting a vault file
+// encrypted by vaultcrypt.
+func ReadContents(path string) ([]byte, error) {
+	r, _, closeFn, err := openContents(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFn()
+	return io.ReadAll(r)
+}
+
+// ExpandEnvPath expands environment variables in a path, in Windows (%APPDATA%) as well
+// as shell ($HOME, ${HOME}) syntax, and a leading ~ to the user's home directory. Only
+// the path as written is expanded: a $ or % in the value of a variable or in the home
+// directory is kept as it is.
 func ExpandEnvPath(path string) string {
-	return os.ExpandEnv(path)
+	home, _ := os.UserHomeDir()
+	prefix := ""
+	if expandHome(path, home) != path {
+		prefix, path = home, path[1:]
+	}
+	return prefix + expandWindowsEnv(path, os.LookupEnv, os.ExpandEnv)
+}
+
+// expandWindowsEnv replaces each %VAR% reference in path with the variable's value
+// from lookup, and the text around the references with literal applied to it. As in
+// cmd.exe, undefined variables are left as written, and so are percent signs that do
+// not enclose a variable name, such as the one in "100%". Names may contain spaces and
+// parentheses (%ProgramFiles(x86)%) but not separators.
+func expandWindowsEnv(path string, lookup func(string) (string, bool), literal func(string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(path, '%')
+		if start < 0 {
+			b.WriteString(literal(path))
+			return b.String()
+		}
+		end := strings.IndexByte(path[start+1:], '%')
+		if end < 0 {
+			b.WriteString(literal(path))
+			return b.String()
+		}
+		end += start + 1
+
+		name := path[start+1 : end]
+		if value, ok := lookupWindowsEnv(name, lookup); ok {
+			b.WriteString(literal(path[:start]))
+			b.WriteString(value)
+			path = path[end+1:]
+			continue
+		}
+		// Not a reference: keep the first % and let the second start the next one
+		b.WriteString(literal(path[:end]))
+		path = path[end:]
+	}
+}
+
+// lookupWindowsEnv looks up name if it can be a variable name.
+func lookupWindowsEnv(name string, lookup func(string) (string, bool)) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `=\/`) {
+		return "", false
+	}
+	return lookup(name)
+}
+
+// expandHome replaces a leading ~ (alone or followed by a separator) with home.
+// ~user forms and an unknown home directory are left as they are.
+func expandHome(path, home string) string {
+	if home == "" || !strings.HasPrefix(path, "~") {
+		return path
+	}
+	rest := path[1:]
+	if rest != "" && rest[0] != '/' && rest[0] != '\\' {
+		return path
+	}
+	return home + rest
+}
+
+// NormalizePath returns the form of path used to decide whether two paths name the
+// same file: environment variables expanded, made absolute and cleaned. On Windows
+// separators are unified to backslashes, 8.3 short names of existing files are
+// expanded, and the result is lowercased, since NTFS paths are case-insensitive.
+func NormalizePath(path string) string {
+	path = ExpandEnvPath(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return normalizePath(longPathName(path), runtime.GOOS == "windows")
+}
+
+// normalizePath cleans an absolute path, by Windows rules when windows is set
+// regardless of the OS running, so both forms can be tested anywhere.
+func normalizePath(p string, windows bool) string {
+	if !windows {
+		return filepath.Clean(p)
+	}
+	volume, rest := cleanWindowsPath(p)
+	return strings.ToLower(volume + rest)
+}
+
+// cleanWindowsPath splits p into its volume (a drive letter, or \\server\share of a
+// UNC path) and the cleaned rest, with separators unified to backslashes. Dot segments
+// are resolved within the volume, so ".." never climbs out of a share.
+func cleanWindowsPath(p string) (volume, rest string) {
+	p = strings.ReplaceAll(p, "/", `\`)
+	switch {
+	case strings.HasPrefix(p, `\\`):
+		// UNC path: \\server\share\...
+		volume, p = uncVolume(p)
+	case len(p) >= 2 && p[1] == ':':
+		volume, p = p[:2], p[2:]
+	}
+	cleaned := path.Clean(strings.ReplaceAll(p, `\`, "/"))
+	if cleaned == "." {
+		cleaned = ""
+	}
+	return volume, strings.ReplaceAll(cleaned, "/", `\`)
+}
+
+// uncVolume splits a UNC path into \\server\share and the rest, which is empty or
+// starts with a backslash.
+func uncVolume(p string) (volume, rest string) {
+	parts := strings.SplitN(p[2:], `\`, 3)
+	volume = `\\` + strings.Join(parts[:min(len(parts), 2)], `\`)
+	return volume, p[len(volume):]
+}
+
+// ResolvePath returns the file that path actually reaches: symlinks and junctions are
+// followed, and on Windows a VirtualStore copy of a file under Program Files, ProgramData
+// or the Windows directory is preferred, since that is the copy legacy games read and
+// write. Paths that cannot be resolved are returned unchanged.
+func ResolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if runtime.GOOS != "windows" {
+		return path
+	}
+
+	virtualized := []string{os.Getenv("ProgramFiles"), os.Getenv("ProgramFiles(x86)"), os.Getenv("ProgramData"), os.Getenv("SystemRoot")}
+	store := virtualStorePath(path, os.Getenv("LOCALAPPDATA"), virtualized)
+	if store == "" {
+		return path
+	}
+	if info, err := os.Stat(store); err == nil && info.Mode().IsRegular() {
+		return store
+	}
+	return path
+}
+
+// virtualStorePath returns where Windows redirects writes to path by a legacy program
+// (<localAppData>\VirtualStore\<path without drive>), or "" if path is not under one
+// of the virtualized directories.
+func virtualStorePath(path, localAppData string, virtualized []string) string {
+	if localAppData == "" {
+		return ""
+	}
+	normalized := normalizePath(path, true)
+	for _, dir := range virtualized {
+		if dir == "" {
+			continue
+		}
+		if strings.HasPrefix(normalized, normalizePath(dir, true)+`\`) {
+			_, rest := cleanWindowsPath(path)
+			return strings.TrimRight(localAppData, `\/`) + `\VirtualStore` + rest
+		}
+	}
+	return ""
 }
 
 // DirExists checks if a directory exists and is accessible.
@@ -122,6 +659,21 @@ func DirExists(path string) bool {
 	return info.IsDir()
 }
 
+// IsDirWritable reports whether a file can be created in dir, by creating and removing
+// a probe file. A missing directory is not writable.
+func IsDirWritable(dir string) bool {
+	if !DirExists(dir) {
+		return false
+	}
+	probe, err := os.CreateTemp(dir, ".tmp-probe-*")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return true
+}
+
 // ListFilesWithExtension returns all files with the specified extension in a directory.
 // ext should include the dot (e.g., ".rpy", ".bmp")
 func ListFilesWithExtension(dir, ext string) ([]string, error) {
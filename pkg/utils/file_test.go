@@ -0,0 +1,613 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtomicCopyWithProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.dat")
+	dest := filepath.Join(dir, "dest.dat")
+
+	data := bytes.Repeat([]byte("x"), 100*1024)
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var lastCopied, lastTotal int64
+	err := AtomicCopyWithProgress(src, dest, func(copied, total int64) {
+		if copied < lastCopied {
+			t.Errorf("Progress went backwards: %d after %d", copied, lastCopied)
+		}
+		calls++
+		lastCopied, lastTotal = copied, total
+	})
+	if err != nil {
+		t.Fatalf("AtomicCopyWithProgress failed: %v", err)
+	}
+
+	if calls < 2 {
+		t.Errorf("Expected several progress reports, got %d", calls)
+	}
+	if lastCopied != int64(len(data)) || lastTotal != int64(len(data)) {
+		t.Errorf("Expected final report %d/%d, got %d/%d", len(data), len(data), lastCopied, lastTotal)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Destination content does not match source")
+	}
+}
+
+func TestAtomicCopyWithProgress_EmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "empty.dat")
+	if err := os.WriteFile(src, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	if err := AtomicCopyWithProgress(src, filepath.Join(dir, "dest.dat"), func(copied, total int64) {
+		calls++
+	}); err != nil {
+		t.Fatalf("AtomicCopyWithProgress failed: %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected one completion report for an empty file, got %d", calls)
+	}
+}
+
+func TestAtomicCopyVerified(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	dest := filepath.Join(dir, "vault", "score.dat")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("score data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AtomicCopyVerified(src, dest, nil); err != nil {
+		t.Fatalf("AtomicCopyVerified failed: %v", err)
+	}
+
+	srcHash, _ := CalculateFileHash(src)
+	destHash, err := CalculateFileHash(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srcHash != destHash {
+		t.Error("Expected destination hash to match source")
+	}
+
+	// No temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the destination file, got %d entries", len(entries))
+	}
+}
+
+func TestStageCopyVerified(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	dest := filepath.Join(dir, "vault", "score.dat")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("new score"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest, []byte("old score"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Discarding leaves dest and its directory as they were
+	staged, err := StageCopyVerified(src, dest, nil)
+	if err != nil {
+		t.Fatalf("StageCopyVerified failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "old score" {
+		t.Errorf("Expected dest to be untouched before Commit, got %q", data)
+	}
+	staged.Discard()
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 {
+		t.Errorf("Expected the staged copy to be removed, got %d entries", len(entries))
+	}
+
+	// Committing replaces dest
+	staged, err = StageCopyVerified(src, dest, nil)
+	if err != nil {
+		t.Fatalf("StageCopyVerified failed: %v", err)
+	}
+	if err := staged.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "new score" {
+		t.Errorf("Expected dest to hold the new score after Commit, got %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 {
+		t.Errorf("Expected only the destination file, got %d entries", len(entries))
+	}
+}
+
+func TestAtomicCopy_PreservesModTime(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	dest := filepath.Join(dir, "copy.dat")
+	if err := os.WriteFile(src, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2025, 12, 1, 12, 0, 0, 250_000_000, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AtomicCopy(src, dest); err != nil {
+		t.Fatalf("AtomicCopy failed: %v", err)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(srcInfo.ModTime()) {
+		t.Errorf("Expected mtime %v, got %v", srcInfo.ModTime(), info.ModTime())
+	}
+}
+
+func TestFilesEqual(t *testing.T) {
+	dir := t.TempDir()
+	large := bytes.Repeat([]byte("0123456789abcdef"), 10000) // spans several read chunks
+	largeEnd := append([]byte(nil), large...)
+	largeEnd[len(largeEnd)-1] = 'x'
+
+	tests := []struct {
+		name string
+		a, b []byte
+		want bool
+	}{
+		{"identical", []byte("save"), []byte("save"), true},
+		{"both empty", nil, nil, true},
+		{"different size", []byte("save"), []byte("save2"), false},
+		{"same size, different bytes", []byte("save"), []byte("savf"), false},
+		{"large identical", large, large, true},
+		{"large, last byte differs", large, largeEnd, false},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := filepath.Join(dir, fmt.Sprintf("a%d.dat", i))
+			b := filepath.Join(dir, fmt.Sprintf("b%d.dat", i))
+			if err := os.WriteFile(a, tt.a, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(b, tt.b, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := FilesEqual(a, b)
+			if err != nil {
+				t.Fatalf("FilesEqual failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := FilesEqual(filepath.Join(dir, "missing.dat"), filepath.Join(dir, "a0.dat")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestThrottledWriter(t *testing.T) {
+	var buf bytes.Buffer
+	clock := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	var writes int
+
+	w := &throttledWriter{
+		w:     writerFunc(func(b []byte) (int, error) { writes++; return buf.Write(b) }),
+		rate:  1000,
+		start: clock,
+		now:   func() time.Time { return clock },
+		sleep: func(d time.Duration) { slept += d; clock = clock.Add(d) },
+	}
+
+	data := bytes.Repeat([]byte("x"), 3000)
+	n, err := w.Write(data)
+	if err != nil || n != len(data) {
+		t.Fatalf("Write returned %d, %v", n, err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("Written data does not match")
+	}
+
+	// 3000 bytes at 1000 bytes/s take 3s, written in chunks of a quarter second
+	if slept != 3*time.Second {
+		t.Errorf("Expected 3s of sleep, got %v", slept)
+	}
+	if writes != 12 {
+		t.Errorf("Expected 12 chunks, got %d", writes)
+	}
+}
+
+func TestAtomicCopy_Throttled(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	dest := filepath.Join(dir, "copy.dat")
+	data := bytes.Repeat([]byte("save"), 1000)
+	if err := os.WriteFile(src, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	CopyRateLimit = 1 << 30
+	defer func() { CopyRateLimit = 0 }()
+
+	if err := AtomicCopy(src, dest); err != nil {
+		t.Fatalf("AtomicCopy failed: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Copied data does not match")
+	}
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) { return f(b) }
+
+func TestCleanupTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 4, 11, 12, 0, 0, 0, time.UTC)
+	stale := now.Add(-time.Hour)
+
+	files := map[string]time.Time{
+		"paths.json":                    stale,
+		"paths.json.tmp":                stale,                    // interrupted config save
+		"th08/.tmp-123":                 stale,                    // interrupted copy
+		"th08/_history/main/.tmp-456":   stale,                    // interrupted backup
+		"th08/.tmp-789":                 now.Add(-time.Minute),    // copy may still be running
+		"th08/score.dat":                now.Add(-48 * time.Hour), // not a temp file
+		"th08/dir/cache.tmp":            stale,                    // no cache beside it: a synced game file
+		"th08/_history/main/a.dat.tmp1": stale,                    // suffix does not match
+	}
+	for name, mtime := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := cleanupTempFiles(dir, TempFileMaxAge, now)
+	if err != nil {
+		t.Fatalf("cleanupTempFiles failed: %v", err)
+	}
+	wantRemoved := map[string]bool{
+		"paths.json.tmp":              true,
+		"th08/.tmp-123":               true,
+		"th08/_history/main/.tmp-456": true,
+	}
+	if len(removed) != len(wantRemoved) {
+		t.Errorf("Expected %d removed files, got %v", len(wantRemoved), removed)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if exists := err == nil; exists == wantRemoved[name] {
+			t.Errorf("%s: exists=%v, want %v", name, exists, !wantRemoved[name])
+		}
+	}
+
+	removed, err = cleanupTempFiles(filepath.Join(dir, "missing"), TempFileMaxAge, now)
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Expected nothing to do for a missing directory, got %v, %v", removed, err)
+	}
+}
+
+func TestIsDirWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	if !IsDirWritable(dir) {
+		t.Errorf("Expected %s to be writable", dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected the probe file to be removed, got %v, %v", entries, err)
+	}
+
+	if IsDirWritable(filepath.Join(dir, "missing")) {
+		t.Error("Expected a missing directory to not be writable")
+	}
+}
+
+func TestNormalizePath_Windows(t *testing.T) {
+	same := [][]string{
+		// Mixed separators
+		{`C:\Games\th08\score.dat`, `C:/Games/th08/score.dat`, `C:\Games/th08\score.dat`},
+		// Mixed case, including the drive letter
+		{`C:\Games\th08\score.dat`, `c:\games\TH08\Score.DAT`},
+		// Redundant separators and dot segments
+		{`C:\Games\th08\score.dat`, `C:\Games\\th08\.\score.dat`, `C:\Games\th06\..\th08\score.dat`},
+		// UNC paths keep their leading double backslash
+		{`\\NAS\saves\th08\score.dat`, `//nas/Saves/th08/score.dat`},
+	}
+	for _, group := range same {
+		want := normalizePath(group[0], true)
+		for _, p := range group[1:] {
+			if got := normalizePath(p, true); got != want {
+				t.Errorf("normalizePath(%q) = %q, want %q (same as %q)", p, got, want, group[0])
+			}
+		}
+	}
+
+	if got := normalizePath(`\\NAS\saves\th08\score.dat`, true); got != `\\nas\saves\th08\score.dat` {
+		t.Errorf("Unexpected UNC form: %q", got)
+	}
+	if normalizePath(`C:\Games\th08\score.dat`, true) == normalizePath(`D:\Games\th08\score.dat`, true) {
+		t.Error("Expected paths on different drives to differ")
+	}
+
+	// Dot segments never climb out of a share
+	if got := normalizePath(`\\nas\touhou\..\..\vault`, true); got != `\\nas\touhou\vault` {
+		t.Errorf("Expected .. to stop at the share, got %q", got)
+	}
+	if got := normalizePath(`\\nas\touhou`, true); got != `\\nas\touhou` {
+		t.Errorf("Unexpected share root form: %q", got)
+	}
+}
+
+func TestSafeRename_AcrossVolumes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "paths.json.tmp")
+	dest := filepath.Join(dir, "paths.json")
+	if err := os.WriteFile(src, []byte(`{"paths":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errorNotSameDevice}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	if err := SafeRename(src, dest); err != nil {
+		t.Fatalf("SafeRename failed: %v", err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || string(data) != `{"paths":{}}` {
+		t.Errorf("Expected dest to be created by the copy, got %q, %v", data, err)
+	}
+	if exists, _ := FileExists(src); exists {
+		t.Error("Expected src to be removed after the copy")
+	}
+
+	if err := SafeRename(src, dest); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing src to fail with not-exist, got %v", err)
+	}
+}
+
+func TestStagedCopy_CommitAcrossVolumes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "score.dat")
+	dest := filepath.Join(dir, "vault.dat")
+	if err := os.WriteFile(src, []byte("new save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest, []byte("old save data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errorNotSameDevice}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	if err := AtomicCopy(src, dest); err != nil {
+		t.Fatalf("AtomicCopy failed: %v", err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil || string(data) != "new save" {
+		t.Errorf("Expected the copy fallback to replace dest, got %q, %v", data, err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(temps) != 0 {
+		t.Errorf("Expected the temp file to be removed, got %v", temps)
+	}
+
+	// Other rename errors are not worked around
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrPermission}
+	}
+	if err := AtomicCopy(src, dest); err == nil {
+		t.Error("Expected a failed rename to fail the copy")
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("THLOCALSYNC_TEST_SAVES", dir)
+
+	want := NormalizePath(filepath.Join(dir, "th08", "score.dat"))
+	for _, p := range []string{
+		"th08/score.dat", // relative to the working directory
+		"./th08//score.dat",
+		"${THLOCALSYNC_TEST_SAVES}/th08/score.dat",
+	} {
+		if got := NormalizePath(p); got != want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "saves", "score.dat")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte("score"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "game")
+	if err := os.Symlink(filepath.Join(dir, "saves"), link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	want, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ResolvePath(filepath.Join(link, "score.dat")); got != want {
+		t.Errorf("Expected the link to resolve to %q, got %q", want, got)
+	}
+
+	missing := filepath.Join(dir, "missing", "score.dat")
+	if got := ResolvePath(missing); got != missing {
+		t.Errorf("Expected a missing path to be unchanged, got %q", got)
+	}
+}
+
+func TestVirtualStorePath(t *testing.T) {
+	localAppData := `C:\Users\reimu\AppData\Local`
+	virtualized := []string{`C:\Program Files`, `C:\Program Files (x86)`, ""}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\Program Files (x86)\東方永夜抄\score.dat`, `C:\Users\reimu\AppData\Local\VirtualStore\Program Files (x86)\東方永夜抄\score.dat`},
+		{`c:/program files/th06/score.dat`, `C:\Users\reimu\AppData\Local\VirtualStore\program files\th06\score.dat`},
+		{`C:\Games\th08\score.dat`, ""},
+		{`C:\Program Files Extra\th08\score.dat`, ""},
+		{`D:\Program Files\th08\score.dat`, ""},
+	}
+	for _, tt := range tests {
+		if got := virtualStorePath(tt.path, localAppData, virtualized); got != tt.want {
+			t.Errorf("virtualStorePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if got := virtualStorePath(tests[0].path, "", virtualized); got != "" {
+		t.Errorf("Expected no VirtualStore without LOCALAPPDATA, got %q", got)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := `C:\Users\reimu`
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`~`, `C:\Users\reimu`},
+		{`~\Documents\score.dat`, `C:\Users\reimu\Documents\score.dat`},
+		{`~/saves/score.dat`, `C:\Users\reimu/saves/score.dat`},
+		{`~marisa\score.dat`, `~marisa\score.dat`},
+		{`D:\~\score.dat`, `D:\~\score.dat`},
+	}
+	for _, tt := range tests {
+		if got := expandHome(tt.path, home); got != tt.want {
+			t.Errorf("expandHome(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if got := expandHome(`~\score.dat`, ""); got != `~\score.dat` {
+		t.Errorf("Expected an unknown home to leave the path unchanged, got %q", got)
+	}
+}
+
+func TestExpandEnvPath(t *testing.T) {
+	t.Setenv("THLOCALSYNC_TEST_SAVES", "/saves")
+	t.Setenv("THLOCALSYNC_TEST_DOLLAR", "/saves/$THLOCALSYNC_TEST_SAVES")
+	t.Setenv("THLOCALSYNC_TEST_PERCENT", "/saves/%THLOCALSYNC_TEST_SAVES%")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`%THLOCALSYNC_TEST_SAVES%/th08/score.dat`, "/saves/th08/score.dat"},
+		{`${THLOCALSYNC_TEST_SAVES}/th08/score.dat`, "/saves/th08/score.dat"},
+		{`$THLOCALSYNC_TEST_SAVES/th08/score.dat`, "/saves/th08/score.dat"},
+		{`%THLOCALSYNC_TEST_UNDEFINED%/score.dat`, "%THLOCALSYNC_TEST_UNDEFINED%/score.dat"},
+		// Values are not expanded again
+		{`%THLOCALSYNC_TEST_DOLLAR%/score.dat`, "/saves/$THLOCALSYNC_TEST_SAVES/score.dat"},
+		{`$THLOCALSYNC_TEST_PERCENT/score.dat`, "/saves/%THLOCALSYNC_TEST_SAVES%/score.dat"},
+		{`D:\Games\th08\score.dat`, `D:\Games\th08\score.dat`},
+	}
+	for _, tt := range tests {
+		if got := ExpandEnvPath(tt.path); got != tt.want {
+			t.Errorf("ExpandEnvPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWindowsEnv(t *testing.T) {
+	env := map[string]string{
+		"APPDATA":           `C:\Users\reimu\AppData\Roaming`,
+		"LOCALAPPDATA":      `C:\Users\reimu\AppData\Local`,
+		"ProgramFiles(x86)": `C:\Program Files (x86)`,
+		"EMPTY":             "",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`%APPDATA%\ShanghaiAlice\th10\scoreth10.dat`, `C:\Users\reimu\AppData\Roaming\ShanghaiAlice\th10\scoreth10.dat`},
+		{`%LOCALAPPDATA%\VirtualStore\Program Files\th08\score.dat`, `C:\Users\reimu\AppData\Local\VirtualStore\Program Files\th08\score.dat`},
+		{`%ProgramFiles(x86)%\th06\score.dat`, `C:\Program Files (x86)\th06\score.dat`},
+		{`%EMPTY%score.dat`, `score.dat`},
+		// Undefined variables are left as written
+		{`%UNDEFINED%\score.dat`, `%UNDEFINED%\score.dat`},
+		{`%UNDEFINED%\%APPDATA%`, `%UNDEFINED%\C:\Users\reimu\AppData\Roaming`},
+		// Literal percent signs
+		{`D:\Games\100%\score.dat`, `D:\Games\100%\score.dat`},
+		{`D:\50% off\%APPDATA%`, `D:\50% off\C:\Users\reimu\AppData\Roaming`},
+		{`D:\100%%APPDATA%`, `D:\100%C:\Users\reimu\AppData\Roaming`},
+		{`%%`, `%%`},
+		{`D:\a%b\c%d`, `D:\a%b\c%d`},
+		{`D:\Games\th08\score.dat`, `D:\Games\th08\score.dat`},
+	}
+	for _, tt := range tests {
+		if got := expandWindowsEnv(tt.path, lookup, func(s string) string { return s }); got != tt.want {
+			t.Errorf("expandWindowsEnv(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	// Only the text around the references goes through literal
+	got := expandWindowsEnv(`d:\%APPDATA%\th10`, lookup, strings.ToUpper)
+	if want := `D:\C:\Users\reimu\AppData\Roaming\TH10`; got != want {
+		t.Errorf("expandWindowsEnv with literal = %q, want %q", got, want)
+	}
+}
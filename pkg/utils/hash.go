@@ -5,33 +5,131 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
-	"os"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/blake3"
+)
+
+// Hash algorithms for change detection, selected by rules.json hash_algo.
+const (
+	HashSHA256 = "sha256"
+	HashXXHash = "xxhash" // XXH64, non-cryptographic
+	HashBLAKE3 = "blake3"
 )
 
-// CalculateFileHash computes the SHA256 hash of a file.
-// Returns the hex-encoded hash string, or an error if the file cannot be read.
+// HashAlgo is the algorithm used by CalculateFileHash and CalculateReaderHash.
+// Set from rules.json hash_algo.
+//
+// SHA256 hashes are plain hex strings, as they always were. Other algorithms prefix
+// the hex digest with their name ("xxhash:..."), so a stored hash records how it was
+// made and hashes from a vault written with another setting are never compared blindly.
+var HashAlgo = HashSHA256
+
+// ValidateHashAlgo checks that algo names a supported hash algorithm. Empty means SHA256.
+func ValidateHashAlgo(algo string) error {
+	switch algo {
+	case "", HashSHA256, HashXXHash, HashBLAKE3:
+		return nil
+	}
+	return fmt.Errorf("unknown hash algorithm %q (use %s, %s or %s)", algo, HashSHA256, HashXXHash, HashBLAKE3)
+}
+
+// newHasher returns a hash.Hash for algo, falling back to SHA256 for unknown names.
+func newHasher(algo string) hash.Hash {
+	switch algo {
+	case HashXXHash:
+		return xxhash.New()
+	case HashBLAKE3:
+		return blake3.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// formatHash encodes a digest made with algo in the stored hash format.
+func formatHash(algo string, sum []byte) string {
+	digest := hex.EncodeToString(sum)
+	if algo == "" || algo == HashSHA256 {
+		return digest
+	}
+	return algo + ":" + digest
+}
+
+// HashAlgoOf returns the algorithm a stored hash was made with.
+func HashAlgoOf(hash string) string {
+	if algo, _, ok := strings.Cut(hash, ":"); ok {
+		return algo
+	}
+	return HashSHA256
+}
+
+// HashHex returns the hex digest of a stored hash without its algorithm prefix, for display.
+func HashHex(hash string) string {
+	if _, digest, ok := strings.Cut(hash, ":"); ok {
+		return digest
+	}
+	return hash
+}
+
+// CalculateFileHash computes the hash of a file with HashAlgo.
+// Returns the hash string, or an error if the file cannot be read.
 func CalculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	return CalculateFileHashWith(filePath, HashAlgo)
+}
+
+// CalculateFileHashWith computes the hash of a file with the given algorithm.
+// Encrypted vault files are hashed by their contents, so they compare with the
+// unencrypted copies elsewhere.
+func CalculateFileHashWith(filePath string, algo string) (string, error) {
+	contents, _, closeFn, err := openContents(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open file for hashing: %w", err)
 	}
-	defer file.Close()
+	defer closeFn()
 
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, file); err != nil {
+	hasher := newHasher(algo)
+	if _, err := io.Copy(hasher, contents); err != nil {
 		return "", fmt.Errorf("failed to read file for hashing: %w", err)
 	}
 
-	hashBytes := hasher.Sum(nil)
-	return hex.EncodeToString(hashBytes), nil
+	return formatHash(algo, hasher.Sum(nil)), nil
+}
+
+// RehashLike returns hash, the current hash of the file at path, made comparable to
+// reference: if reference was made with another algorithm, the file is rehashed with
+// that one. If rehashing fails, hash is returned unchanged.
+func RehashLike(path, hash, reference string) string {
+	algo := HashAlgoOf(reference)
+	if HashAlgoOf(hash) == algo {
+		return hash
+	}
+	rehashed, err := CalculateFileHashWith(path, algo)
+	if err != nil {
+		return hash
+	}
+	return rehashed
 }
 
 // CalculateStringHash computes the SHA256 hash of a string.
-// Returns the hex-encoded hash string.
+// Returns the hex-encoded hash string. Always SHA256 regardless of HashAlgo,
+// since device IDs are derived from it.
 func CalculateStringHash(data string) string {
 	hasher := sha256.New()
 	hasher.Write([]byte(data))
 	hashBytes := hasher.Sum(nil)
 	return hex.EncodeToString(hashBytes)
 }
+
+// CalculateReaderHash computes the hash of everything read from r with HashAlgo.
+// Used to hash an already-open file without reopening it.
+func CalculateReaderHash(r io.Reader) (string, error) {
+	hasher := newHasher(HashAlgo)
+	if _, err := io.Copy(hasher, r); err != nil {
+		return "", fmt.Errorf("failed to read file for hashing: %w", err)
+	}
+
+	return formatHash(HashAlgo, hasher.Sum(nil)), nil
+}
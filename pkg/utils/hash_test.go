@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCalculateFileHash_Algorithms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "score.dat")
+	if err := os.WriteFile(path, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { HashAlgo = HashSHA256 })
+
+	seen := make(map[string]bool)
+	for _, algo := range []string{HashSHA256, HashXXHash, HashBLAKE3} {
+		HashAlgo = algo
+		hash, err := CalculateFileHash(path)
+		if err != nil {
+			t.Fatalf("%s: CalculateFileHash failed: %v", algo, err)
+		}
+		if got := HashAlgoOf(hash); got != algo {
+			t.Errorf("%s: HashAlgoOf(%q) = %s", algo, hash, got)
+		}
+		if (algo == HashSHA256) == strings.Contains(hash, ":") {
+			t.Errorf("%s: unexpected hash format %q", algo, hash)
+		}
+		readerHash, err := CalculateReaderHash(strings.NewReader("save"))
+		if err != nil || readerHash != hash {
+			t.Errorf("%s: reader hash %q differs from file hash %q (%v)", algo, readerHash, hash, err)
+		}
+		seen[HashHex(hash)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected three different digests, got %v", seen)
+	}
+
+	// Plain SHA256 hashes keep their historical format
+	if got := CalculateStringHash("save"); got != mustHashWith(t, path, HashSHA256) {
+		t.Errorf("Expected SHA256 file and string hashes to agree, got %s", got)
+	}
+}
+
+func TestRehashLike(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "score.dat")
+	if err := os.WriteFile(path, []byte("save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded := mustHashWith(t, path, HashSHA256)
+	current := mustHashWith(t, path, HashXXHash)
+
+	if got := RehashLike(path, current, recorded); got != recorded {
+		t.Errorf("Expected rehash with the recorded algorithm to match, got %s", got)
+	}
+	if got := RehashLike(path, current, "xxhash:0000"); got != current {
+		t.Errorf("Expected same-algorithm hash to be kept, got %s", got)
+	}
+}
+
+func TestValidateHashAlgo(t *testing.T) {
+	for _, algo := range []string{"", HashSHA256, HashXXHash, HashBLAKE3} {
+		if err := ValidateHashAlgo(algo); err != nil {
+			t.Errorf("ValidateHashAlgo(%q) returned error: %v", algo, err)
+		}
+	}
+	if err := ValidateHashAlgo("md5"); err == nil {
+		t.Error("Expected an error for md5")
+	}
+}
+
+func mustHashWith(t *testing.T, path, algo string) string {
+	t.Helper()
+	hash, err := CalculateFileHashWith(path, algo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
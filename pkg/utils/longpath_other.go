@@ -0,0 +1,8 @@
+//go:build !windows
+
+package utils
+
+// longPathName returns path unchanged; short names only exist on Windows.
+func longPathName(path string) string {
+	return path
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package utils
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetLongPathName = kernel32.NewProc("GetLongPathNameW")
+
+// longPathName expands 8.3 short names (e.g. PROGRA~2) in path to their long form.
+// Paths that do not exist are returned unchanged.
+func longPathName(path string) string {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return path
+	}
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	for {
+		n, _, _ := procGetLongPathName.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if n == 0 {
+			return path
+		}
+		if int(n) < len(buf) {
+			return syscall.UTF16ToString(buf[:n])
+		}
+		// Buffer too small: n is the required size
+		buf = make([]uint16, n)
+	}
+}
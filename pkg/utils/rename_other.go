@@ -0,0 +1,18 @@
+//go:build !windows
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is the errno of a rename across file systems
+const errorNotSameDevice = syscall.EXDEV
+
+// isCrossDevice reports whether err is a rename failing because source and destination
+// are on different file systems.
+func isCrossDevice(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && errno == errorNotSameDevice
+}
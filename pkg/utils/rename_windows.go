@@ -0,0 +1,19 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE, returned when a rename would move a
+// file to another drive or network share
+const errorNotSameDevice = syscall.Errno(17)
+
+// isCrossDevice reports whether err is a rename failing because source and destination
+// are on different volumes.
+func isCrossDevice(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && errno == errorNotSameDevice
+}
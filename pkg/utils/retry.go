@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+)
+
+const (
+	// DefaultRetryAttempts is how many times a file operation is tried before a transient error is returned
+	DefaultRetryAttempts = 3
+
+	// DefaultRetryBackoff is the delay before the first retry; it doubles after each retry
+	DefaultRetryBackoff = 200 * time.Millisecond
+)
+
+// RetryPolicy controls how often Retry runs an operation.
+type RetryPolicy struct {
+	Attempts int           // Total attempts, including the first; less than 2 means no retry
+	Backoff  time.Duration // Delay before the first retry, doubled after each retry
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: DefaultRetryAttempts, Backoff: DefaultRetryBackoff}
+}
+
+// Retry runs op until it succeeds, fails with an error that IsTransientError does not
+// accept, or policy.Attempts is used up, and returns op's last error. onRetry, if not
+// nil, is called before each retry with the number of the failed attempt and its error.
+func Retry(policy RetryPolicy, op func() error, onRetry func(attempt int, err error)) error {
+	return retry(policy, op, onRetry, IsTransientError, time.Sleep)
+}
+
+// retry is Retry with the error classification and sleep replaceable in tests.
+func retry(policy RetryPolicy, op func() error, onRetry func(attempt int, err error), transient func(error) bool, sleep func(time.Duration)) error {
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= policy.Attempts || !transient(err) {
+			return err
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+		sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// IsTransientError reports whether err is an I/O error that may succeed when retried,
+// such as a sharing violation or a read error on removable media. Missing files and
+// denied access are never transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return false
+	}
+	return isTransientErrno(err)
+}
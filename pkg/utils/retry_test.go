@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errBusy := errors.New("busy")
+	transient := func(err error) bool { return errors.Is(err, errBusy) }
+	policy := RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		errs      []error // returned by successive attempts; nil after the list runs out
+		wantCalls int
+		wantErr   error
+		wantSleep []time.Duration
+	}{
+		{"Succeeds first time", nil, 1, nil, nil},
+		{"Succeeds on retry", []error{errBusy, errBusy}, 3, nil, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}},
+		{"Gives up after attempts", []error{errBusy, errBusy, errBusy, errBusy}, 3, errBusy, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}},
+		{"Permanent error not retried", []error{fs.ErrPermission}, 1, fs.ErrPermission, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			op := func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			}
+			var retried []int
+			var slept []time.Duration
+
+			err := retry(policy, op, func(attempt int, err error) { retried = append(retried, attempt) }, transient, func(d time.Duration) { slept = append(slept, d) })
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d attempts, got %d", tt.wantCalls, calls)
+			}
+			if len(retried) != len(tt.wantSleep) {
+				t.Errorf("Expected %d retries reported, got %v", len(tt.wantSleep), retried)
+			}
+			if fmt.Sprint(slept) != fmt.Sprint(tt.wantSleep) {
+				t.Errorf("Expected backoff %v, got %v", tt.wantSleep, slept)
+			}
+		})
+	}
+
+	// A single attempt never retries
+	calls := 0
+	retry(RetryPolicy{Attempts: 1}, func() error { calls++; return errBusy }, nil, transient, func(time.Duration) { t.Error("Unexpected sleep") })
+	if calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	busy := syscall.EIO
+	if runtime.GOOS == "windows" {
+		busy = syscall.Errno(32) // ERROR_SHARING_VIOLATION
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Nil", nil, false},
+		{"Transient errno", &os.PathError{Op: "open", Path: "score.dat", Err: busy}, true},
+		{"Wrapped transient errno", fmt.Errorf("failed to copy data: %w", &os.PathError{Op: "read", Path: "score.dat", Err: busy}), true},
+		{"Not found", &os.PathError{Op: "open", Path: "score.dat", Err: fs.ErrNotExist}, false},
+		{"Permission denied", &os.PathError{Op: "open", Path: "score.dat", Err: fs.ErrPermission}, false},
+		{"Other error", errors.New("copy verification failed: hash mismatch"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes seen when removable media is briefly busy or unreliable
+const (
+	errorNotReady         = syscall.Errno(21)   // ERROR_NOT_READY
+	errorCRC              = syscall.Errno(23)   // ERROR_CRC
+	errorGenFailure       = syscall.Errno(31)   // ERROR_GEN_FAILURE
+	errorSharingViolation = syscall.Errno(32)   // ERROR_SHARING_VIOLATION
+	errorLockViolation    = syscall.Errno(33)   // ERROR_LOCK_VIOLATION
+	errorSemTimeout       = syscall.Errno(121)  // ERROR_SEM_TIMEOUT
+	errorIODevice         = syscall.Errno(1117) // ERROR_IO_DEVICE
+)
+
+// isTransientErrno reports whether err wraps a Windows error code worth retrying.
+func isTransientErrno(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case errorNotReady, errorCRC, errorGenFailure, errorSharingViolation, errorLockViolation, errorSemTimeout, errorIODevice:
+		return true
+	}
+	return false
+}
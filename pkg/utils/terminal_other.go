@@ -0,0 +1,15 @@
+//go:build !windows
+
+package utils
+
+import "os"
+
+// IsInteractive reports whether stdin is a terminal that a user can type into.
+// Pipes and redirected files are not; character devices such as /dev/null still count.
+func IsInteractive() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsInteractive reports whether stdin is a console that a user can type into.
+// Pipes, redirected files and NUL (which also reports as a character device) are not.
+func IsInteractive() bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(os.Stdin.Fd()), &mode) == nil
+}
@@ -1,32 +1,80 @@
 package utils
 
 import (
-	"math"
+	"fmt"
 	"time"
 )
 
 const (
-	// TimeDriftTolerance is the maximum time difference (in seconds) to consider two timestamps as equal.
+	// DefaultTimeDriftTolerance is the default maximum time difference (in seconds) to consider two timestamps as equal.
 	// This accounts for filesystem timestamp precision and minor clock drift.
-	TimeDriftTolerance = 3
+	DefaultTimeDriftTolerance = 3
 )
 
 // TimeWithinDrift checks if two timestamps are within the drift tolerance.
-// Returns true if the absolute difference is <= TimeDriftTolerance seconds.
-func TimeWithinDrift(t1, t2 time.Time) bool {
-	diff := math.Abs(float64(t1.Unix() - t2.Unix()))
-	return diff <= TimeDriftTolerance
+// Returns true if the absolute difference is <= tolerance seconds.
+// The difference keeps sub-second precision, matching IsNewerThan.
+func TimeWithinDrift(t1, t2 time.Time, tolerance int) bool {
+	diff := t1.Sub(t2)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= time.Duration(tolerance)*time.Second
 }
 
-// TimeDiffSeconds returns the difference in seconds between t1 and t2 (t1 - t2).
+// TimeDiffSeconds returns the difference in seconds between t1 and t2 (t1 - t2), for display.
 // Positive value means t1 is newer, negative means t2 is newer.
 func TimeDiffSeconds(t1, t2 time.Time) int64 {
 	return t1.Unix() - t2.Unix()
 }
 
 // IsNewerThan checks if t1 is definitively newer than t2, accounting for drift tolerance.
-// Returns true only if t1 is more than TimeDriftTolerance seconds newer than t2.
-func IsNewerThan(t1, t2 time.Time) bool {
-	diff := TimeDiffSeconds(t1, t2)
-	return diff > TimeDriftTolerance
+// Returns true only if t1 is more than tolerance seconds newer than t2, so exactly one of
+// TimeWithinDrift(t1, t2), IsNewerThan(t1, t2) and IsNewerThan(t2, t1) holds.
+func IsNewerThan(t1, t2 time.Time, tolerance int) bool {
+	return t1.Sub(t2) > time.Duration(tolerance)*time.Second
+}
+
+// HumanizeTime describes t relative to now, e.g. "just now", "2 hours ago" or "in 5 minutes".
+// Times more than a year away are counted in years; a zero time is "never".
+func HumanizeTime(t time.Time) string {
+	return humanizeTime(t, time.Now())
+}
+
+func humanizeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	diff := now.Sub(t)
+	future := diff < 0
+	if future {
+		diff = -diff
+	}
+	if diff < time.Minute {
+		return "just now"
+	}
+
+	var n int
+	var unit string
+	switch {
+	case diff < time.Hour:
+		n, unit = int(diff/time.Minute), "minute"
+	case diff < 24*time.Hour:
+		n, unit = int(diff/time.Hour), "hour"
+	case diff < 30*24*time.Hour:
+		n, unit = int(diff/(24*time.Hour)), "day"
+	case diff < 365*24*time.Hour:
+		n, unit = int(diff/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int(diff/(365*24*time.Hour)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
 }
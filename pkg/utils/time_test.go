@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeComparison_SubSecond(t *testing.T) {
+	base := time.Date(2025, 12, 1, 12, 0, 0, 900_000_000, time.UTC)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		within bool
+		newer  bool
+	}{
+		{"same", 0, true, false},
+		{"exactly at tolerance", 3 * time.Second, true, false},
+		{"over tolerance", 3*time.Second + 200*time.Millisecond, false, true},
+		// Whole-second truncation sees 12:00:00 vs 12:00:03 here and would call them equal
+		{"over tolerance within the same second", 3*time.Second + 50*time.Millisecond, false, true},
+	}
+	for _, tt := range tests {
+		later := base.Add(tt.offset)
+		if got := TimeWithinDrift(later, base, 3); got != tt.within {
+			t.Errorf("%s: TimeWithinDrift = %v, want %v", tt.name, got, tt.within)
+		}
+		if got := IsNewerThan(later, base, 3); got != tt.newer {
+			t.Errorf("%s: IsNewerThan = %v, want %v", tt.name, got, tt.newer)
+		}
+		if IsNewerThan(base, later, 3) {
+			t.Errorf("%s: expected the earlier time not to be newer", tt.name)
+		}
+	}
+}
+
+func TestHumanizeTime(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Time{}, "never"},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(30 * time.Second), "just now"},
+		{now.Add(-time.Minute), "1 minute ago"},
+		{now.Add(-59 * time.Minute), "59 minutes ago"},
+		{now.Add(-2*time.Hour - 10*time.Minute), "2 hours ago"},
+		{now.Add(-36 * time.Hour), "1 day ago"},
+		{now.Add(-45 * 24 * time.Hour), "1 month ago"},
+		{now.Add(-800 * 24 * time.Hour), "2 years ago"},
+		{now.Add(5 * time.Minute), "in 5 minutes"},
+	}
+
+	for _, tt := range tests {
+		if got := humanizeTime(tt.t, now); got != tt.want {
+			t.Errorf("humanizeTime(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package vaultcrypt
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyFile is the file in the vault directory holding the salt and KDF parameters of
+// the vault's key. It holds nothing secret, but without it the key cannot be derived:
+// losing it loses the vault, so it is never rewritten once created.
+const KeyFile = ".encryption.json"
+
+// scrypt parameters of new vaults: about 100ms and 32MB per unlock
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// keyCheck is encrypted into the key file, so a wrong passphrase is told apart from a
+// corrupted vault file
+const keyCheck = "thlocalsync"
+
+// keyFile is the content of KeyFile.
+type keyFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Check   []byte `json:"check"`
+}
+
+// Unlock derives the vault's key from passphrase with the salt and parameters in the
+// KeyFile of vaultDir. A vault without a KeyFile gets a new one, with a fresh salt;
+// created reports that. A passphrase that does not match fails with ErrWrongPassphrase.
+func Unlock(vaultDir, passphrase string) (key *Key, created bool, err error) {
+	if passphrase == "" {
+		return nil, false, errors.New("empty passphrase")
+	}
+
+	path := filepath.Join(vaultDir, KeyFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := createKeyFile(path, passphrase)
+		return key, err == nil, err
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", KeyFile, err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, false, fmt.Errorf("failed to parse %s: %w", KeyFile, err)
+	}
+	if kf.Version != 1 || kf.KDF != "scrypt" {
+		return nil, false, fmt.Errorf("unsupported %s (version %d, kdf %q)", KeyFile, kf.Version, kf.KDF)
+	}
+
+	key, err = deriveKey(passphrase, kf.Salt, kf.N, kf.R, kf.P)
+	if err != nil {
+		return nil, false, err
+	}
+	if check, err := key.Decrypt(kf.Check); err != nil || string(check) != keyCheck {
+		return nil, false, ErrWrongPassphrase
+	}
+	return key, false, nil
+}
+
+// createKeyFile derives a key from passphrase with a new salt and writes KeyFile at
+// path. It is created exclusively, so two devices setting up the same vault at once
+// cannot end up with different salts.
+func createKeyFile(path, passphrase string) (*Key, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := deriveKey(passphrase, salt, scryptN, scryptR, scryptP)
+	if err != nil {
+		return nil, err
+	}
+	check, err := key.Encrypt([]byte(keyCheck))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(keyFile{
+		Version: 1,
+		KDF:     "scrypt",
+		Salt:    salt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Check:   check,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create vault directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", KeyFile, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write %s: %w", KeyFile, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write %s: %w", KeyFile, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", KeyFile, err)
+	}
+	return key, nil
+}
+
+// deriveKey derives the AES-256 key from passphrase with scrypt.
+func deriveKey(passphrase string, salt []byte, n, r, p int) (*Key, error) {
+	raw, err := scrypt.Key([]byte(passphrase), salt, n, r, p, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return newKey(raw)
+}
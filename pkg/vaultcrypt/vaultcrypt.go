@@ -0,0 +1,207 @@
+// Package vaultcrypt implements the optional encryption at rest of the vault.
+//
+// With rules.json encrypt_vault, every file written under the vault directory (save
+// files, set files, replays, history and quarantine copies) is stored AES-256-GCM
+// encrypted with a key derived by scrypt from a passphrase. The salt and KDF parameters
+// live in the vault's KeyFile, so every device derives the same key from the same
+// passphrase. Encrypted files start with Magic; files without it are read as they are,
+// so a vault written before encryption was enabled keeps working and its files are
+// encrypted as they are next written.
+//
+// The whole file is sealed at once: save files are small, and a single GCM tag
+// authenticates the file as a whole.
+package vaultcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Magic starts every encrypted file
+	Magic = "THLSENC1"
+
+	// EnvPassphrase is the environment variable holding the passphrase, for
+	// unattended runs where it cannot be prompted for
+	EnvPassphrase = "THLOCALSYNC_PASSPHRASE"
+
+	nonceSize = 12
+	tagSize   = 16
+
+	// Overhead is how much larger an encrypted file is than its contents
+	Overhead = len(Magic) + nonceSize + tagSize
+)
+
+var (
+	// ErrLocked is returned when an encrypted file is read without the key.
+	ErrLocked = errors.New("file is encrypted; set encrypt_vault in rules.json and provide the passphrase")
+
+	// ErrWrongPassphrase is returned by Unlock when the passphrase does not match the vault's key.
+	ErrWrongPassphrase = errors.New("wrong passphrase for the vault")
+)
+
+// Key encrypts and decrypts vault files.
+type Key struct {
+	aead cipher.AEAD
+}
+
+// newKey returns the Key for a 32-byte AES-256 key.
+func newKey(raw []byte) (*Key, error) {
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// Encrypt seals plaintext into the encrypted file format: Magic, a random nonce and
+// the GCM ciphertext with its tag.
+func (k *Key) Encrypt(plaintext []byte) ([]byte, error) {
+	out := make([]byte, len(Magic)+nonceSize, len(plaintext)+Overhead)
+	copy(out, Magic)
+	nonce := out[len(Magic):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return k.aead.Seal(out, nonce, plaintext, []byte(Magic)), nil
+}
+
+// Decrypt opens data written by Encrypt. A wrong key and a tampered or truncated file
+// fail alike.
+func (k *Key) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) || len(data) < Overhead {
+		return nil, errors.New("not an encrypted vault file")
+	}
+	nonce := data[len(Magic) : len(Magic)+nonceSize]
+	plaintext, err := k.aead.Open(nil, nonce, data[len(Magic)+nonceSize:], []byte(Magic))
+	if err != nil {
+		return nil, errors.New("decryption failed (wrong passphrase or corrupted file)")
+	}
+	return plaintext, nil
+}
+
+// IsEncrypted reports whether data, or at least its first len(Magic) bytes, is an
+// encrypted file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// The key and vault directory of this run, set by Enable. Process-wide like the other
+// rules.json settings.
+var (
+	activeKey *Key
+	vaultRoot string
+)
+
+// Enable makes Covers report the files under vaultDir, and lets encrypted files be
+// read with key.
+func Enable(key *Key, vaultDir string) {
+	activeKey = key
+	vaultRoot = filepath.Clean(vaultDir)
+}
+
+// Disable turns encryption off again. Encrypted files can no longer be read.
+func Disable() {
+	activeKey = nil
+	vaultRoot = ""
+}
+
+// Enabled reports whether encryption is on.
+func Enabled() bool {
+	return activeKey != nil
+}
+
+// Covers reports whether a file written to path must be encrypted: encryption is on
+// and path lies in the vault directory.
+func Covers(path string) bool {
+	if activeKey == nil {
+		return false
+	}
+	rel, err := filepath.Rel(vaultRoot, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Sniff reports whether r holds an encrypted file and rewinds it.
+func Sniff(r io.ReadSeeker) (bool, error) {
+	header := make([]byte, len(Magic))
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return IsEncrypted(header[:n]), nil
+}
+
+// NewReader returns a reader of the contents of r: r itself, rewound, if it is not
+// encrypted, otherwise its decrypted contents. size is the size of r and the returned
+// size that of the contents. Reading an encrypted file fails with ErrLocked while
+// encryption is off.
+func NewReader(r io.ReadSeeker, size int64) (io.Reader, int64, error) {
+	encrypted, err := Sniff(r)
+	if err != nil || !encrypted {
+		return r, size, err
+	}
+	if activeKey == nil {
+		return nil, 0, ErrLocked
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	plaintext, err := activeKey.Decrypt(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(plaintext), int64(len(plaintext)), nil
+}
+
+// ContentSize returns the size of the contents of a file of size bytes.
+func ContentSize(size int64, encrypted bool) int64 {
+	if !encrypted {
+		return size
+	}
+	return max(size-int64(Overhead), 0)
+}
+
+// writer buffers the contents written to it and writes them encrypted on Close.
+type writer struct {
+	w   io.Writer
+	key *Key
+	buf bytes.Buffer
+}
+
+// NewWriter returns a writer that encrypts what is written to it into w once it is
+// closed. Only valid while encryption is on.
+func NewWriter(w io.Writer) io.WriteCloser {
+	return &writer{w: w, key: activeKey}
+}
+
+func (e *writer) Write(p []byte) (int, error) {
+	return e.buf.Write(p)
+}
+
+func (e *writer) Close() error {
+	if e.key == nil {
+		return errors.New("vault encryption is not enabled")
+	}
+	sealed, err := e.key.Encrypt(e.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(sealed)
+	return err
+}
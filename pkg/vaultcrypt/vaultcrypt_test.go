@@ -0,0 +1,115 @@
+package vaultcrypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnlock(t *testing.T) {
+	vaultDir := filepath.Join(t.TempDir(), "vault")
+
+	key, created, err := Unlock(vaultDir, "correct horse")
+	if err != nil || !created {
+		t.Fatalf("Expected a new key file, got created=%v, %v", created, err)
+	}
+	sealed, err := key.Encrypt([]byte("score data"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if len(sealed) != len("score data")+Overhead || !IsEncrypted(sealed) {
+		t.Fatalf("Unexpected encrypted form: %q", sealed)
+	}
+
+	// Another device derives the same key from the same passphrase
+	again, created, err := Unlock(vaultDir, "correct horse")
+	if err != nil || created {
+		t.Fatalf("Expected the existing key file, got created=%v, %v", created, err)
+	}
+	plaintext, err := again.Decrypt(sealed)
+	if err != nil || string(plaintext) != "score data" {
+		t.Errorf("Expected the contents back, got %q, %v", plaintext, err)
+	}
+
+	if _, _, err := Unlock(vaultDir, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := again.Decrypt(sealed); err == nil {
+		t.Error("Expected a tampered file to fail")
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	t.Cleanup(Disable)
+	vaultDir := t.TempDir()
+	key, _, err := Unlock(vaultDir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.Encrypt([]byte("score data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unencrypted files read as they are
+	r, size, err := NewReader(bytes.NewReader([]byte("plain")), 5)
+	if data, _ := io.ReadAll(r); err != nil || string(data) != "plain" || size != 5 {
+		t.Errorf("Expected the plain file unchanged, got %q (%d), %v", data, size, err)
+	}
+
+	if _, _, err := NewReader(bytes.NewReader(sealed), int64(len(sealed))); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked while encryption is off, got %v", err)
+	}
+
+	Enable(key, vaultDir)
+	r, size, err = NewReader(bytes.NewReader(sealed), int64(len(sealed)))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "score data" || size != int64(len("score data")) {
+		t.Errorf("Expected the decrypted contents, got %q (%d)", data, size)
+	}
+	if ContentSize(int64(len(sealed)), true) != size {
+		t.Errorf("Expected ContentSize to match the decrypted size %d", size)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Write([]byte("written"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if plaintext, err := key.Decrypt(buf.Bytes()); err != nil || string(plaintext) != "written" {
+		t.Errorf("Expected NewWriter to encrypt, got %q, %v", plaintext, err)
+	}
+}
+
+func TestCovers(t *testing.T) {
+	t.Cleanup(Disable)
+	vaultDir := t.TempDir()
+	inVault := filepath.Join(vaultDir, "th08", "score.dat")
+
+	if Covers(inVault) {
+		t.Error("Expected nothing to be covered while encryption is off")
+	}
+
+	key, _, err := Unlock(vaultDir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Enable(key, vaultDir)
+	if !Covers(inVault) {
+		t.Errorf("Expected %s to be covered", inVault)
+	}
+	if Covers(filepath.Join(filepath.Dir(vaultDir), "game", "score.dat")) || Covers(vaultDir+"-other") {
+		t.Error("Expected files outside the vault not to be covered")
+	}
+	if _, err := os.Stat(filepath.Join(vaultDir, KeyFile)); err != nil {
+		t.Errorf("Expected the key file in the vault: %v", err)
+	}
+}